@@ -22,14 +22,17 @@ import (
 	"github.com/gogrlx/grlx/v2/internal/certs"
 	"github.com/gogrlx/grlx/v2/internal/config"
 	"github.com/gogrlx/grlx/v2/internal/cook"
+	"github.com/gogrlx/grlx/v2/internal/drift"
 	"github.com/gogrlx/grlx/v2/internal/facts"
 	"github.com/gogrlx/grlx/v2/internal/ingredients/cmd"
+	grlxfile "github.com/gogrlx/grlx/v2/internal/ingredients/file/grlx"
 	"github.com/gogrlx/grlx/v2/internal/ingredients/test"
 	"github.com/gogrlx/grlx/v2/internal/jobs"
 	"github.com/gogrlx/grlx/v2/internal/natsapi"
 	"github.com/gogrlx/grlx/v2/internal/pki"
 	"github.com/gogrlx/grlx/v2/internal/props"
 	"github.com/gogrlx/grlx/v2/internal/rbac"
+	"github.com/gogrlx/grlx/v2/internal/schedule"
 
 	nats_server "github.com/nats-io/nats-server/v2/server"
 	nats "github.com/nats-io/nats.go"
@@ -46,6 +49,7 @@ var (
 	srvMu     sync.Mutex
 	s         *nats_server.Server
 	apiServer *http.Server
+	schedules *schedule.Scheduler
 	GitCommit string
 	Tag       string
 )
@@ -81,8 +85,13 @@ func main() {
 	props.InitStore(config.PropsDir)
 	props.LoadStaticProps(config.StaticProps())
 	loadCohortRegistry()
+	schedules = schedule.New(config.ScheduleFile, natsapi.RunSchedule)
+	natsapi.SetScheduler(schedules)
+	loadSchedules()
+	loadDriftReports()
 	createConfigRoot()
 	initAuditLogger()
+	auth.OnPolicyChange(reloadUserPermissions)
 	loadAuthPolicy()
 	pki.SetupPKIFarmer()
 	if err := certs.GenCert(); err != nil {
@@ -163,6 +172,18 @@ func loadAuthPolicy() {
 	}
 }
 
+// reloadUserPermissions regenerates the CLI users' NATS permissions after
+// the auth policy changes. Until the NATS server is running there is
+// nothing to reload; RunNATSServer provisions the users itself.
+func reloadUserPermissions() {
+	if getNATSServer() == nil {
+		return
+	}
+	if err := pki.ReloadNKeys(); err != nil {
+		log.Errorf("Failed to reload NATS user permissions: %v", err)
+	}
+}
+
 func loadCohortRegistry() {
 	registry, err := rbac.LoadCohortsFromConfig()
 	if err != nil {
@@ -179,6 +200,31 @@ func loadCohortRegistry() {
 	}
 }
 
+// loadSchedules loads the schedules from the farmer config, along with
+// those created through the API.
+func loadSchedules() {
+	entries, err := schedule.LoadFromConfig()
+	if err != nil {
+		log.Errorf("Failed to load schedule config: %v", err)
+	}
+	if err = schedules.Load(entries); err != nil {
+		log.Errorf("Failed to load schedules: %v", err)
+		return
+	}
+	if n := len(schedules.List()); n > 0 {
+		log.Infof("Loaded %d schedule(s)", n)
+	}
+}
+
+// loadDriftReports loads the drift reports kept from earlier checks.
+func loadDriftReports() {
+	store := drift.New(config.DriftDir)
+	if err := store.Load(); err != nil {
+		log.Errorf("Failed to load drift reports: %v", err)
+	}
+	natsapi.SetDriftStore(store)
+}
+
 func createConfigRoot() {
 	ConfigRoot := config.ConfigRoot
 	_, err := os.Stat(ConfigRoot)
@@ -277,6 +323,7 @@ func handleSIGHUP(ctx context.Context, done chan<- struct{}) {
 		props.ClearStaticProps()
 		props.LoadStaticProps(config.StaticProps())
 		loadCohortRegistry()
+		loadSchedules()
 		loadAuthPolicy()
 		// Don't restart the API server if shutdown was requested while we
 		// were reloading.
@@ -385,18 +432,22 @@ func ConnectFarmer(ctx context.Context, done chan<- struct{}) {
 		log.Errorf("Failed to connect log-nats backend: %v", err)
 	}
 
-	_, err = nc.Subscribe("grlx.sprouts.announce.>", func(m *nats.Msg) {
-		log.Infof("Received a join event: %s\n", string(m.Data))
-	})
-	if err != nil {
-		log.Errorf("Got an error on Subscribe: %+v\n", err)
+	if err = natsapi.StartPresenceTracker(ctx, nc); err != nil {
+		log.Errorf("Failed to start sprout presence tracking: %v", err)
 	}
 
 	test.RegisterNatsConn(nc)
 	cmd.RegisterNatsConn(nc)
 	cook.RegisterNatsConn(nc)
-	jobs.RegisterNatsConn(nc)
+	if err = cook.StartCookQueue(ctx, nc); err != nil {
+		log.Errorf("Failed to start delivery of queued cooks: %v", err)
+	}
+	jsJobs := pki.JetStreamEnabled() && startJetStreamJobs(ctx, nc)
+	if !jsJobs {
+		jobs.RegisterNatsConn(nc)
+	}
 	facts.RegisterFarmerListener(nc)
+	grlxfile.RegisterFarmerListener(nc)
 
 	// Set version info and subscribe NATS API handlers.
 	natsapi.SetBuildVersion(config.Version{
@@ -410,9 +461,38 @@ func ConnectFarmer(ctx context.Context, done chan<- struct{}) {
 	} else {
 		log.Info("NATS API handlers registered")
 	}
-	// Start the job log reaper to clean up old job files.
-	jobStore := jobs.NewStore()
-	jobStore.StartReaperCtx(ctx, config.JobLogTTL)
+	// Schedules and drift checks create jobs, so they only start once the
+	// job listeners and API handlers are in place.
+	schedules.Start(ctx)
+	natsapi.StartDriftChecker(ctx, config.DriftInterval)
+	// Start the job log reaper to clean up old job files. JetStream
+	// expires job history itself via the stream's MaxAge.
+	if !jsJobs {
+		jobStore := jobs.NewStore()
+		jobStore.StartReaperCtx(ctx, config.JobLogTTL)
+	}
 	<-ctx.Done()
 	nc.Close()
 }
+
+// startJetStreamJobs creates the job stream and points the jobs API at it.
+// It reports false, leaving the caller to fall back to the file store, if
+// the stream cannot be set up.
+func startJetStreamJobs(ctx context.Context, nc *nats.Conn) bool {
+	jsStore, err := jobs.NewJetStreamStore(ctx, nc, config.JobLogTTL)
+	if err != nil {
+		log.Errorf("Failed to set up JetStream job store, falling back to files: %v", err)
+		return false
+	}
+	if err = jsStore.RecordJobCreation(); err != nil {
+		log.Errorf("Failed to subscribe JetStream job store, falling back to files: %v", err)
+		return false
+	}
+	if err = jsStore.IndexJobs(ctx); err != nil {
+		log.Errorf("Failed to index JetStream job store, falling back to files: %v", err)
+		return false
+	}
+	natsapi.SetJobStore(jsStore)
+	log.Infof("Job history is stored in JetStream stream %s", jobs.JobStreamName)
+	return true
+}
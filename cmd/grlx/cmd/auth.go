@@ -232,6 +232,12 @@ var authExplainCmd = &cobra.Command{
 			if result.IsAdmin {
 				fmt.Println("Admin:  yes (all actions permitted)")
 			}
+			if result.TokenFormat != "" {
+				fmt.Printf("Token:  %s\n", result.TokenFormat)
+			}
+			if result.TokenFormat == "legacy" && result.LegacyTokensUntil != "" {
+				fmt.Printf("        legacy tokens are accepted until %s\n", result.LegacyTokensUntil)
+			}
 			if len(result.Actions) > 0 {
 				fmt.Println("\nPermissions:")
 				for _, a := range result.Actions {
@@ -3,6 +3,7 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -25,22 +26,34 @@ var (
 	cookTimeout int
 	testMode    bool
 	targetState string
+	queueCook   bool
+	batchSize   string
+	batchWait   time.Duration
+	maxFail     string
+	topCook     bool
 )
 
 var cmdCook = &cobra.Command{
-	Use:   "cook <recipe> (-T <target> | -C <cohort>) [flags]",
+	Use:   "cook (<recipe> | --top) (-T <target> | -C <cohort>) [flags]",
 	Short: "Cook a recipe against a target or cohort and see the output locally.",
 	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) != 1 {
+		if (topCook && len(args) != 0) || (!topCook && len(args) != 1) {
 			cmd.Help()
 			return
 		}
 		var cmdCook apitypes.CmdCook
-		cmdCook.Recipe = cook.RecipeName(args[0])
+		if !topCook {
+			cmdCook.Recipe = cook.RecipeName(args[0])
+		}
+		cmdCook.Top = topCook
 		cmdCook.Async = async
 		cmdCook.Env = environment
 		cmdCook.Test = testMode
 		cmdCook.State = targetState
+		cmdCook.Queue = queueCook
+		cmdCook.Batch = batchSize
+		cmdCook.BatchWait = batchWait
+		cmdCook.MaxFail = maxFail
 
 		effectiveTarget, err := resolveEffectiveTarget()
 		if err != nil {
@@ -55,7 +68,9 @@ var cmdCook = &cobra.Command{
 				log.Fatal(err)
 			}
 		}
-		// topic: grlx.cook."+envelope.JobID+"."+pki.GetSproutID()
+		if cmdCook.Top && outputMode != "json" {
+			printTopMatches(results.TopMatches)
+		}
 		jid := results.JID
 		nc, err := client.NewNatsClient()
 		if err != nil {
@@ -63,9 +78,8 @@ var cmdCook = &cobra.Command{
 		}
 		finished := make(chan struct{}, 1)
 		completions := make(chan cook.SproutStepCompletion)
-		topic := fmt.Sprintf("grlx.cook.*.%s", jid)
 		completionSteps := make(map[string][]cook.StepCompletion)
-		sub, err := nc.Subscribe(topic, func(msg *nats.Msg) {
+		handle := func(msg *nats.Msg) {
 			var step cook.StepCompletion
 			err := json.Unmarshal(msg.Data, &step)
 			if err != nil {
@@ -78,8 +92,31 @@ var cmdCook = &cobra.Command{
 			completions <- cook.SproutStepCompletion{SproutID: sproutID, CompletedStep: step}
 			if string(step.ID) == fmt.Sprintf("completed-%s", jid) {
 				return
+			} else if string(step.ID) == fmt.Sprintf("cancelled-%s", jid) {
+				printTex.Lock()
+				fmt.Printf("%s::%s cancelled\n", sproutID, jid)
+				for _, reason := range step.Changes {
+					fmt.Printf("\t%s\n", reason)
+				}
+				printTex.Unlock()
+				return
 			} else if string(step.ID) == fmt.Sprintf("start-%s", jid) {
 				return
+			} else if string(step.ID) == fmt.Sprintf("queued-%s", jid) {
+				printTex.Lock()
+				fmt.Printf("%s::%s queued behind other jobs\n", sproutID, jid)
+				printTex.Unlock()
+				return
+			} else if string(step.ID) == fmt.Sprintf("deferred-%s", jid) {
+				printTex.Lock()
+				fmt.Printf("%s::%s queued until the sprout reconnects\n", sproutID, jid)
+				printTex.Unlock()
+				return
+			} else if string(step.ID) == fmt.Sprintf("batch-%s", jid) {
+				printTex.Lock()
+				fmt.Printf("%s::%s sent in %s\n", sproutID, jid, strings.Join(step.Changes, ", "))
+				printTex.Unlock()
+				return
 			}
 
 			switch outputMode {
@@ -95,8 +132,12 @@ var cmdCook = &cobra.Command{
 					b.WriteString(color.GreenString(fmt.Sprintf("\tResult: %s\n", "Success")))
 				case cook.StepFailed:
 					b.WriteString(color.RedString(fmt.Sprintf("\tResult: %s\n", "Failure")))
+				case cook.StepTimedOut:
+					b.WriteString(color.RedString(fmt.Sprintf("\tResult: %s\n", "Timed Out")))
 				case cook.StepSkipped:
 					b.WriteString(color.YellowString(fmt.Sprintf("\tResult: %s\n", "Skipped")))
+				case cook.StepCancelled:
+					b.WriteString(color.YellowString(fmt.Sprintf("\tResult: %s\n", "Cancelled")))
 				default:
 					b.WriteString(color.YellowString(fmt.Sprintf("\tResult: %s\n", "Unknown")))
 				}
@@ -113,10 +154,16 @@ var cmdCook = &cobra.Command{
 				fmt.Print(b.String())
 				printTex.Unlock()
 			}
-		})
-		if err != nil {
-			log.Printf("Error subscribing to %s: %v\n", topic, err)
-			log.Fatal(err)
+		}
+		// Subscribe to each targeted sprout: a user scoped to some sprouts
+		// may not subscribe to a wildcard over all of them.
+		for _, topic := range jobs.CompletionSubjects(jid, results.Sprouts) {
+			sub, subErr := nc.Subscribe(topic, handle)
+			if subErr != nil {
+				log.Printf("Error subscribing to %s: %v\n", topic, subErr)
+				log.Fatal(subErr)
+			}
+			defer sub.Unsubscribe()
 		}
 		triggerMsg := config.TriggerMsg{JID: jid}
 		b, _ := json.Marshal(triggerMsg)
@@ -134,8 +181,20 @@ var cmdCook = &cobra.Command{
 			if cliStore, storeErr := jobs.NewCLIStore(cliStorePath); storeErr == nil {
 				userKey, _ := auth.GetPubkey()
 				cliListener := jobs.NewCLIListener(cliStore, nc, userKey)
-				cliListener.RecordJobInit(jid, string(cmdCook.Recipe), targetedSprouts)
-				if subErr := cliListener.SubscribeJob(jid); subErr != nil {
+				if cmdCook.Top {
+					// Record each sprout with the recipes the top file
+					// gave it.
+					for _, sproutID := range targetedSprouts {
+						var recipes []string
+						for _, recipe := range results.TopMatches[sproutID].Recipes {
+							recipes = append(recipes, string(recipe))
+						}
+						cliListener.RecordJobInit(jid, strings.Join(recipes, ", "), []string{sproutID})
+					}
+				} else {
+					cliListener.RecordJobInit(jid, string(cmdCook.Recipe), targetedSprouts)
+				}
+				if subErr := cliListener.SubscribeJob(jid, results.Sprouts...); subErr != nil {
 					log.Errorf("CLI job store: failed to subscribe: %v", subErr)
 				} else {
 					defer cliListener.Stop()
@@ -143,28 +202,60 @@ var cmdCook = &cobra.Command{
 			}
 		}
 
-		localTimeout := time.After(time.Duration(cookTimeout) * time.Second)
+		// A rolling cook is quiet while it pauses between batches.
+		idleTimeout := time.Duration(cookTimeout)*time.Second + batchWait
+		localTimeout := time.After(idleTimeout)
 		dripTimeout := time.After(120 * time.Second)
 		concurrent := 0
-		defer sub.Unsubscribe()
+		queued := make(map[string]bool)
+		deferred := 0
+		// Sprouts that have finished; a rolling cook is done once all
+		// targeted sprouts have, rather than when one batch has.
+		done := make(map[string]bool)
 		defer nc.Flush()
 	waitLoop:
 		for {
 			select {
 			case completion := <-completions:
-				if string(completion.CompletedStep.ID) == fmt.Sprintf("start-%s", jid) {
+				if string(completion.CompletedStep.ID) == fmt.Sprintf("deferred-%s", jid) {
+					// The sprout is offline, so there is nothing to wait for.
+					completionSteps[completion.SproutID] = append(completionSteps[completion.SproutID], completion.CompletedStep)
+					deferred++
+					done[completion.SproutID] = true
+					if deferred == len(targetedSprouts) {
+						break waitLoop
+					}
+					continue
+				}
+				if string(completion.CompletedStep.ID) == fmt.Sprintf("batch-%s", jid) {
+					dripTimeout = time.After(120 * time.Second)
+				}
+				if string(completion.CompletedStep.ID) == fmt.Sprintf("queued-%s", jid) {
+					// A queued job counts as in flight until it completes;
+					// its start marker arrives once a cook slot frees up.
+					queued[completion.SproutID] = true
+					concurrent++
+					localTimeout = time.After(idleTimeout)
+					dripTimeout = time.After(120 * time.Second)
+					continue
+				}
+				if string(completion.CompletedStep.ID) == fmt.Sprintf("start-%s", jid) && !queued[completion.SproutID] {
 					concurrent++
 				}
-				if string(completion.CompletedStep.ID) == fmt.Sprintf("completed-%s", jid) {
+				if string(completion.CompletedStep.ID) == fmt.Sprintf("completed-%s", jid) ||
+					string(completion.CompletedStep.ID) == fmt.Sprintf("cancelled-%s", jid) {
 					// waitgroups are not necesary here because we are looping sequentially over a channel
-					concurrent--
+					if !done[completion.SproutID] {
+						concurrent--
+					}
+					done[completion.SproutID] = true
 				}
-				if concurrent == 0 {
+				if concurrent <= 0 && (cmdCook.Batch == "" || len(done) >= len(targetedSprouts)) {
 					dripTimeout = time.After(time.Second / 10)
 				}
 
 				completionSteps[completion.SproutID] = append(completionSteps[completion.SproutID], completion.CompletedStep)
-				localTimeout = time.After(time.Duration(cookTimeout) * time.Second)
+				localTimeout = time.After(idleTimeout)
 			case <-finished:
 				break waitLoop
 			case <-dripTimeout:
@@ -181,6 +272,9 @@ var cmdCook = &cobra.Command{
 			wrapper := map[string]interface{}{}
 			wrapper["jid"] = jid
 			wrapper["sprouts"] = completionSteps
+			if cmdCook.Top {
+				wrapper["top"] = results.TopMatches
+			}
 			jsonBytes, err := json.Marshal(wrapper)
 			if err != nil {
 				log.Fatal(err)
@@ -190,18 +284,26 @@ var cmdCook = &cobra.Command{
 			fallthrough
 		case "text":
 			for k, v := range completionSteps {
-				successes := -2 // -2 because we don't count the start and completed steps
+				successes := 0
 				failures := 0
 				skipped := 0
+				cancelled := 0
 				errors := []string{}
 				for _, step := range v {
+					switch string(step.ID) {
+					case "batch-" + jid, "deferred-" + jid, "start-" + jid, "completed-" + jid, "cancelled-" + jid:
+						// lifecycle markers, not steps
+						continue
+					}
 					switch step.CompletionStatus {
 					case cook.StepCompleted:
 						successes++
-					case cook.StepFailed:
+					case cook.StepFailed, cook.StepTimedOut:
 						failures++
 					case cook.StepSkipped:
 						skipped++
+					case cook.StepCancelled:
+						cancelled++
 					}
 					if step.Error != nil {
 						errors = append(errors, step.Error.Error())
@@ -213,6 +315,9 @@ var cmdCook = &cobra.Command{
 				if skipped > 0 {
 					fmt.Printf("\tSkipped:\t%d\n", skipped)
 				}
+				if cancelled > 0 {
+					fmt.Printf("\tCancelled:\t%d\n", cancelled)
+				}
 				fmt.Printf("\tErrors:\t\t%d\n", len(errors))
 				for _, err := range errors {
 					fmt.Printf("\t\t%s\n", err)
@@ -222,6 +327,28 @@ var cmdCook = &cobra.Command{
 	},
 }
 
+// printTopMatches shows which top file entries matched each sprout in a
+// full-state cook.
+func printTopMatches(matches map[string]cook.TopAssignment) {
+	sproutIDs := make([]string, 0, len(matches))
+	for sproutID := range matches {
+		sproutIDs = append(sproutIDs, sproutID)
+	}
+	sort.Strings(sproutIDs)
+	for _, sproutID := range sproutIDs {
+		a := matches[sproutID]
+		if len(a.Entries) == 0 {
+			fmt.Printf("%s: %s\n", sproutID, color.YellowString("no top entries matched, skipping"))
+			continue
+		}
+		recipes := make([]string, len(a.Recipes))
+		for i, recipe := range a.Recipes {
+			recipes[i] = string(recipe)
+		}
+		fmt.Printf("%s: entries %s -> recipes %s\n", sproutID, strings.Join(a.Entries, ", "), strings.Join(recipes, ", "))
+	}
+}
+
 func init() {
 	cmdCook.Flags().StringVarP(&environment, "environment", "E", "", "")
 	cmdCook.Flags().BoolVar(&async, "async", false, "Don't print any output, just return the JID to look up results later")
@@ -229,5 +356,10 @@ func init() {
 	cmdCook.Flags().IntVar(&cookTimeout, "cook-timeout", 30, "Cancel cook execution and return after X seconds")
 	cmdCook.Flags().BoolVar(&testMode, "test", false, "Run in test mode (dry run without applying changes)")
 	cmdCook.Flags().StringVarP(&targetState, "state", "s", "", "Run only the named state and its requisite dependencies")
+	cmdCook.Flags().BoolVar(&queueCook, "queue", false, "Queue the cook for sprouts that are offline and deliver it when they reconnect")
+	cmdCook.Flags().BoolVar(&topCook, "top", false, "Cook each sprout with the recipes the farmer's top file assigns it")
+	cmdCook.Flags().StringVar(&batchSize, "batch", "", "Cook on this many sprouts at a time, as a number or a percentage of targets (e.g. 5 or 20%)")
+	cmdCook.Flags().DurationVar(&batchWait, "batch-wait", 0, "Pause between batches")
+	cmdCook.Flags().StringVar(&maxFail, "max-fail", "", "Stop sending batches once more than this many sprouts, or this percentage of targets, have failed")
 	rootCmd.AddCommand(cmdCook)
 }
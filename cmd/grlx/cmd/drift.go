@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/gogrlx/grlx/v2/internal/api/client"
+	"github.com/gogrlx/grlx/v2/internal/drift"
+	"github.com/gogrlx/grlx/v2/internal/log"
+)
+
+var driftAll bool
+
+var cmdDrift = &cobra.Command{
+	Use:   "drift",
+	Short: "Show which sprouts have drifted from their recipes",
+	Long: `The farmer periodically cooks the recipes the top file assigns to each
+sprout in test mode (every driftinterval, one hour by default). The steps
+that would change are the sprout's drift.
+
+Without a subcommand, lists the drifted sprouts, the steps that drifted and
+since when, and the steps that started (+) or stopped (-) drifting since
+the check before.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		listDrift()
+	},
+}
+
+var cmdDriftList = &cobra.Command{
+	Use:   "list",
+	Short: "List drifted sprouts",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		listDrift()
+	},
+}
+
+var cmdDriftShow = &cobra.Command{
+	Use:   "show <sproutID>",
+	Short: "Show the latest drift report of a sprout, with what would change",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r, err := client.GetDrift(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		if outputMode == "json" {
+			jw, _ := json.MarshalIndent(r, "", "  ")
+			fmt.Println(string(jw))
+			return
+		}
+		printDriftReport(r, true)
+	},
+}
+
+var cmdDriftCheck = &cobra.Command{
+	Use:   "check [(-T <target> | -C <cohort>)]",
+	Short: "Check sprouts for drift now",
+	Long: `Start a drift check of the targeted sprouts, or of every sprout you may
+cook on. The check runs on the farmer; 'grlx drift' shows the reports as
+each sprout finishes.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var sproutIDs []string
+		if sproutTarget != "" || cohortTarget != "" {
+			effectiveTarget, err := resolveEffectiveTarget()
+			if err != nil {
+				log.Fatal(err)
+			}
+			sproutIDs, err = client.ResolveTargets(effectiveTarget)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if len(sproutIDs) == 0 {
+				log.Fatalf("No accepted sprouts match the target.")
+			}
+		}
+		result, err := client.CheckDrift(sproutIDs)
+		if err != nil {
+			log.Fatalf("Failed to start drift check: %v", err)
+		}
+		if outputMode == "json" {
+			jw, _ := json.Marshal(result)
+			fmt.Println(string(jw))
+			return
+		}
+		fmt.Printf("Drift check %s started on %d sprout(s): %s\n", result.JID, len(result.SproutIDs), strings.Join(result.SproutIDs, ", "))
+	},
+}
+
+func listDrift() {
+	reports, err := client.ListDrift()
+	if err != nil {
+		log.Fatalf("Failed to list drift: %v", err)
+	}
+	if !driftAll {
+		drifted := reports[:0]
+		for _, r := range reports {
+			if r.Drifted() || len(r.Resolved) > 0 || len(r.Failed) > 0 {
+				drifted = append(drifted, r)
+			}
+		}
+		reports = drifted
+	}
+	if outputMode == "json" {
+		jw, _ := json.Marshal(reports)
+		fmt.Println(string(jw))
+		return
+	}
+	if len(reports) == 0 {
+		fmt.Println("No drift found.")
+		return
+	}
+	for i, r := range reports {
+		if i > 0 {
+			fmt.Println()
+		}
+		printDriftReport(r, false)
+	}
+}
+
+// printDriftReport prints a sprout's drifted steps and the steps that
+// changed since the previous check, and with changes set what each
+// drifted step would change.
+func printDriftReport(r drift.Report, changes bool) {
+	state := color.GreenString("in sync")
+	if r.Drifted() {
+		state = color.YellowString("%d step(s) drifted", len(r.Steps))
+	}
+	fmt.Printf("%s: %s (checked %s, job %s)\n", r.SproutID, state, formatScheduleTime(r.CheckedAt), r.JID)
+	appeared := make(map[string]bool, len(r.Appeared))
+	for _, id := range r.Appeared {
+		appeared[id] = true
+	}
+	for _, step := range r.Steps {
+		marker := " "
+		if appeared[step.ID] {
+			marker = color.YellowString("+")
+		}
+		fmt.Printf("  %s %-32s since %s\n", marker, truncate(step.ID, 32), formatScheduleTime(step.Since))
+		if changes {
+			for _, change := range step.Changes {
+				fmt.Printf("        %s\n", change)
+			}
+		}
+	}
+	for _, id := range r.Resolved {
+		fmt.Printf("  %s %-32s resolved\n", color.GreenString("-"), truncate(id, 32))
+	}
+	for _, id := range r.Failed {
+		fmt.Printf("  %s %-32s test failed\n", color.RedString("!"), truncate(id, 32))
+	}
+}
+
+func init() {
+	cmdDrift.Flags().BoolVar(&driftAll, "all", false, "Include sprouts that are in sync")
+	cmdDriftList.Flags().BoolVar(&driftAll, "all", false, "Include sprouts that are in sync")
+	addTargetFlags(cmdDriftCheck)
+	cmdDrift.AddCommand(cmdDriftList)
+	cmdDrift.AddCommand(cmdDriftShow)
+	cmdDrift.AddCommand(cmdDriftCheck)
+	rootCmd.AddCommand(cmdDrift)
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/gogrlx/grlx/v2/internal/auth"
 	"github.com/gogrlx/grlx/v2/internal/log"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"github.com/spf13/cobra"
 
 	"github.com/gogrlx/grlx/v2/internal/api/client"
@@ -207,17 +209,18 @@ var cmdJobsWatch = &cobra.Command{
 		}
 		defer nc.Flush()
 
-		topic := fmt.Sprintf("grlx.cook.*.%s", jid)
 		finished := make(chan struct{}, 1)
+		// A rolling cook is not finished while it has batches left to send.
+		moreBatches := false
 
-		sub, err := nc.Subscribe(topic, func(msg *nats.Msg) {
+		handle := func(subject string, data []byte) {
 			var step cook.StepCompletion
-			if err := json.Unmarshal(msg.Data, &step); err != nil {
+			if err := json.Unmarshal(data, &step); err != nil {
 				log.Errorf("Error unmarshalling message: %v\n", err)
 				return
 			}
 
-			subComponents := strings.Split(msg.Subject, ".")
+			subComponents := strings.Split(subject, ".")
 			sproutID := subComponents[2]
 
 			if strings.HasPrefix(string(step.ID), "completed-") {
@@ -225,7 +228,60 @@ var cmdJobsWatch = &cobra.Command{
 				fmt.Printf("\n%s :: Job %s completed on %s\n",
 					color.GreenString("DONE"), jid, sproutID)
 				printTex.Unlock()
-				finished <- struct{}{}
+				if moreBatches {
+					return
+				}
+				select {
+				case finished <- struct{}{}:
+				default:
+				}
+				return
+			}
+			if strings.HasPrefix(string(step.ID), "cancelled-") {
+				printTex.Lock()
+				fmt.Printf("\n%s :: Job %s cancelled on %s\n",
+					color.RedString("CANCELLED"), jid, sproutID)
+				for _, reason := range step.Changes {
+					fmt.Printf("  %s\n", reason)
+				}
+				printTex.Unlock()
+				if len(step.Changes) > 0 {
+					// The farmer stopped the rolling cook before this sprout.
+					moreBatches = false
+				}
+				if moreBatches {
+					return
+				}
+				select {
+				case finished <- struct{}{}:
+				default:
+				}
+				return
+			}
+			if strings.HasPrefix(string(step.ID), "queued-") {
+				printTex.Lock()
+				fmt.Printf("%s :: Job %s queued on %s\n",
+					color.YellowString("QUEUED"), jid, sproutID)
+				printTex.Unlock()
+				return
+			}
+			if strings.HasPrefix(string(step.ID), "batch-") {
+				var n, batches int
+				if len(step.Changes) > 0 {
+					fmt.Sscanf(step.Changes[0], "batch %d of %d", &n, &batches)
+				}
+				moreBatches = n < batches
+				printTex.Lock()
+				fmt.Printf("%s :: Job %s sent to %s in %s\n",
+					color.CyanString("BATCH"), jid, sproutID, strings.Join(step.Changes, ", "))
+				printTex.Unlock()
+				return
+			}
+			if strings.HasPrefix(string(step.ID), "deferred-") {
+				printTex.Lock()
+				fmt.Printf("%s :: Job %s queued until %s reconnects\n",
+					color.YellowString("QUEUED"), jid, sproutID)
+				printTex.Unlock()
 				return
 			}
 			if strings.HasPrefix(string(step.ID), "start-") {
@@ -244,8 +300,12 @@ var cmdJobsWatch = &cobra.Command{
 				b.WriteString(fmt.Sprintf("  Result: %s\n", color.GreenString("Success")))
 			case cook.StepFailed:
 				b.WriteString(fmt.Sprintf("  Result: %s\n", color.RedString("Failure")))
+			case cook.StepTimedOut:
+				b.WriteString(fmt.Sprintf("  Result: %s\n", color.RedString("Timed Out")))
 			case cook.StepSkipped:
 				b.WriteString(fmt.Sprintf("  Result: %s\n", color.YellowString("Skipped")))
+			case cook.StepCancelled:
+				b.WriteString(fmt.Sprintf("  Result: %s\n", color.YellowString("Cancelled")))
 			default:
 				b.WriteString(fmt.Sprintf("  Result: %s\n", color.YellowString("Unknown")))
 			}
@@ -259,11 +319,36 @@ var cmdJobsWatch = &cobra.Command{
 			printTex.Lock()
 			fmt.Print(b.String())
 			printTex.Unlock()
-		})
-		if err != nil {
-			log.Fatal(err)
 		}
-		defer sub.Unsubscribe()
+
+		// Prefer replaying from the farmer's job stream, which also covers
+		// steps that finished before we connected or while reconnecting.
+		if replay, replayErr := replayJob(nc, jid, handle); replayErr == nil {
+			defer replay.Stop()
+		} else {
+			// The job's sprouts are not known up front, so subscribe to
+			// every accepted sprout the user can see: a user scoped to
+			// some sprouts may not subscribe to a wildcard over all of them.
+			sprouts, listErr := client.ListSprouts()
+			if listErr != nil {
+				log.Fatal(listErr)
+			}
+			var sproutIDs []string
+			for _, sprout := range sprouts {
+				if sprout.KeyState == "accepted" {
+					sproutIDs = append(sproutIDs, sprout.ID)
+				}
+			}
+			for _, topic := range jobs.CompletionSubjects(jid, sproutIDs) {
+				sub, subErr := nc.Subscribe(topic, func(msg *nats.Msg) {
+					handle(msg.Subject, msg.Data)
+				})
+				if subErr != nil {
+					log.Fatal(subErr)
+				}
+				defer sub.Unsubscribe()
+			}
+		}
 
 		fmt.Printf("Watching job %s (timeout %ds)...\n", jid, watchTimeout)
 
@@ -275,9 +360,31 @@ var cmdJobsWatch = &cobra.Command{
 	},
 }
 
+// replayJob delivers every completion the farmer's job stream holds for
+// jid, then new ones as they arrive. The ordered consumer picks up where
+// it left off after a reconnect. It fails when the farmer does not keep
+// jobs in JetStream.
+func replayJob(nc *nats.Conn, jid string, handle func(subject string, data []byte)) (jetstream.ConsumeContext, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cons, err := js.OrderedConsumer(ctx, jobs.JobStreamName, jetstream.OrderedConsumerConfig{
+		FilterSubjects: []string{fmt.Sprintf("grlx.cook.*.%s", jid)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cons.Consume(func(msg jetstream.Msg) {
+		handle(msg.Subject(), msg.Data())
+	})
+}
+
 var cmdJobsCancel = &cobra.Command{
 	Use:   "cancel <JID>",
-	Short: "Cancel a running or pending job",
+	Short: "Cancel a running, queued or pending job",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		jid := args[0]
@@ -289,6 +396,34 @@ var cmdJobsCancel = &cobra.Command{
 	},
 }
 
+var cmdJobsQueue = &cobra.Command{
+	Use:   "queue <sproutID>",
+	Short: "Show the jobs a sprout is cooking and the ones waiting for a slot",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		queue, err := client.ListSproutQueue(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(queue) == 0 {
+			fmt.Println("No jobs running or queued.")
+			return
+		}
+		fmt.Printf("%-20s  %-10s  %5s  %s\n", "JID", "STATE", "POS", "SINCE")
+		fmt.Println(strings.Repeat("-", 70))
+		for _, job := range queue {
+			state := color.CyanString("running")
+			pos := "—"
+			if job.State == cook.JobQueued {
+				state = color.YellowString("queued")
+				pos = fmt.Sprintf("%d", job.Position)
+			}
+			fmt.Printf("%-20s  %-10s  %5s  %s\n",
+				truncate(job.JobID, 20), state, pos, job.Since.Format(time.RFC3339))
+		}
+	},
+}
+
 func printJobsTable(summaries []jobs.JobSummary) {
 	// Check whether any job has invoker info to decide column visibility.
 	hasInvoker := false
@@ -355,6 +490,12 @@ func printJobDetail(s *jobs.JobSummary) {
 	}
 	fmt.Printf("Steps:   %d total (%d succeeded, %d failed, %d skipped)\n",
 		s.Total, s.Succeeded, s.Failed, s.Skipped)
+	if s.TimedOut > 0 {
+		fmt.Printf("         %d of the failed steps timed out\n", s.TimedOut)
+	}
+	if s.Cancelled > 0 {
+		fmt.Printf("         %d steps were cancelled\n", s.Cancelled)
+	}
 	fmt.Println()
 
 	if len(s.Steps) == 0 {
@@ -365,9 +506,13 @@ func printJobDetail(s *jobs.JobSummary) {
 	fmt.Println("Steps:")
 	fmt.Println(strings.Repeat("-", 80))
 	for _, step := range s.Steps {
-		// Skip the synthetic start/completed markers
-		if strings.HasPrefix(string(step.ID), "start-") ||
-			strings.HasPrefix(string(step.ID), "completed-") {
+		// Skip the synthetic batch/deferred/queued/start/completed/cancelled markers
+		if strings.HasPrefix(string(step.ID), "batch-") ||
+			strings.HasPrefix(string(step.ID), "deferred-") ||
+			strings.HasPrefix(string(step.ID), "queued-") ||
+			strings.HasPrefix(string(step.ID), "start-") ||
+			strings.HasPrefix(string(step.ID), "completed-") ||
+			strings.HasPrefix(string(step.ID), "cancelled-") {
 			continue
 		}
 
@@ -377,8 +522,12 @@ func printJobDetail(s *jobs.JobSummary) {
 			fmt.Printf("  Result: %s\n", color.GreenString("Success"))
 		case cook.StepFailed:
 			fmt.Printf("  Result: %s\n", color.RedString("Failure"))
+		case cook.StepTimedOut:
+			fmt.Printf("  Result: %s\n", color.RedString("Timed Out"))
 		case cook.StepSkipped:
 			fmt.Printf("  Result: %s\n", color.YellowString("Skipped"))
+		case cook.StepCancelled:
+			fmt.Printf("  Result: %s\n", color.YellowString("Cancelled"))
 		case cook.StepInProgress:
 			fmt.Printf("  Result: %s\n", color.CyanString("In Progress"))
 		case cook.StepNotStarted:
@@ -410,6 +559,10 @@ func formatStatus(status jobs.JobStatus) string {
 		return color.YellowString("pending")
 	case jobs.JobPartial:
 		return color.YellowString("partial")
+	case jobs.JobQueued:
+		return color.YellowString("queued")
+	case jobs.JobCancelled:
+		return color.RedString("cancelled")
 	default:
 		return "unknown"
 	}
@@ -536,7 +689,7 @@ func humanizeBytes(b int64) string {
 func init() {
 	cmdJobsList.Flags().IntVar(&jobsLimit, "limit", 50, "Maximum number of jobs to return")
 	cmdJobsList.Flags().BoolVar(&jobsLocal, "local", false, "List jobs from local CLI-side storage instead of the farmer")
-	cmdJobsList.Flags().StringVar(&jobsUser, "user", "", "Filter jobs by invoking user's pubkey (use 'me' for current user)")
+	cmdJobsList.Flags().StringVar(&jobsUser, "user", "", "Filter jobs by invoking user's pubkey (use 'me' for current user, or schedule:<name> for a schedule's runs)")
 	cmdJobsList.Flags().StringVarP(&jobsCohort, "cohort", "C", "", "Filter jobs to sprouts in a cohort")
 	cmdJobsShow.Flags().BoolVar(&jobsLocal, "local", false, "Show job from local CLI-side storage instead of the farmer")
 	cmdJobsWatch.Flags().IntVar(&watchTimeout, "timeout", 120, "Watch timeout in seconds")
@@ -546,6 +699,7 @@ func init() {
 	cmdJobs.AddCommand(cmdJobsShow)
 	cmdJobs.AddCommand(cmdJobsWatch)
 	cmdJobs.AddCommand(cmdJobsCancel)
+	cmdJobs.AddCommand(cmdJobsQueue)
 	cmdJobs.AddCommand(cmdJobsPurge)
 	cmdJobs.AddCommand(cmdJobsStats)
 	cmdJobs.AddCommand(cmdJobsDelete)
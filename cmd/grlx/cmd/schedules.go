@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/gogrlx/grlx/v2/internal/api/client"
+	"github.com/gogrlx/grlx/v2/internal/log"
+	"github.com/gogrlx/grlx/v2/internal/schedule"
+)
+
+var (
+	scheduleCron     string
+	scheduleInterval time.Duration
+	scheduleCohort   string
+	scheduleRecipe   string
+	scheduleCommand  string
+	scheduleTest     bool
+	scheduleSplay    time.Duration
+	schedulePaused   bool
+)
+
+var cmdSchedules = &cobra.Command{
+	Use:   "schedules",
+	Short: "Manage cooks and commands the farmer runs on a schedule",
+	Long: `Schedules run a recipe or a command on a cohort at the times given by a
+cron expression, or at a fixed interval. Each run creates a job attributed
+to schedule:<name>, which 'grlx jobs list --user schedule:<name>' lists.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var cmdSchedulesList = &cobra.Command{
+	Use:   "list",
+	Short: "List all schedules",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := client.ListSchedules()
+		if err != nil {
+			log.Fatalf("Failed to list schedules: %v", err)
+		}
+		switch outputMode {
+		case "json":
+			jw, _ := json.Marshal(entries)
+			fmt.Println(string(jw))
+		default:
+			if len(entries) == 0 {
+				fmt.Println("No schedules configured.")
+				return
+			}
+			fmt.Printf("%-20s  %-16s  %-16s  %-24s  %-8s  %s\n",
+				"NAME", "WHEN", "COHORT", "RUNS", "STATE", "NEXT RUN")
+			fmt.Println(strings.Repeat("-", 112))
+			for _, e := range entries {
+				fmt.Printf("%-20s  %-16s  %-16s  %-24s  %-8s  %s\n",
+					truncate(e.Name, 20),
+					truncate(scheduleWhen(e), 16),
+					truncate(e.Cohort, 16),
+					truncate(scheduleWork(e), 24),
+					scheduleState(e),
+					formatScheduleTime(e.NextRun),
+				)
+			}
+		}
+	},
+}
+
+var cmdSchedulesShow = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show a schedule and its last run",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		e, err := client.GetSchedule(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		printSchedule(e)
+	},
+}
+
+var cmdSchedulesCreate = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a schedule",
+	Long: `Create a schedule that runs a recipe (--recipe) or a command (--command)
+on a cohort (-C) either at the times of a cron expression (--cron) or at a
+fixed interval (--every). Within a run, each sprout's job is delayed by
+its own random amount of up to --splay. A run reaches only the sprouts
+your role lets you cook on, or run commands on, at the time of the run.
+
+Examples:
+  grlx schedules create nightly-patch --cron "0 3 * * *" -C webservers --recipe patch --splay 10m
+  grlx schedules create disk-check --every 15m -C all --command "df -h"`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		e := schedule.Entry{
+			Name:     args[0],
+			Cron:     scheduleCron,
+			Interval: scheduleInterval,
+			Cohort:   scheduleCohort,
+			Recipe:   scheduleRecipe,
+			Command:  scheduleCommand,
+			Test:     scheduleTest,
+			Splay:    scheduleSplay,
+			Paused:   schedulePaused,
+		}
+		if err := e.Validate(); err != nil {
+			log.Fatal(err)
+		}
+		created, err := client.CreateSchedule(e)
+		if err != nil {
+			log.Fatalf("Failed to create schedule: %v", err)
+		}
+		printSchedule(created)
+	},
+}
+
+var cmdSchedulesDelete = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a schedule created through the API",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := client.DeleteSchedule(args[0]); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Schedule %s deleted\n", args[0])
+	},
+}
+
+var cmdSchedulesPause = &cobra.Command{
+	Use:   "pause <name>",
+	Short: "Stop a schedule from running until it is resumed",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := client.PauseSchedule(args[0]); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Schedule %s paused\n", args[0])
+	},
+}
+
+var cmdSchedulesResume = &cobra.Command{
+	Use:   "resume <name>",
+	Short: "Let a paused schedule run again",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		e, err := client.ResumeSchedule(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Schedule %s resumed, next run %s\n", e.Name, formatScheduleTime(e.NextRun))
+	},
+}
+
+func printSchedule(e schedule.Entry) {
+	if outputMode == "json" {
+		jw, _ := json.MarshalIndent(e, "", "  ")
+		fmt.Println(string(jw))
+		return
+	}
+	fmt.Printf("Schedule: %s\n", e.Name)
+	fmt.Printf("  When:     %s\n", scheduleWhen(e))
+	if e.Splay > 0 {
+		fmt.Printf("  Splay:    up to %s\n", e.Splay)
+	}
+	fmt.Printf("  Cohort:   %s\n", e.Cohort)
+	fmt.Printf("  Runs:     %s\n", scheduleWork(e))
+	if e.Test {
+		fmt.Printf("  Test:     yes\n")
+	}
+	fmt.Printf("  State:    %s\n", scheduleState(e))
+	source := e.Source
+	if e.CreatedBy != "" {
+		source += ", created by " + e.CreatedBy
+	}
+	fmt.Printf("  Source:   %s\n", source)
+	fmt.Printf("  Next run: %s\n", formatScheduleTime(e.NextRun))
+	if e.LastRun.IsZero() {
+		return
+	}
+	fmt.Printf("  Last run: %s (job %s)\n", formatScheduleTime(e.LastRun), e.LastJID)
+	if e.LastError != "" {
+		color.Red("  Error:    %s", e.LastError)
+	}
+}
+
+func scheduleWhen(e schedule.Entry) string {
+	if e.Cron != "" {
+		return e.Cron
+	}
+	return "every " + e.Interval.String()
+}
+
+func scheduleWork(e schedule.Entry) string {
+	if e.Command != "" {
+		return "cmd: " + e.Command
+	}
+	return "recipe: " + e.Recipe
+}
+
+func scheduleState(e schedule.Entry) string {
+	switch {
+	case e.Paused:
+		return color.YellowString("paused")
+	case e.LastError != "":
+		return color.RedString("failing")
+	default:
+		return color.GreenString("active")
+	}
+}
+
+func formatScheduleTime(t time.Time) string {
+	if t.IsZero() {
+		return "—"
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+func init() {
+	cmdSchedulesCreate.Flags().StringVar(&scheduleCron, "cron", "", "Cron expression giving the run times, e.g. \"0 3 * * *\" or @daily")
+	cmdSchedulesCreate.Flags().DurationVar(&scheduleInterval, "every", 0, "Run at a fixed interval instead of a cron expression")
+	cmdSchedulesCreate.Flags().StringVarP(&scheduleCohort, "cohort", "C", "", "Cohort to run on")
+	cmdSchedulesCreate.Flags().StringVar(&scheduleRecipe, "recipe", "", "Recipe to cook")
+	cmdSchedulesCreate.Flags().StringVar(&scheduleCommand, "command", "", "Command to run instead of a recipe")
+	cmdSchedulesCreate.Flags().BoolVar(&scheduleTest, "test", false, "Run in test mode (dry run without applying changes)")
+	cmdSchedulesCreate.Flags().DurationVar(&scheduleSplay, "splay", 0, "Delay each sprout's job in a run by a random amount up to this duration")
+	cmdSchedulesCreate.Flags().BoolVar(&schedulePaused, "paused", false, "Create the schedule paused")
+	cmdSchedules.AddCommand(cmdSchedulesList)
+	cmdSchedules.AddCommand(cmdSchedulesShow)
+	cmdSchedules.AddCommand(cmdSchedulesCreate)
+	cmdSchedules.AddCommand(cmdSchedulesDelete)
+	cmdSchedules.AddCommand(cmdSchedulesPause)
+	cmdSchedules.AddCommand(cmdSchedulesResume)
+	rootCmd.AddCommand(cmdSchedules)
+}
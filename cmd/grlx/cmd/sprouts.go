@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -96,7 +97,7 @@ var cmdSproutsList = &cobra.Command{
 				}
 			}
 
-			header := fmt.Sprintf("%-*s  %-*s  %s", maxID, "SPROUT ID", maxState, "KEY STATE", "STATUS")
+			header := fmt.Sprintf("%-*s  %-*s  %-7s  %s", maxID, "SPROUT ID", maxState, "KEY STATE", "STATUS", "LAST SEEN")
 			fmt.Println(header)
 			fmt.Println(strings.Repeat("-", len(header)+2))
 
@@ -122,7 +123,11 @@ var cmdSproutsList = &cobra.Command{
 					stateStr = color.RedString(stateStr)
 				}
 
-				fmt.Printf("%-*s  %-*s  %s\n", maxID, s.ID, maxState, stateStr, statusColor(status))
+				lastSeen := "-"
+				if s.LastSeen != nil {
+					lastSeen = s.LastSeen.Format(time.RFC3339)
+				}
+				fmt.Printf("%-*s  %-*s  %s  %s\n", maxID, s.ID, maxState, stateStr, statusColor(fmt.Sprintf("%-7s", status)), lastSeen)
 			}
 
 			// Summary line.
@@ -174,6 +179,18 @@ var cmdSproutsShow = &cobra.Command{
 					color.Red("Status:    offline")
 				}
 			}
+			if info.ConnectedSince != nil {
+				fmt.Printf("Online:    since %s\n", info.ConnectedSince.Format(time.RFC3339))
+			}
+			if info.LastSeen != nil {
+				fmt.Printf("Last Seen: %s\n", info.LastSeen.Format(time.RFC3339))
+			}
+			if info.Reconnects > 0 {
+				fmt.Printf("Reconnects: %d\n", info.Reconnects)
+			}
+			if info.Version != nil {
+				fmt.Printf("Version:   %s (%s, %s)\n", info.Version.Tag, info.Version.Arch, info.Version.Compiler)
+			}
 			if info.NKey != "" {
 				fmt.Printf("NKey:      %s\n", info.NKey)
 			}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	_ "github.com/gogrlx/grlx/v2/internal/ingredients/archive"
 	_ "github.com/gogrlx/grlx/v2/internal/ingredients/cmd"
+	_ "github.com/gogrlx/grlx/v2/internal/ingredients/cron"
 	_ "github.com/gogrlx/grlx/v2/internal/ingredients/file"
+	_ "github.com/gogrlx/grlx/v2/internal/ingredients/file/grlx"
 	_ "github.com/gogrlx/grlx/v2/internal/ingredients/file/http"
 	_ "github.com/gogrlx/grlx/v2/internal/ingredients/file/local"
+	_ "github.com/gogrlx/grlx/v2/internal/ingredients/file/s3"
 	_ "github.com/gogrlx/grlx/v2/internal/ingredients/group"
 	_ "github.com/gogrlx/grlx/v2/internal/ingredients/pkg"
 	_ "github.com/gogrlx/grlx/v2/internal/ingredients/user"
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"os"
 	"os/signal"
 	"sync/atomic"
@@ -17,6 +18,7 @@ import (
 	"github.com/gogrlx/grlx/v2/internal/cook"
 	"github.com/gogrlx/grlx/v2/internal/ingredients"
 	"github.com/gogrlx/grlx/v2/internal/ingredients/cmd"
+	grlxfile "github.com/gogrlx/grlx/v2/internal/ingredients/file/grlx"
 	"github.com/gogrlx/grlx/v2/internal/ingredients/test"
 	"github.com/gogrlx/grlx/v2/internal/jobs"
 	"github.com/gogrlx/grlx/v2/internal/pki"
@@ -61,6 +63,16 @@ func main() {
 		log.Debugf("Error submitting NKey: %v", err)
 		time.Sleep(nkeyRetryDelay)
 	}
+	// Recipes and commands are only run if signed by the farmer key
+	// pinned here.
+	for err := pki.PinFarmerNKey(); err != nil; err = pki.PinFarmerNKey() {
+		if errors.Is(err, pki.ErrFarmerKeyChanged) {
+			log.Errorf("Farmer NKey does not match the key pinned in %s; recipes and commands will be rejected", config.SproutFarmerNKeyFile)
+			break
+		}
+		log.Debugf("Error fetching farmer NKey: %v", err)
+		time.Sleep(nkeyRetryDelay)
+	}
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 	done := make(chan struct{})
@@ -91,6 +103,14 @@ func createConfigRoot() {
 	}
 }
 
+// reannounce announces the sprout again after a reconnect, so the farmer
+// can deliver anything it queued in the meantime.
+func reannounce(nc *nats.Conn) {
+	if err := announce(nc); err != nil {
+		log.Errorf("failed to announce after reconnecting: %v", err)
+	}
+}
+
 func ConnectSprout(ctx context.Context, done chan<- struct{}) {
 	defer close(done)
 	var connectionAttempts atomic.Int64
@@ -98,10 +118,11 @@ func ConnectSprout(ctx context.Context, done chan<- struct{}) {
 	SproutRootCA := config.SproutRootCA
 	FarmerInterface := config.FarmerInterface
 	FarmerBusURL := config.FarmerBusURL
-	// Capture job-log settings before the local tls.Config below shadows the
+	// Capture job-log and heartbeat settings before the local tls.Config below shadows the
 	// config package identifier.
 	jobLogDir := config.JobLogDir
 	jobLogTTL := config.JobLogTTL
+	heartbeatInterval := config.HeartbeatInterval
 	opt, err := nats.NkeyOptionFromSeed(config.NKeySproutPrivFile)
 	if err != nil {
 		log.Panicf("failed to load NKey seed: %v", err)
@@ -126,6 +147,7 @@ func ConnectSprout(ctx context.Context, done chan<- struct{}) {
 		nats.DisconnectHandler(func(_ *nats.Conn) {
 			log.Debugf("Reconnecting to Farmer, attempt: %d\n", connectionAttempts.Add(1))
 		}),
+		nats.ReconnectHandler(reannounce),
 	)
 	for err != nil {
 		select {
@@ -139,6 +161,7 @@ func ConnectSprout(ctx context.Context, done chan<- struct{}) {
 			nats.DisconnectHandler(func(_ *nats.Conn) {
 				log.Debugf("Reconnecting to Farmer, attempt: %d\n", connectionAttempts.Add(1))
 			}),
+			nats.ReconnectHandler(reannounce),
 		)
 	}
 	log.Debugf("Successfully connected to the Farmer")
@@ -150,10 +173,12 @@ func ConnectSprout(ctx context.Context, done chan<- struct{}) {
 	test.RegisterNatsConn(nc)
 	cmd.RegisterNatsConn(nc)
 	cook.RegisterNatsConn(nc)
+	grlxfile.RegisterNatsConn(nc)
 	err = natsInit(nc)
 	if err != nil {
 		log.Panicf("Error with natsInit: %v", err)
 	}
+	startHeartbeat(ctx, nc, heartbeatInterval)
 	// Expire old local job logs written by cook runs on this sprout.
 	jobs.StartSproutReaper(ctx, jobLogDir, jobLogTTL)
 	<-ctx.Done()
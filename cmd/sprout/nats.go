@@ -2,8 +2,11 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"runtime"
+	"time"
 
 	log "github.com/gogrlx/grlx/v2/internal/log"
 
@@ -24,13 +27,51 @@ func init() {
 	pki.SetupPKISprout()
 }
 
-func natsInit(nc *nats.Conn) error {
+func sproutVersion() config.Version {
+	return config.Version{
+		Arch:      runtime.GOARCH,
+		Compiler:  runtime.Version(),
+		GitCommit: GitCommit,
+		Tag:       Tag,
+	}
+}
+
+// startHeartbeat publishes a heartbeat every interval until ctx is
+// cancelled, so the farmer can tell this sprout is online.
+func startHeartbeat(ctx context.Context, nc *nats.Conn, interval time.Duration) {
+	if interval <= 0 {
+		log.Notice("Heartbeats disabled (interval <= 0)")
+		return
+	}
+	subject := "grlx.sprouts." + sproutID + ".heartbeat"
+	b, _ := json.Marshal(config.Heartbeat{
+		Version:  sproutVersion(),
+		SproutID: sproutID,
+		Interval: interval,
+	})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := nc.Publish(subject, b); err != nil {
+				log.Errorf("failed to publish heartbeat: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// announce tells the farmer this sprout has connected. The farmer
+// delivers any cooks it queued while the sprout was away in response, so
+// it must only be sent once the sprout's subscriptions are in place.
+func announce(nc *nats.Conn) error {
 	log.Debugf("Announcing on Farmer...")
 	startup := config.Startup{}
-	startup.Version.Arch = runtime.GOARCH
-	startup.Version.Compiler = runtime.Version()
-	startup.Version.GitCommit = GitCommit
-	startup.Version.Tag = Tag
+	startup.Version = sproutVersion()
 	startup.SproutID = sproutID
 	startupEvent := "grlx.sprouts.announce." + sproutID
 	b, _ := json.Marshal(startup)
@@ -39,11 +80,14 @@ func natsInit(nc *nats.Conn) error {
 		return err
 	}
 	if err = nc.LastError(); err != nil {
-		log.Fatal(err)
-	} else {
-		log.Tracef("Successfully published startup message on `%s`.", startupEvent)
+		return err
 	}
+	log.Tracef("Successfully published startup message on `%s`.", startupEvent)
+	return nil
+}
 
+func natsInit(nc *nats.Conn) error {
+	var err error
 	// Publish system facts on startup.
 	sysFacts := facts.Collect()
 	sysFacts.SproutID = sproutID
@@ -64,10 +108,30 @@ func natsInit(nc *nats.Conn) error {
 	}
 
 	_, err = nc.Subscribe("grlx.sprouts."+sproutID+".cmd.run", func(m *nats.Msg) {
+		signed, verifyErr := pki.VerifyFarmerMessage(m.Subject, m.Data)
+		if verifyErr != nil {
+			log.Errorf("rejected command on %s: %v", m.Subject, verifyErr)
+			resultsB, _ := json.Marshal(apitypes.CmdRun{ErrCode: -1, Stderr: "sprout rejected the command: " + verifyErr.Error()})
+			m.Respond(resultsB)
+			return
+		}
 		var cmdRun apitypes.CmdRun
-		json.NewDecoder(bytes.NewBuffer(m.Data)).Decode(&cmdRun)
+		json.NewDecoder(bytes.NewBuffer(signed.Payload)).Decode(&cmdRun)
+		if cmdRun.JobID != signed.JobID {
+			log.Errorf("rejected command on %s: command job %s does not match signed job %s", m.Subject, cmdRun.JobID, signed.JobID)
+			resultsB, _ := json.Marshal(apitypes.CmdRun{ErrCode: -1, Stderr: "sprout rejected the command: job ID mismatch"})
+			m.Respond(resultsB)
+			return
+		}
 		log.Trace(cmdRun)
-		results, err := cmd.SRun(cmdRun)
+		// Register the run like a cook job so the cancel subject can
+		// stop it. Farmers that predate job IDs send none.
+		ctx, done := context.Background(), func() {}
+		if cmdRun.JobID != "" {
+			ctx, done = cook.TrackJob(ctx, cmdRun.JobID)
+		}
+		results, err := cmd.SRun(ctx, cmdRun)
+		done()
 		if err != nil {
 			log.Error(err)
 		}
@@ -92,8 +156,20 @@ func natsInit(nc *nats.Conn) error {
 		return err
 	}
 	_, err = nc.Subscribe("grlx.sprouts."+sproutID+".cook", func(m *nats.Msg) {
+		signed, verifyErr := pki.VerifyFarmerMessage(m.Subject, m.Data)
 		var rEnvelope cook.RecipeEnvelope
-		json.NewDecoder(bytes.NewBuffer(m.Data)).Decode(&rEnvelope)
+		if verifyErr == nil {
+			json.NewDecoder(bytes.NewBuffer(signed.Payload)).Decode(&rEnvelope)
+			if rEnvelope.JobID != signed.JobID {
+				verifyErr = fmt.Errorf("envelope job %s does not match signed job %s", rEnvelope.JobID, signed.JobID)
+			}
+		}
+		if verifyErr != nil {
+			log.Errorf("rejected recipe on %s: %v", m.Subject, verifyErr)
+			ackB, _ := json.Marshal(cook.Ack{Acknowledged: false, JobID: signed.JobID})
+			m.Respond(ackB)
+			return
+		}
 		log.Trace(rEnvelope)
 		ackB, _ := json.Marshal(cook.Ack{Acknowledged: true, JobID: rEnvelope.JobID})
 		m.Respond(ackB)
@@ -107,6 +183,47 @@ func natsInit(nc *nats.Conn) error {
 		return err
 	}
 
+	// Stop a running or queued job for the jobs.cancel API.
+	_, err = nc.Subscribe("grlx.sprouts."+sproutID+".cancel", func(m *nats.Msg) {
+		signed, verifyErr := pki.VerifyFarmerMessage(m.Subject, m.Data)
+		if verifyErr != nil {
+			log.Errorf("rejected cancel request on %s: %v", m.Subject, verifyErr)
+			return
+		}
+		var req struct {
+			JID string `json:"jid"`
+		}
+		if err := json.Unmarshal(signed.Payload, &req); err != nil || req.JID == "" {
+			log.Errorf("invalid cancel request: %s", string(signed.Payload))
+			return
+		}
+		if req.JID != signed.JobID {
+			log.Errorf("rejected cancel request on %s: job %s does not match signed job %s", m.Subject, req.JID, signed.JobID)
+			return
+		}
+		if cook.CancelJob(req.JID) {
+			log.Noticef("cancelled job %s", req.JID)
+		} else {
+			log.Debugf("cancel request for unknown job %s", req.JID)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Report running and queued jobs for the jobs.queue API.
+	_, err = nc.Subscribe("grlx.sprouts."+sproutID+".jobs.queue", func(m *nats.Msg) {
+		if _, verifyErr := pki.VerifyFarmerMessage(m.Subject, m.Data); verifyErr != nil {
+			log.Errorf("rejected queue request on %s: %v", m.Subject, verifyErr)
+			return
+		}
+		b, _ := json.Marshal(cook.DefaultScheduler().Jobs())
+		m.Respond(b)
+	})
+	if err != nil {
+		return err
+	}
+
 	// Interactive shell sessions.
 	_, err = nc.Subscribe("grlx.sprouts."+sproutID+".shell.start", func(m *nats.Msg) {
 		shell.HandleShellStart(nc, m)
@@ -115,5 +232,5 @@ func natsInit(nc *nats.Conn) error {
 		return err
 	}
 
-	return nil
+	return announce(nc)
 }
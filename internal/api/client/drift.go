@@ -0,0 +1,58 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gogrlx/grlx/v2/internal/drift"
+)
+
+// DriftCheckResponse identifies a drift check started on the farmer.
+type DriftCheckResponse struct {
+	JID       string   `json:"jid"`
+	SproutIDs []string `json:"sprout_ids"`
+}
+
+// ListDrift retrieves the latest drift report of every checked sprout.
+func ListDrift() ([]drift.Report, error) {
+	resp, err := NatsRequest("drift.list", nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Reports []drift.Report `json:"reports"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("list drift: %w", err)
+	}
+	return result.Reports, nil
+}
+
+// GetDrift retrieves the latest drift report of a sprout.
+func GetDrift(sproutID string) (drift.Report, error) {
+	params := map[string]string{"sprout_id": sproutID}
+	resp, err := NatsRequest("drift.get", params)
+	if err != nil {
+		return drift.Report{}, err
+	}
+	var r drift.Report
+	if err := json.Unmarshal(resp, &r); err != nil {
+		return drift.Report{}, fmt.Errorf("get drift: %w", err)
+	}
+	return r, nil
+}
+
+// CheckDrift starts a drift check of sproutIDs, or of every sprout the
+// current user may cook on if none are given.
+func CheckDrift(sproutIDs []string) (*DriftCheckResponse, error) {
+	params := map[string][]string{"sprout_ids": sproutIDs}
+	resp, err := NatsRequest("drift.check", params)
+	if err != nil {
+		return nil, err
+	}
+	var result DriftCheckResponse
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("check drift: %w", err)
+	}
+	return &result, nil
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gogrlx/grlx/v2/internal/drift"
+)
+
+func TestListDrift_Success(t *testing.T) {
+	cleanup := startTestNATS(t)
+	defer cleanup()
+
+	since := time.Date(2026, 1, 1, 3, 0, 0, 0, time.UTC)
+	mockHandler(t, NatsConn, "grlx.api.drift.list", map[string]any{
+		"reports": []drift.Report{
+			{SproutID: "web-01", JID: "jid-1", Steps: []drift.Step{{ID: "motd", Since: since}}, Appeared: []string{"motd"}},
+			{SproutID: "db-01", JID: "jid-1", Steps: []drift.Step{}, Resolved: []string{"sshd"}},
+		},
+	})
+
+	got, err := ListDrift()
+	if err != nil {
+		t.Fatalf("ListDrift: %v", err)
+	}
+	if len(got) != 2 || !got[0].Drifted() || !got[0].Steps[0].Since.Equal(since) || got[1].Drifted() {
+		t.Fatalf("unexpected reports %+v", got)
+	}
+}
+
+func TestCheckDrift_Success(t *testing.T) {
+	cleanup := startTestNATS(t)
+	defer cleanup()
+
+	mockHandler(t, NatsConn, "grlx.api.drift.check", map[string]any{
+		"jid":        "jid-2",
+		"sprout_ids": []string{"web-01"},
+	})
+
+	got, err := CheckDrift(nil)
+	if err != nil {
+		t.Fatalf("CheckDrift: %v", err)
+	}
+	if got.JID != "jid-2" || len(got.SproutIDs) != 1 {
+		t.Fatalf("unexpected response %+v", got)
+	}
+}
+
+func TestGetDrift_Error(t *testing.T) {
+	cleanup := startTestNATS(t)
+	defer cleanup()
+
+	mockErrorHandler(t, NatsConn, "grlx.api.drift.get", "sprout has not been checked for drift")
+
+	if _, err := GetDrift("web-01"); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -7,7 +7,7 @@ import (
 
 func TestInjectToken_NilPayload(t *testing.T) {
 	// Without valid auth config, injectToken should return nil unchanged.
-	got := injectToken(nil)
+	got := injectToken("jobs.list", nil)
 	if got != nil {
 		// If auth is configured in test env, the token gets injected.
 		// Just verify it's valid JSON.
@@ -19,7 +19,7 @@ func TestInjectToken_NilPayload(t *testing.T) {
 }
 
 func TestInjectToken_EmptyPayload(t *testing.T) {
-	got := injectToken([]byte{})
+	got := injectToken("jobs.list", []byte{})
 	if len(got) == 0 {
 		// No auth configured — returned empty unchanged.
 		return
@@ -32,7 +32,7 @@ func TestInjectToken_EmptyPayload(t *testing.T) {
 
 func TestInjectToken_ExistingJSON(t *testing.T) {
 	input := []byte(`{"limit":10,"sprout_id":"web-01"}`)
-	got := injectToken(input)
+	got := injectToken("jobs.list", input)
 
 	// Without auth config, payload should be returned unchanged.
 	var obj map[string]interface{}
@@ -52,7 +52,7 @@ func TestInjectToken_ExistingJSON(t *testing.T) {
 func TestInjectToken_NonObjectPayload(t *testing.T) {
 	// Array payload — can't inject token, should return unchanged.
 	input := []byte(`[1,2,3]`)
-	got := injectToken(input)
+	got := injectToken("jobs.list", input)
 	if string(got) != string(input) {
 		// This is fine either way — just ensure it's valid JSON.
 		var arr []interface{}
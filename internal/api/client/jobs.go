@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/gogrlx/grlx/v2/internal/cook"
 	"github.com/gogrlx/grlx/v2/internal/jobs"
 )
 
@@ -53,6 +54,21 @@ func ListJobsForSprout(sproutID string) ([]jobs.JobSummary, error) {
 	return summaries, nil
 }
 
+// ListSproutQueue returns the jobs a sprout is cooking and the ones
+// waiting for a free cook slot.
+func ListSproutQueue(sproutID string) ([]cook.ScheduledJob, error) {
+	params := map[string]string{"sprout_id": sproutID}
+	resp, err := NatsRequest("jobs.queue", params)
+	if err != nil {
+		return nil, err
+	}
+	var queue []cook.ScheduledJob
+	if err := json.Unmarshal(resp, &queue); err != nil {
+		return nil, fmt.Errorf("list sprout queue: %w", err)
+	}
+	return queue, nil
+}
+
 // DeleteJob deletes a job from the farmer-side store.
 func DeleteJob(jid string) error {
 	params := map[string]string{"jid": jid}
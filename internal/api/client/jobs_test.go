@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gogrlx/grlx/v2/internal/cook"
 	"github.com/gogrlx/grlx/v2/internal/jobs"
 )
 
@@ -118,6 +119,28 @@ func TestListJobsForSprout_Success(t *testing.T) {
 	}
 }
 
+func TestListSproutQueue_Success(t *testing.T) {
+	cleanup := startTestNATS(t)
+	defer cleanup()
+
+	want := []cook.ScheduledJob{
+		{JobID: "jid-001", State: cook.JobRunning, Since: time.Now().UTC()},
+		{JobID: "jid-002", State: cook.JobQueued, Position: 1, Since: time.Now().UTC()},
+	}
+	mockHandler(t, NatsConn, "grlx.api.jobs.queue", want)
+
+	got, err := ListSproutQueue("web-01")
+	if err != nil {
+		t.Fatalf("ListSproutQueue: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 jobs, got %d", len(got))
+	}
+	if got[1].State != cook.JobQueued || got[1].Position != 1 {
+		t.Fatalf("expected queued job at position 1, got %+v", got[1])
+	}
+}
+
 func TestDeleteJob_Success(t *testing.T) {
 	cleanup := startTestNATS(t)
 	defer cleanup()
@@ -51,7 +51,14 @@ func NewNatsClient() (*nats.Conn, error) {
 		MinVersion: tls.VersionTLS12,
 	}
 
-	connOpts := []nats.Option{nats.Name("grlx-cli"), nats.Nkey(pubkey, auth.Sign), nats.Secure(tlsCfg)}
+	// Replies arrive under the user's own inbox prefix, the only inbox the
+	// farmer lets this user subscribe to.
+	connOpts := []nats.Option{
+		nats.Name("grlx-cli"),
+		nats.Nkey(pubkey, auth.Sign),
+		nats.Secure(tlsCfg),
+		nats.CustomInboxPrefix(auth.InboxPrefix(pubkey)),
+	}
 
 	log.Tracef("Connecting to %s", URL)
 	return nats.Connect(URL, connOpts...)
@@ -90,7 +97,7 @@ func NatsRequest(method string, params any) (json.RawMessage, error) {
 
 	// Inject the auth token into the JSON payload so the farmer can
 	// identify the invoking user for attribution and RBAC checks.
-	data = injectToken(data)
+	data = injectToken(method, data)
 
 	msg, err := NatsConn.Request(subject, data, NatsRequestTimeout)
 	if err != nil {
@@ -109,12 +116,13 @@ func NatsRequest(method string, params any) (json.RawMessage, error) {
 	return resp.Result, nil
 }
 
-// injectToken merges a "token" field into the JSON payload. If the
-// payload is nil or empty, it creates a new JSON object with just
-// the token. If the token cannot be generated, the payload is
-// returned unchanged (the request will proceed unauthenticated).
-func injectToken(data []byte) []byte {
-	token, err := auth.NewToken()
+// injectToken merges a "token" field into the JSON payload. The token is
+// bound to method and the payload, so it cannot be replayed against
+// another request. If the payload is nil or empty, it creates a new JSON
+// object with just the token. If the token cannot be generated, the
+// payload is returned unchanged (the request will proceed unauthenticated).
+func injectToken(method string, data []byte) []byte {
+	token, err := auth.NewRequestToken(method, data)
 	if err != nil {
 		return data
 	}
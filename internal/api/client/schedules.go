@@ -0,0 +1,71 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gogrlx/grlx/v2/internal/schedule"
+)
+
+// ListSchedules retrieves every schedule from the farmer.
+func ListSchedules() ([]schedule.Entry, error) {
+	resp, err := NatsRequest("schedule.list", nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Schedules []schedule.Entry `json:"schedules"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("list schedules: %w", err)
+	}
+	return result.Schedules, nil
+}
+
+// GetSchedule retrieves the named schedule from the farmer.
+func GetSchedule(name string) (schedule.Entry, error) {
+	return scheduleRequest("schedule.get", name)
+}
+
+// CreateSchedule creates a schedule owned by the current user.
+func CreateSchedule(e schedule.Entry) (schedule.Entry, error) {
+	resp, err := NatsRequest("schedule.create", e)
+	if err != nil {
+		return schedule.Entry{}, err
+	}
+	var created schedule.Entry
+	if err := json.Unmarshal(resp, &created); err != nil {
+		return schedule.Entry{}, fmt.Errorf("create schedule: %w", err)
+	}
+	return created, nil
+}
+
+// DeleteSchedule deletes the named schedule.
+func DeleteSchedule(name string) error {
+	params := map[string]string{"name": name}
+	_, err := NatsRequest("schedule.delete", params)
+	return err
+}
+
+// PauseSchedule stops the named schedule from running until it is resumed.
+func PauseSchedule(name string) (schedule.Entry, error) {
+	return scheduleRequest("schedule.pause", name)
+}
+
+// ResumeSchedule lets a paused schedule run again.
+func ResumeSchedule(name string) (schedule.Entry, error) {
+	return scheduleRequest("schedule.resume", name)
+}
+
+func scheduleRequest(method, name string) (schedule.Entry, error) {
+	params := map[string]string{"name": name}
+	resp, err := NatsRequest(method, params)
+	if err != nil {
+		return schedule.Entry{}, err
+	}
+	var e schedule.Entry
+	if err := json.Unmarshal(resp, &e); err != nil {
+		return schedule.Entry{}, fmt.Errorf("%s: %w", method, err)
+	}
+	return e, nil
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gogrlx/grlx/v2/internal/schedule"
+)
+
+func TestListSchedules_Success(t *testing.T) {
+	cleanup := startTestNATS(t)
+	defer cleanup()
+
+	mockHandler(t, NatsConn, "grlx.api.schedule.list", map[string]any{
+		"schedules": []schedule.Entry{
+			{Name: "nightly", Cron: "0 3 * * *", Cohort: "web", Recipe: "patch", Source: schedule.SourceConfig},
+			{Name: "uptime", Interval: 15 * time.Minute, Cohort: "all", Command: "uptime", Paused: true},
+		},
+	})
+
+	got, err := ListSchedules()
+	if err != nil {
+		t.Fatalf("ListSchedules: %v", err)
+	}
+	if len(got) != 2 || got[0].Name != "nightly" || got[1].Interval != 15*time.Minute || !got[1].Paused {
+		t.Fatalf("unexpected schedules %+v", got)
+	}
+}
+
+func TestPauseSchedule_Error(t *testing.T) {
+	cleanup := startTestNATS(t)
+	defer cleanup()
+
+	mockErrorHandler(t, NatsConn, "grlx.api.schedule.pause", "no such schedule")
+
+	if _, err := PauseSchedule("missing"); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -3,14 +3,21 @@ package client
 import (
 	"encoding/json"
 	"fmt"
+	"time"
+
+	"github.com/gogrlx/grlx/v2/internal/config"
 )
 
 // SproutInfo represents a sprout with its key state and connectivity status.
 type SproutInfo struct {
-	ID        string `json:"id"`
-	KeyState  string `json:"key_state"`
-	Connected bool   `json:"connected"`
-	NKey      string `json:"nkey,omitempty"`
+	ID             string          `json:"id"`
+	KeyState       string          `json:"key_state"`
+	Connected      bool            `json:"connected"`
+	NKey           string          `json:"nkey,omitempty"`
+	LastSeen       *time.Time      `json:"last_seen,omitempty"`
+	ConnectedSince *time.Time      `json:"connected_since,omitempty"`
+	Version        *config.Version `json:"version,omitempty"`
+	Reconnects     int             `json:"reconnects,omitempty"`
 }
 
 // SproutListResponse is the response from the sprouts.list API.
@@ -9,6 +9,7 @@ import (
 
 	//. "github.com/gogrlx/grlx/v2/internal/config"
 	apitypes "github.com/gogrlx/grlx/v2/internal/api/types"
+	"github.com/gogrlx/grlx/v2/internal/cook"
 	"github.com/gogrlx/grlx/v2/internal/ingredients/cmd"
 	log "github.com/gogrlx/grlx/v2/internal/log"
 	"github.com/gogrlx/grlx/v2/internal/pki"
@@ -51,6 +52,10 @@ func HCmdRun(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if command.JobID == "" {
+		command.JobID = cook.GenerateJobID()
+	}
+
 	var results apitypes.TargetedResults
 	var wg sync.WaitGroup
 	var m sync.Mutex
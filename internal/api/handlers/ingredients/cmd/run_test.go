@@ -12,6 +12,7 @@ import (
 
 	natsserver "github.com/nats-io/nats-server/v2/server"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
 
 	apitypes "github.com/gogrlx/grlx/v2/internal/api/types"
 	"github.com/gogrlx/grlx/v2/internal/config"
@@ -28,6 +29,16 @@ func setupCmdTestPKI(t *testing.T) string {
 			t.Fatal(err)
 		}
 	}
+	// Commands are signed with the farmer's NKey before they are sent.
+	kp, err := nkeys.CreateUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed, _ := kp.Seed()
+	config.NKeyFarmerPrivFile = filepath.Join(dir, "farmer.nkey")
+	if err = os.WriteFile(config.NKeyFarmerPrivFile, seed, 0o600); err != nil {
+		t.Fatal(err)
+	}
 	return dir
 }
 
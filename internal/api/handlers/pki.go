@@ -98,3 +98,10 @@ func PutNKey(w http.ResponseWriter, r *http.Request) {
 func GetCertificate(w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, config.RootCA)
 }
+
+// GetFarmerNKey serves the farmer's public NKey. Sprouts pin it at
+// enrollment and verify the farmer's signature on every recipe and
+// command against it.
+func GetFarmerNKey(w http.ResponseWriter, r *http.Request) {
+	http.ServeFile(w, r, config.NKeyFarmerPubFile)
+}
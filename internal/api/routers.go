@@ -21,6 +21,7 @@ func NewRouter(certificate string) *http.ServeMux {
 	// PKI bootstrap routes (no auth required — pre-enrollment sprouts use these)
 	mux.Handle("GET /auth/cert/", Logger(http.HandlerFunc(handlers.GetCertificate), "GetCertificate"))
 	mux.Handle("PUT /pki/putnkey", Logger(http.HandlerFunc(handlers.PutNKey), "PutNKey"))
+	mux.Handle("GET /pki/farmernkey", Logger(http.HandlerFunc(handlers.GetFarmerNKey), "GetFarmerNKey"))
 
 	// Health check (unauthenticated).
 	mux.Handle("GET /health", Logger(http.HandlerFunc(handlers.GetHealth), "GetHealth"))
@@ -13,11 +13,31 @@ type (
 	CmdCook struct {
 		Async   bool            `json:"async"`
 		Env     string          `json:"env"`
+		Queue   bool            `json:"queue,omitempty"`
 		Recipe  cook.RecipeName `json:"recipe"`
 		State   string          `json:"state,omitempty"`
 		Test    bool            `json:"test"`
 		Timeout time.Duration   `json:"timeout"`
 
+		// Batch, when set, cooks the targets a batch at a time rather than
+		// all at once. It is a number of sprouts ("5") or a percentage of
+		// the targets ("20%"). BatchWait pauses between batches, and
+		// MaxFail, in the same form as Batch, stops the remaining batches
+		// once more sprouts than it allows have failed.
+		Batch     string        `json:"batch,omitempty"`
+		BatchWait time.Duration `json:"batch_wait,omitempty"`
+		MaxFail   string        `json:"max_fail,omitempty"`
+
+		// Top cooks each target with the recipes the farmer's top file
+		// assigns it, in place of Recipe. TopMatches reports, for each
+		// target, the top file entries that matched it and their recipes.
+		Top        bool                          `json:"top,omitempty"`
+		TopMatches map[string]cook.TopAssignment `json:"top_matches,omitempty"`
+
+		// Sprouts lists the sprouts the job is sent to, so the CLI can
+		// subscribe to each one's completions before triggering it.
+		Sprouts []string `json:"sprouts,omitempty"`
+
 		Errors map[string]error `json:"errors"`
 		JID    string           `json:"jid"`
 	}
@@ -30,6 +50,10 @@ type (
 		Env     EnvVar        `json:"env"`
 		Timeout time.Duration `json:"timeout"`
 
+		// JobID identifies the run on the sprout so that it can be
+		// cancelled like a cook job.
+		JobID string `json:"jid,omitempty"`
+
 		// StreamTopic, when set, enables live output streaming over NATS.
 		// Each chunk of stdout/stderr is published to this topic as JSON.
 		StreamTopic string `json:"stream_topic,omitempty"`
@@ -102,6 +126,12 @@ type ExplainResponse struct {
 	IsAdmin  bool                 `json:"isAdmin"`
 	Actions  []ActionExplain      `json:"actions"`
 	Warnings []rbac.PolicyWarning `json:"warnings,omitempty"`
+	// TokenFormat is the format of the token the request was made with:
+	// "request-bound" or "legacy".
+	TokenFormat string `json:"tokenFormat,omitempty"`
+	// LegacyTokensUntil is the end of the farmer's legacy token
+	// migration window, if one is set.
+	LegacyTokensUntil string `json:"legacyTokensUntil,omitempty"`
 }
 
 // ActionExplain describes a single permitted action.
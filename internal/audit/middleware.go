@@ -82,6 +82,10 @@ var readOnlyActions = map[string]bool{
 	"pki.list":       true,
 	"recipes.list":   true,
 	"recipes.get":    true,
+	"schedule.list":  true,
+	"schedule.get":   true,
+	"drift.list":     true,
+	"drift.get":      true,
 	"audit.dates":    true,
 	"audit.query":    true,
 }
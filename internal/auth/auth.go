@@ -29,6 +29,9 @@ var (
 	roleStore   *rbac.RoleStore
 	userRoleMap *rbac.UserRoleMap
 	cohortReg   *rbac.Registry
+
+	listenersMu     sync.Mutex
+	policyListeners []func()
 )
 
 // LoadPolicy reads roles, users, and cohorts from the farmer config.
@@ -37,6 +40,12 @@ var (
 // (same pubkey under multiple roles).
 // It validates the policy and logs warnings for misconfigurations.
 func LoadPolicy() error {
+	changed := false
+	defer func() {
+		if changed {
+			notifyPolicyChange()
+		}
+	}()
 	policyMu.Lock()
 	defer policyMu.Unlock()
 
@@ -88,9 +97,35 @@ func LoadPolicy() error {
 		log.Warnf("rbac policy: [%s] %s", w.Kind, w.Message)
 	}
 
+	changed = true
 	return nil
 }
 
+// OnPolicyChange registers fn to be called whenever the policy is
+// reloaded or a user is added or removed. Listeners run after the policy
+// lock is released, so they may read the new policy.
+func OnPolicyChange(fn func()) {
+	listenersMu.Lock()
+	defer listenersMu.Unlock()
+	policyListeners = append(policyListeners, fn)
+}
+
+func notifyPolicyChange() {
+	listenersMu.Lock()
+	listeners := append([]func(){}, policyListeners...)
+	listenersMu.Unlock()
+	for _, fn := range listeners {
+		fn()
+	}
+}
+
+// InboxPrefix returns the reply inbox prefix the CLI connects with. Each
+// user may only subscribe beneath its own prefix, so replies meant for
+// one user cannot be read by another.
+func InboxPrefix(pubkey string) string {
+	return "_INBOX." + pubkey
+}
+
 // currentPolicyLocked returns a Policy snapshot. Must be called with
 // policyMu held (at least read).
 func currentPolicyLocked() *rbac.Policy {
@@ -304,6 +339,22 @@ func TokenScopeFilter(token string, action rbac.Action, sproutIDs []string, allS
 	return role.ScopeFilter(action, sproutIDs, resolver)
 }
 
+// PubkeyScopeFilter returns the subset of sproutIDs that the role of the
+// user with pubkey permits for the given action. It is for work done on a
+// user's behalf without a token, such as the runs of a schedule the user
+// created.
+func PubkeyScopeFilter(pubkey string, action rbac.Action, sproutIDs []string, allSproutIDs []string) []string {
+	if DangerouslyAllowRoot() {
+		return sproutIDs
+	}
+	role := lookupRole(pubkey)
+	if role == nil {
+		return nil
+	}
+	resolver := CohortResolver(allSproutIDs)
+	return role.ScopeFilter(action, sproutIDs, resolver)
+}
+
 // WhoAmI returns the public key, role name, and username for a given token.
 func WhoAmI(token string) (pubkey string, roleName string, username string, err error) {
 	ua, err := decodeToken(token)
@@ -445,6 +496,12 @@ func GetRole(name string) (*rbac.Role, error) {
 // AddUser adds a pubkey→role mapping to the config and reloads the policy.
 // It writes to the "users" config section (new format).
 func AddUser(pubkey, roleName string) error {
+	changed := false
+	defer func() {
+		if changed {
+			notifyPolicyChange()
+		}
+	}()
 	policyMu.Lock()
 	defer policyMu.Unlock()
 
@@ -483,11 +540,18 @@ func AddUser(pubkey, roleName string) error {
 		userRoleMap.Set(pubkey, roleName)
 	}
 
+	changed = true
 	return nil
 }
 
 // RemoveUser removes a pubkey from the config and reloads the policy.
 func RemoveUser(pubkey string) error {
+	changed := false
+	defer func() {
+		if changed {
+			notifyPolicyChange()
+		}
+	}()
 	policyMu.Lock()
 	defer policyMu.Unlock()
 
@@ -549,6 +613,7 @@ func RemoveUser(pubkey string) error {
 		userRoleMap.Delete(pubkey)
 	}
 
+	changed = true
 	return nil
 }
 
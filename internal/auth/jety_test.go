@@ -337,6 +337,48 @@ func TestAddUserSuccess(t *testing.T) {
 	}
 }
 
+func TestOnPolicyChange(t *testing.T) {
+	setupJetyForTest(t)
+	defer clearJetyKeys(t)
+	defer func(orig []func()) { policyListeners = orig }(policyListeners)
+
+	rs := rbac.NewRoleStore()
+	rs.Register(&rbac.Role{
+		Name:  "admin",
+		Rules: []rbac.Rule{{Action: rbac.ActionAdmin, Scope: "*"}},
+	})
+	SetPolicy(rs, rbac.NewUserRoleMap(), nil)
+	defer SetPolicy(nil, nil, nil)
+
+	calls := 0
+	OnPolicyChange(func() {
+		// Listeners run without the policy lock held.
+		CurrentPolicy()
+		calls++
+	})
+
+	kp, _ := nkeys.CreateAccount()
+	pk, _ := kp.PublicKey()
+	if err := AddUser(pk, "admin"); err != nil {
+		t.Fatalf("AddUser failed: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 notification after AddUser, got %d", calls)
+	}
+	if err := AddUser(pk, "admin"); err != ErrUserExists {
+		t.Fatalf("expected ErrUserExists, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected a failed AddUser not to notify, got %d calls", calls)
+	}
+	if err := RemoveUser(pk); err != nil {
+		t.Fatalf("RemoveUser failed: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 notifications after RemoveUser, got %d", calls)
+	}
+}
+
 func TestAddUserInvalidPubkey(t *testing.T) {
 	setupJetyForTest(t)
 	defer clearJetyKeys(t)
@@ -237,6 +237,35 @@ func TestTokenScopeFilterWildcard(t *testing.T) {
 	}
 }
 
+func TestPubkeyScopeFilter(t *testing.T) {
+	rs := rbac.NewRoleStore()
+	rs.Register(&rbac.Role{
+		Name:  "web-operator",
+		Rules: []rbac.Rule{{Action: rbac.ActionCook, Scope: "cohort:web-servers"}},
+	})
+	urm := rbac.NewUserRoleMap()
+	urm.Set("UWEBOPERATOR", "web-operator")
+	reg := rbac.NewRegistry()
+	reg.Register(&rbac.Cohort{
+		Name:    "web-servers",
+		Type:    rbac.CohortTypeStatic,
+		Members: []string{"web-1"},
+	})
+	SetPolicy(rs, urm, reg)
+	defer SetPolicy(nil, nil, nil)
+
+	sprouts := []string{"web-1", "db-1"}
+	if got := PubkeyScopeFilter("UWEBOPERATOR", rbac.ActionCook, sprouts, sprouts); len(got) != 1 || got[0] != "web-1" {
+		t.Errorf("expected only web-1, got %v", got)
+	}
+	if got := PubkeyScopeFilter("UWEBOPERATOR", rbac.ActionCmd, sprouts, sprouts); len(got) != 0 {
+		t.Errorf("expected no sprouts without the cmd action, got %v", got)
+	}
+	if got := PubkeyScopeFilter("UUNKNOWN", rbac.ActionCook, sprouts, sprouts); got != nil {
+		t.Errorf("expected nil for an unknown user, got %v", got)
+	}
+}
+
 func TestWhoAmIWithValidToken(t *testing.T) {
 	token, pk := makeValidToken(t)
 
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nkeys"
+	"github.com/taigrr/jety"
+
+	"github.com/gogrlx/grlx/v2/internal/log"
+)
+
+// usedNonces remembers the nonces of request-bound tokens the farmer has
+// accepted, until the tokens expire. The cache is held in memory only, so
+// a token used before the farmer restarts can be replayed once more after
+// it, until the token expires; requestTokenTTL bounds that window.
+var usedNonces = &nonceCache{seen: map[string]time.Time{}}
+
+type nonceCache struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// use records nonce as used until expires. It returns false if the nonce
+// was already used.
+func (c *nonceCache) use(nonce string, expires, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for n, exp := range c.seen {
+		if now.After(exp) {
+			delete(c.seen, n)
+		}
+	}
+	if _, ok := c.seen[nonce]; ok {
+		return false
+	}
+	c.seen[nonce] = expires
+	return true
+}
+
+// NewRequestToken creates a token for a single call to the API method
+// with params, signed with the local private key.
+func NewRequestToken(method string, params []byte) (string, error) {
+	seed, err := getPrivateSeed()
+	if err != nil {
+		return "", err
+	}
+	kp, err := nkeys.FromSeed([]byte(seed))
+	if err != nil {
+		return "", err
+	}
+	defer kp.Wipe()
+	return createRequestToken(kp, method, params, time.Now())
+}
+
+// VerifyRequestToken checks that token may be used for a call to method
+// with params, and returns the token's format. Request-bound tokens must
+// match the method and params and are accepted only once. Legacy tokens,
+// which any request could replay, are rejected unless the farmer sets a
+// legacy_tokens_until deadline, and are accepted only until it passes.
+func VerifyRequestToken(token string, method string, params []byte) (string, error) {
+	ua, err := decodeToken(token)
+	if err != nil {
+		return "", errors.Join(ErrTokenMalformed, err)
+	}
+	if _, err = ua.IsValid(); err != nil {
+		return ua.Format(), err
+	}
+	if ua.Version < TokenVersionRequest {
+		if err = legacyTokensAllowed(time.Now()); err != nil {
+			return ua.Format(), err
+		}
+		log.Debugf("accepted legacy auth token from %s for %s", ua.Pubkey, method)
+		return ua.Format(), nil
+	}
+	if !ua.boundTo(method, params) {
+		return ua.Format(), ErrTokenNotBound
+	}
+	expires, _ := time.Parse(time.RFC3339, ua.Expires)
+	if !usedNonces.use(ua.Nonce, expires, time.Now()) {
+		return ua.Format(), ErrTokenReplayed
+	}
+	return ua.Format(), nil
+}
+
+// LegacyTokensUntil returns the farmer's legacy_tokens_until setting:
+// the end of the migration window during which clients that predate
+// request-bound tokens may still connect. It is empty if no window has
+// been opened.
+func LegacyTokensUntil() string {
+	return jety.GetString("legacy_tokens_until")
+}
+
+// legacyTokensAllowed returns nil if legacy tokens are still accepted at
+// now. The deadline may be an RFC 3339 time or a date; a missing or
+// unparseable deadline closes the window rather than leaving it open.
+func legacyTokensAllowed(now time.Time) error {
+	until := LegacyTokensUntil()
+	if until == "" {
+		return errors.Join(ErrLegacyToken, errors.New("legacy tokens are rejected unless legacy_tokens_until is set"))
+	}
+	deadline, err := time.Parse(time.RFC3339, until)
+	if err != nil {
+		deadline, err = time.Parse(time.DateOnly, until)
+	}
+	if err != nil {
+		log.Errorf("invalid legacy_tokens_until %q, rejecting legacy tokens: %v", until, err)
+		return ErrLegacyToken
+	}
+	if now.After(deadline) {
+		return errors.Join(ErrLegacyToken, fmt.Errorf("legacy tokens were accepted until %s", until))
+	}
+	return nil
+}
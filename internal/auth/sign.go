@@ -1,27 +1,78 @@
 package auth
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/nats-io/nkeys"
 )
 
+// TokenVersionRequest marks a token bound to a single API request.
+const TokenVersionRequest = 2
+
+// requestTokenTTL is how long a request-bound token stays valid. It only
+// has to survive the round trip to the farmer.
+const requestTokenTTL = 2 * time.Minute
+
 type UserAuth struct {
 	Expires string `json:"expires"`
 	Pubkey  string `json:"pubkey"`
 	Sig     string `json:"sig"`
+
+	// Request-bound tokens also sign the API method, a hash of the request
+	// params, a nonce and the time they were issued, and are accepted only
+	// once. Legacy tokens leave these empty and sign only Expires.
+	Version  int    `json:"v,omitempty"`
+	Method   string `json:"method,omitempty"`
+	Params   string `json:"params,omitempty"`
+	Nonce    string `json:"nonce,omitempty"`
+	IssuedAt string `json:"iat,omitempty"`
 }
 
-var ErrExpired = errors.New("auth token expired")
+var (
+	ErrExpired        = errors.New("auth token expired")
+	ErrTokenNotBound  = errors.New("auth token was issued for a different request")
+	ErrTokenReplayed  = errors.New("auth token has already been used")
+	ErrLegacyToken    = errors.New("legacy auth tokens are no longer accepted, please upgrade grlx")
+	ErrTokenIssuedAt  = errors.New("auth token issue time is invalid")
+	ErrTokenMalformed = errors.New("auth token is malformed")
+)
+
+// signedBytes returns the bytes the token's signature covers.
+func (u UserAuth) signedBytes() []byte {
+	if u.Version < TokenVersionRequest {
+		return []byte(u.Expires)
+	}
+	return []byte(strings.Join([]string{
+		"grlx-token-v2",
+		u.Pubkey,
+		u.Method,
+		u.Params,
+		u.Nonce,
+		u.IssuedAt,
+		u.Expires,
+	}, "\n"))
+}
+
+// Format describes the token format, as shown by auth.explain.
+func (u UserAuth) Format() string {
+	if u.Version >= TokenVersionRequest {
+		return "request-bound"
+	}
+	return "legacy"
+}
 
 // Sign adds a signature digest to the UserAuth struct using the provided
 // KeyPair. The signature digest is base64 encoded.
 func (u UserAuth) Sign(kp nkeys.KeyPair) (UserAuth, error) {
-	b, err := kp.Sign([]byte(u.Expires))
+	b, err := kp.Sign(u.signedBytes())
 	if err != nil {
 		return u, err
 	}
@@ -49,7 +100,47 @@ func (u UserAuth) IsValid() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return u.Pubkey, kp.Verify([]byte(u.Expires), sig)
+	if u.Version >= TokenVersionRequest {
+		iat, iatErr := time.Parse(time.RFC3339, u.IssuedAt)
+		if iatErr != nil || iat.After(exp) {
+			return "", ErrTokenIssuedAt
+		}
+	}
+	return u.Pubkey, kp.Verify(u.signedBytes(), sig)
+}
+
+// boundTo reports whether a request-bound token was issued for method
+// with params.
+func (u UserAuth) boundTo(method string, params []byte) bool {
+	hash, err := ParamsHash(params)
+	if err != nil {
+		return false
+	}
+	return u.Method == method && u.Params == hash
+}
+
+// ParamsHash returns the hash of a request's params that a request-bound
+// token signs. The token field itself is left out, and the params are
+// re-encoded with sorted keys so that client and farmer hash the same
+// bytes however the JSON was laid out.
+func ParamsHash(params []byte) (string, error) {
+	var v any = map[string]any{}
+	if len(bytes.TrimSpace(params)) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(params))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return "", errors.Join(ErrTokenMalformed, err)
+		}
+	}
+	if m, ok := v.(map[string]any); ok {
+		delete(m, "token")
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return "", errors.Join(ErrTokenMalformed, err)
+	}
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
 }
 
 // decodeToken decodes a base64 encoded token and returns the UserAuth
@@ -86,3 +177,38 @@ func createSignedToken(kp nkeys.KeyPair) (string, error) {
 	}
 	return base64.StdEncoding.EncodeToString(b), nil
 }
+
+// createRequestToken creates a token that is only valid for one call to
+// method with params, for a short time.
+func createRequestToken(kp nkeys.KeyPair, method string, params []byte, now time.Time) (string, error) {
+	pk, err := kp.PublicKey()
+	if err != nil {
+		return "", err
+	}
+	hash, err := ParamsHash(params)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, 16)
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	ua := UserAuth{
+		Version:  TokenVersionRequest,
+		Pubkey:   pk,
+		Method:   method,
+		Params:   hash,
+		Nonce:    base64.RawURLEncoding.EncodeToString(nonce),
+		IssuedAt: now.UTC().Format(time.RFC3339),
+		Expires:  now.UTC().Add(requestTokenTTL).Format(time.RFC3339),
+	}
+	ua, err = ua.Sign(kp)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(ua)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
@@ -3,10 +3,12 @@ package auth
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/nats-io/nkeys"
+	"github.com/taigrr/jety"
 )
 
 // helper: create an nkeys account keypair for testing.
@@ -251,3 +253,92 @@ func TestSignPreservesOtherFields(t *testing.T) {
 		t.Errorf("Sign changed Pubkey: got %q, want %q", signed.Pubkey, pk)
 	}
 }
+
+func TestRequestToken(t *testing.T) {
+	kp := mustCreateKeyPair(t)
+	pk, _ := kp.PublicKey()
+	now := time.Now()
+
+	token, err := createRequestToken(kp, "cook", []byte(`{"b":1,"a":"x"}`), now)
+	if err != nil {
+		t.Fatalf("createRequestToken: %v", err)
+	}
+	ua, err := decodeToken(token)
+	if err != nil {
+		t.Fatalf("decodeToken: %v", err)
+	}
+	if ua.Format() != "request-bound" {
+		t.Errorf("Format() = %q, want request-bound", ua.Format())
+	}
+	if got, err := ua.IsValid(); err != nil || got != pk {
+		t.Fatalf("IsValid() = %q, %v", got, err)
+	}
+
+	// The binding ignores key order and the injected token itself.
+	if !ua.boundTo("cook", []byte(`{"a":"x","token":"abc","b":1}`)) {
+		t.Error("expected the token to be bound to the same params")
+	}
+	if ua.boundTo("cook", []byte(`{"a":"y","b":1}`)) {
+		t.Error("expected the token not to be bound to different params")
+	}
+	if ua.boundTo("cmd.run", []byte(`{"a":"x","b":1}`)) {
+		t.Error("expected the token not to be bound to a different method")
+	}
+
+	// Every bound field is covered by the signature.
+	for name, modify := range map[string]func(*UserAuth){
+		"method":  func(u *UserAuth) { u.Method = "cmd.run" },
+		"params":  func(u *UserAuth) { u.Params = "other" },
+		"nonce":   func(u *UserAuth) { u.Nonce = "other" },
+		"version": func(u *UserAuth) { u.Version = 0 },
+	} {
+		forged := ua
+		modify(&forged)
+		if _, err := forged.IsValid(); err == nil {
+			t.Errorf("expected a token with a modified %s to be invalid", name)
+		}
+	}
+}
+
+func TestNonceCache(t *testing.T) {
+	c := &nonceCache{seen: map[string]time.Time{}}
+	now := time.Now()
+	if !c.use("n1", now.Add(time.Minute), now) {
+		t.Fatal("expected a new nonce to be accepted")
+	}
+	if c.use("n1", now.Add(time.Minute), now) {
+		t.Error("expected a used nonce to be rejected")
+	}
+	if !c.use("n2", now.Add(time.Minute), now.Add(2*time.Minute)) {
+		t.Error("expected a new nonce to be accepted")
+	}
+	if _, ok := c.seen["n1"]; ok {
+		t.Error("expected expired nonces to be dropped")
+	}
+}
+
+func TestLegacyTokensAllowed(t *testing.T) {
+	defer jety.Set("legacy_tokens_until", "")
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		until   string
+		allowed bool
+	}{
+		{until: "", allowed: false},
+		{until: "2026-07-01", allowed: true},
+		{until: "2026-05-01", allowed: false},
+		{until: "2026-06-01T13:00:00Z", allowed: true},
+		{until: "2026-06-01T11:00:00Z", allowed: false},
+		{until: "next tuesday", allowed: false},
+	}
+	for _, tc := range tests {
+		jety.Set("legacy_tokens_until", tc.until)
+		err := legacyTokensAllowed(now)
+		if tc.allowed && err != nil {
+			t.Errorf("until %q: expected legacy tokens to be allowed, got %v", tc.until, err)
+		}
+		if !tc.allowed && !errors.Is(err, ErrLegacyToken) {
+			t.Errorf("until %q: expected %v, got %v", tc.until, ErrLegacyToken, err)
+		}
+	}
+}
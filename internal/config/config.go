@@ -58,9 +58,17 @@ var (
 	FarmerPKI             string
 	FarmerURL             string
 	GrlxRootCA            string
+	HeartbeatInterval     time.Duration
 	CohortRefreshInterval time.Duration
+	CookConcurrency       int
+	CookQueueDir          string
+	CookQueueTTL          time.Duration
+	DriftDir              string
+	DriftInterval         time.Duration
+	JetStreamDir          string
 	JobLogDir             string
 	JobLogTTL             time.Duration
+	JobStore              string
 	PropsDir              string
 	KeyFile               string
 	LogLevel              log.Level
@@ -71,11 +79,22 @@ var (
 	RecipeDir             string
 	RootCA                string
 	RootCAPriv            string
+	ScheduleFile          string
+	SproutFarmerNKeyFile  string
 	SproutID              string
 	SproutPKI             string
 	SproutRootCA          string
 )
 
+// Values for the farmer's jobstore setting.
+const (
+	// JobStoreFile keeps job history in JSONL files under JobLogDir.
+	JobStoreFile = "file"
+	// JobStoreJetStream keeps job history in a JetStream stream on the
+	// embedded NATS server, stored under JetStreamDir.
+	JobStoreJetStream = "jetstream"
+)
+
 // Binary represents the type of grlx binary being configured.
 type Binary string
 
@@ -174,8 +193,15 @@ func LoadConfig(binary string) {
 			jety.SetDefault("auditlevel", "write")
 			jety.SetDefault("joblogdir", "/var/cache/grlx/farmer/jobs")
 			jety.SetDefault("joblogttl", 30*24*time.Hour) // 30 days default
+			jety.SetDefault("jobstore", JobStoreFile)
+			jety.SetDefault("jetstreamdir", "/var/cache/grlx/farmer/jetstream")
 			jety.SetDefault("cohortrefreshinterval", 5*time.Minute)
 			jety.SetDefault("propsdir", "/var/cache/grlx/farmer/props")
+			jety.SetDefault("cookqueuedir", "/var/cache/grlx/farmer/cookqueue")
+			jety.SetDefault("cookqueuettl", 24*time.Hour)
+			jety.SetDefault("schedulefile", "/var/cache/grlx/farmer/schedules.json")
+			jety.SetDefault("driftdir", "/var/cache/grlx/farmer/drift")
+			jety.SetDefault("driftinterval", time.Hour)
 			jety.SetDefault("nkeyfarmerpubfile", filepath.Join(systemConfigRoot, "pki/farmer/farmer.nkey.pub"))
 			jety.SetDefault("nkeyfarmerprivfile", filepath.Join(systemConfigRoot, "pki/farmer/farmer.nkey"))
 			jety.SetDefault("rootca", filepath.Join(systemConfigRoot, "pki/farmer/tls-rootca.pem"))
@@ -183,7 +209,14 @@ func LoadConfig(binary string) {
 			jety.SetDefault("farmerorganization", "grlx farmer")
 			JobLogDir = jety.GetString("joblogdir")
 			JobLogTTL = jety.GetDuration("joblogttl")
+			JobStore = jety.GetString("jobstore")
+			JetStreamDir = jety.GetString("jetstreamdir")
 			PropsDir = jety.GetString("propsdir")
+			CookQueueDir = jety.GetString("cookqueuedir")
+			CookQueueTTL = jety.GetDuration("cookqueuettl")
+			ScheduleFile = jety.GetString("schedulefile")
+			DriftDir = jety.GetString("driftdir")
+			DriftInterval = jety.GetDuration("driftinterval")
 			CertHosts = jety.GetStringSlice("certhosts")
 
 			AdminPubKeys := jety.GetStringMap("pubkeys")
@@ -243,13 +276,18 @@ func LoadConfig(binary string) {
 			jety.SetDefault("sproutpki", filepath.Join(systemConfigRoot, "pki/sprout")+"/")
 			jety.SetDefault("sproutrootca", filepath.Join(systemConfigRoot, "pki/sprout/tls-rootca.pem"))
 			jety.SetDefault("nkeysproutpubfile", filepath.Join(systemConfigRoot, "pki/sprout/sprout.nkey.pub"))
+			jety.SetDefault("sproutfarmernkeyfile", filepath.Join(systemConfigRoot, "pki/sprout/farmer.nkey.pub"))
 			jety.SetDefault("joblogdir", "/var/cache/grlx/sprout/jobs")
 			jety.SetDefault("joblogttl", 30*24*time.Hour) // 30 days default
 			jety.SetDefault("nkeysproutprivfile", filepath.Join(systemConfigRoot, "pki/sprout/sprout.nkey"))
 			jety.SetDefault("cachedir", "/var/cache/grlx/sprout/files/provided")
 			jety.SetDefault("rootca_retry_delay", 5*time.Second)
 			jety.SetDefault("nkey_retry_delay", 5*time.Second)
+			jety.SetDefault("cookconcurrency", 4)
+			jety.SetDefault("heartbeatinterval", 30*time.Second)
 
+			CookConcurrency = jety.GetInt("cookconcurrency")
+			HeartbeatInterval = jety.GetDuration("heartbeatinterval")
 			JobLogDir = jety.GetString("joblogdir")
 			JobLogTTL = jety.GetDuration("joblogttl")
 		}
@@ -300,6 +338,7 @@ func LoadConfig(binary string) {
 	FarmerOrganization = jety.GetString("farmerorganization")
 	RootCA = jety.GetString("rootca")
 	RootCAPriv = jety.GetString("rootcapriv")
+	SproutFarmerNKeyFile = jety.GetString("sproutfarmernkeyfile")
 	SproutID = jety.GetString("sproutid")
 	SproutPKI = jety.GetString("sproutpki")
 	SproutRootCA = jety.GetString("sproutrootca")
@@ -337,6 +376,22 @@ func StaticProps() map[string]interface{} {
 	return jety.GetStringMap("props.static")
 }
 
+// S3Settings returns the "s3" config section as a map. The sprout's s3://
+// file provider uses it for any connection setting that a recipe step does
+// not set itself. Returns nil if the section is not defined.
+//
+// Expected YAML structure:
+//
+//	s3:
+//	  region: us-east-1
+//	  endpoint: https://minio.internal:9000
+//	  access_key_id: AKIA...
+//	  secret_access_key: ...
+//	  path_style: true
+func S3Settings() map[string]interface{} {
+	return jety.GetStringMap("s3")
+}
+
 func SetSproutID(id string) {
 	jety.Set("sproutid", id)
 	jety.WriteConfig()
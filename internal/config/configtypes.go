@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 type (
 	Version struct {
 		Arch      string `json:"arch"`
@@ -16,6 +18,13 @@ type (
 		Version  Version `json:"version"`
 		SproutID string  `json:"id"`
 	}
+	// Heartbeat is published by a sprout every Interval so the farmer can
+	// tell which sprouts are online without probing them.
+	Heartbeat struct {
+		Version  Version       `json:"version"`
+		SproutID string        `json:"id"`
+		Interval time.Duration `json:"interval"`
+	}
 	TriggerMsg struct {
 		JID string `json:"jid"`
 	}
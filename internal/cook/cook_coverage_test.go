@@ -13,8 +13,10 @@ import (
 
 	"github.com/nats-io/nats-server/v2/server"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
 
 	"github.com/gogrlx/grlx/v2/internal/config"
+	"github.com/gogrlx/grlx/v2/internal/pki"
 )
 
 // startCookTestNATS starts an embedded NATS server and registers the connection
@@ -800,6 +802,33 @@ func TestRecipeToStepEdgeCases(t *testing.T) {
 			t.Errorf("expected require condition, got %q", step.Requisites[0].Condition)
 		}
 	})
+
+	t.Run("cook keys are not ingredient properties", func(t *testing.T) {
+		step, err := recipeToStep("build", map[string]interface{}{
+			"cmd.run": []interface{}{
+				map[string]interface{}{"name": "make"},
+				map[string]interface{}{"timeout": 30},
+				map[string]interface{}{"locks": "build"},
+				map[string]interface{}{"retry": map[string]interface{}{"attempts": 2}},
+				map[string]interface{}{"onlyif": "test -f Makefile"},
+				map[string]interface{}{"unless": "test -f out"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if step.Timeout != 30*time.Second {
+			t.Errorf("expected timeout 30s, got %v", step.Timeout)
+		}
+		for _, key := range cookKeys {
+			if _, ok := step.Properties[key]; ok {
+				t.Errorf("expected %q to be removed from properties", key)
+			}
+		}
+		if step.Properties["name"] != "make" {
+			t.Errorf("expected property name 'make', got %v", step.Properties["name"])
+		}
+	})
 }
 
 // --- makeRecipeSteps edge cases ---
@@ -1282,15 +1311,40 @@ func TestCookRecipeEnvelopeCookerFactoryError(t *testing.T) {
 
 // --- SendCookEvent ---
 
+// useTestFarmerNKey points the farmer NKey config at a fresh key, which
+// SendCookEvent signs envelopes with, and returns its public key.
+func useTestFarmerNKey(t *testing.T) string {
+	t.Helper()
+	kp, err := nkeys.CreateUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed, _ := kp.Seed()
+	pubkey, _ := kp.PublicKey()
+	orig := config.NKeyFarmerPrivFile
+	t.Cleanup(func() { config.NKeyFarmerPrivFile = orig })
+	config.NKeyFarmerPrivFile = filepath.Join(t.TempDir(), "farmer.nkey")
+	if err = os.WriteFile(config.NKeyFarmerPrivFile, seed, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return pubkey
+}
+
 func TestSendCookEvent(t *testing.T) {
 	nc, cleanup := startCookTestNATS(t)
 	defer cleanup()
+	verifier := pki.NewVerifier(useTestFarmerNKey(t))
 
 	// Subscribe to the cook subject for the test sprout, replying with an ack.
 	sproutID := "send-cook-sprout"
 	sub, err := nc.Subscribe("grlx.sprouts."+sproutID+".cook", func(msg *nats.Msg) {
+		signed, err := verifier.Verify(msg.Subject, msg.Data)
+		if err != nil {
+			t.Errorf("verify envelope: %v", err)
+			return
+		}
 		var env RecipeEnvelope
-		if err := json.Unmarshal(msg.Data, &env); err != nil {
+		if err := json.Unmarshal(signed.Payload, &env); err != nil {
 			t.Errorf("unmarshal envelope: %v", err)
 			return
 		}
@@ -1315,11 +1369,12 @@ func TestSendCookEvent(t *testing.T) {
 func TestSendCookEventTestMode(t *testing.T) {
 	nc, cleanup := startCookTestNATS(t)
 	defer cleanup()
+	useTestFarmerNKey(t)
 
 	sproutID := "send-cook-test-sprout"
 	sub, err := nc.Subscribe("grlx.sprouts."+sproutID+".cook", func(msg *nats.Msg) {
 		var env RecipeEnvelope
-		if err := json.Unmarshal(msg.Data, &env); err != nil {
+		if err := json.Unmarshal(pki.MessagePayload(msg.Data), &env); err != nil {
 			t.Errorf("unmarshal: %v", err)
 			return
 		}
@@ -1344,11 +1399,12 @@ func TestSendCookEventTestMode(t *testing.T) {
 func TestSendCookEventWithInvoker(t *testing.T) {
 	nc, cleanup := startCookTestNATS(t)
 	defer cleanup()
+	useTestFarmerNKey(t)
 
 	sproutID := "send-cook-invoker-sprout"
 	sub, err := nc.Subscribe("grlx.sprouts."+sproutID+".cook", func(msg *nats.Msg) {
 		var env RecipeEnvelope
-		if err := json.Unmarshal(msg.Data, &env); err != nil {
+		if err := json.Unmarshal(pki.MessagePayload(msg.Data), &env); err != nil {
 			t.Errorf("unmarshal: %v", err)
 			return
 		}
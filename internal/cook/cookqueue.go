@@ -0,0 +1,257 @@
+package cook
+
+// The cook queue holds recipe envelopes for sprouts that could not be
+// reached when a cook was requested with queueing enabled. Each envelope is
+// kept on the farmer's disk until the sprout announces itself again or the
+// envelope expires. Envelopes are stored unsigned and signed at delivery,
+// since a signed message is only accepted for a few minutes.
+//
+// While it waits, a job is marked with a deferred-<jid> completion, which
+// the job stores report as queued.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/gogrlx/grlx/v2/internal/config"
+	"github.com/gogrlx/grlx/v2/internal/log"
+	"github.com/gogrlx/grlx/v2/internal/pki"
+)
+
+const (
+	cookQueueSweepInterval = time.Minute
+	queuedRecordTimeout    = 5 * time.Second
+)
+
+// QueuedCook is a recipe envelope waiting for its sprout to reconnect.
+type QueuedCook struct {
+	SproutID string         `json:"sprout_id"`
+	Envelope RecipeEnvelope `json:"envelope"`
+	QueuedAt time.Time      `json:"queued_at"`
+	Expires  time.Time      `json:"expires"`
+}
+
+// cookQueueMu serializes access to the queue directory, so that an
+// envelope is only ever taken for delivery once.
+var cookQueueMu sync.Mutex
+
+func queuedCookPath(sproutID, jobID string) string {
+	return filepath.Join(config.CookQueueDir, sproutID, jobID+".json")
+}
+
+// queueCook stores envelope for later delivery to sproutID and records
+// the job as queued.
+func queueCook(sproutID string, envelope RecipeEnvelope, ttl time.Duration) error {
+	now := time.Now().UTC()
+	qc := QueuedCook{
+		SproutID: sproutID,
+		Envelope: envelope,
+		QueuedAt: now,
+		Expires:  now.Add(ttl),
+	}
+	b, err := json.Marshal(qc)
+	if err != nil {
+		return err
+	}
+	path := queuedCookPath(sproutID, envelope.JobID)
+	cookQueueMu.Lock()
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err == nil {
+		err = os.WriteFile(path, b, 0o600)
+	}
+	cookQueueMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("queueing job %s: %w", envelope.JobID, err)
+	}
+	log.Noticef("sprout %s is unreachable, queued job %s until %s", sproutID, envelope.JobID, qc.Expires.Format(time.RFC3339))
+	if conn == nil {
+		return nil
+	}
+	// Wait for the job listener to record the job, so that the deferred
+	// marker is not recorded ahead of it.
+	envB, _ := json.Marshal(envelope)
+	if _, err = conn.Request("grlx.sprouts."+sproutID+".cook.queued", envB, queuedRecordTimeout); err != nil {
+		log.Debugf("job %s was not recorded before being deferred: %v", envelope.JobID, err)
+	}
+	publishFarmerMarker(sproutID, envelope.JobID, StepCompletion{
+		ID:               StepID("deferred-" + envelope.JobID),
+		CompletionStatus: StepNotStarted,
+		Started:          time.Now(),
+	})
+	return nil
+}
+
+// takeQueuedCooks removes and returns the cooks queued for sproutID,
+// oldest first.
+func takeQueuedCooks(sproutID string) ([]QueuedCook, error) {
+	cookQueueMu.Lock()
+	defer cookQueueMu.Unlock()
+	dir := filepath.Join(config.CookQueueDir, sproutID)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var queued []QueuedCook
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		b, readErr := os.ReadFile(path)
+		if readErr != nil {
+			log.Errorf("failed to read queued cook %s: %v", path, readErr)
+			continue
+		}
+		var qc QueuedCook
+		if jsonErr := json.Unmarshal(b, &qc); jsonErr != nil {
+			log.Errorf("failed to parse queued cook %s: %v", path, jsonErr)
+			continue
+		}
+		if rmErr := os.Remove(path); rmErr != nil {
+			log.Errorf("failed to remove queued cook %s: %v", path, rmErr)
+			continue
+		}
+		queued = append(queued, qc)
+	}
+	sort.Slice(queued, func(i, j int) bool {
+		return queued[i].QueuedAt.Before(queued[j].QueuedAt)
+	})
+	return queued, nil
+}
+
+// DeliverQueuedCooks sends sproutID every unexpired cook queued for it.
+// Cooks the sprout still cannot be reached for go back in the queue.
+func DeliverQueuedCooks(sproutID string) {
+	queued, err := takeQueuedCooks(sproutID)
+	if err != nil {
+		log.Errorf("failed to load queued cooks for %s: %v", sproutID, err)
+		return
+	}
+	for i, qc := range queued {
+		if time.Now().After(qc.Expires) {
+			dropQueuedCook(qc, "it expired")
+			continue
+		}
+		err = deliverCook(sproutID, qc.Envelope)
+		switch {
+		case err == nil:
+			log.Noticef("delivered queued job %s to sprout %s", qc.Envelope.JobID, sproutID)
+		case errors.Is(err, ErrSproutUnreachable):
+			// Keep the rest for the next announcement.
+			for _, rest := range queued[i:] {
+				requeueCook(rest)
+			}
+			return
+		default:
+			dropQueuedCook(qc, err.Error())
+		}
+	}
+}
+
+// CancelQueuedCook removes a cook that has not yet been delivered to
+// sproutID, reporting whether one was queued.
+func CancelQueuedCook(sproutID, jobID string) bool {
+	if !pki.IsValidSproutID(sproutID) || strings.ContainsAny(jobID, `/\`) {
+		return false
+	}
+	cookQueueMu.Lock()
+	err := os.Remove(queuedCookPath(sproutID, jobID))
+	cookQueueMu.Unlock()
+	if err != nil {
+		return false
+	}
+	log.Noticef("cancelled queued job %s for sprout %s", jobID, sproutID)
+	publishFarmerCancelled(sproutID, jobID)
+	return true
+}
+
+// ExpireQueuedCooks drops every queued cook whose expiry has passed at now.
+func ExpireQueuedCooks(now time.Time) {
+	sprouts, err := os.ReadDir(config.CookQueueDir)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Errorf("failed to read cook queue: %v", err)
+		}
+		return
+	}
+	for _, sprout := range sprouts {
+		if !sprout.IsDir() {
+			continue
+		}
+		queued, takeErr := takeQueuedCooks(sprout.Name())
+		if takeErr != nil {
+			log.Errorf("failed to load queued cooks for %s: %v", sprout.Name(), takeErr)
+			continue
+		}
+		for _, qc := range queued {
+			if now.After(qc.Expires) {
+				dropQueuedCook(qc, "it expired")
+			} else {
+				requeueCook(qc)
+			}
+		}
+	}
+}
+
+// StartCookQueue delivers queued cooks to each sprout that announces
+// itself on nc, and expires old ones, until ctx is cancelled.
+func StartCookQueue(ctx context.Context, nc *nats.Conn) error {
+	_, err := nc.Subscribe("grlx.sprouts.announce.*", func(m *nats.Msg) {
+		sproutID := strings.TrimPrefix(m.Subject, "grlx.sprouts.announce.")
+		if !pki.IsValidSproutID(sproutID) {
+			return
+		}
+		go DeliverQueuedCooks(sproutID)
+	})
+	if err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(cookQueueSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				ExpireQueuedCooks(now)
+			}
+		}
+	}()
+	return nil
+}
+
+func requeueCook(qc QueuedCook) {
+	b, err := json.Marshal(qc)
+	if err != nil {
+		log.Errorf("failed to requeue job %s: %v", qc.Envelope.JobID, err)
+		return
+	}
+	path := queuedCookPath(qc.SproutID, qc.Envelope.JobID)
+	cookQueueMu.Lock()
+	defer cookQueueMu.Unlock()
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err == nil {
+		err = os.WriteFile(path, b, 0o600)
+	}
+	if err != nil {
+		log.Errorf("failed to requeue job %s: %v", qc.Envelope.JobID, err)
+	}
+}
+
+func dropQueuedCook(qc QueuedCook, reason string) {
+	log.Errorf("dropping queued job %s for sprout %s: %s", qc.Envelope.JobID, qc.SproutID, reason)
+	publishFarmerCancelled(qc.SproutID, qc.Envelope.JobID)
+}
@@ -0,0 +1,177 @@
+package cook
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/gogrlx/grlx/v2/internal/config"
+	"github.com/gogrlx/grlx/v2/internal/pki"
+)
+
+// useTestCookQueue points the cook queue at an empty directory and
+// returns a channel of the lifecycle markers published for sproutID. It
+// also stands in for the job listener that records queued jobs.
+func useTestCookQueue(t *testing.T, nc *nats.Conn, sproutID string) <-chan StepCompletion {
+	t.Helper()
+	orig := config.CookQueueDir
+	t.Cleanup(func() { config.CookQueueDir = orig })
+	config.CookQueueDir = t.TempDir()
+
+	recorder, err := nc.Subscribe("grlx.sprouts."+sproutID+".cook.queued", func(m *nats.Msg) {
+		m.Respond(nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	markers := make(chan StepCompletion, 8)
+	sub, err := nc.Subscribe("grlx.cook."+sproutID+".*", func(m *nats.Msg) {
+		var sc StepCompletion
+		json.Unmarshal(m.Data, &sc)
+		markers <- sc
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		recorder.Unsubscribe()
+		sub.Unsubscribe()
+	})
+	return markers
+}
+
+func expectMarker(t *testing.T, markers <-chan StepCompletion, id string) {
+	t.Helper()
+	select {
+	case sc := <-markers:
+		if string(sc.ID) != id {
+			t.Errorf("expected marker %s, got %s", id, sc.ID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no %s marker published", id)
+	}
+}
+
+func TestSendCookEventUnreachable(t *testing.T) {
+	nc, cleanup := startCookTestNATS(t)
+	defer cleanup()
+	useTestFarmerNKey(t)
+	sproutID := "offline-sprout"
+	useTestCookQueue(t, nc, sproutID)
+
+	err := SendCookEvent(sproutID, "independent", GenerateJobID(), false)
+	if !errors.Is(err, ErrSproutUnreachable) {
+		t.Fatalf("expected %v without queueing, got %v", ErrSproutUnreachable, err)
+	}
+	if _, statErr := os.Stat(filepath.Join(config.CookQueueDir, sproutID)); !errors.Is(statErr, os.ErrNotExist) {
+		t.Error("expected nothing to be queued without WithQueue")
+	}
+}
+
+func TestQueuedCookDelivery(t *testing.T) {
+	nc, cleanup := startCookTestNATS(t)
+	defer cleanup()
+	verifier := pki.NewVerifier(useTestFarmerNKey(t))
+	sproutID := "queued-sprout"
+	markers := useTestCookQueue(t, nc, sproutID)
+
+	jid := GenerateJobID()
+	if err := SendCookEvent(sproutID, "independent", jid, false, WithQueue(time.Hour)); err != nil {
+		t.Fatalf("SendCookEvent: %v", err)
+	}
+	expectMarker(t, markers, "deferred-"+jid)
+	if _, err := os.Stat(queuedCookPath(sproutID, jid)); err != nil {
+		t.Fatalf("expected the cook to be queued: %v", err)
+	}
+
+	// Still unreachable: the cook stays queued.
+	DeliverQueuedCooks(sproutID)
+	if _, err := os.Stat(queuedCookPath(sproutID, jid)); err != nil {
+		t.Fatalf("expected the cook to stay queued: %v", err)
+	}
+
+	received := make(chan RecipeEnvelope, 1)
+	sprout, err := nc.Subscribe("grlx.sprouts."+sproutID+".cook", func(m *nats.Msg) {
+		signed, verifyErr := verifier.Verify(m.Subject, m.Data)
+		if verifyErr != nil {
+			t.Errorf("verify envelope: %v", verifyErr)
+			return
+		}
+		var env RecipeEnvelope
+		json.Unmarshal(signed.Payload, &env)
+		data, _ := json.Marshal(Ack{Acknowledged: true, JobID: env.JobID})
+		m.Respond(data)
+		received <- env
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sprout.Unsubscribe()
+
+	DeliverQueuedCooks(sproutID)
+	select {
+	case env := <-received:
+		if env.JobID != jid {
+			t.Errorf("expected job %s, got %s", jid, env.JobID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("queued cook was not delivered")
+	}
+	if _, err = os.Stat(queuedCookPath(sproutID, jid)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the delivered cook to leave the queue, got %v", err)
+	}
+}
+
+func TestQueuedCookExpiry(t *testing.T) {
+	nc, cleanup := startCookTestNATS(t)
+	defer cleanup()
+	useTestFarmerNKey(t)
+	sproutID := "expiring-sprout"
+	markers := useTestCookQueue(t, nc, sproutID)
+
+	jid := GenerateJobID()
+	if err := QueueCookEvent(sproutID, "independent", jid, false, time.Minute); err != nil {
+		t.Fatalf("QueueCookEvent: %v", err)
+	}
+	expectMarker(t, markers, "deferred-"+jid)
+
+	ExpireQueuedCooks(time.Now())
+	if _, err := os.Stat(queuedCookPath(sproutID, jid)); err != nil {
+		t.Fatalf("expected an unexpired cook to be kept: %v", err)
+	}
+	ExpireQueuedCooks(time.Now().Add(2 * time.Minute))
+	expectMarker(t, markers, "cancelled-"+jid)
+	if _, err := os.Stat(queuedCookPath(sproutID, jid)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the expired cook to be dropped, got %v", err)
+	}
+}
+
+func TestCancelQueuedCook(t *testing.T) {
+	nc, cleanup := startCookTestNATS(t)
+	defer cleanup()
+	useTestFarmerNKey(t)
+	sproutID := "cancelled-sprout"
+	markers := useTestCookQueue(t, nc, sproutID)
+
+	jid := GenerateJobID()
+	if err := QueueCookEvent(sproutID, "independent", jid, false, time.Hour); err != nil {
+		t.Fatalf("QueueCookEvent: %v", err)
+	}
+	expectMarker(t, markers, "deferred-"+jid)
+
+	if CancelQueuedCook(sproutID, "other-jid") {
+		t.Error("expected an unknown job not to be cancelled")
+	}
+	if !CancelQueuedCook(sproutID, jid) {
+		t.Fatal("expected the queued job to be cancelled")
+	}
+	expectMarker(t, markers, "cancelled-"+jid)
+	if CancelQueuedCook(sproutID, jid) {
+		t.Error("expected a cancelled job to be gone")
+	}
+}
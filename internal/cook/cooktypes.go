@@ -14,6 +14,12 @@ const (
 	OnChangesAny ReqType = "onchanges_any"
 	OnFailAny    ReqType = "onfail_any"
 	RequireAny   ReqType = "require_any"
+
+	// Watch and WatchAny behave like Require and RequireAny, but when a
+	// watched step made changes the watching step is cooked through its
+	// Watcher hook, if it has one.
+	Watch    ReqType = "watch"
+	WatchAny ReqType = "watch_any"
 )
 
 const (
@@ -22,6 +28,12 @@ const (
 	StepCompleted
 	StepFailed
 	StepSkipped
+	// StepTimedOut is a failure caused by the step's (or the recipe's)
+	// timeout expiring while it ran.
+	StepTimedOut
+	// StepCancelled marks a step that had not finished when its job was
+	// cancelled.
+	StepCancelled
 )
 
 type (
@@ -38,6 +50,9 @@ type (
 		Started          time.Time     `json:"started,omitempty"`
 		Duration         time.Duration `json:"duration,omitempty"`
 		Error            error
+		// WouldChange is set by a step cooked in test mode that would
+		// have made changes.
+		WouldChange bool `json:"would_change,omitempty"`
 	}
 	RecipeCooker interface {
 		Apply(context.Context) (Result, error)
@@ -47,11 +62,24 @@ type (
 		Methods() (string, []string)
 		PropertiesForMethod(method string) (map[string]string, error)
 	}
+	// Watcher is implemented by ingredients that react to a watched step
+	// changing, e.g. service.running restarts its service. When a watch or
+	// watch_any requisite fires, ModWatch is called in place of Apply, or
+	// in place of Test with test set.
+	Watcher interface {
+		ModWatch(ctx context.Context, test bool) (Result, error)
+	}
 	RecipeEnvelope struct {
 		JobID     string
 		Steps     []Step
 		Test      bool
 		InvokedBy string `json:"invoked_by,omitempty"`
+		// Timeout bounds the whole recipe. Zero means DefaultCookTimeout.
+		Timeout time.Duration `json:"timeout,omitempty"`
+		// Props is a snapshot of the target sprout's props taken by the
+		// farmer, so that templates rendered on the sprout see the same
+		// values as the recipe did.
+		Props map[string]interface{} `json:"props,omitempty"`
 	}
 	Ack struct {
 		Acknowledged bool
@@ -69,6 +97,19 @@ type (
 		Requisites  RequisiteSet
 		Properties  map[string]interface{}
 		IsRequisite bool
+		// Locks names resources (e.g. "pkg" or a file path) that the step
+		// holds exclusively while it runs, across all jobs on the sprout.
+		Locks []string `json:"locks,omitempty"`
+		// Timeout bounds the step's Apply or Test call. Zero means the
+		// step is only bounded by the recipe's timeout.
+		Timeout time.Duration `json:"timeout,omitempty"`
+		// Retry re-runs a step that did not reach the desired outcome.
+		// Each attempt gets the full Timeout.
+		Retry *Retry `json:"retry,omitempty"`
+		// OnlyIf and Unless guard the step: it is skipped unless every
+		// OnlyIf guard holds, and skipped if every Unless guard holds.
+		OnlyIf []Guard `json:"onlyif,omitempty"`
+		Unless []Guard `json:"unless,omitempty"`
 	}
 	Targets   []StepID
 	Requisite struct {
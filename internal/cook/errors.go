@@ -11,4 +11,7 @@ var (
 	ErrRecipePathIsDirectory = errors.New("recipe path resolved to a directory instead of a .grlx file")
 	ErrTargetStepNotFound    = errors.New("target step not found in recipe")
 	ErrDanglingRequisite     = errors.New("step requires an unknown step")
+	ErrSproutUnreachable     = errors.New("sprout did not respond")
+	ErrNoTopFile             = errors.New("no top file in the recipe directory")
+	ErrInvalidTopFile        = errors.New("invalid top file")
 )
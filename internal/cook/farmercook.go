@@ -15,9 +15,11 @@ import (
 
 	"github.com/gogrlx/grlx/v2/internal/log"
 	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
 	"gopkg.in/yaml.v3"
 
 	"github.com/gogrlx/grlx/v2/internal/config"
+	"github.com/gogrlx/grlx/v2/internal/pki"
 	"github.com/gogrlx/grlx/v2/internal/props"
 )
 
@@ -27,8 +29,16 @@ type CookOption func(*cookOptions)
 type cookOptions struct {
 	invokedBy  string
 	targetStep StepID
+	queueTTL   time.Duration
+	recipes    []RecipeName
+	steps      []Step
+	timeout    *time.Duration
 }
 
+// cookRequestTimeout is how long the farmer waits for a sprout to
+// acknowledge a recipe.
+var cookRequestTimeout = 30 * time.Second
+
 // WithInvoker sets the pubkey of the user who initiated the cook.
 func WithInvoker(pubkey string) CookOption {
 	return func(o *cookOptions) {
@@ -45,6 +55,48 @@ func WithTargetStep(id StepID) CookOption {
 	}
 }
 
+// WithQueue queues the cook for up to ttl if the sprout cannot be
+// reached, delivering it when the sprout next announces itself. A zero
+// ttl disables queueing.
+func WithQueue(ttl time.Duration) CookOption {
+	return func(o *cookOptions) {
+		o.queueTTL = ttl
+	}
+}
+
+// WithRecipes cooks recipes, merged into a single envelope, in place of
+// the recipe ID passed to SendCookEvent. It is used for full-state cooks,
+// which run every recipe the top file assigns to a sprout.
+func WithRecipes(recipes ...RecipeName) CookOption {
+	return func(o *cookOptions) {
+		o.recipes = recipes
+	}
+}
+
+// WithTimeoutReport stores the timeout of the envelope sent in d, zero if
+// it uses the sprout's DefaultCookTimeout, so that the caller knows how
+// long the job may take.
+func WithTimeoutReport(d *time.Duration) CookOption {
+	return func(o *cookOptions) {
+		o.timeout = d
+	}
+}
+
+// WithSteps cooks steps in place of a recipe. It is used for scheduled
+// commands, which run as a single cmd.run step.
+func WithSteps(steps ...Step) CookOption {
+	return func(o *cookOptions) {
+		o.steps = steps
+	}
+}
+
+// TemplateFuncMap returns the functions available to recipe templates.
+// Ingredients that render templates on the sprout use it so that file
+// templates and recipes share the same helpers.
+func TemplateFuncMap(sproutID string) template.FuncMap {
+	return populateFuncMap(sproutID)
+}
+
 func populateFuncMap(sproutID string) template.FuncMap {
 	v := template.FuncMap{}
 	v["props"] = props.GetStringPropFunc(sproutID)
@@ -94,75 +146,140 @@ func populateFuncMap(sproutID string) template.FuncMap {
 }
 
 func SendCookEvent(sproutID string, recipeID RecipeName, JID string, test bool, opts ...CookOption) error {
-	basepath := getBasePath()
-	includes, err := collectAllIncludes(sproutID, basepath, recipeID)
+	var co cookOptions
+	for _, opt := range opts {
+		opt(&co)
+	}
+	rEnvelope, err := buildCookEnvelope(sproutID, recipeID, JID, test, co)
 	if err != nil {
 		return err
 	}
+	err = deliverCook(sproutID, rEnvelope)
+	if errors.Is(err, ErrSproutUnreachable) && co.queueTTL > 0 {
+		return queueCook(sproutID, rEnvelope, co.queueTTL)
+	}
+	return err
+}
+
+// QueueCookEvent builds the recipe envelope as SendCookEvent does, but
+// queues it for ttl without trying to reach the sprout first. It is for
+// sprouts already known to be offline.
+func QueueCookEvent(sproutID string, recipeID RecipeName, JID string, test bool, ttl time.Duration, opts ...CookOption) error {
+	var co cookOptions
+	for _, opt := range opts {
+		opt(&co)
+	}
+	rEnvelope, err := buildCookEnvelope(sproutID, recipeID, JID, test, co)
+	if err != nil {
+		return err
+	}
+	return queueCook(sproutID, rEnvelope, ttl)
+}
+
+func buildCookEnvelope(sproutID string, recipeID RecipeName, JID string, test bool, co cookOptions) (RecipeEnvelope, error) {
+	if len(co.steps) > 0 {
+		return RecipeEnvelope{
+			JobID:     JID,
+			Steps:     co.steps,
+			Test:      test,
+			InvokedBy: co.invokedBy,
+			Props:     props.GetProps(sproutID),
+		}, nil
+	}
+	basepath := getBasePath()
+	cooked := []RecipeName{recipeID}
+	if len(co.recipes) > 0 {
+		cooked = co.recipes
+	}
+	isCooked := make(map[RecipeName]bool, len(cooked))
+	includeSet := make(map[RecipeName]bool)
+	var includes []RecipeName
+	for _, recipe := range cooked {
+		isCooked[recipe] = true
+		recipeIncludes, err := collectAllIncludes(sproutID, basepath, recipe)
+		if err != nil {
+			return RecipeEnvelope{}, err
+		}
+		// Recipes merged into one envelope may share includes.
+		for _, inc := range recipeIncludes {
+			if !includeSet[inc] {
+				includeSet[inc] = true
+				includes = append(includes, inc)
+			}
+		}
+	}
+	var err error
 	recipesteps := make(map[string]interface{})
+	var recipeTimeout time.Duration
 	for _, inc := range includes {
 		// load all imported files into recipefile list
 		fp, fpErr := ResolveRecipeFilePath(basepath, inc)
 		if fpErr != nil {
 			log.Errorf("could not find include %s: %v", inc, err)
-			return errors.Join(ErrNoRecipe, fpErr)
+			return RecipeEnvelope{}, errors.Join(ErrNoRecipe, fpErr)
 		}
 		f, fpErr := os.ReadFile(fp)
 		if fpErr != nil {
-			return fpErr
+			return RecipeEnvelope{}, fpErr
 		}
 		b, renderErr := renderRecipeTemplate(sproutID, fp, f)
 		if renderErr != nil {
-			return renderErr
+			return RecipeEnvelope{}, renderErr
 		}
 		var recipe map[string]interface{}
 		marshallErr := yaml.Unmarshal(b, &recipe)
 		if marshallErr != nil {
-			return marshallErr
+			return RecipeEnvelope{}, marshallErr
 		}
 		m, loadErr := stepsFromMap(recipe)
 		if loadErr != nil {
-			return loadErr
+			return RecipeEnvelope{}, loadErr
+		}
+		// Only the recipes being cooked set the overall timeout; included
+		// recipes cannot extend or shorten it. Merged recipes get the
+		// longest of their timeouts.
+		if isCooked[inc] {
+			timeout, timeoutErr := extractTimeout(recipe)
+			if timeoutErr != nil {
+				return RecipeEnvelope{}, timeoutErr
+			}
+			recipeTimeout = max(recipeTimeout, timeout)
 		}
 		// range over all keys under each recipe ID for matching ingredients
 		recipesteps, err = joinMaps(recipesteps, m)
 		if err != nil {
-			return err
+			return RecipeEnvelope{}, err
 		}
 	}
 	for id, step := range recipesteps {
 		switch s := step.(type) {
 		case map[string]interface{}:
 			if len(s) != 1 {
-				return errors.Join(ErrInvalidFormat, fmt.Errorf("recipe %s must have one directive, but has %d", id, len(s)))
+				return RecipeEnvelope{}, errors.Join(ErrInvalidFormat, fmt.Errorf("recipe %s must have one directive, but has %d", id, len(s)))
 			}
 
 		default:
-			return errors.Join(ErrInvalidFormat, fmt.Errorf("recipe %s must me a map[string]interface{} but found %T", id, step))
+			return RecipeEnvelope{}, errors.Join(ErrInvalidFormat, fmt.Errorf("recipe %s must me a map[string]interface{} but found %T", id, step))
 		}
 	}
 	steps, err := makeRecipeSteps(recipesteps)
 	if err != nil {
-		return err
+		return RecipeEnvelope{}, err
 	}
 	tree, err := validateRecipeTree(steps)
 	if err != nil {
-		return err
+		return RecipeEnvelope{}, err
 	}
 	validSteps := []Step{}
 	for _, step := range tree {
 		validSteps = append(validSteps, *step)
 	}
-	var co cookOptions
-	for _, opt := range opts {
-		opt(&co)
-	}
 	// If a target step was requested, prune the tree to that step plus the
 	// transitive closure of its requisite dependencies.
 	if co.targetStep != "" {
 		pruned, pruneErr := PruneToTarget(validSteps, co.targetStep)
 		if pruneErr != nil {
-			return pruneErr
+			return RecipeEnvelope{}, pruneErr
 		}
 		validSteps = pruned
 	}
@@ -171,11 +288,30 @@ func SendCookEvent(sproutID string, recipeID RecipeName, JID string, test bool,
 		Steps:     validSteps,
 		Test:      test,
 		InvokedBy: co.invokedBy,
+		Props:     props.GetProps(sproutID),
+		Timeout:   recipeTimeout,
 	}
+	if co.timeout != nil {
+		*co.timeout = recipeTimeout
+	}
+	return rEnvelope, nil
+}
+
+// deliverCook signs rEnvelope and sends it to sproutID, waiting for the
+// sprout to acknowledge it.
+func deliverCook(sproutID string, rEnvelope RecipeEnvelope) error {
 	b, _ := json.Marshal(rEnvelope)
-	log.Noticef("cooking sprout %s: %s", sproutID, JID)
+	subject := "grlx.sprouts." + sproutID + ".cook"
+	b, err := pki.SignMessage(subject, rEnvelope.JobID, b)
+	if err != nil {
+		return err
+	}
+	log.Noticef("cooking sprout %s: %s", sproutID, rEnvelope.JobID)
 	var ack Ack
-	msg, err := conn.Request("grlx.sprouts."+sproutID+".cook", b, 30*time.Second)
+	msg, err := conn.Request(subject, b, cookRequestTimeout)
+	if errors.Is(err, nats.ErrTimeout) || errors.Is(err, nats.ErrNoResponders) {
+		return errors.Join(ErrSproutUnreachable, err)
+	}
 	if err != nil {
 		return err
 	}
@@ -186,7 +322,7 @@ func SendCookEvent(sproutID string, recipeID RecipeName, JID string, test bool,
 	if !ack.Acknowledged {
 		return errors.New("sprout did not acknowledge recipe")
 	}
-	if ack.JobID != JID {
+	if ack.JobID != rEnvelope.JobID {
 		return errors.New("sprout acknowledged recipe but returned wrong JobID")
 	}
 	return nil
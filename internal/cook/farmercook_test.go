@@ -72,6 +72,21 @@ func TestResolveRecipeFilePath(t *testing.T) {
 	}
 }
 
+func TestBuildCookEnvelopeWithSteps(t *testing.T) {
+	step := Step{Ingredient: "cmd", Method: "run", ID: "uptime", Properties: map[string]interface{}{"name": "uptime"}}
+	co := cookOptions{steps: []Step{step}, invokedBy: "schedule:uptime"}
+	env, err := buildCookEnvelope("steps-sprout", "", "steps-job", true, co)
+	if err != nil {
+		t.Fatalf("buildCookEnvelope: %v", err)
+	}
+	if len(env.Steps) != 1 || env.Steps[0].ID != "uptime" {
+		t.Errorf("expected only the given step, got %+v", env.Steps)
+	}
+	if env.JobID != "steps-job" || !env.Test || env.InvokedBy != "schedule:uptime" {
+		t.Errorf("unexpected envelope %+v", env)
+	}
+}
+
 // func TestParseRecipeFile(t *testing.T) {
 // 	testCases := []struct {
 // 		id          string
@@ -0,0 +1,55 @@
+package cook
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gogrlx/grlx/v2/internal/log"
+)
+
+// MarkBatchStarted records that the farmer is sending jobID to sproutID as
+// part of batch n of a rolling cook with the given number of batches.
+func MarkBatchStarted(sproutID, jobID string, n, batches int) {
+	publishFarmerMarker(sproutID, jobID, StepCompletion{
+		ID:               StepID("batch-" + jobID),
+		CompletionStatus: StepNotStarted,
+		Changes:          []string{fmt.Sprintf("batch %d of %d", n, batches)},
+		Started:          time.Now(),
+	})
+}
+
+// CancelUndelivered marks jobID cancelled on a sprout the farmer never
+// sent it to, giving reason as the marker's only change.
+func CancelUndelivered(sproutID, jobID, reason string) {
+	publishFarmerMarker(sproutID, jobID, StepCompletion{
+		ID:               StepID("cancelled-" + jobID),
+		CompletionStatus: StepCancelled,
+		Changes:          []string{reason},
+		Started:          time.Now(),
+	})
+}
+
+// publishFarmerCancelled marks a job the farmer never delivered as
+// cancelled, so it does not stay queued.
+func publishFarmerCancelled(sproutID, jobID string) {
+	publishFarmerMarker(sproutID, jobID, StepCompletion{
+		ID:               StepID("cancelled-" + jobID),
+		CompletionStatus: StepCancelled,
+		Started:          time.Now(),
+	})
+}
+
+// publishFarmerMarker sends a lifecycle marker for a job on sproutID's
+// behalf, for jobs that have not reached the sprout.
+func publishFarmerMarker(sproutID, jobID string, marker StepCompletion) {
+	if conn == nil {
+		return
+	}
+	b, err := json.Marshal(marker)
+	if err != nil {
+		log.Errorf("failed to marshal step completion: %v", err)
+		return
+	}
+	conn.Publish("grlx.cook."+sproutID+"."+jobID, b)
+}
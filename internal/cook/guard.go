@@ -0,0 +1,183 @@
+package cook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/gogrlx/grlx/v2/internal/pki"
+	"github.com/gogrlx/grlx/v2/internal/props"
+)
+
+var ErrGuard = errors.New("could not evaluate step guard")
+
+type (
+	// Guard is a single onlyif or unless condition, evaluated on the
+	// sprout before the step is cooked. Exactly one field is set:
+	//
+	//	onlyif:
+	//	  - cmd: systemctl is-enabled nginx
+	//	  - file_exists: /etc/nginx/nginx.conf
+	//	  - prop: os == linux
+	//
+	// A plain string is shorthand for a cmd guard.
+	Guard struct {
+		// Cmd holds when the shell command exits 0.
+		Cmd string `json:"cmd,omitempty"`
+		// FileExists holds when the path exists.
+		FileExists string `json:"file_exists,omitempty"`
+		// Prop holds when the prop expression is true: "name" for a
+		// prop that is set, "name == value" or "name != value".
+		Prop string `json:"prop,omitempty"`
+	}
+)
+
+func (g Guard) String() string {
+	switch {
+	case g.Cmd != "":
+		return "cmd `" + g.Cmd + "`"
+	case g.FileExists != "":
+		return "file_exists `" + g.FileExists + "`"
+	default:
+		return "prop `" + g.Prop + "`"
+	}
+}
+
+// holds evaluates the guard. A command that exits non-zero or a missing
+// file is false rather than an error; errors are reserved for guards that
+// could not be evaluated at all.
+func (g Guard) holds(ctx context.Context) (bool, error) {
+	switch {
+	case g.Cmd != "":
+		err := exec.CommandContext(ctx, "/bin/sh", "-c", g.Cmd).Run()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			return false, nil
+		}
+		if err != nil {
+			return false, errors.Join(ErrGuard, fmt.Errorf("%s: %w", g, err))
+		}
+		return true, nil
+	case g.FileExists != "":
+		_, err := os.Stat(g.FileExists)
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, errors.Join(ErrGuard, fmt.Errorf("%s: %w", g, err))
+		}
+		return true, nil
+	default:
+		name, op, want := parsePropExpr(g.Prop)
+		value := props.GetStringProp(pki.GetSproutID(), name)
+		switch op {
+		case "==":
+			return value == want, nil
+		case "!=":
+			return value != want, nil
+		default:
+			return value != "", nil
+		}
+	}
+}
+
+// parsePropExpr splits a prop guard into the prop name, the operator
+// ("==", "!=" or "" for a bare name) and the value to compare against.
+func parsePropExpr(expr string) (name, op, value string) {
+	for _, op := range []string{"==", "!="} {
+		if name, value, ok := strings.Cut(expr, op); ok {
+			return strings.TrimSpace(name), op, strings.TrimSpace(value)
+		}
+	}
+	return strings.TrimSpace(expr), "", ""
+}
+
+// checkGuards evaluates the step's onlyif and unless guards. The step
+// should be skipped, for the returned reason, unless every onlyif guard
+// holds and at least one unless guard does not.
+func checkGuards(ctx context.Context, step Step) (bool, string, error) {
+	for _, g := range step.OnlyIf {
+		holds, err := g.holds(ctx)
+		if err != nil {
+			return false, "", err
+		}
+		if !holds {
+			return true, fmt.Sprintf("skipped: onlyif %s is false", g), nil
+		}
+	}
+	if len(step.Unless) == 0 {
+		return false, "", nil
+	}
+	for _, g := range step.Unless {
+		holds, err := g.holds(ctx)
+		if err != nil {
+			return false, "", err
+		}
+		if !holds {
+			return false, "", nil
+		}
+	}
+	if len(step.Unless) == 1 {
+		return true, fmt.Sprintf("skipped: unless %s is true", step.Unless[0]), nil
+	}
+	return true, "skipped: every unless guard is true", nil
+}
+
+// extractGuards reads a step's optional onlyif or unless key, which may
+// be a command string, a single guard map, or a list of either.
+func extractGuards(m map[string]interface{}, key string) ([]Guard, error) {
+	v, ok := m[key]
+	if !ok {
+		return nil, nil
+	}
+	list, isList := v.([]interface{})
+	if !isList {
+		list = []interface{}{v}
+	}
+	guards := make([]Guard, 0, len(list))
+	for _, item := range list {
+		g, err := parseGuard(key, item)
+		if err != nil {
+			return nil, err
+		}
+		guards = append(guards, g)
+	}
+	return guards, nil
+}
+
+func parseGuard(key string, v interface{}) (Guard, error) {
+	switch gv := v.(type) {
+	case string:
+		if strings.TrimSpace(gv) == "" {
+			return Guard{}, errors.Join(fmt.Errorf("error: %s command must not be empty", key), ErrInvalidFormat)
+		}
+		return Guard{Cmd: gv}, nil
+	case map[string]interface{}:
+		if len(gv) != 1 {
+			return Guard{}, errors.Join(fmt.Errorf("error: each %s guard must have exactly one of cmd, file_exists or prop", key), ErrInvalidFormat)
+		}
+		for kind, arg := range gv {
+			s, ok := arg.(string)
+			if !ok || strings.TrimSpace(s) == "" {
+				return Guard{}, errors.Join(fmt.Errorf("error: %s %s must be a non-empty string, got %v", key, kind, arg), ErrInvalidFormat)
+			}
+			switch kind {
+			case "cmd":
+				return Guard{Cmd: s}, nil
+			case "file_exists":
+				return Guard{FileExists: s}, nil
+			case "prop":
+				if name, _, _ := parsePropExpr(s); name == "" {
+					return Guard{}, errors.Join(fmt.Errorf("error: %s prop %q does not name a prop", key, s), ErrInvalidFormat)
+				}
+				return Guard{Prop: s}, nil
+			default:
+				return Guard{}, errors.Join(fmt.Errorf("error: unknown %s guard %q", key, kind), ErrInvalidFormat)
+			}
+		}
+	}
+	return Guard{}, errors.Join(fmt.Errorf("error: %s must be a command, a guard map, or a list of them, got %T", key, v), ErrInvalidFormat)
+}
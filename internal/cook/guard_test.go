@@ -0,0 +1,170 @@
+package cook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gogrlx/grlx/v2/internal/config"
+	"github.com/gogrlx/grlx/v2/internal/props"
+)
+
+func TestExtractGuards(t *testing.T) {
+	testCases := []struct {
+		id         string
+		stepString string
+		expected   []Guard
+		wantErr    bool
+	}{
+		{id: "none", stepString: `{}`, expected: nil},
+		{id: "shorthand", stepString: `{"onlyif": "test -d /srv"}`, expected: []Guard{{Cmd: "test -d /srv"}}},
+		{id: "single map", stepString: `{"onlyif": {"file_exists": "/etc/hosts"}}`, expected: []Guard{{FileExists: "/etc/hosts"}}},
+		{
+			id:         "list",
+			stepString: `{"onlyif": ["true", {"cmd": "false"}, {"prop": "os == linux"}]}`,
+			expected:   []Guard{{Cmd: "true"}, {Cmd: "false"}, {Prop: "os == linux"}},
+		},
+		{id: "empty command", stepString: `{"onlyif": " "}`, wantErr: true},
+		{id: "two kinds", stepString: `{"onlyif": {"cmd": "true", "prop": "os"}}`, wantErr: true},
+		{id: "unknown kind", stepString: `{"onlyif": {"service": "nginx"}}`, wantErr: true},
+		{id: "non-string", stepString: `{"onlyif": {"cmd": 1}}`, wantErr: true},
+		{id: "empty prop name", stepString: `{"onlyif": {"prop": "== linux"}}`, wantErr: true},
+		{id: "bad type", stepString: `{"onlyif": 5}`, wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			m := make(map[string]interface{})
+			if err := json.Unmarshal([]byte(tc.stepString), &m); err != nil {
+				t.Fatal(err)
+			}
+			guards, err := extractGuards(m, "onlyif")
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidFormat) {
+					t.Errorf("expected %v, got %v", ErrInvalidFormat, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(guards, tc.expected) {
+				t.Errorf("expected %+v but got %+v", tc.expected, guards)
+			}
+		})
+	}
+}
+
+func TestGuardHolds(t *testing.T) {
+	oldSproutID := config.SproutID
+	config.SproutID = "guard-sprout"
+	defer func() { config.SproutID = oldSproutID }()
+	if err := props.SetProp("guard-sprout", "os", "linux"); err != nil {
+		t.Fatal(err)
+	}
+	defer props.DeleteProp("guard-sprout", "os")
+
+	existing := filepath.Join(t.TempDir(), "present")
+	if err := os.WriteFile(existing, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		guard    Guard
+		expected bool
+	}{
+		{Guard{Cmd: "true"}, true},
+		{Guard{Cmd: "exit 3"}, false},
+		{Guard{FileExists: existing}, true},
+		{Guard{FileExists: existing + ".missing"}, false},
+		{Guard{Prop: "os"}, true},
+		{Guard{Prop: "arch"}, false},
+		{Guard{Prop: "os == linux"}, true},
+		{Guard{Prop: "os==windows"}, false},
+		{Guard{Prop: "os != windows"}, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.guard.String(), func(t *testing.T) {
+			got, err := tc.guard.holds(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := (Guard{Cmd: "true"}).holds(ctx); !errors.Is(err, ErrGuard) {
+		t.Errorf("expected a command that cannot run to be %v, got %v", ErrGuard, err)
+	}
+}
+
+func TestCheckGuards(t *testing.T) {
+	yes, no := Guard{Cmd: "true"}, Guard{Cmd: "false"}
+	testCases := []struct {
+		id     string
+		onlyIf []Guard
+		unless []Guard
+		skip   bool
+		reason string
+	}{
+		{id: "no guards", skip: false},
+		{id: "onlyif holds", onlyIf: []Guard{yes, yes}, skip: false},
+		{id: "onlyif fails", onlyIf: []Guard{yes, no}, skip: true, reason: "onlyif cmd `false` is false"},
+		{id: "unless holds", unless: []Guard{yes}, skip: true, reason: "unless cmd `true` is true"},
+		{id: "unless all hold", unless: []Guard{yes, yes}, skip: true, reason: "every unless guard"},
+		{id: "unless partly holds", unless: []Guard{yes, no}, skip: false},
+		{id: "onlyif and unless", onlyIf: []Guard{yes}, unless: []Guard{no}, skip: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			skip, reason, err := checkGuards(context.Background(), Step{OnlyIf: tc.onlyIf, Unless: tc.unless})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if skip != tc.skip {
+				t.Errorf("expected skip=%v, got %v", tc.skip, skip)
+			}
+			if !strings.Contains(reason, tc.reason) {
+				t.Errorf("expected reason to contain %q, got %q", tc.reason, reason)
+			}
+		})
+	}
+}
+
+func TestRunStepGuards(t *testing.T) {
+	origCooker := NewRecipeCooker
+	defer func() { NewRecipeCooker = origCooker }()
+	NewRecipeCooker = func(StepID, Ingredient, string, map[string]interface{}) (RecipeCooker, error) {
+		return &mockRecipeCooker{applyResult: Result{Succeeded: true, Changed: true}}, nil
+	}
+
+	for _, testMode := range []bool{false, true} {
+		completion := runStep(context.Background(), Step{ID: "guarded", OnlyIf: []Guard{{Cmd: "false"}}}, testMode, nil)
+		if completion.CompletionStatus != StepSkipped {
+			t.Errorf("test=%v: expected the step to be skipped, got %d", testMode, completion.CompletionStatus)
+		}
+		if completion.ChangesMade || len(completion.Changes) != 1 || !strings.HasPrefix(completion.Changes[0], "skipped: onlyif") {
+			t.Errorf("test=%v: expected the skip reason as the only note, got %v", testMode, completion.Changes)
+		}
+	}
+
+	completion := runStep(context.Background(), Step{ID: "guarded", Unless: []Guard{{Cmd: "false"}}}, false, nil)
+	if completion.CompletionStatus != StepCompleted || !completion.ChangesMade {
+		t.Errorf("expected the step to run, got %+v", completion)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	completion = runStep(ctx, Step{ID: "guarded", OnlyIf: []Guard{{Cmd: "true"}}}, false, nil)
+	if completion.CompletionStatus != StepFailed || !errors.Is(completion.Error, ErrGuard) {
+		t.Errorf("expected a guard that cannot be evaluated to fail the step, got %+v", completion)
+	}
+}
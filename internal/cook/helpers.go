@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/nats-io/nats.go"
 	"gopkg.in/yaml.v3"
@@ -37,6 +38,10 @@ func makeRecipeSteps(recipes map[string]interface{}) ([]*Step, error) {
 	return steps, nil
 }
 
+// cookKeys are the step keys handled by the cook rather than the
+// ingredient.
+var cookKeys = []string{"locks", "timeout", "retry", "onlyif", "unless"}
+
 func recipeToStep(id string, recipe map[string]interface{}) (Step, error) {
 	var step Step
 	if len(recipe) != 1 {
@@ -65,6 +70,31 @@ func recipeToStep(id string, recipe map[string]interface{}) (Step, error) {
 		if err != nil {
 			return Step{}, err
 		}
+		locks, err := extractLocks(m)
+		if err != nil {
+			return Step{}, err
+		}
+		timeout, err := extractTimeout(m)
+		if err != nil {
+			return Step{}, err
+		}
+		retry, err := extractRetry(m)
+		if err != nil {
+			return Step{}, err
+		}
+		onlyIf, err := extractGuards(m, "onlyif")
+		if err != nil {
+			return Step{}, err
+		}
+		unless, err := extractGuards(m, "unless")
+		if err != nil {
+			return Step{}, err
+		}
+		// The cook enforces these itself; ingredients would otherwise see
+		// them as their own properties, e.g. cmd.run's string timeout.
+		for _, key := range cookKeys {
+			delete(m, key)
+		}
 		step = Step{
 			ID:          StepID(id),
 			Ingredient:  Ingredient(rp[0]),
@@ -72,6 +102,11 @@ func recipeToStep(id string, recipe map[string]interface{}) (Step, error) {
 			Requisites:  reqs,
 			Properties:  m,
 			IsRequisite: false,
+			Locks:       locks,
+			Timeout:     timeout,
+			Retry:       retry,
+			OnlyIf:      onlyIf,
+			Unless:      unless,
 		}
 		return step, nil
 	}
@@ -151,7 +186,7 @@ func extractRequisites(step map[string]interface{}) (RequisiteSet, error) {
 				switch ReqType(k) {
 				case OnChanges, OnFail, Require:
 					fallthrough
-				case OnChangesAny, OnFailAny, RequireAny:
+				case OnChangesAny, OnFailAny, RequireAny, Watch, WatchAny:
 					reqs, err := deInterfaceRequisites(ReqType(k), v)
 					if err != nil {
 						return []Requisite{}, err
@@ -166,6 +201,64 @@ func extractRequisites(step map[string]interface{}) (RequisiteSet, error) {
 	return requisites, nil
 }
 
+// extractLocks reads the optional "locks" key of a step, which may be a
+// single resource name or a list of them.
+func extractLocks(step map[string]interface{}) ([]string, error) {
+	lt, ok := step["locks"]
+	if !ok {
+		return nil, nil
+	}
+	switch v := lt.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		locks := make([]string, 0, len(v))
+		for _, l := range v {
+			name, ok := l.(string)
+			if !ok {
+				return nil, errors.Join(fmt.Errorf("error: locks must be a string or a list of strings, got %T", l), ErrInvalidFormat)
+			}
+			locks = append(locks, name)
+		}
+		return locks, nil
+	default:
+		return nil, errors.Join(fmt.Errorf("error: locks must be a string or a list of strings, got %T", lt), ErrInvalidFormat)
+	}
+}
+
+// extractTimeout reads the optional timeout key of a step or recipe.
+func extractTimeout(m map[string]interface{}) (time.Duration, error) {
+	t, ok := m["timeout"]
+	if !ok {
+		return 0, nil
+	}
+	return parseDuration("timeout", t)
+}
+
+// parseDuration accepts a Go duration string ("90s", "5m") or a number of
+// seconds. name is used in errors.
+func parseDuration(name string, t interface{}) (time.Duration, error) {
+	var d time.Duration
+	switch v := t.(type) {
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, errors.Join(fmt.Errorf("error: invalid %s %q: %w", name, v, err), ErrInvalidFormat)
+		}
+		d = parsed
+	case int:
+		d = time.Duration(v) * time.Second
+	case float64:
+		d = time.Duration(v * float64(time.Second))
+	default:
+		return 0, errors.Join(fmt.Errorf("error: %s must be a duration string or a number of seconds, got %T", name, t), ErrInvalidFormat)
+	}
+	if d <= 0 {
+		return 0, errors.Join(fmt.Errorf("error: %s must be positive, got %v", name, t), ErrInvalidFormat)
+	}
+	return d, nil
+}
+
 func joinMaps(a, b map[string]interface{}) (map[string]interface{}, error) {
 	c := make(map[string]interface{})
 	for k, v := range a {
@@ -5,8 +5,10 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"testing"
+	"time"
 )
 
 // func collectIncludesRecurse(sproutID, basepath string, starter map[RecipeName]bool) (map[RecipeName]bool, error) {
@@ -94,7 +96,17 @@ func TestExtractRequisites(t *testing.T) {
 		id          string
 		stepString  string
 		ExpectedReq RequisiteSet
-	}{{id: "empty", stepString: "{}", ExpectedReq: RequisiteSet{}}}
+	}{
+		{id: "empty", stepString: "{}", ExpectedReq: RequisiteSet{}},
+		{
+			id:         "watch",
+			stepString: `{"requisites": [{"watch": "nginx-conf"}, {"watch_any": ["site-a", "site-b"]}]}`,
+			ExpectedReq: RequisiteSet{
+				{Condition: Watch, StepIDs: []StepID{"nginx-conf"}},
+				{Condition: WatchAny, StepIDs: []StepID{"site-a", "site-b"}},
+			},
+		},
+	}
 	for _, tc := range testCases {
 		t.Run(tc.id, func(t *testing.T) {
 			m := make(map[string]interface{})
@@ -113,6 +125,83 @@ func TestExtractRequisites(t *testing.T) {
 	}
 }
 
+func TestExtractLocks(t *testing.T) {
+	testCases := []struct {
+		id         string
+		stepString string
+		expected   []string
+		wantErr    bool
+	}{
+		{id: "none", stepString: `{}`, expected: nil},
+		{id: "string", stepString: `{"locks": "apt"}`, expected: []string{"apt"}},
+		{id: "list", stepString: `{"locks": ["apt", "dpkg"]}`, expected: []string{"apt", "dpkg"}},
+		{id: "number", stepString: `{"locks": 3}`, wantErr: true},
+		{id: "mixed list", stepString: `{"locks": ["apt", 3]}`, wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			m := make(map[string]interface{})
+			if err := json.Unmarshal([]byte(tc.stepString), &m); err != nil {
+				t.Fatal(err)
+			}
+			locks, err := extractLocks(m)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidFormat) {
+					t.Errorf("expected %v, got %v", ErrInvalidFormat, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(locks, tc.expected) {
+				t.Errorf("expected %v but got %v", tc.expected, locks)
+			}
+		})
+	}
+}
+
+func TestExtractTimeout(t *testing.T) {
+	testCases := []struct {
+		id         string
+		stepString string
+		expected   time.Duration
+		wantErr    bool
+	}{
+		{id: "none", stepString: `{}`, expected: 0},
+		{id: "duration", stepString: `{"timeout": "90s"}`, expected: 90 * time.Second},
+		{id: "seconds", stepString: `{"timeout": 2.5}`, expected: 2500 * time.Millisecond},
+		{id: "invalid string", stepString: `{"timeout": "soon"}`, wantErr: true},
+		{id: "negative", stepString: `{"timeout": "-1m"}`, wantErr: true},
+		{id: "wrong type", stepString: `{"timeout": ["1m"]}`, wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			m := make(map[string]interface{})
+			if err := json.Unmarshal([]byte(tc.stepString), &m); err != nil {
+				t.Fatal(err)
+			}
+			timeout, err := extractTimeout(m)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidFormat) {
+					t.Errorf("expected %v, got %v", ErrInvalidFormat, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if timeout != tc.expected {
+				t.Errorf("expected %v but got %v", tc.expected, timeout)
+			}
+		})
+	}
+	// YAML decodes whole numbers as int.
+	if d, err := parseDuration("timeout", 30); err != nil || d != 30*time.Second {
+		t.Errorf("expected 30s, got %v, %v", d, err)
+	}
+}
+
 func TestExtractIncludes(t *testing.T) {
 	testCases := []struct {
 		id          string
@@ -0,0 +1,150 @@
+package cook
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultRetryInterval is the wait before the first retry when a retry
+	// block does not set an interval.
+	DefaultRetryInterval = 10 * time.Second
+	// maxRetryInterval caps the wait between attempts once backoff has
+	// been applied.
+	maxRetryInterval = 10 * time.Minute
+)
+
+const (
+	// UntilSucceeded retries a step until it succeeds. It is the default.
+	UntilSucceeded RetryUntil = "succeeded"
+	// UntilChanged retries a step until it succeeds and reports a change,
+	// e.g. to wait for a command to actually do something.
+	UntilChanged RetryUntil = "changed"
+)
+
+type (
+	// RetryUntil names the outcome that ends a step's retries early.
+	RetryUntil string
+
+	// Retry is a step's retry block:
+	//
+	//	retry:
+	//	  attempts: 5
+	//	  interval: 2s
+	//	  backoff: 2
+	//	  until: succeeded
+	//
+	// The step runs up to Attempts times (2 if unset). After attempt n it
+	// waits Interval * Backoff^(n-1) before trying again.
+	Retry struct {
+		Attempts int           `json:"attempts"`
+		Interval time.Duration `json:"interval"`
+		Backoff  float64       `json:"backoff,omitempty"`
+		Until    RetryUntil    `json:"until,omitempty"`
+	}
+)
+
+// satisfied reports whether c is the outcome the retry block is waiting
+// for. A nil Retry is always satisfied.
+func (r *Retry) satisfied(c StepCompletion) bool {
+	if r == nil {
+		return true
+	}
+	if c.CompletionStatus != StepCompleted {
+		return false
+	}
+	if r.Until == UntilChanged {
+		return c.ChangesMade
+	}
+	return true
+}
+
+// delay returns how long to wait after the given (1-based) attempt.
+func (r *Retry) delay(attempt int) time.Duration {
+	d := float64(r.Interval)
+	if r.Backoff > 1 {
+		for i := 1; i < attempt; i++ {
+			d *= r.Backoff
+			if d >= float64(maxRetryInterval) {
+				return maxRetryInterval
+			}
+		}
+	}
+	return min(time.Duration(d), maxRetryInterval)
+}
+
+// attemptOutcome summarizes one attempt for the step's notes.
+func attemptOutcome(c StepCompletion) string {
+	switch c.CompletionStatus {
+	case StepCompleted:
+		if c.ChangesMade {
+			return "succeeded with changes"
+		}
+		return "succeeded"
+	case StepTimedOut:
+		return "timed out"
+	default:
+		if c.Error != nil {
+			return fmt.Sprintf("failed: %v", c.Error)
+		}
+		return "failed"
+	}
+}
+
+// extractRetry reads a step's optional retry block.
+func extractRetry(m map[string]interface{}) (*Retry, error) {
+	rv, ok := m["retry"]
+	if !ok {
+		return nil, nil
+	}
+	block, ok := rv.(map[string]interface{})
+	if !ok {
+		return nil, errors.Join(fmt.Errorf("error: retry must be a map, got %T", rv), ErrInvalidFormat)
+	}
+	retry := &Retry{Attempts: 2, Interval: DefaultRetryInterval, Until: UntilSucceeded}
+	for k, v := range block {
+		switch k {
+		case "attempts":
+			attempts, isInt := v.(int)
+			if f, isFloat := v.(float64); isFloat && f == float64(int(f)) {
+				attempts, isInt = int(f), true
+			}
+			if !isInt || attempts < 1 {
+				return nil, errors.Join(fmt.Errorf("error: retry attempts must be a positive whole number, got %v", v), ErrInvalidFormat)
+			}
+			retry.Attempts = attempts
+		case "interval":
+			interval, err := parseDuration("retry interval", v)
+			if err != nil {
+				return nil, err
+			}
+			retry.Interval = interval
+		case "backoff":
+			var backoff float64
+			switch b := v.(type) {
+			case int:
+				backoff = float64(b)
+			case float64:
+				backoff = b
+			default:
+				return nil, errors.Join(fmt.Errorf("error: retry backoff must be a number, got %T", v), ErrInvalidFormat)
+			}
+			if backoff < 1 {
+				return nil, errors.Join(fmt.Errorf("error: retry backoff must be at least 1, got %v", v), ErrInvalidFormat)
+			}
+			retry.Backoff = backoff
+		case "until":
+			until, _ := v.(string)
+			switch RetryUntil(until) {
+			case UntilSucceeded, UntilChanged:
+				retry.Until = RetryUntil(until)
+			default:
+				return nil, errors.Join(fmt.Errorf("error: retry until must be %q or %q, got %v", UntilSucceeded, UntilChanged, v), ErrInvalidFormat)
+			}
+		default:
+			return nil, errors.Join(fmt.Errorf("error: unknown retry option %q", k), ErrInvalidFormat)
+		}
+	}
+	return retry, nil
+}
@@ -0,0 +1,164 @@
+package cook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtractRetry(t *testing.T) {
+	testCases := []struct {
+		id         string
+		stepString string
+		expected   *Retry
+		wantErr    bool
+	}{
+		{id: "none", stepString: `{}`, expected: nil},
+		{
+			id:         "defaults",
+			stepString: `{"retry": {}}`,
+			expected:   &Retry{Attempts: 2, Interval: DefaultRetryInterval, Until: UntilSucceeded},
+		},
+		{
+			id:         "full",
+			stepString: `{"retry": {"attempts": 5, "interval": "2s", "backoff": 1.5, "until": "changed"}}`,
+			expected:   &Retry{Attempts: 5, Interval: 2 * time.Second, Backoff: 1.5, Until: UntilChanged},
+		},
+		{id: "not a map", stepString: `{"retry": 3}`, wantErr: true},
+		{id: "zero attempts", stepString: `{"retry": {"attempts": 0}}`, wantErr: true},
+		{id: "fractional attempts", stepString: `{"retry": {"attempts": 1.5}}`, wantErr: true},
+		{id: "bad interval", stepString: `{"retry": {"interval": "later"}}`, wantErr: true},
+		{id: "small backoff", stepString: `{"retry": {"backoff": 0.5}}`, wantErr: true},
+		{id: "bad until", stepString: `{"retry": {"until": "failed"}}`, wantErr: true},
+		{id: "unknown key", stepString: `{"retry": {"tries": 3}}`, wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			m := make(map[string]interface{})
+			if err := json.Unmarshal([]byte(tc.stepString), &m); err != nil {
+				t.Fatal(err)
+			}
+			retry, err := extractRetry(m)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidFormat) {
+					t.Errorf("expected %v, got %v", ErrInvalidFormat, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(retry, tc.expected) {
+				t.Errorf("expected %+v but got %+v", tc.expected, retry)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	constant := &Retry{Interval: time.Second}
+	backoff := &Retry{Interval: time.Second, Backoff: 2}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 100: maxRetryInterval} {
+		if got := backoff.delay(attempt); got != want {
+			t.Errorf("backoff delay after attempt %d: expected %v, got %v", attempt, want, got)
+		}
+		if got := constant.delay(attempt); got != time.Second {
+			t.Errorf("constant delay after attempt %d: expected 1s, got %v", attempt, got)
+		}
+	}
+}
+
+// flakyCooker fails until it has been applied failures+1 times.
+type flakyCooker struct {
+	mockRecipeCooker
+	failures int
+	calls    int
+}
+
+func (f *flakyCooker) Apply(context.Context) (Result, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return Result{Failed: true, Notes: []fmt.Stringer{SimpleNote("mirror unreachable")}}, errors.New("download failed")
+	}
+	return Result{Succeeded: true, Changed: true, Notes: []fmt.Stringer{SimpleNote("downloaded")}}, nil
+}
+
+func TestRunStepRetry(t *testing.T) {
+	origCooker := NewRecipeCooker
+	defer func() { NewRecipeCooker = origCooker }()
+
+	testCases := []struct {
+		id       string
+		failures int
+		retry    *Retry
+		status   CompletionStatus
+		calls    int
+	}{
+		{id: "no retry", failures: 1, retry: nil, status: StepFailed, calls: 1},
+		{id: "succeeds on retry", failures: 2, retry: &Retry{Attempts: 3, Interval: time.Millisecond}, status: StepCompleted, calls: 3},
+		{id: "attempts exhausted", failures: 5, retry: &Retry{Attempts: 3, Interval: time.Millisecond}, status: StepFailed, calls: 3},
+		{id: "stops once satisfied", failures: 0, retry: &Retry{Attempts: 3, Interval: time.Millisecond}, status: StepCompleted, calls: 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			cooker := &flakyCooker{failures: tc.failures}
+			NewRecipeCooker = func(StepID, Ingredient, string, map[string]interface{}) (RecipeCooker, error) {
+				return cooker, nil
+			}
+			completion := runStep(context.Background(), Step{ID: "download", Retry: tc.retry}, false, nil)
+			if completion.CompletionStatus != tc.status {
+				t.Errorf("expected status %d, got %d", tc.status, completion.CompletionStatus)
+			}
+			if cooker.calls != tc.calls {
+				t.Errorf("expected %d attempts, got %d", tc.calls, cooker.calls)
+			}
+			notes := strings.Join(completion.Changes, "\n")
+			if tc.retry != nil && strings.Count(notes, "attempt ") != tc.calls {
+				t.Errorf("expected a note per attempt, got %q", notes)
+			}
+			if tc.failures > 0 && !strings.Contains(notes, "mirror unreachable") {
+				t.Errorf("expected notes from failed attempts to be kept, got %q", notes)
+			}
+		})
+	}
+}
+
+func TestRunStepRetryUntilChanged(t *testing.T) {
+	origCooker := NewRecipeCooker
+	defer func() { NewRecipeCooker = origCooker }()
+
+	calls := 0
+	NewRecipeCooker = func(StepID, Ingredient, string, map[string]interface{}) (RecipeCooker, error) {
+		calls++
+		return &mockRecipeCooker{applyResult: Result{Succeeded: true}}, nil
+	}
+	retry := &Retry{Attempts: 3, Interval: time.Millisecond, Until: UntilChanged}
+	completion := runStep(context.Background(), Step{ID: "wait", Retry: retry}, false, nil)
+	if completion.CompletionStatus != StepCompleted {
+		t.Errorf("expected the last attempt's status, got %d", completion.CompletionStatus)
+	}
+	if n := strings.Count(strings.Join(completion.Changes, "\n"), "attempt "); n != 3 {
+		t.Errorf("expected 3 attempts while waiting for a change, got %d", n)
+	}
+}
+
+func TestRunStepRetryCancelled(t *testing.T) {
+	origCooker := NewRecipeCooker
+	defer func() { NewRecipeCooker = origCooker }()
+
+	cooker := &flakyCooker{failures: 5}
+	NewRecipeCooker = func(StepID, Ingredient, string, map[string]interface{}) (RecipeCooker, error) {
+		return cooker, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	runStep(ctx, Step{ID: "download", Retry: &Retry{Attempts: 5, Interval: time.Hour}}, false, nil)
+	if cooker.calls != 1 {
+		t.Errorf("expected the recipe deadline to stop retries, got %d attempts", cooker.calls)
+	}
+}
@@ -0,0 +1,301 @@
+package cook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gogrlx/grlx/v2/internal/config"
+	"github.com/gogrlx/grlx/v2/internal/log"
+	"github.com/gogrlx/grlx/v2/internal/pki"
+)
+
+// DefaultCookConcurrency is used when the sprout config does not set a
+// positive cookconcurrency.
+const DefaultCookConcurrency = 4
+
+const (
+	JobQueued  JobState = "queued"
+	JobRunning JobState = "running"
+)
+
+type (
+	// JobState is the scheduler's view of a job on a sprout.
+	JobState string
+
+	// ScheduledJob describes a job that is waiting for, or holding, one of
+	// the sprout's cook slots.
+	ScheduledJob struct {
+		JobID    string    `json:"jid"`
+		State    JobState  `json:"state"`
+		Position int       `json:"position,omitempty"`
+		Since    time.Time `json:"since"`
+	}
+
+	// Scheduler cooks recipe envelopes on a sprout. At most limit jobs
+	// cook at once and the rest wait in FIFO order. Steps that declare the
+	// same lock never run at the same time, even across jobs.
+	Scheduler struct {
+		mu      sync.Mutex
+		limit   int
+		running map[string]ScheduledJob
+		queue   []*pendingJob
+		locks   *lockTable
+		cancels map[string]context.CancelCauseFunc
+	}
+
+	pendingJob struct {
+		job   ScheduledJob
+		ready chan struct{}
+	}
+
+	// lockTable hands out named resource locks. Each lock is a semaphore
+	// of size one so waiting can be abandoned when a context is done.
+	lockTable struct {
+		mu   sync.Mutex
+		sems map[string]chan struct{}
+	}
+)
+
+var (
+	defaultScheduler     *Scheduler
+	defaultSchedulerOnce sync.Once
+)
+
+// NewScheduler returns a Scheduler that cooks up to limit jobs at once.
+func NewScheduler(limit int) *Scheduler {
+	if limit <= 0 {
+		limit = DefaultCookConcurrency
+	}
+	return &Scheduler{
+		limit:   limit,
+		running: map[string]ScheduledJob{},
+		locks:   &lockTable{sems: map[string]chan struct{}{}},
+		cancels: map[string]context.CancelCauseFunc{},
+	}
+}
+
+// DefaultScheduler returns the sprout-wide scheduler used by
+// CookRecipeEnvelope. Its limit is read from the config on first use.
+func DefaultScheduler() *Scheduler {
+	defaultSchedulerOnce.Do(func() {
+		defaultScheduler = NewScheduler(config.CookConcurrency)
+	})
+	return defaultScheduler
+}
+
+// Cook waits for a free slot and then cooks envelope, returning once
+// every step has finished, the recipe has timed out or the job has been
+// cancelled. Jobs that have to wait are announced with a queued-<jid>
+// completion.
+func (s *Scheduler) Cook(envelope RecipeEnvelope) error {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	ready := s.enqueue(envelope.JobID, cancel)
+	defer s.finish(envelope.JobID)
+	select {
+	case <-ready:
+	default:
+		publishMarker(envelope.JobID, fmt.Sprintf("queued-%s", envelope.JobID))
+		log.Noticef("job %s queued: %d jobs already cooking", envelope.JobID, s.limit)
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			ids := make([]StepID, 0, len(envelope.Steps))
+			for _, step := range envelope.Steps {
+				ids = append(ids, step.ID)
+			}
+			publishCancelled(envelope.JobID, ids)
+			return ErrJobCancelled
+		}
+	}
+	return s.cook(ctx, envelope)
+}
+
+// Cancel stops jobID, whether it is cooking or still queued. Steps that
+// have not finished are reported as cancelled. It returns false if the
+// job is not known to the scheduler.
+func (s *Scheduler) Cancel(jobID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cancel, ok := s.cancels[jobID]
+	if !ok {
+		return false
+	}
+	for i, pending := range s.queue {
+		if pending.job.JobID == jobID {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			break
+		}
+	}
+	cancel(ErrJobCancelled)
+	return true
+}
+
+// CancelJob cancels jobID on the default scheduler.
+func CancelJob(jobID string) bool {
+	return DefaultScheduler().Cancel(jobID)
+}
+
+// Track registers jobID, run outside the scheduler's slots such as an
+// ad-hoc cmd.run, so that Cancel can stop it. The returned context is
+// cancelled by Cancel; call done once the job has finished.
+func (s *Scheduler) Track(parent context.Context, jobID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+	s.mu.Lock()
+	s.cancels[jobID] = cancel
+	s.mu.Unlock()
+	return ctx, func() {
+		s.mu.Lock()
+		delete(s.cancels, jobID)
+		s.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// TrackJob registers jobID with the default scheduler.
+func TrackJob(parent context.Context, jobID string) (context.Context, func()) {
+	return DefaultScheduler().Track(parent, jobID)
+}
+
+// Jobs returns the running jobs followed by the queued ones in the order
+// they will start.
+func (s *Scheduler) Jobs() []ScheduledJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]ScheduledJob, 0, len(s.running)+len(s.queue))
+	for _, job := range s.running {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Since.Before(jobs[j].Since)
+	})
+	for i, pending := range s.queue {
+		job := pending.job
+		job.Position = i + 1
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// enqueue registers jobID and returns a channel that is closed once the
+// job may start. cancel is called if the job is cancelled.
+func (s *Scheduler) enqueue(jobID string, cancel context.CancelCauseFunc) <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cancels[jobID] = cancel
+	pending := &pendingJob{
+		job:   ScheduledJob{JobID: jobID, State: JobQueued, Since: time.Now()},
+		ready: make(chan struct{}),
+	}
+	s.queue = append(s.queue, pending)
+	s.promote()
+	return pending.ready
+}
+
+// finish releases jobID's slot and starts the next queued job.
+func (s *Scheduler) finish(jobID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, jobID)
+	delete(s.cancels, jobID)
+	s.promote()
+}
+
+// promote moves jobs from the head of the queue into free slots. The
+// caller must hold s.mu.
+func (s *Scheduler) promote() {
+	for len(s.queue) > 0 && len(s.running) < s.limit {
+		next := s.queue[0]
+		s.queue = s.queue[1:]
+		next.job.State = JobRunning
+		next.job.Since = time.Now()
+		s.running[next.job.JobID] = next.job
+		close(next.ready)
+	}
+}
+
+// acquire takes every named lock, in sorted order so that two steps can
+// never each hold a lock the other is waiting for. The returned function
+// releases them. If ctx is done first, any locks already taken are
+// released and ctx's error is returned.
+func (l *lockTable) acquire(ctx context.Context, names []string) (func(), error) {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	var held []chan struct{}
+	release := func() {
+		for _, sem := range held {
+			<-sem
+		}
+	}
+	for i, name := range sorted {
+		if i > 0 && name == sorted[i-1] {
+			continue
+		}
+		sem := l.sem(name)
+		select {
+		case sem <- struct{}{}:
+			held = append(held, sem)
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+func (l *lockTable) sem(name string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sem, ok := l.sems[name]
+	if !ok {
+		sem = make(chan struct{}, 1)
+		l.sems[name] = sem
+	}
+	return sem
+}
+
+// publishCancelled reports each of ids as cancelled, followed by the
+// job's cancelled-<jid> marker.
+func publishCancelled(jobID string, ids []StepID) {
+	now := time.Now()
+	for _, id := range append(ids, StepID(fmt.Sprintf("cancelled-%s", jobID))) {
+		completion := StepCompletion{
+			ID:               id,
+			CompletionStatus: StepCancelled,
+			Started:          now,
+		}
+		logStepResult(jobID, completion)
+		b, err := json.Marshal(completion)
+		if err != nil {
+			log.Errorf("failed to marshal step completion: %v", err)
+			continue
+		}
+		if conn == nil {
+			continue
+		}
+		conn.Publish("grlx.cook."+pki.GetSproutID()+"."+jobID, b)
+	}
+}
+
+// publishMarker sends a synthetic completion (such as start-<jid>) that
+// tracks a job's lifecycle rather than one of its steps.
+func publishMarker(jobID string, id string) {
+	completion := StepCompletion{
+		ID:               StepID(id),
+		CompletionStatus: StepCompleted,
+		Started:          time.Now(),
+	}
+	b, err := json.Marshal(completion)
+	if err != nil {
+		log.Errorf("failed to marshal step completion: %v", err)
+		return
+	}
+	if conn == nil {
+		return
+	}
+	conn.Publish("grlx.cook."+pki.GetSproutID()+"."+jobID, b)
+}
@@ -0,0 +1,114 @@
+package cook
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSchedulerLimit(t *testing.T) {
+	s := NewScheduler(2)
+	first := s.enqueue("job-1", func(error) {})
+	second := s.enqueue("job-2", func(error) {})
+	third := s.enqueue("job-3", func(error) {})
+
+	for _, ready := range []<-chan struct{}{first, second} {
+		select {
+		case <-ready:
+		default:
+			t.Fatal("expected the first two jobs to start immediately")
+		}
+	}
+	select {
+	case <-third:
+		t.Fatal("expected the third job to wait for a free slot")
+	default:
+	}
+
+	jobs := s.Jobs()
+	if len(jobs) != 3 {
+		t.Fatalf("expected 3 jobs, got %d", len(jobs))
+	}
+	if jobs[0].State != JobRunning || jobs[1].State != JobRunning {
+		t.Errorf("expected two running jobs first, got %+v", jobs)
+	}
+	if jobs[2].JobID != "job-3" || jobs[2].State != JobQueued || jobs[2].Position != 1 {
+		t.Errorf("expected job-3 queued at position 1, got %+v", jobs[2])
+	}
+
+	s.finish("job-1")
+	select {
+	case <-third:
+	case <-time.After(time.Second):
+		t.Fatal("expected the third job to start once a slot was freed")
+	}
+	for _, job := range s.Jobs() {
+		if job.State != JobRunning {
+			t.Errorf("expected every remaining job to be running, got %+v", job)
+		}
+	}
+}
+
+func TestNewSchedulerDefaultLimit(t *testing.T) {
+	if s := NewScheduler(0); s.limit != DefaultCookConcurrency {
+		t.Errorf("expected limit %d, got %d", DefaultCookConcurrency, s.limit)
+	}
+}
+
+func TestLockTable(t *testing.T) {
+	locks := &lockTable{sems: map[string]chan struct{}{}}
+	release, err := locks.acquire(context.Background(), []string{"pkg", "apt", "pkg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		r, err := locks.acquire(context.Background(), []string{"apt"})
+		if err == nil {
+			r()
+		}
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("expected a held lock to block other steps")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Unrelated locks are not affected.
+	other, err := locks.acquire(context.Background(), []string{"db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other()
+
+	release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("expected the lock to be handed over after release")
+	}
+}
+
+func TestLockTableCancel(t *testing.T) {
+	locks := &lockTable{sems: map[string]chan struct{}{}}
+	release, err := locks.acquire(context.Background(), []string{"b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err = locks.acquire(ctx, []string{"a", "b"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	// "a" was taken before waiting on "b" and must have been released.
+	r, err := locks.acquire(context.Background(), []string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r()
+}
@@ -7,31 +7,38 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"sync"
 	"time"
 
 	"github.com/gogrlx/grlx/v2/internal/log"
 
 	"github.com/gogrlx/grlx/v2/internal/config"
 	"github.com/gogrlx/grlx/v2/internal/pki"
+	"github.com/gogrlx/grlx/v2/internal/props"
 )
 
 var (
 	ErrCookTimeout     = errors.New("recipe cooking timed out")
+	ErrStepTimeout     = errors.New("step timed out")
 	ErrStalled         = errors.New("no steps are in progress")
 	ErrRequisiteNotMet = errors.New("requisite not met")
-	nonCCM             = sync.Mutex{}
+	ErrJobCancelled    = errors.New("job cancelled")
 )
 
 // DefaultCookTimeout is the maximum time allowed for a recipe envelope to
-// complete all steps. If all steps are not finished within this duration,
-// the operation is cancelled and an error is returned.
+// complete all steps, unless the recipe sets its own timeout. If all steps
+// are not finished within this duration, the operation is cancelled and an
+// error is returned.
 const DefaultCookTimeout = 30 * time.Minute
 
+// CookRecipeEnvelope cooks envelope on the sprout's default scheduler,
+// waiting in its queue if the concurrency limit has been reached.
 func CookRecipeEnvelope(envelope RecipeEnvelope) error {
-	nonCCM.Lock()
-	defer nonCCM.Unlock()
+	return DefaultScheduler().Cook(envelope)
+}
+
+func (s *Scheduler) cook(ctx context.Context, envelope RecipeEnvelope) error {
 	log.Tracef("received new envelope: %v", envelope)
+	loadEnvelopeProps(envelope)
 
 	completionMap := map[StepID]StepCompletion{}
 	for _, step := range envelope.Steps {
@@ -62,24 +69,27 @@ func CookRecipeEnvelope(envelope RecipeEnvelope) error {
 	// considered finished: one per step plus the seeded "start" completion.
 	expected := len(envelope.Steps) + 1
 	completed := 0
-	// ctx bounds the total wall-clock time for the entire recipe. When it fires,
-	// in-flight steps observe the cancellation through their Apply/Test context.
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultCookTimeout)
+	// ctx bounds the total wall-clock time for the entire recipe and is
+	// cancelled if the job is. When it fires, in-flight steps observe the
+	// cancellation through their Apply/Test context.
+	recipeTimeout := envelope.Timeout
+	if recipeTimeout <= 0 {
+		recipeTimeout = DefaultCookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, recipeTimeout)
 	defer cancel()
 	for {
 		select {
 		// each time a step completes, check if any other steps can be started
 		case completion := <-completionChan:
-			b, marshalErr := json.Marshal(completion)
-			if marshalErr != nil {
-				log.Errorf("failed to marshal step completion: %v", marshalErr)
-			}
-
-			conn.Publish("grlx.cook."+pki.GetSproutID()+"."+envelope.JobID, b)
-			log.Infof("Step %s completed with status %v", completion.ID, completion)
+			// The completion is recorded before the rest of a cancelled
+			// job is stopped, so a step that finished is not lost.
+			cancelled := errors.Is(context.Cause(ctx), ErrJobCancelled)
+			recordCompletion(envelope.JobID, completionMap, completion, cancelled)
 			completed++
-			logStepResult(envelope.JobID, completion)
-			completionMap[completion.ID] = completion
+			if cancelled {
+				return cancelRemaining(envelope, completionMap)
+			}
 			noneInProgress := true
 			for id, step := range completionMap {
 				if step.CompletionStatus == StepInProgress {
@@ -110,51 +120,19 @@ func CookRecipeEnvelope(envelope RecipeEnvelope) error {
 				entry.CompletionStatus = StepInProgress
 				completionMap[id] = entry
 				// all requisites are met, so start the step in a goroutine
-				go func(ctx context.Context, step Step, cChan chan StepCompletion, testMode bool) {
-					started := time.Now()
-					// use the ingredient package to load and cook the step
-					ingredient, err := NewRecipeCooker(step.ID, step.Ingredient, step.Method, step.Properties)
+				go func(ctx context.Context, step Step, cChan chan StepCompletion, testMode bool, watched []StepID) {
+					release, err := s.locks.acquire(ctx, step.Locks)
 					if err != nil {
 						cChan <- StepCompletion{
 							ID:               step.ID,
 							CompletionStatus: StepFailed,
-							Started:          started,
-							Duration:         time.Since(started),
-							Error:            err,
+							Error:            fmt.Errorf("waiting for locks %v: %w", step.Locks, err),
 						}
 						return
 					}
-					var res Result
-					if testMode {
-						res, err = ingredient.Test(ctx)
-					} else {
-						res, err = ingredient.Apply(ctx)
-					}
-
-					duration := time.Since(started)
-					// in test mode, res.Changed and res.Notes may not be populated
-					var changed bool
-					var notes []string
-					if !testMode {
-						changed = res.Changed
-						for _, note := range res.Notes {
-							notes = append(notes, note.String())
-						}
-					}
-					status := StepCompleted
-					if !res.Succeeded {
-						status = StepFailed
-					}
-					cChan <- StepCompletion{
-						ID:               step.ID,
-						CompletionStatus: status,
-						ChangesMade:      changed,
-						Changes:          notes,
-						Started:          started,
-						Duration:         duration,
-						Error:            err,
-					}
-				}(ctx, stepMap[id], completionChan, envelope.Test)
+					defer release()
+					cChan <- runStep(ctx, step, testMode, watched)
+				}(ctx, stepMap[id], completionChan, envelope.Test, watchedChanges(stepMap[id], completionMap, envelope.Test))
 				noneInProgress = false
 			}
 			if noneInProgress {
@@ -177,20 +155,231 @@ func CookRecipeEnvelope(envelope RecipeEnvelope) error {
 				log.Info("All steps completed")
 				return nil
 			}
-		// The recipe exceeded its total time budget.
 		case <-ctx.Done():
-			log.Errorf("recipe %s timed out after %v", envelope.JobID, DefaultCookTimeout)
-			completion := StepCompletion{
-				ID:               StepID(fmt.Sprintf("timeout-%s", envelope.JobID)),
-				CompletionStatus: StepFailed,
-				Error:            ErrCookTimeout,
+			if errors.Is(context.Cause(ctx), ErrJobCancelled) {
+				// Record the steps that finished before the
+				// cancellation was seen.
+			drain:
+				for {
+					select {
+					case completion := <-completionChan:
+						recordCompletion(envelope.JobID, completionMap, completion, true)
+					default:
+						break drain
+					}
+				}
+				return cancelRemaining(envelope, completionMap)
 			}
-			b, marshalErr := json.Marshal(completion)
-			if marshalErr != nil {
-				log.Errorf("failed to marshal timeout completion: %v", marshalErr)
+			// The recipe exceeded its total time budget. Record the
+			// steps that finished before the timeout was seen.
+			log.Errorf("recipe %s timed out after %v", envelope.JobID, recipeTimeout)
+		drainTimeout:
+			for {
+				select {
+				case completion := <-completionChan:
+					recordCompletion(envelope.JobID, completionMap, completion, false)
+				default:
+					break drainTimeout
+				}
 			}
-			conn.Publish("grlx.cook."+pki.GetSproutID()+"."+envelope.JobID, b)
-			return ErrCookTimeout
+			return timeOutRemaining(envelope, completionMap)
+		}
+	}
+}
+
+// timeOutRemaining stops a job that exceeded its recipe timeout,
+// reporting every step that had not finished as timed out.
+func timeOutRemaining(envelope RecipeEnvelope, completionMap map[StepID]StepCompletion) error {
+	now := time.Now()
+	for _, step := range envelope.Steps {
+		switch completionMap[step.ID].CompletionStatus {
+		case StepNotStarted, StepInProgress:
+			recordCompletion(envelope.JobID, completionMap, StepCompletion{
+				ID:               step.ID,
+				CompletionStatus: StepTimedOut,
+				Started:          now,
+				Error:            ErrCookTimeout,
+			}, false)
+		}
+	}
+	completion := StepCompletion{
+		ID:               StepID(fmt.Sprintf("timeout-%s", envelope.JobID)),
+		CompletionStatus: StepFailed,
+		Error:            ErrCookTimeout,
+	}
+	b, marshalErr := json.Marshal(completion)
+	if marshalErr != nil {
+		log.Errorf("failed to marshal timeout completion: %v", marshalErr)
+	}
+	conn.Publish("grlx.cook."+pki.GetSproutID()+"."+envelope.JobID, b)
+	return ErrCookTimeout
+}
+
+// recordCompletion publishes completion and records it in completionMap.
+// If the job has been cancelled, a step interrupted by the cancellation
+// reports in as failed; it is recorded as cancelled instead.
+func recordCompletion(jobID string, completionMap map[StepID]StepCompletion, completion StepCompletion, cancelled bool) {
+	if cancelled && (completion.CompletionStatus == StepFailed || completion.CompletionStatus == StepTimedOut) {
+		completion.CompletionStatus = StepCancelled
+		completion.Error = ErrJobCancelled
+	}
+	b, marshalErr := json.Marshal(completion)
+	if marshalErr != nil {
+		log.Errorf("failed to marshal step completion: %v", marshalErr)
+	}
+	conn.Publish("grlx.cook."+pki.GetSproutID()+"."+jobID, b)
+	log.Infof("Step %s completed with status %v", completion.ID, completion)
+	logStepResult(jobID, completion)
+	completionMap[completion.ID] = completion
+}
+
+// cancelRemaining stops a cancelled job, reporting every step that had
+// not finished as cancelled.
+func cancelRemaining(envelope RecipeEnvelope, completionMap map[StepID]StepCompletion) error {
+	var remaining []StepID
+	for _, step := range envelope.Steps {
+		switch completionMap[step.ID].CompletionStatus {
+		case StepNotStarted, StepInProgress:
+			remaining = append(remaining, step.ID)
+		}
+	}
+	log.Noticef("job %s cancelled with %d steps unfinished", envelope.JobID, len(remaining))
+	publishCancelled(envelope.JobID, remaining)
+	return ErrJobCancelled
+}
+
+// runStep cooks a single step, retrying it as its retry block allows.
+// The notes of every attempt are kept, and the returned completion
+// reflects the last attempt. watched lists the watched steps that made
+// changes; if there are any and the ingredient is a Watcher, its ModWatch
+// hook is cooked instead.
+func runStep(ctx context.Context, step Step, testMode bool, watched []StepID) StepCompletion {
+	started := time.Now()
+	// use the ingredient package to load and cook the step
+	ingredient, err := NewRecipeCooker(step.ID, step.Ingredient, step.Method, step.Properties)
+	if err != nil {
+		return StepCompletion{
+			ID:               step.ID,
+			CompletionStatus: StepFailed,
+			Started:          started,
+			Duration:         time.Since(started),
+			Error:            err,
+		}
+	}
+	// Guards are evaluated in test mode too, so that a test run reports
+	// the steps that would actually be cooked.
+	skip, reason, err := checkGuards(ctx, step)
+	if err != nil || skip {
+		completion := StepCompletion{
+			ID:               step.ID,
+			CompletionStatus: StepSkipped,
+			Started:          started,
+			Duration:         time.Since(started),
+		}
+		if err != nil {
+			completion.CompletionStatus = StepFailed
+			completion.Error = err
+		} else {
+			completion.Changes = []string{reason}
+		}
+		return completion
+	}
+	var watcher Watcher
+	var notes []string
+	if len(watched) > 0 {
+		if w, ok := ingredient.(Watcher); ok {
+			watcher = w
+			notes = append(notes, fmt.Sprintf("watched steps changed: %v", watched))
+		}
+	}
+	attempts := 1
+	// Test runs make no changes, so retrying them would only repeat the
+	// same answer.
+	if step.Retry != nil && !testMode {
+		attempts = step.Retry.Attempts
+	}
+	var completion StepCompletion
+retry:
+	for attempt := 1; ; attempt++ {
+		completion = cookAttempt(ctx, ingredient, watcher, step, testMode)
+		if attempts > 1 {
+			notes = append(notes, fmt.Sprintf("attempt %d of %d: %s", attempt, attempts, attemptOutcome(completion)))
+		}
+		notes = append(notes, completion.Changes...)
+		if attempt >= attempts || step.Retry.satisfied(completion) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			break retry
+		case <-time.After(step.Retry.delay(attempt)):
+		}
+	}
+	completion.Changes = notes
+	completion.Started = started
+	completion.Duration = time.Since(started)
+	return completion
+}
+
+// cookAttempt runs the step's ingredient once, bounded by the step's
+// timeout. A non-nil watcher is cooked in place of the ingredient.
+func cookAttempt(ctx context.Context, ingredient RecipeCooker, watcher Watcher, step Step, testMode bool) StepCompletion {
+	stepCtx := ctx
+	if step.Timeout > 0 {
+		var stepCancel context.CancelFunc
+		stepCtx, stepCancel = context.WithTimeout(ctx, step.Timeout)
+		defer stepCancel()
+	}
+	var res Result
+	var err error
+	if watcher != nil {
+		res, err = watcher.ModWatch(stepCtx, testMode)
+	} else if testMode {
+		res, err = ingredient.Test(stepCtx)
+	} else {
+		res, err = ingredient.Apply(stepCtx)
+	}
+
+	// In test mode nothing was changed; res.Changed reports what would
+	// have been, and the notes describe it.
+	var changed, wouldChange bool
+	var notes []string
+	for _, note := range res.Notes {
+		notes = append(notes, note.String())
+	}
+	if testMode {
+		wouldChange = res.Changed
+	} else {
+		changed = res.Changed
+	}
+	status := StepCompleted
+	if !res.Succeeded {
+		status = StepFailed
+		if errors.Is(stepCtx.Err(), context.DeadlineExceeded) {
+			status = StepTimedOut
+			err = errors.Join(ErrStepTimeout, err)
+		}
+	}
+	return StepCompletion{
+		ID:               step.ID,
+		CompletionStatus: status,
+		ChangesMade:      changed,
+		WouldChange:      wouldChange,
+		Changes:          notes,
+		Error:            err,
+	}
+}
+
+// loadEnvelopeProps seeds the local props cache with the snapshot sent by
+// the farmer so that sprout-side templates can resolve props.
+func loadEnvelopeProps(envelope RecipeEnvelope) {
+	if len(envelope.Props) == 0 {
+		return
+	}
+	sproutID := pki.GetSproutID()
+	for name, value := range envelope.Props {
+		if err := props.SetProp(sproutID, name, fmt.Sprintf("%v", value)); err != nil {
+			log.Errorf("failed to load prop %s from envelope %s: %v", name, envelope.JobID, err)
 		}
 	}
 }
@@ -221,6 +410,39 @@ func logStepResult(jobID string, completion StepCompletion) {
 	f.WriteString("\n")
 }
 
+// watchedChanges returns the steps named by step's watch and watch_any
+// requisites that made changes. In test mode a step that would have made
+// changes counts, so the watcher's ModWatch is tested too.
+func watchedChanges(step Step, completionMap map[StepID]StepCompletion, testMode bool) []StepID {
+	var changed []StepID
+	for _, reqSet := range step.Requisites {
+		if reqSet.Condition != Watch && reqSet.Condition != WatchAny {
+			continue
+		}
+		for _, id := range reqSet.StepIDs {
+			c := completionMap[id]
+			if c.ChangesMade || (testMode && c.WouldChange) {
+				changed = append(changed, id)
+			}
+		}
+	}
+	return changed
+}
+
+// requisiteStatus returns c as requisites see it: a timed-out step is a
+// failed step, and a skipped step is a completed step that made no
+// changes, so it satisfies require but never triggers onchanges or onfail.
+func requisiteStatus(c StepCompletion) StepCompletion {
+	switch c.CompletionStatus {
+	case StepTimedOut:
+		c.CompletionStatus = StepFailed
+	case StepSkipped:
+		c.CompletionStatus = StepCompleted
+		c.ChangesMade = false
+	}
+	return c
+}
+
 // RequisitesAreMet returns true if all of the requisites for the given step are met
 // All top-level requisites are ANDed together, and meta states can be combined with an ANY clauses
 // to use OR logic instead
@@ -234,7 +456,7 @@ func RequisitesAreMet(step Step, completionMap map[StepID]StepCompletion) (bool,
 		switch reqSet.Condition {
 		case OnChanges:
 			for _, req := range reqSet.StepIDs {
-				reqStatus := completionMap[req]
+				reqStatus := requisiteStatus(completionMap[req])
 				// if the step is completed or failed, and no changes were made, then the requisite cannot be met
 				if reqStatus.CompletionStatus == StepCompleted || reqStatus.CompletionStatus == StepFailed {
 					if !reqStatus.ChangesMade {
@@ -247,7 +469,7 @@ func RequisitesAreMet(step Step, completionMap map[StepID]StepCompletion) (bool,
 			}
 		case OnFail:
 			for _, req := range reqSet.StepIDs {
-				reqStatus := completionMap[req]
+				reqStatus := requisiteStatus(completionMap[req])
 				// if the step is completed, then the requisite cannot be met
 				if reqStatus.CompletionStatus == StepCompleted {
 					return false, errors.Join(ErrRequisiteNotMet, fmt.Errorf(errStr, reqSet.Condition, string(req)))
@@ -256,9 +478,9 @@ func RequisitesAreMet(step Step, completionMap map[StepID]StepCompletion) (bool,
 					unmet = true
 				}
 			}
-		case Require:
+		case Require, Watch:
 			for _, req := range reqSet.StepIDs {
-				reqStatus := completionMap[req]
+				reqStatus := requisiteStatus(completionMap[req])
 				if reqStatus.CompletionStatus == StepFailed {
 					return false, errors.Join(ErrRequisiteNotMet, fmt.Errorf(errStr, reqSet.Condition, string(req)))
 				} else if reqStatus.CompletionStatus != StepCompleted {
@@ -270,7 +492,7 @@ func RequisitesAreMet(step Step, completionMap map[StepID]StepCompletion) (bool,
 			met := false
 			pendingRemaining := false
 			for _, req := range reqSet.StepIDs {
-				reqStatus := completionMap[req]
+				reqStatus := requisiteStatus(completionMap[req])
 				if reqStatus.CompletionStatus == StepCompleted || reqStatus.CompletionStatus == StepFailed {
 					if reqStatus.ChangesMade {
 						met = true
@@ -289,7 +511,7 @@ func RequisitesAreMet(step Step, completionMap map[StepID]StepCompletion) (bool,
 			met := false
 			pendingRemaining := false
 			for _, req := range reqSet.StepIDs {
-				reqStatus := completionMap[req]
+				reqStatus := requisiteStatus(completionMap[req])
 				if reqStatus.CompletionStatus == StepFailed {
 					met = true
 				} else if reqStatus.CompletionStatus != StepCompleted {
@@ -302,11 +524,11 @@ func RequisitesAreMet(step Step, completionMap map[StepID]StepCompletion) (bool,
 			if !met {
 				unmet = true
 			}
-		case RequireAny:
+		case RequireAny, WatchAny:
 			met := false
 			pendingRemaining := false
 			for _, req := range reqSet.StepIDs {
-				reqStatus := completionMap[req]
+				reqStatus := requisiteStatus(completionMap[req])
 				if reqStatus.CompletionStatus == StepCompleted {
 					met = true
 				} else if reqStatus.CompletionStatus != StepFailed {
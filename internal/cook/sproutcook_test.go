@@ -1,8 +1,17 @@
 package cook
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/gogrlx/grlx/v2/internal/config"
 )
 
 func TestRequisitesAreMet(t *testing.T) {
@@ -24,6 +33,14 @@ func TestRequisitesAreMet(t *testing.T) {
 			ID:               "notstarted",
 			CompletionStatus: StepNotStarted,
 		},
+		"timedout": {
+			ID:               "timedout",
+			CompletionStatus: StepTimedOut,
+		},
+		"skipped": {
+			ID:               "skipped",
+			CompletionStatus: StepSkipped,
+		},
 	}
 
 	testCases := []struct {
@@ -83,6 +100,54 @@ func TestRequisitesAreMet(t *testing.T) {
 			},
 			expected: false, err: nil,
 		},
+		{
+			id: "require a timed out step",
+			requisites: RequisiteSet{Requisite{
+				Condition: Require,
+				StepIDs:   []StepID{"timedout"},
+			}},
+			expected: false, err: ErrRequisiteNotMet,
+		},
+		{
+			id: "onfail a timed out step",
+			requisites: RequisiteSet{Requisite{
+				Condition: OnFail,
+				StepIDs:   []StepID{"timedout"},
+			}},
+			expected: true, err: nil,
+		},
+		{
+			id: "require a skipped step",
+			requisites: RequisiteSet{Requisite{
+				Condition: Require,
+				StepIDs:   []StepID{"skipped"},
+			}},
+			expected: true, err: nil,
+		},
+		{
+			id: "onchanges a skipped step",
+			requisites: RequisiteSet{Requisite{
+				Condition: OnChanges,
+				StepIDs:   []StepID{"skipped"},
+			}},
+			expected: false, err: ErrRequisiteNotMet,
+		},
+		{
+			id: "onfail a skipped step",
+			requisites: RequisiteSet{Requisite{
+				Condition: OnFail,
+				StepIDs:   []StepID{"skipped"},
+			}},
+			expected: false, err: ErrRequisiteNotMet,
+		},
+		{
+			id: "require_any a skipped step",
+			requisites: RequisiteSet{Requisite{
+				Condition: RequireAny,
+				StepIDs:   []StepID{"skipped", "failed"},
+			}},
+			expected: true, err: nil,
+		},
 		{
 			id: "two anyrequisites, one met, one pending",
 			requisites: RequisiteSet{
@@ -106,3 +171,422 @@ func TestRequisitesAreMet(t *testing.T) {
 		})
 	}
 }
+
+// blockingCooker waits for its context to be done, like an ingredient
+// running a command that never finishes.
+type blockingCooker struct {
+	mockRecipeCooker
+}
+
+func (b *blockingCooker) Apply(ctx context.Context) (Result, error) {
+	<-ctx.Done()
+	return Result{Failed: true}, ctx.Err()
+}
+
+func TestCookRecipeEnvelopeTimeouts(t *testing.T) {
+	nc, cleanup := startCookTestNATS(t)
+	defer cleanup()
+
+	oldDir := config.JobLogDir
+	config.JobLogDir = t.TempDir()
+	defer func() { config.JobLogDir = oldDir }()
+	origCooker := NewRecipeCooker
+	defer func() { NewRecipeCooker = origCooker }()
+	oldSproutID := config.SproutID
+	config.SproutID = "timeout-sprout"
+	defer func() { config.SproutID = oldSproutID }()
+
+	NewRecipeCooker = func(id StepID, ingredient Ingredient, method string, params map[string]interface{}) (RecipeCooker, error) {
+		if id == "slow" {
+			return &blockingCooker{}, nil
+		}
+		return &mockRecipeCooker{applyResult: Result{Succeeded: true}}, nil
+	}
+
+	collect := func(t *testing.T, jid string) <-chan StepCompletion {
+		return collectCompletions(t, nc, "grlx.cook.timeout-sprout."+jid)
+	}
+	find := findCompletion
+
+	t.Run("step timeout", func(t *testing.T) {
+		completions := collect(t, "step-timeout")
+		err := CookRecipeEnvelope(RecipeEnvelope{
+			JobID: "step-timeout",
+			Steps: []Step{
+				{ID: "slow", Ingredient: "cmd", Method: "run", Timeout: 50 * time.Millisecond},
+				{
+					ID: "cleanup", Ingredient: "cmd", Method: "run",
+					Requisites: RequisiteSet{{Condition: OnFail, StepIDs: []StepID{"slow"}}},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("expected the recipe to finish, got %v", err)
+		}
+		if c := find(t, completions, "slow"); c.CompletionStatus != StepTimedOut {
+			t.Errorf("expected slow to time out, got status %d", c.CompletionStatus)
+		}
+		if c := find(t, completions, "cleanup"); c.CompletionStatus != StepCompleted {
+			t.Errorf("expected onfail step to run after a timeout, got status %d", c.CompletionStatus)
+		}
+	})
+
+	t.Run("recipe timeout", func(t *testing.T) {
+		completions := collect(t, "recipe-timeout")
+		err := CookRecipeEnvelope(RecipeEnvelope{
+			JobID:   "recipe-timeout",
+			Timeout: 50 * time.Millisecond,
+			Steps: []Step{
+				{ID: "quick", Ingredient: "cmd", Method: "run"},
+				{ID: "slow", Ingredient: "cmd", Method: "run"},
+				{
+					ID: "after", Ingredient: "cmd", Method: "run",
+					Requisites: RequisiteSet{{Condition: Require, StepIDs: []StepID{"slow"}}},
+				},
+			},
+		})
+		if !errors.Is(err, ErrCookTimeout) {
+			t.Fatalf("expected %v, got %v", ErrCookTimeout, err)
+		}
+		got := map[StepID]CompletionStatus{}
+		for c := find(t, completions, "quick"); ; c = <-completions {
+			got[c.ID] = c.CompletionStatus
+			if c.ID == "timeout-recipe-timeout" {
+				break
+			}
+		}
+		want := map[StepID]CompletionStatus{"quick": StepCompleted, "slow": StepTimedOut, "after": StepTimedOut}
+		for id, status := range want {
+			if got[id] != status {
+				t.Errorf("expected %s to finish with status %d, got %v", id, status, got)
+			}
+		}
+	})
+}
+
+// collectCompletions subscribes to subject and delivers the completions
+// published on it.
+func collectCompletions(t *testing.T, nc *nats.Conn, subject string) <-chan StepCompletion {
+	t.Helper()
+	completions := make(chan StepCompletion, 16)
+	sub, err := nc.Subscribe(subject, func(msg *nats.Msg) {
+		// Error does not survive a JSON round trip, so only decode
+		// the fields under test.
+		var c struct {
+			ID               StepID
+			CompletionStatus CompletionStatus
+		}
+		if json.Unmarshal(msg.Data, &c) == nil {
+			completions <- StepCompletion{ID: c.ID, CompletionStatus: c.CompletionStatus}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sub.Unsubscribe() })
+	return completions
+}
+
+// findCompletion waits for the completion of step id.
+func findCompletion(t *testing.T, completions <-chan StepCompletion, id StepID) StepCompletion {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case c := <-completions:
+			if c.ID == id {
+				return c
+			}
+		case <-timeout:
+			t.Fatalf("no completion for %s", id)
+		}
+	}
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	nc, cleanup := startCookTestNATS(t)
+	defer cleanup()
+
+	oldDir := config.JobLogDir
+	config.JobLogDir = t.TempDir()
+	defer func() { config.JobLogDir = oldDir }()
+	origCooker := NewRecipeCooker
+	defer func() { NewRecipeCooker = origCooker }()
+	oldSproutID := config.SproutID
+	config.SproutID = "cancel-sprout"
+	defer func() { config.SproutID = oldSproutID }()
+
+	started := make(chan struct{}, 1)
+	NewRecipeCooker = func(id StepID, ingredient Ingredient, method string, params map[string]interface{}) (RecipeCooker, error) {
+		if id == "slow" {
+			started <- struct{}{}
+			return &blockingCooker{}, nil
+		}
+		return &mockRecipeCooker{applyResult: Result{Succeeded: true}}, nil
+	}
+
+	s := NewScheduler(1)
+	if s.Cancel("unknown") {
+		t.Error("expected cancelling an unknown job to report false")
+	}
+	running := collectCompletions(t, nc, "grlx.cook.cancel-sprout.running-job")
+	queued := collectCompletions(t, nc, "grlx.cook.cancel-sprout.queued-job")
+
+	runningErr := make(chan error, 1)
+	go func() {
+		runningErr <- s.Cook(RecipeEnvelope{
+			JobID: "running-job",
+			Steps: []Step{
+				{ID: "slow", Ingredient: "cmd", Method: "run"},
+				{
+					ID: "after", Ingredient: "cmd", Method: "run",
+					Requisites: RequisiteSet{{Condition: Require, StepIDs: []StepID{"slow"}}},
+				},
+			},
+		})
+	}()
+	<-started
+
+	queuedErr := make(chan error, 1)
+	go func() {
+		queuedErr <- s.Cook(RecipeEnvelope{
+			JobID: "queued-job",
+			Steps: []Step{{ID: "never", Ingredient: "cmd", Method: "run"}},
+		})
+	}()
+	findCompletion(t, queued, "queued-queued-job")
+
+	if !s.Cancel("queued-job") {
+		t.Fatal("expected the queued job to be cancelled")
+	}
+	if err := <-queuedErr; !errors.Is(err, ErrJobCancelled) {
+		t.Errorf("expected %v, got %v", ErrJobCancelled, err)
+	}
+	if c := findCompletion(t, queued, "never"); c.CompletionStatus != StepCancelled {
+		t.Errorf("expected the queued job's step to be cancelled, got status %d", c.CompletionStatus)
+	}
+	findCompletion(t, queued, "cancelled-queued-job")
+
+	if !s.Cancel("running-job") {
+		t.Fatal("expected the running job to be cancelled")
+	}
+	select {
+	case err := <-runningErr:
+		if !errors.Is(err, ErrJobCancelled) {
+			t.Errorf("expected %v, got %v", ErrJobCancelled, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled job kept running")
+	}
+	for _, id := range []StepID{"slow", "after", "cancelled-running-job"} {
+		if c := findCompletion(t, running, id); c.CompletionStatus != StepCancelled {
+			t.Errorf("expected %s to be reported cancelled, got status %d", id, c.CompletionStatus)
+		}
+	}
+	if jobs := s.Jobs(); len(jobs) != 0 {
+		t.Errorf("expected cancelled jobs to leave the scheduler, got %+v", jobs)
+	}
+	if s.Cancel("running-job") {
+		t.Error("expected a finished job to no longer be cancellable")
+	}
+}
+
+func TestSchedulerCancelRecordsFinishedSteps(t *testing.T) {
+	nc, cleanup := startCookTestNATS(t)
+	defer cleanup()
+
+	oldDir := config.JobLogDir
+	config.JobLogDir = t.TempDir()
+	defer func() { config.JobLogDir = oldDir }()
+	origCooker := NewRecipeCooker
+	defer func() { NewRecipeCooker = origCooker }()
+	oldSproutID := config.SproutID
+	config.SproutID = "cancel-sprout"
+	defer func() { config.SproutID = oldSproutID }()
+
+	started := make(chan struct{}, 1)
+	NewRecipeCooker = func(id StepID, ingredient Ingredient, method string, params map[string]interface{}) (RecipeCooker, error) {
+		if id == "slow" {
+			started <- struct{}{}
+			return &blockingCooker{}, nil
+		}
+		return &mockRecipeCooker{applyResult: Result{Succeeded: true, Changed: true}}, nil
+	}
+	s := NewScheduler(1)
+	completions := collectCompletions(t, nc, "grlx.cook.cancel-sprout.finishing-job")
+	cookErr := make(chan error, 1)
+	go func() {
+		cookErr <- s.Cook(RecipeEnvelope{
+			JobID: "finishing-job",
+			Steps: []Step{
+				{ID: "done", Ingredient: "cmd", Method: "run"},
+				{
+					ID: "slow", Ingredient: "cmd", Method: "run",
+					Requisites: RequisiteSet{{Condition: Require, StepIDs: []StepID{"done"}}},
+				},
+			},
+		})
+	}()
+	<-started
+	if !s.Cancel("finishing-job") {
+		t.Fatal("expected the running job to be cancelled")
+	}
+	if err := <-cookErr; !errors.Is(err, ErrJobCancelled) {
+		t.Fatalf("expected %v, got %v", ErrJobCancelled, err)
+	}
+	if c := findCompletion(t, completions, "done"); c.CompletionStatus != StepCompleted {
+		t.Errorf("expected the finished step to stay completed, got status %d", c.CompletionStatus)
+	}
+	if c := findCompletion(t, completions, "slow"); c.CompletionStatus != StepCancelled {
+		t.Errorf("expected the interrupted step to be cancelled, got status %d", c.CompletionStatus)
+	}
+}
+
+// watchingCooker records whether it was cooked through ModWatch.
+type watchingCooker struct {
+	mockRecipeCooker
+	modWatched bool
+}
+
+func (w *watchingCooker) ModWatch(context.Context, bool) (Result, error) {
+	w.modWatched = true
+	return Result{Succeeded: true, Changed: true, Notes: []fmt.Stringer{SimpleNote("restarted")}}, nil
+}
+
+func TestWatchedChanges(t *testing.T) {
+	completionMap := map[StepID]StepCompletion{
+		"changed":   {ID: "changed", CompletionStatus: StepCompleted, ChangesMade: true},
+		"unchanged": {ID: "unchanged", CompletionStatus: StepCompleted},
+		"skipped":   {ID: "skipped", CompletionStatus: StepSkipped},
+	}
+	step := Step{Requisites: RequisiteSet{
+		{Condition: Watch, StepIDs: []StepID{"unchanged", "skipped"}},
+		{Condition: WatchAny, StepIDs: []StepID{"changed"}},
+		{Condition: OnChanges, StepIDs: []StepID{"changed"}},
+	}}
+	if got := watchedChanges(step, completionMap, false); !reflect.DeepEqual(got, []StepID{"changed"}) {
+		t.Errorf("expected only the changed watched step, got %v", got)
+	}
+	wouldChange := map[StepID]StepCompletion{
+		"unchanged": {ID: "unchanged", CompletionStatus: StepCompleted, WouldChange: true},
+	}
+	if got := watchedChanges(step, wouldChange, true); !reflect.DeepEqual(got, []StepID{"unchanged"}) {
+		t.Errorf("expected a step that would change to count in test mode, got %v", got)
+	}
+	if got := watchedChanges(step, wouldChange, false); len(got) != 0 {
+		t.Errorf("expected would-be changes to be ignored outside test mode, got %v", got)
+	}
+	if met, err := RequisitesAreMet(step, completionMap); !met || err != nil {
+		t.Errorf("expected watch requisites to be met like require, got %v, %v", met, err)
+	}
+	failed := map[StepID]StepCompletion{"changed": {CompletionStatus: StepFailed, ChangesMade: true}}
+	watchFailed := Step{Requisites: RequisiteSet{{Condition: Watch, StepIDs: []StepID{"changed"}}}}
+	if _, err := RequisitesAreMet(watchFailed, failed); !errors.Is(err, ErrRequisiteNotMet) {
+		t.Errorf("expected watching a failed step to fail like require, got %v", err)
+	}
+}
+
+func TestRunStepWatch(t *testing.T) {
+	origCooker := NewRecipeCooker
+	defer func() { NewRecipeCooker = origCooker }()
+
+	testCases := []struct {
+		id         string
+		watched    []StepID
+		modWatched bool
+	}{
+		{id: "watched step changed", watched: []StepID{"config"}, modWatched: true},
+		{id: "nothing changed", watched: nil, modWatched: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			cooker := &watchingCooker{mockRecipeCooker: mockRecipeCooker{applyResult: Result{Succeeded: true}}}
+			NewRecipeCooker = func(StepID, Ingredient, string, map[string]interface{}) (RecipeCooker, error) {
+				return cooker, nil
+			}
+			completion := runStep(context.Background(), Step{ID: "nginx"}, false, tc.watched)
+			if cooker.modWatched != tc.modWatched {
+				t.Errorf("expected ModWatch called=%v", tc.modWatched)
+			}
+			if completion.ChangesMade != tc.modWatched {
+				t.Errorf("expected ChangesMade=%v, got %v", tc.modWatched, completion.ChangesMade)
+			}
+		})
+	}
+
+	// Ingredients without a hook treat watch like require.
+	NewRecipeCooker = func(StepID, Ingredient, string, map[string]interface{}) (RecipeCooker, error) {
+		return &mockRecipeCooker{applyResult: Result{Succeeded: true}}, nil
+	}
+	if completion := runStep(context.Background(), Step{ID: "plain"}, false, []StepID{"config"}); completion.CompletionStatus != StepCompleted {
+		t.Errorf("expected a plain ingredient to be applied, got %+v", completion)
+	}
+}
+
+func TestCookRecipeEnvelopeTestModeWatch(t *testing.T) {
+	nc, cleanup := startCookTestNATS(t)
+	defer cleanup()
+
+	oldDir := config.JobLogDir
+	config.JobLogDir = t.TempDir()
+	defer func() { config.JobLogDir = oldDir }()
+	origCooker := NewRecipeCooker
+	defer func() { NewRecipeCooker = origCooker }()
+	oldSproutID := config.SproutID
+	config.SproutID = "watch-sprout"
+	defer func() { config.SproutID = oldSproutID }()
+
+	watcher := &watchingCooker{mockRecipeCooker: mockRecipeCooker{testResult: Result{Succeeded: true}}}
+	NewRecipeCooker = func(id StepID, ingredient Ingredient, method string, params map[string]interface{}) (RecipeCooker, error) {
+		if id == "nginx" {
+			return watcher, nil
+		}
+		return &mockRecipeCooker{testResult: Result{Succeeded: true, Changed: true}}, nil
+	}
+
+	completions := collectCompletions(t, nc, "grlx.cook.watch-sprout.test-watch")
+	err := CookRecipeEnvelope(RecipeEnvelope{
+		JobID: "test-watch",
+		Test:  true,
+		Steps: []Step{
+			{ID: "config", Ingredient: "file", Method: "managed"},
+			{
+				ID: "nginx", Ingredient: "service", Method: "running",
+				Requisites: RequisiteSet{{Condition: Watch, StepIDs: []StepID{"config"}}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected the recipe to finish, got %v", err)
+	}
+	findCompletion(t, completions, "nginx")
+	if !watcher.modWatched {
+		t.Error("expected ModWatch to be tested when a watched step would change")
+	}
+}
+
+func TestRunStepTestModeWouldChange(t *testing.T) {
+	origCooker := NewRecipeCooker
+	defer func() { NewRecipeCooker = origCooker }()
+
+	cooker := &mockRecipeCooker{
+		applyResult: Result{Succeeded: true, Changed: true},
+		testResult:  Result{Succeeded: true, Changed: true, Notes: []fmt.Stringer{SimpleNote("would create /etc/motd")}},
+	}
+	NewRecipeCooker = func(StepID, Ingredient, string, map[string]interface{}) (RecipeCooker, error) {
+		return cooker, nil
+	}
+
+	completion := runStep(context.Background(), Step{ID: "motd"}, true, nil)
+	if completion.ChangesMade || !completion.WouldChange {
+		t.Errorf("expected a test cook to report only what would change, got %+v", completion)
+	}
+	if len(completion.Changes) != 1 || completion.Changes[0] != "would create /etc/motd" {
+		t.Errorf("expected the test notes, got %v", completion.Changes)
+	}
+
+	completion = runStep(context.Background(), Step{ID: "motd"}, false, nil)
+	if !completion.ChangesMade || completion.WouldChange {
+		t.Errorf("expected an applied cook to report changes made, got %+v", completion)
+	}
+}
@@ -13,11 +13,11 @@ func SummarizeSteps(steps []SproutStepCompletion) map[string]Summary {
 		switch step.CompletedStep.CompletionStatus {
 		case StepCompleted:
 			stepSummary.Succeeded += 1
-		case StepFailed:
+		case StepFailed, StepTimedOut:
 			stepSummary.Failures += 1
 			stepSummary.Errors = append(stepSummary.Errors, step.CompletedStep.Error)
-		case StepSkipped:
-			// Skipped steps don't count as successes or failures
+		case StepSkipped, StepCancelled:
+			// Skipped and cancelled steps don't count as successes or failures
 		}
 		summary[step.SproutID] = stepSummary
 	}
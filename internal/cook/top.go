@@ -0,0 +1,176 @@
+package cook
+
+// The top file, top.grlx in the recipe directory, assigns recipes to
+// sprouts. Each named entry selects sprouts by cohort, by a regular
+// expression over the sprout ID, by prop values, or by a combination of
+// these, all of which must match:
+//
+//	base:
+//	  sprouts: '.*'
+//	  recipes:
+//	    - base
+//	web:
+//	  cohort: webservers
+//	  props:
+//	    env: prod
+//	  recipes:
+//	    - nginx
+//	    - certs.renew
+//
+// A full-state cook merges the recipes of every entry matching a sprout
+// into a single envelope.
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/gogrlx/grlx/v2/internal/config"
+	"github.com/gogrlx/grlx/v2/internal/props"
+	"github.com/gogrlx/grlx/v2/internal/rbac"
+)
+
+// TopFileName is the name of the top file, without its extension.
+const TopFileName = "top"
+
+// TopEntry assigns Recipes to the sprouts it selects.
+type TopEntry struct {
+	Name    string            `yaml:"-"`
+	Cohort  string            `yaml:"cohort,omitempty"`
+	Sprouts string            `yaml:"sprouts,omitempty"`
+	Props   map[string]string `yaml:"props,omitempty"`
+	Recipes []RecipeName      `yaml:"recipes"`
+
+	sprouts *regexp.Regexp
+}
+
+// TopFile is a parsed top file, with its entries in file order.
+type TopFile struct {
+	Entries []TopEntry
+}
+
+// TopAssignment is what the top file gives one sprout: the entries that
+// matched it and their recipes, merged in entry order without repeats.
+type TopAssignment struct {
+	Entries []string     `json:"entries"`
+	Recipes []RecipeName `json:"recipes"`
+}
+
+// LoadTopFile reads and parses the top file from the recipe directory.
+func LoadTopFile() (TopFile, error) {
+	path := filepath.Join(getBasePath(), TopFileName+"."+config.GrlxExt)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return TopFile{}, errors.Join(ErrNoTopFile, err)
+	}
+	if err != nil {
+		return TopFile{}, err
+	}
+	return ParseTopFile(b)
+}
+
+// ParseTopFile parses the contents of a top file.
+func ParseTopFile(b []byte) (TopFile, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return TopFile{}, errors.Join(ErrInvalidTopFile, err)
+	}
+	var top TopFile
+	if len(doc.Content) == 0 {
+		return top, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return TopFile{}, errors.Join(ErrInvalidTopFile, errors.New("top file must be a map of entry names to entries"))
+	}
+	// Decode entry by entry to keep them in file order.
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		var entry TopEntry
+		if err := root.Content[i+1].Decode(&entry); err != nil {
+			return TopFile{}, errors.Join(ErrInvalidTopFile, fmt.Errorf("entry %s: %w", root.Content[i].Value, err))
+		}
+		entry.Name = root.Content[i].Value
+		if err := entry.compile(); err != nil {
+			return TopFile{}, errors.Join(ErrInvalidTopFile, fmt.Errorf("entry %s: %w", entry.Name, err))
+		}
+		top.Entries = append(top.Entries, entry)
+	}
+	return top, nil
+}
+
+func (e *TopEntry) compile() error {
+	if e.Cohort == "" && e.Sprouts == "" && len(e.Props) == 0 {
+		return errors.New("must select sprouts by cohort, sprouts or props")
+	}
+	if len(e.Recipes) == 0 {
+		return errors.New("must list at least one recipe")
+	}
+	if e.Sprouts != "" {
+		re, err := regexp.Compile("^(?:" + e.Sprouts + ")$")
+		if err != nil {
+			return err
+		}
+		e.sprouts = re
+	}
+	return nil
+}
+
+// Assign works out the recipes for each of sproutIDs. Cohorts are looked
+// up with resolve, which may be nil if no cohorts are configured, in
+// which case entries that name a cohort match nothing. Every sprout is
+// present in the result, with no entries if none matched it.
+func (t TopFile) Assign(sproutIDs []string, resolve func(string) (map[string]bool, error)) (map[string]TopAssignment, error) {
+	cohorts := map[string]map[string]bool{}
+	for _, entry := range t.Entries {
+		if entry.Cohort == "" || resolve == nil {
+			continue
+		}
+		if _, ok := cohorts[entry.Cohort]; ok {
+			continue
+		}
+		members, err := resolve(entry.Cohort)
+		if err != nil {
+			return nil, fmt.Errorf("top entry %s: %w", entry.Name, err)
+		}
+		cohorts[entry.Cohort] = members
+	}
+
+	assignments := make(map[string]TopAssignment, len(sproutIDs))
+	for _, sproutID := range sproutIDs {
+		var a TopAssignment
+		seen := map[RecipeName]bool{}
+		for _, entry := range t.Entries {
+			if !entry.matches(sproutID, cohorts) {
+				continue
+			}
+			a.Entries = append(a.Entries, entry.Name)
+			for _, recipe := range entry.Recipes {
+				if !seen[recipe] {
+					seen[recipe] = true
+					a.Recipes = append(a.Recipes, recipe)
+				}
+			}
+		}
+		assignments[sproutID] = a
+	}
+	return assignments, nil
+}
+
+func (e TopEntry) matches(sproutID string, cohorts map[string]map[string]bool) bool {
+	if e.Cohort != "" && !cohorts[e.Cohort][sproutID] {
+		return false
+	}
+	if e.sprouts != nil && !e.sprouts.MatchString(sproutID) {
+		return false
+	}
+	for name, want := range e.Props {
+		if !rbac.MatchesPropValue(props.GetStringProp(sproutID, name), want) {
+			return false
+		}
+	}
+	return true
+}
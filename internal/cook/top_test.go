@@ -0,0 +1,139 @@
+package cook
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gogrlx/grlx/v2/internal/props"
+)
+
+const testTopFile = `
+base:
+  sprouts: '.*'
+  recipes:
+    - independent
+web:
+  cohort: webservers
+  recipes:
+    - apache
+    - independent
+prod-db:
+  sprouts: 'db-\d+'
+  props:
+    env: prod*
+  recipes:
+    - dev
+`
+
+func TestParseTopFile(t *testing.T) {
+	top, err := ParseTopFile([]byte(testTopFile))
+	if err != nil {
+		t.Fatalf("ParseTopFile: %v", err)
+	}
+	var names []string
+	for _, entry := range top.Entries {
+		names = append(names, entry.Name)
+	}
+	if want := []string{"base", "web", "prod-db"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("expected entries in file order %v, got %v", want, names)
+	}
+
+	invalid := map[string]string{
+		"not a map":       "- base\n",
+		"no selector":     "base:\n  recipes: [independent]\n",
+		"no recipes":      "base:\n  sprouts: '.*'\n",
+		"bad regex":       "base:\n  sprouts: '('\n  recipes: [independent]\n",
+		"bad entry shape": "base: independent\n",
+	}
+	for name, contents := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseTopFile([]byte(contents)); !errors.Is(err, ErrInvalidTopFile) {
+				t.Errorf("expected %v, got %v", ErrInvalidTopFile, err)
+			}
+		})
+	}
+}
+
+func TestTopFileAssign(t *testing.T) {
+	top, err := ParseTopFile([]byte(testTopFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	props.SetProp("db-01", "env", "production")
+	props.SetProp("db-02", "env", "staging")
+	defer props.DeleteProp("db-01", "env")
+	defer props.DeleteProp("db-02", "env")
+
+	resolve := func(name string) (map[string]bool, error) {
+		if name != "webservers" {
+			return nil, errors.New("unknown cohort")
+		}
+		return map[string]bool{"web-01": true}, nil
+	}
+	got, err := top.Assign([]string{"web-01", "db-01", "db-02"}, resolve)
+	if err != nil {
+		t.Fatalf("Assign: %v", err)
+	}
+	want := map[string]TopAssignment{
+		"web-01": {Entries: []string{"base", "web"}, Recipes: []RecipeName{"independent", "apache"}},
+		"db-01":  {Entries: []string{"base", "prod-db"}, Recipes: []RecipeName{"independent", "dev"}},
+		"db-02":  {Entries: []string{"base"}, Recipes: []RecipeName{"independent"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Assign() = %+v, want %+v", got, want)
+	}
+
+	// Without cohorts, cohort entries match nothing.
+	got, err = top.Assign([]string{"web-01"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries := got["web-01"].Entries; !reflect.DeepEqual(entries, []string{"base"}) {
+		t.Errorf("expected only base to match without cohorts, got %v", entries)
+	}
+
+	failing := func(string) (map[string]bool, error) { return nil, errors.New("boom") }
+	if _, err = top.Assign([]string{"web-01"}, failing); err == nil {
+		t.Error("expected a cohort resolution error")
+	}
+}
+
+func TestLoadTopFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(RecipeDirEnvVar, dir)
+	if _, err := LoadTopFile(); !errors.Is(err, ErrNoTopFile) {
+		t.Errorf("expected %v, got %v", ErrNoTopFile, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "top.grlx"), []byte(testTopFile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	top, err := LoadTopFile()
+	if err != nil || len(top.Entries) != 3 {
+		t.Errorf("expected 3 entries, got %+v, %v", top, err)
+	}
+}
+
+func TestBuildCookEnvelopeWithRecipes(t *testing.T) {
+	co := cookOptions{recipes: []RecipeName{"independent", "apache"}}
+	env, err := buildCookEnvelope("top-sprout", "", "top-job", false, co)
+	if err != nil {
+		t.Fatalf("buildCookEnvelope: %v", err)
+	}
+	single, err := buildCookEnvelope("top-sprout", "apache", "apache-job", false, cookOptions{})
+	if err != nil {
+		t.Fatalf("buildCookEnvelope: %v", err)
+	}
+	ids := map[StepID]bool{}
+	for _, step := range env.Steps {
+		ids[step.ID] = true
+	}
+	if !ids["touch unimportant file"] {
+		t.Error("expected the independent recipe's step in the merged envelope")
+	}
+	if len(env.Steps) != len(single.Steps)+1 {
+		t.Errorf("expected apache's %d steps plus one, got %d", len(single.Steps), len(env.Steps))
+	}
+}
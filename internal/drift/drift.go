@@ -0,0 +1,204 @@
+// Package drift keeps track of how far sprouts have drifted from their
+// recipes.
+//
+// The farmer periodically cooks the recipes the top file assigns to each
+// sprout in test mode. The steps that report they would change are the
+// sprout's drift. The latest report for each sprout is kept, noting how
+// long each step has been drifting and which steps started or stopped
+// drifting since the check before, in one JSON file per sprout so that
+// reports survive farmer restarts.
+package drift
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogrlx/grlx/v2/internal/cook"
+)
+
+// InvokedBy is the invoker recorded on the test cooks of drift checks.
+const InvokedBy = "drift"
+
+// Step is a step that would make changes if its recipe were cooked.
+type Step struct {
+	ID      string    `json:"id"`
+	Changes []string  `json:"changes,omitempty"`
+	Since   time.Time `json:"since"`
+}
+
+// Report is the result of the latest drift check of a sprout.
+type Report struct {
+	SproutID  string            `json:"sprout_id"`
+	JID       string            `json:"jid"`
+	CheckedAt time.Time         `json:"checked_at"`
+	Recipes   []cook.RecipeName `json:"recipes,omitempty"`
+	// Steps are the steps that would change, sorted by ID.
+	Steps []Step `json:"steps"`
+	// Failed lists the steps whose test failed, so whether they drifted
+	// is unknown.
+	Failed []string `json:"failed,omitempty"`
+	// Appeared and Resolved list the steps that started and stopped
+	// drifting since the previous check.
+	Appeared []string `json:"appeared,omitempty"`
+	Resolved []string `json:"resolved,omitempty"`
+}
+
+// Drifted reports whether any step of the sprout would change.
+func (r Report) Drifted() bool {
+	return len(r.Steps) > 0
+}
+
+// Store holds the latest drift report of each sprout.
+type Store struct {
+	dir string
+
+	mu      sync.Mutex
+	reports map[string]Report
+}
+
+// New returns a store that keeps its reports in dir. An empty dir keeps
+// them in memory only.
+func New(dir string) *Store {
+	return &Store{dir: dir, reports: map[string]Report{}}
+}
+
+// Load reads the reports kept in the store's directory, replacing those
+// in memory. A missing directory holds no reports.
+func (s *Store) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reports = map[string]Report{}
+	if s.dir == "" {
+		return nil
+	}
+	files, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		b, readErr := os.ReadFile(filepath.Join(s.dir, f.Name()))
+		if readErr != nil {
+			errs = append(errs, readErr)
+			continue
+		}
+		var r Report
+		if jsonErr := json.Unmarshal(b, &r); jsonErr != nil {
+			errs = append(errs, errors.Join(ErrInvalidReport, jsonErr))
+			continue
+		}
+		if r.SproutID != strings.TrimSuffix(f.Name(), ".json") {
+			errs = append(errs, errors.Join(ErrInvalidReport, errors.New(f.Name())))
+			continue
+		}
+		s.reports[r.SproutID] = r
+	}
+	return errors.Join(errs...)
+}
+
+// Record stores the report of a drift check of sproutID from the step
+// completions of its test cook, and returns it. Steps that were already
+// drifting keep the time they started to.
+func (s *Store) Record(sproutID, jid string, recipes []cook.RecipeName, checkedAt time.Time, completions []cook.StepCompletion) (Report, error) {
+	checkedAt = checkedAt.UTC()
+	r := Report{
+		SproutID:  sproutID,
+		JID:       jid,
+		CheckedAt: checkedAt,
+		Recipes:   recipes,
+		Steps:     []Step{},
+	}
+	for _, c := range completions {
+		switch {
+		case c.CompletionStatus == cook.StepFailed || c.CompletionStatus == cook.StepTimedOut:
+			r.Failed = append(r.Failed, string(c.ID))
+		case c.WouldChange:
+			r.Steps = append(r.Steps, Step{ID: string(c.ID), Changes: c.Changes, Since: checkedAt})
+		}
+	}
+	sort.Slice(r.Steps, func(i, j int) bool { return r.Steps[i].ID < r.Steps[j].ID })
+	sort.Strings(r.Failed)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := map[string]Step{}
+	for _, step := range s.reports[sproutID].Steps {
+		previous[step.ID] = step
+	}
+	current := map[string]bool{}
+	for i, step := range r.Steps {
+		current[step.ID] = true
+		if before, ok := previous[step.ID]; ok {
+			r.Steps[i].Since = before.Since
+		} else {
+			r.Appeared = append(r.Appeared, step.ID)
+		}
+	}
+	for id := range previous {
+		// A step that failed its test may still be drifting.
+		if !current[id] && !slices.Contains(r.Failed, id) {
+			r.Resolved = append(r.Resolved, id)
+		}
+	}
+	sort.Strings(r.Resolved)
+
+	s.reports[sproutID] = r
+	return r, s.save(r)
+}
+
+// Get returns the latest report for sproutID.
+func (s *Store) Get(sproutID string) (Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.reports[sproutID]
+	if !ok {
+		return Report{}, ErrNoReport
+	}
+	return r, nil
+}
+
+// List returns the latest report of every checked sprout, sorted by
+// sprout ID.
+func (s *Store) List() []Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reports := make([]Report, 0, len(s.reports))
+	for _, r := range s.reports {
+		reports = append(reports, r)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].SproutID < reports[j].SproutID })
+	return reports
+}
+
+// save writes r to its sprout's file. It must be called with s.mu held.
+func (s *Store) save(r Report) error {
+	if s.dir == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	path := filepath.Join(s.dir, r.SproutID+".json")
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,98 @@
+package drift
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gogrlx/grlx/v2/internal/cook"
+)
+
+func completion(id string, status cook.CompletionStatus, wouldChange bool) cook.StepCompletion {
+	return cook.StepCompletion{ID: cook.StepID(id), CompletionStatus: status, WouldChange: wouldChange}
+}
+
+func TestStoreRecord(t *testing.T) {
+	s := New("")
+	first := time.Date(2026, 1, 1, 3, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+
+	r, err := s.Record("web-01", "jid-1", []cook.RecipeName{"base"}, first, []cook.StepCompletion{
+		completion("motd", cook.StepCompleted, true),
+		completion("nginx", cook.StepCompleted, true),
+		completion("sshd", cook.StepCompleted, false),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Drifted() || len(r.Steps) != 2 || !reflect.DeepEqual(r.Appeared, []string{"motd", "nginx"}) {
+		t.Fatalf("unexpected first report %+v", r)
+	}
+
+	r, err = s.Record("web-01", "jid-2", []cook.RecipeName{"base"}, second, []cook.StepCompletion{
+		completion("motd", cook.StepCompleted, true),
+		completion("nginx", cook.StepCompleted, false),
+		completion("sshd", cook.StepCompleted, true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Steps) != 2 || r.Steps[0].ID != "motd" || !r.Steps[0].Since.Equal(first) || !r.Steps[1].Since.Equal(second) {
+		t.Errorf("expected motd to have drifted since the first check, got %+v", r.Steps)
+	}
+	if !reflect.DeepEqual(r.Appeared, []string{"sshd"}) || !reflect.DeepEqual(r.Resolved, []string{"nginx"}) {
+		t.Errorf("expected sshd to appear and nginx to be resolved, got %v and %v", r.Appeared, r.Resolved)
+	}
+
+	// A step whose test fails is neither drifting nor resolved.
+	r, err = s.Record("web-01", "jid-3", []cook.RecipeName{"base"}, second.Add(time.Hour), []cook.StepCompletion{
+		completion("motd", cook.StepFailed, false),
+		completion("sshd", cook.StepCompleted, false),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Drifted() || !reflect.DeepEqual(r.Failed, []string{"motd"}) || !reflect.DeepEqual(r.Resolved, []string{"sshd"}) {
+		t.Errorf("unexpected report %+v", r)
+	}
+
+	if _, err = s.Get("db-01"); !errors.Is(err, ErrNoReport) {
+		t.Errorf("expected %v, got %v", ErrNoReport, err)
+	}
+}
+
+func TestStorePersistence(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "drift")
+	s := New(dir)
+	if err := s.Load(); err != nil {
+		t.Fatalf("expected a missing directory to hold no reports, got %v", err)
+	}
+	at := time.Date(2026, 1, 1, 3, 0, 0, 0, time.UTC)
+	for _, id := range []string{"web-01", "db-01"} {
+		if _, err := s.Record(id, "jid-1", nil, at, []cook.StepCompletion{completion("motd", cook.StepCompleted, true)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := New(dir)
+	if err := restarted.Load(); !errors.Is(err, ErrInvalidReport) {
+		t.Errorf("expected %v, got %v", ErrInvalidReport, err)
+	}
+	list := restarted.List()
+	if len(list) != 2 || list[0].SproutID != "db-01" || list[1].SproutID != "web-01" {
+		t.Fatalf("expected both reports after a restart, got %+v", list)
+	}
+	r, err := restarted.Record("web-01", "jid-2", nil, at.Add(time.Hour), []cook.StepCompletion{completion("motd", cook.StepCompleted, true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Appeared) != 0 || !r.Steps[0].Since.Equal(at) {
+		t.Errorf("expected the drift to carry over the restart, got %+v", r)
+	}
+}
@@ -0,0 +1,8 @@
+package drift
+
+import "errors"
+
+var (
+	ErrNoReport      = errors.New("sprout has not been checked for drift")
+	ErrInvalidReport = errors.New("invalid drift report")
+)
@@ -0,0 +1,131 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gogrlx/grlx/v2/internal/cook"
+	"github.com/gogrlx/grlx/v2/internal/ingredients"
+)
+
+var (
+	ErrArchiveMethodUndefined = errors.New("archive method undefined")
+	ErrUnknownFormat          = errors.New("unknown archive format")
+	ErrUnsafePath             = errors.New("archive entry escapes the destination")
+	ErrNoToplevel             = errors.New("archive does not have a single top-level directory")
+	ErrPathConflict           = errors.New("archive entry conflicts with an existing path")
+	ErrInvalidStrip           = errors.New("strip_components must be a non-negative integer")
+)
+
+// Compile-time interface check.
+var _ cook.RecipeCooker = Archive{}
+
+type Archive struct {
+	id     string
+	method string
+	params map[string]interface{}
+}
+
+func (a Archive) Parse(id, method string, params map[string]interface{}) (cook.RecipeCooker, error) {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	parsed := Archive{
+		id: id, method: method,
+		params: params,
+	}
+	if err := parsed.validate(); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+func (a Archive) validate() error {
+	set, err := a.PropertiesForMethod(a.method)
+	if err != nil {
+		return err
+	}
+	propSet, err := ingredients.PropMapToPropSet(set)
+	if err != nil {
+		return err
+	}
+	for _, v := range propSet {
+		if v.IsReq {
+			if v.Key == "name" {
+				name, ok := a.params[v.Key].(string)
+				if !ok {
+					return ingredients.ErrMissingName
+				}
+				if name == "" {
+					return ingredients.ErrMissingName
+				}
+			} else {
+				if _, ok := a.params[v.Key]; !ok {
+					return fmt.Errorf("missing required property %s", v.Key)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (a Archive) Test(ctx context.Context) (cook.Result, error) {
+	switch a.method {
+	case "extracted":
+		return a.extracted(ctx, true)
+	default:
+		return cook.Result{Succeeded: false, Failed: true, Changed: false, Notes: nil},
+			errors.Join(ErrArchiveMethodUndefined, fmt.Errorf("method %s undefined", a.method))
+	}
+}
+
+func (a Archive) Apply(ctx context.Context) (cook.Result, error) {
+	switch a.method {
+	case "extracted":
+		return a.extracted(ctx, false)
+	default:
+		return cook.Result{Succeeded: false, Failed: true, Changed: false, Notes: nil},
+			errors.Join(ErrArchiveMethodUndefined, fmt.Errorf("method %s undefined", a.method))
+	}
+}
+
+func (a Archive) PropertiesForMethod(method string) (map[string]string, error) {
+	switch method {
+	case "extracted":
+		return ingredients.MethodPropsSet{
+			ingredients.MethodProps{Key: "name", Type: "string", IsReq: true, Description: "the directory to extract the archive into"},
+			ingredients.MethodProps{Key: "source", Type: "string", IsReq: true, Description: "the path/URL of the archive"},
+			ingredients.MethodProps{Key: "source_hash", Type: "string", IsReq: false, Description: "hash to verify the archive specified by source"},
+			ingredients.MethodProps{Key: "skip_verify", Type: "bool", IsReq: false, Description: "do not verify the archive against source_hash"},
+			ingredients.MethodProps{Key: "archive_format", Type: "string", IsReq: false, Description: "`tar` or `zip`; detected from the archive when unset"},
+			ingredients.MethodProps{Key: "strip_components", Type: "string", IsReq: false, Description: "number of leading path components to remove from each entry"},
+			ingredients.MethodProps{Key: "user", Type: "string", IsReq: false, Description: "owner of the extracted files"},
+			ingredients.MethodProps{Key: "group", Type: "string", IsReq: false, Description: "group of the extracted files"},
+			ingredients.MethodProps{Key: "if_missing", Type: "string", IsReq: false, Description: "skip extraction when this path exists"},
+			ingredients.MethodProps{Key: "enforce_toplevel", Type: "bool", IsReq: false, Description: "fail unless every entry is under a single top-level directory"},
+			ingredients.MethodProps{Key: "makedirs", Type: "bool", IsReq: false, Description: "create the parent directories of name if they do not exist"},
+		}.ToMap(), nil
+	default:
+		return nil, fmt.Errorf("method %s undefined", method)
+	}
+}
+
+func (a Archive) Methods() (string, []string) {
+	return "archive", []string{"extracted"}
+}
+
+func (a Archive) Properties() (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	b, err := json.Marshal(a.params)
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(b, &m)
+	return m, err
+}
+
+func init() {
+	ingredients.RegisterAllMethods(Archive{})
+}
@@ -0,0 +1,571 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gogrlx/grlx/v2/internal/cook"
+	"github.com/gogrlx/grlx/v2/internal/ingredients"
+	"github.com/gogrlx/grlx/v2/internal/ingredients/file"
+)
+
+func (a Archive) extracted(ctx context.Context, test bool) (cook.Result, error) {
+	// Params: "name", "source", "source_hash", "skip_verify", "archive_format",
+	// "strip_components", "user", "group", "if_missing", "enforce_toplevel",
+	// "makedirs"
+
+	var notes []fmt.Stringer
+
+	name, ok := a.params["name"].(string)
+	if !ok || name == "" {
+		return cook.Result{
+			Succeeded: false, Failed: true,
+			Changed: false, Notes: []fmt.Stringer{},
+		}, ingredients.ErrMissingName
+	}
+	name = filepath.Clean(name)
+	if name == "/" {
+		return cook.Result{
+			Succeeded: false, Failed: true,
+			Changed: false, Notes: []fmt.Stringer{},
+		}, file.ErrModifyRoot
+	}
+
+	source, _ := a.params["source"].(string)
+	sourceHash, _ := a.params["source_hash"].(string)
+	skipVerify, _ := a.params["skip_verify"].(bool)
+	makedirs, _ := a.params["makedirs"].(bool)
+	archiveFormat, _ := a.params["archive_format"].(string)
+	enforceToplevel, _ := a.params["enforce_toplevel"].(bool)
+	if source == "" {
+		return cook.Result{
+			Succeeded: false, Failed: true,
+			Changed: false, Notes: []fmt.Stringer{},
+		}, file.ErrMissingSource
+	}
+	if sourceHash == "" && !skipVerify {
+		return cook.Result{
+			Succeeded: false, Failed: true,
+			Changed: false, Notes: []fmt.Stringer{},
+		}, file.ErrMissingHash
+	}
+	strip, err := stripComponents(a.params["strip_components"])
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true,
+			Changed: false, Notes: []fmt.Stringer{},
+		}, err
+	}
+	userName, _ := a.params["user"].(string)
+	groupName, _ := a.params["group"].(string)
+	uid, gid, err := file.LookupOwner(userName, groupName)
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true,
+			Changed: false, Notes: []fmt.Stringer{},
+		}, err
+	}
+
+	if ifMissing, _ := a.params["if_missing"].(string); ifMissing != "" {
+		if _, statErr := os.Lstat(ifMissing); statErr == nil {
+			return cook.Result{
+				Succeeded: true, Failed: false,
+				Changed: false, Notes: []fmt.Stringer{
+					cook.Snprintf("`%s` exists, skipping extraction", ifMissing),
+				},
+			}, nil
+		}
+	}
+
+	dir := filepath.Dir(name)
+	if _, statErr := os.Stat(dir); os.IsNotExist(statErr) && !makedirs {
+		return cook.Result{
+			Succeeded: false, Failed: true,
+			Changed: false, Notes: []fmt.Stringer{
+				cook.Snprintf("parent directory `%s` does not exist and makedirs is false", dir),
+			},
+		}, file.ErrPathNotFound
+	}
+
+	cachedName := fmt.Sprintf("%s-source", a.id)
+	cacheParams := map[string]interface{}{
+		"source":      source,
+		"skip_verify": skipVerify,
+		"name":        cachedName,
+	}
+	if sourceHash != "" {
+		cacheParams["hash"] = sourceHash
+	}
+	cacheFile, err := file.File{}.Parse(cachedName, "cached", cacheParams)
+	if err != nil {
+		notes = append(notes, cook.Snprintf("failed to parse cache for source `%s`", source))
+		return cook.Result{
+			Succeeded: false, Failed: true,
+			Changed: false, Notes: notes,
+		}, err
+	}
+	var cacheRes cook.Result
+	if test {
+		cacheRes, err = cacheFile.Test(ctx)
+	} else {
+		cacheRes, err = cacheFile.Apply(ctx)
+	}
+	notes = append(notes, cacheRes.Notes...)
+	if err != nil || !cacheRes.Succeeded {
+		return cook.Result{
+			Succeeded: false, Failed: true,
+			Changed: false, Notes: notes,
+		}, errors.Join(err, file.ErrCacheFailure)
+	}
+	// Until the source has been cached there is nothing to compare
+	// against, so assume everything will be extracted.
+	if test && cacheRes.Changed {
+		notes = append(notes, cook.Snprintf("archive `%s` would be extracted to `%s`", source, name))
+		return cook.Result{
+			Succeeded: true, Failed: false,
+			Changed: true, Notes: notes,
+		}, nil
+	}
+	archivePath, err := cacheFile.(file.File).CachePath()
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true,
+			Changed: false, Notes: notes,
+		}, err
+	}
+	af, err := detectFormat(archivePath, archiveFormat)
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true,
+			Changed: false, Notes: notes,
+		}, err
+	}
+
+	x := extraction{
+		dest: name, strip: strip,
+		uid: uid, gid: gid,
+		writes:   map[string]bool{},
+		symlinks: map[string]bool{},
+	}
+	if err = walk(archivePath, af, func(e entry, r io.Reader) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return x.plan(e, r)
+	}); err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true,
+			Changed: false, Notes: notes,
+		}, err
+	}
+	if enforceToplevel && (len(x.toplevel) != 1 || x.topFile) {
+		return cook.Result{
+			Succeeded: false, Failed: true,
+			Changed: false, Notes: notes,
+		}, errors.Join(ErrNoToplevel, fmt.Errorf("found %d top-level entries", len(x.toplevel)))
+	}
+	for _, rel := range x.skipped {
+		notes = append(notes, cook.Snprintf("skipping `%s`: not a file, directory or link", rel))
+	}
+	changed := len(x.order) > 0 || len(x.chown) > 0
+	if !changed {
+		return cook.Result{
+			Succeeded: true, Failed: false,
+			Changed: false, Notes: notes,
+		}, nil
+	}
+
+	if test {
+		for _, rel := range x.order {
+			notes = append(notes, cook.Snprintf("would extract `%s`", x.target(rel)))
+		}
+		for _, p := range x.chown {
+			notes = append(notes, cook.Snprintf("would chown `%s`", p))
+		}
+		return cook.Result{
+			Succeeded: true, Failed: false,
+			Changed: true, Notes: notes,
+		}, nil
+	}
+
+	if err = os.MkdirAll(name, 0o755); err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true,
+			Changed: false, Notes: notes,
+		}, err
+	}
+	if err = walk(archivePath, af, func(e entry, r io.Reader) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return x.extract(e, r)
+	}); err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true,
+			Changed: true, Notes: notes,
+		}, err
+	}
+	for _, p := range x.chown {
+		if err = os.Lchown(p, uid, gid); err != nil {
+			return cook.Result{
+				Succeeded: false, Failed: true,
+				Changed: true, Notes: notes,
+			}, err
+		}
+	}
+	if len(x.order) > 0 {
+		notes = append(notes, cook.Snprintf("extracted %d entries from `%s` to `%s`", len(x.order), source, name))
+	}
+	if len(x.chown) > 0 {
+		notes = append(notes, cook.Snprintf("changed ownership of %d existing entries under `%s`", len(x.chown), name))
+	}
+	return cook.Result{
+		Succeeded: true, Failed: false,
+		Changed: true, Notes: notes,
+	}, nil
+}
+
+// stripComponents reads the strip_components property, which recipes may
+// give as a number or a string.
+func stripComponents(v interface{}) (int, error) {
+	var n int
+	switch s := v.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		n = s
+	case float64:
+		if s != float64(int(s)) {
+			return 0, ErrInvalidStrip
+		}
+		n = int(s)
+	case string:
+		if s == "" {
+			return 0, nil
+		}
+		parsed, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, errors.Join(ErrInvalidStrip, err)
+		}
+		n = parsed
+	default:
+		return 0, ErrInvalidStrip
+	}
+	if n < 0 {
+		return 0, ErrInvalidStrip
+	}
+	return n, nil
+}
+
+// extraction tracks what extracting an archive into dest involves.
+type extraction struct {
+	dest     string
+	strip    int
+	uid, gid int
+
+	// writes holds the entries, by their path relative to dest, that
+	// are missing or differ from the archive; order lists them as they
+	// appear in it.
+	writes map[string]bool
+	order  []string
+	// chown holds existing entries that match the archive but not the
+	// requested ownership.
+	chown   []string
+	skipped []string
+	// symlinks holds the symlink entries seen so far.
+	symlinks map[string]bool
+	// toplevel holds the first component of every entry name before
+	// stripping; topFile is set when one of them is not a directory.
+	toplevel map[string]bool
+	topFile  bool
+}
+
+func (x *extraction) target(rel string) string {
+	return filepath.Join(x.dest, filepath.FromSlash(rel))
+}
+
+// cleanName cleans an entry name, refusing names that leave the
+// destination.
+func cleanName(name string) (string, error) {
+	clean := path.Clean(strings.ReplaceAll(name, "\\", "/"))
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", errors.Join(ErrUnsafePath, fmt.Errorf("entry %q", name))
+	}
+	return clean, nil
+}
+
+// relPath cleans an entry name and strips the leading components from it.
+// ok is false when nothing is left to extract.
+func (x *extraction) relPath(name string) (rel string, ok bool, err error) {
+	clean, err := cleanName(name)
+	if err != nil || clean == "." {
+		return "", false, err
+	}
+	parts := strings.Split(clean, "/")
+	if len(parts) <= x.strip {
+		return "", false, nil
+	}
+	return path.Join(parts[x.strip:]...), true, nil
+}
+
+// checkParents refuses to write through a symlink between dest and rel,
+// whether it already exists or comes earlier in the archive: a chain of
+// links can point anywhere.
+func (x *extraction) checkParents(rel string) error {
+	parts := strings.Split(rel, "/")
+	missing := false
+	for i := 1; i < len(parts); i++ {
+		parent := path.Join(parts[:i]...)
+		p := x.target(parent)
+		if x.symlinks[parent] {
+			return errors.Join(ErrUnsafePath, fmt.Errorf("`%s` is a symlink in the archive", p))
+		}
+		if missing {
+			continue
+		}
+		info, err := os.Lstat(p)
+		if os.IsNotExist(err) {
+			missing = true
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return errors.Join(ErrUnsafePath, fmt.Errorf("`%s` is a symlink", p))
+		}
+		if !info.IsDir() {
+			return errors.Join(ErrPathConflict, fmt.Errorf("`%s` is not a directory", p))
+		}
+	}
+	return nil
+}
+
+// linkTarget returns the path relative to dest that a link entry points
+// at, refusing links that leave dest.
+func (x *extraction) linkTarget(rel string, e entry) (string, error) {
+	if e.kind == kindHardlink {
+		target, ok, err := x.relPath(e.linkname)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", errors.Join(ErrUnsafePath, fmt.Errorf("hard link %q points at stripped entry %q", e.name, e.linkname))
+		}
+		return target, nil
+	}
+	if e.linkname == "" || path.IsAbs(e.linkname) {
+		return "", errors.Join(ErrUnsafePath, fmt.Errorf("symlink %q points at %q", e.name, e.linkname))
+	}
+	target := path.Join(path.Dir(rel), e.linkname)
+	if target == ".." || strings.HasPrefix(target, "../") {
+		return "", errors.Join(ErrUnsafePath, fmt.Errorf("symlink %q points at %q", e.name, e.linkname))
+	}
+	return target, nil
+}
+
+// plan records whether an entry needs writing, without touching dest.
+func (x *extraction) plan(e entry, r io.Reader) error {
+	clean, err := cleanName(e.name)
+	if err != nil {
+		return err
+	}
+	if clean != "." {
+		if x.toplevel == nil {
+			x.toplevel = map[string]bool{}
+		}
+		top, _, _ := strings.Cut(clean, "/")
+		x.toplevel[top] = true
+		if top == clean && e.kind != kindDir {
+			x.topFile = true
+		}
+	}
+	rel, ok, err := x.relPath(e.name)
+	if err != nil || !ok {
+		return err
+	}
+	if e.kind == kindOther {
+		x.skipped = append(x.skipped, rel)
+		return nil
+	}
+	if err = x.checkParents(rel); err != nil {
+		return err
+	}
+	if e.kind == kindSymlink {
+		x.symlinks[rel] = true
+	}
+	target := x.target(rel)
+	info, statErr := os.Lstat(target)
+	exists := statErr == nil
+	if statErr != nil && !os.IsNotExist(statErr) {
+		return statErr
+	}
+	write := !exists
+	switch e.kind {
+	case kindDir:
+		if exists && !info.IsDir() {
+			return errors.Join(ErrPathConflict, fmt.Errorf("`%s` is not a directory", target))
+		}
+	case kindFile:
+		if exists && info.IsDir() {
+			return errors.Join(ErrPathConflict, fmt.Errorf("`%s` is a directory", target))
+		}
+		if exists {
+			write, err = fileDiffers(target, info, e, r)
+			if err != nil {
+				return err
+			}
+		}
+	case kindSymlink, kindHardlink:
+		linkRel, linkErr := x.linkTarget(rel, e)
+		if linkErr != nil {
+			return linkErr
+		}
+		if exists && info.IsDir() {
+			return errors.Join(ErrPathConflict, fmt.Errorf("`%s` is a directory", target))
+		}
+		if exists && e.kind == kindSymlink {
+			current, readErr := os.Readlink(target)
+			write = readErr != nil || current != e.linkname
+		}
+		if exists && e.kind == kindHardlink {
+			linked, linkStatErr := os.Lstat(x.target(linkRel))
+			write = linkStatErr != nil || x.writes[linkRel] || !os.SameFile(info, linked)
+		}
+	}
+	if write {
+		if !x.writes[rel] {
+			x.order = append(x.order, rel)
+		}
+		x.writes[rel] = true
+		return nil
+	}
+	if file.OwnerDiffers(info, x.uid, x.gid) {
+		x.chown = append(x.chown, target)
+	}
+	return nil
+}
+
+// fileDiffers reports whether the existing file at target differs from
+// the entry in content or permissions.
+func fileDiffers(target string, info fs.FileInfo, e entry, r io.Reader) (bool, error) {
+	if !info.Mode().IsRegular() || info.Size() != e.size || info.Mode().Perm() != filePerm(e) {
+		return true, nil
+	}
+	f, err := os.Open(target)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	want := make([]byte, 32*1024)
+	have := make([]byte, 32*1024)
+	for {
+		n, readErr := io.ReadFull(r, want)
+		if n > 0 {
+			if _, err = io.ReadFull(f, have[:n]); err != nil {
+				return true, nil
+			}
+			if !bytes.Equal(want[:n], have[:n]) {
+				return true, nil
+			}
+		}
+		if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+			return false, nil
+		}
+		if readErr != nil {
+			return false, readErr
+		}
+	}
+}
+
+func filePerm(e entry) fs.FileMode {
+	if e.mode == 0 {
+		return 0o644
+	}
+	return e.mode
+}
+
+func dirPerm(e entry) fs.FileMode {
+	if e.mode == 0 {
+		return 0o755
+	}
+	return e.mode
+}
+
+// extract writes an entry that plan found missing or different.
+func (x *extraction) extract(e entry, r io.Reader) error {
+	rel, ok, err := x.relPath(e.name)
+	if err != nil || !ok || !x.writes[rel] {
+		return err
+	}
+	if err = x.checkParents(rel); err != nil {
+		return err
+	}
+	target := x.target(rel)
+	if err = os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	switch e.kind {
+	case kindDir:
+		if err = os.Mkdir(target, dirPerm(e)); err != nil && !os.IsExist(err) {
+			return err
+		}
+	case kindFile:
+		if err = writeFile(target, r, filePerm(e)); err != nil {
+			return err
+		}
+	case kindSymlink:
+		if err = os.Remove(target); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err = os.Symlink(e.linkname, target); err != nil {
+			return err
+		}
+	case kindHardlink:
+		linkRel, linkErr := x.linkTarget(rel, e)
+		if linkErr != nil {
+			return linkErr
+		}
+		if err = os.Remove(target); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err = os.Link(x.target(linkRel), target); err != nil {
+			return err
+		}
+	}
+	if x.uid >= 0 || x.gid >= 0 {
+		return os.Lchown(target, x.uid, x.gid)
+	}
+	return nil
+}
+
+// writeFile replaces target with the content of r through a temporary
+// file, so a failed extraction never leaves a partial file behind.
+func writeFile(target string, r io.Reader, perm fs.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".grlx-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
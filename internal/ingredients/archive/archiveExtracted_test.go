@@ -0,0 +1,459 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+
+	"github.com/gogrlx/grlx/v2/internal/config"
+	"github.com/gogrlx/grlx/v2/internal/ingredients/file"
+	_ "github.com/gogrlx/grlx/v2/internal/ingredients/file/local"
+)
+
+// fixture is an entry of a test archive; dir, symlink and hardlink
+// entries ignore body.
+type fixture struct {
+	name     string
+	body     string
+	dir      bool
+	symlink  string
+	hardlink string
+}
+
+// writeArchive packs files into dir as kind: tar, tar.gz, tar.xz,
+// tar.zst or zip.
+func writeArchive(t *testing.T, dir, kind string, files []fixture) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if kind == "zip" {
+		zw := zip.NewWriter(&buf)
+		for _, f := range files {
+			hdr := &zip.FileHeader{Name: f.name, Method: zip.Deflate}
+			switch {
+			case f.dir:
+				hdr.Name = strings.TrimSuffix(f.name, "/") + "/"
+				hdr.SetMode(os.ModeDir | 0o755)
+			case f.symlink != "":
+				hdr.SetMode(os.ModeSymlink | 0o777)
+			default:
+				hdr.SetMode(0o644)
+			}
+			w, err := zw.CreateHeader(hdr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body := f.body
+			if f.symlink != "" {
+				body = f.symlink
+			}
+			if _, err = w.Write([]byte(body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		var w io.WriteCloser
+		switch kind {
+		case "tar":
+			w = nopCloser{&buf}
+		case "tar.gz":
+			w = gzip.NewWriter(&buf)
+		case "tar.xz":
+			xw, err := xz.NewWriter(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w = xw
+		case "tar.zst":
+			zw, err := zstd.NewWriter(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w = zw
+		default:
+			t.Fatalf("unknown archive kind %s", kind)
+		}
+		tw := tar.NewWriter(w)
+		for _, f := range files {
+			hdr := &tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.body)), Typeflag: tar.TypeReg}
+			switch {
+			case f.dir:
+				hdr.Typeflag, hdr.Mode, hdr.Size = tar.TypeDir, 0o755, 0
+			case f.symlink != "":
+				hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, f.symlink, 0
+			case f.hardlink != "":
+				hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeLink, f.hardlink, 0
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				t.Fatal(err)
+			}
+			if hdr.Size > 0 {
+				if _, err := tw.Write([]byte(f.body)); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, fmt.Sprintf("bundle-%d.%s", buf.Len(), kind))
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func sha256Of(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(b))
+}
+
+func useCacheDir(t *testing.T) {
+	t.Helper()
+	orig := config.CacheDir
+	config.CacheDir = t.TempDir()
+	t.Cleanup(func() { config.CacheDir = orig })
+}
+
+var appFiles = []fixture{
+	{name: "app-1.0/", dir: true},
+	{name: "app-1.0/bin/", dir: true},
+	{name: "app-1.0/bin/run", body: "#!/bin/sh\necho run\n"},
+	{name: "app-1.0/README", body: "read me\n"},
+	{name: "app-1.0/current", symlink: "bin/run"},
+}
+
+func TestExtractedFormats(t *testing.T) {
+	for _, kind := range []string{"tar", "tar.gz", "tar.xz", "tar.zst", "zip"} {
+		t.Run(kind, func(t *testing.T) {
+			useCacheDir(t)
+			src := writeArchive(t, t.TempDir(), kind, appFiles)
+			dest := filepath.Join(t.TempDir(), "app")
+			a, err := Archive{}.Parse("app", "extracted", map[string]interface{}{
+				"name": dest, "source": src, "source_hash": sha256Of(t, src),
+				"strip_components": 1, "enforce_toplevel": true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := a.Apply(context.Background())
+			if err != nil || !res.Changed {
+				t.Fatalf("Apply() = %+v, %v", res, err)
+			}
+			got, err := os.ReadFile(filepath.Join(dest, "bin", "run"))
+			if err != nil || string(got) != "#!/bin/sh\necho run\n" {
+				t.Fatalf("bin/run = %q, %v", got, err)
+			}
+			if link, err := os.Readlink(filepath.Join(dest, "current")); err != nil || link != "bin/run" {
+				t.Fatalf("current -> %q, %v", link, err)
+			}
+
+			res, err = a.Test(context.Background())
+			if err != nil || res.Changed {
+				t.Fatalf("Test() after Apply() = %+v, %v", res, err)
+			}
+			res, err = a.Apply(context.Background())
+			if err != nil || res.Changed {
+				t.Fatalf("second Apply() = %+v, %v", res, err)
+			}
+		})
+	}
+}
+
+func TestExtractedTestMode(t *testing.T) {
+	useCacheDir(t)
+	src := writeArchive(t, t.TempDir(), "tar.gz", appFiles)
+	dest := filepath.Join(t.TempDir(), "app")
+	a, err := Archive{}.Parse("app", "extracted", map[string]interface{}{
+		"name": dest, "source": src, "source_hash": sha256Of(t, src),
+		"strip_components": "1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The source is not cached yet.
+	res, err := a.Test(context.Background())
+	if err != nil || !res.Changed {
+		t.Fatalf("Test() = %+v, %v", res, err)
+	}
+	if _, err = os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("Test() created %s", dest)
+	}
+
+	// Once cached, test mode lists the files that would be written.
+	cached, err := file.File{}.Parse("app-source", "cached", map[string]interface{}{
+		"name": "app-source", "source": src, "hash": sha256Of(t, src),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cached.Apply(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(filepath.Join(dest, "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dest, "README"), []byte("read me\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dest, "bin", "run"), []byte("stale\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res, err = a.Test(context.Background())
+	if err != nil || !res.Changed {
+		t.Fatalf("Test() = %+v, %v", res, err)
+	}
+	var notes []string
+	for _, n := range res.Notes {
+		notes = append(notes, n.String())
+	}
+	joined := strings.Join(notes, "\n")
+	for _, want := range []string{filepath.Join(dest, "bin", "run"), filepath.Join(dest, "current")} {
+		if !strings.Contains(joined, "would extract `"+want+"`") {
+			t.Errorf("notes %q do not mention %s", joined, want)
+		}
+	}
+	if strings.Contains(joined, "README") {
+		t.Errorf("notes %q mention the unchanged README", joined)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dest, "bin", "run")); string(got) != "stale\n" {
+		t.Errorf("Test() rewrote bin/run")
+	}
+}
+
+func TestExtractedUnsafe(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []fixture
+		err   error
+	}{
+		{name: "dotdot", files: []fixture{{name: "../evil", body: "x"}}, err: ErrUnsafePath},
+		{name: "nested dotdot", files: []fixture{{name: "a/../../evil", body: "x"}}, err: ErrUnsafePath},
+		{name: "absolute", files: []fixture{{name: "/etc/evil", body: "x"}}, err: ErrUnsafePath},
+		{name: "absolute symlink", files: []fixture{{name: "link", symlink: "/etc/passwd"}}, err: ErrUnsafePath},
+		{name: "escaping symlink", files: []fixture{{name: "a/link", symlink: "../../etc/passwd"}}, err: ErrUnsafePath},
+		{name: "escaping hardlink", files: []fixture{{name: "link", hardlink: "../etc/passwd"}}, err: ErrUnsafePath},
+		{name: "write through symlink", files: []fixture{{name: "link", symlink: "."}, {name: "link/evil", body: "x"}}, err: ErrUnsafePath},
+		{name: "chained symlinks", files: []fixture{{name: "a/b", symlink: ".."}, {name: "a/b/c", symlink: ".."}, {name: "a/b/c/evil", body: "x"}}, err: ErrUnsafePath},
+		{name: "no toplevel", files: []fixture{{name: "a/one", body: "1"}, {name: "b/two", body: "2"}}, err: ErrNoToplevel},
+		{name: "toplevel file", files: []fixture{{name: "one", body: "1"}}, err: ErrNoToplevel},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			useCacheDir(t)
+			src := writeArchive(t, t.TempDir(), "tar", tc.files)
+			parent := t.TempDir()
+			dest := filepath.Join(parent, "dest")
+			a, err := Archive{}.Parse("unsafe", "extracted", map[string]interface{}{
+				"name": dest, "source": src, "source_hash": sha256Of(t, src),
+				"enforce_toplevel": tc.err == ErrNoToplevel,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = a.Apply(context.Background())
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Apply() error = %v, want %v", err, tc.err)
+			}
+			if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+				t.Errorf("Apply() wrote %d entries before failing", len(entries))
+			}
+			if _, statErr := os.Stat(filepath.Join(parent, "evil")); statErr == nil {
+				t.Fatal("entry was written outside the destination")
+			}
+		})
+	}
+
+	// A symlink already in the destination is not followed.
+	useCacheDir(t)
+	outside := t.TempDir()
+	dest := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dest, "dir")); err != nil {
+		t.Fatal(err)
+	}
+	src := writeArchive(t, t.TempDir(), "tar", []fixture{{name: "dir/evil", body: "x"}})
+	a, err := Archive{}.Parse("unsafe", "extracted", map[string]interface{}{
+		"name": dest, "source": src, "source_hash": sha256Of(t, src),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.Apply(context.Background()); !errors.Is(err, ErrUnsafePath) {
+		t.Fatalf("Apply() error = %v, want %v", err, ErrUnsafePath)
+	}
+	if _, err = os.Stat(filepath.Join(outside, "evil")); err == nil {
+		t.Fatal("entry was written through an existing symlink")
+	}
+}
+
+func TestExtractedIfMissing(t *testing.T) {
+	useCacheDir(t)
+	src := writeArchive(t, t.TempDir(), "zip", appFiles)
+	dest := t.TempDir()
+	marker := filepath.Join(dest, "app-1.0", "README")
+	a, err := Archive{}.Parse("app", "extracted", map[string]interface{}{
+		"name": dest, "source": src, "source_hash": sha256Of(t, src), "if_missing": marker,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := a.Apply(context.Background())
+	if err != nil || !res.Changed {
+		t.Fatalf("Apply() = %+v, %v", res, err)
+	}
+	if err = os.WriteFile(filepath.Join(dest, "app-1.0", "bin", "run"), []byte("local edit\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res, err = a.Apply(context.Background())
+	if err != nil || res.Changed {
+		t.Fatalf("Apply() with if_missing present = %+v, %v", res, err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dest, "app-1.0", "bin", "run")); string(got) != "local edit\n" {
+		t.Errorf("if_missing did not skip extraction")
+	}
+}
+
+func TestExtractedHardlink(t *testing.T) {
+	useCacheDir(t)
+	src := writeArchive(t, t.TempDir(), "tar.gz", []fixture{
+		{name: "pkg/lib.so.1", body: "elf"},
+		{name: "pkg/lib.so", hardlink: "pkg/lib.so.1"},
+	})
+	dest := t.TempDir()
+	a, err := Archive{}.Parse("pkg", "extracted", map[string]interface{}{
+		"name": dest, "source": src, "source_hash": sha256Of(t, src), "strip_components": 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.Apply(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	orig, err := os.Stat(filepath.Join(dest, "lib.so.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	linked, err := os.Stat(filepath.Join(dest, "lib.so"))
+	if err != nil || !os.SameFile(orig, linked) {
+		t.Fatalf("lib.so is not a hard link of lib.so.1: %v", err)
+	}
+	if res, err := a.Test(context.Background()); err != nil || res.Changed {
+		t.Fatalf("Test() after Apply() = %+v, %v", res, err)
+	}
+}
+
+func TestExtractedErrors(t *testing.T) {
+	useCacheDir(t)
+	src := writeArchive(t, t.TempDir(), "tar", appFiles)
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+		err    error
+	}{
+		{name: "root", params: map[string]interface{}{"name": "/", "source": src, "source_hash": sha256Of(t, src)}, err: file.ErrModifyRoot},
+		{name: "missing hash", params: map[string]interface{}{"name": "/opt/app", "source": src}, err: file.ErrMissingHash},
+		{name: "bad strip", params: map[string]interface{}{"name": "/opt/app", "source": src, "source_hash": sha256Of(t, src), "strip_components": "x"}, err: ErrInvalidStrip},
+		{name: "no parent", params: map[string]interface{}{"name": filepath.Join(t.TempDir(), "a", "b"), "source": src, "source_hash": sha256Of(t, src)}, err: file.ErrPathNotFound},
+		{name: "bad format", params: map[string]interface{}{"name": t.TempDir(), "source": src, "source_hash": sha256Of(t, src), "archive_format": "rar"}, err: ErrUnknownFormat},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := Archive{}.Parse("bad", "extracted", tc.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := a.Apply(context.Background())
+			if !errors.Is(err, tc.err) || !res.Failed {
+				t.Fatalf("Apply() = %+v, %v, want %v", res, err, tc.err)
+			}
+		})
+	}
+
+	// A file in the archive where a directory exists is a conflict.
+	dest := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dest, "app-1.0", "README"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	a, err := Archive{}.Parse("conflict", "extracted", map[string]interface{}{"name": dest, "source": src, "source_hash": sha256Of(t, src)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.Apply(context.Background()); !errors.Is(err, ErrPathConflict) {
+		t.Fatalf("Apply() error = %v, want %v", err, ErrPathConflict)
+	}
+}
+
+func TestExtractedOwnership(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing ownership needs root")
+	}
+	nobody, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skip("no nobody user")
+	}
+	useCacheDir(t)
+	src := writeArchive(t, t.TempDir(), "tar.gz", appFiles)
+	dest := t.TempDir()
+	a, err := Archive{}.Parse("app", "extracted", map[string]interface{}{
+		"name": dest, "source": src, "source_hash": sha256Of(t, src), "user": "nobody",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.Apply(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Lstat(filepath.Join(dest, "app-1.0", "bin", "run"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uid := strconv.Itoa(int(info.Sys().(*syscall.Stat_t).Uid)); uid != nobody.Uid {
+		t.Fatalf("bin/run is owned by %s, want %s", uid, nobody.Uid)
+	}
+
+	// Files that match the archive but not the owner are chowned only.
+	if err = os.Lchown(filepath.Join(dest, "app-1.0", "README"), 0, -1); err != nil {
+		t.Fatal(err)
+	}
+	res, err := a.Test(context.Background())
+	if err != nil || !res.Changed || len(res.Notes) != 1 || !strings.Contains(res.Notes[0].String(), "would chown") {
+		t.Fatalf("Test() = %+v, %v", res, err)
+	}
+	if _, err = a.Apply(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if res, err = a.Test(context.Background()); err != nil || res.Changed {
+		t.Fatalf("Test() after Apply() = %+v, %v", res, err)
+	}
+}
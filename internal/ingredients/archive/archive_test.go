@@ -0,0 +1,125 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gogrlx/grlx/v2/internal/ingredients"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		params map[string]interface{}
+		err    bool
+	}{
+		{name: "valid", method: "extracted", params: map[string]interface{}{"name": "/opt/app", "source": "/tmp/app.tar.gz"}},
+		{name: "missing name", method: "extracted", params: map[string]interface{}{"source": "/tmp/app.tar.gz"}, err: true},
+		{name: "missing source", method: "extracted", params: map[string]interface{}{"name": "/opt/app"}, err: true},
+		{name: "nil params", method: "extracted", params: nil, err: true},
+		{name: "unknown method", method: "compressed", params: map[string]interface{}{"name": "/opt/app"}, err: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Archive{}.Parse("id", tc.method, tc.params)
+			if (err != nil) != tc.err {
+				t.Fatalf("Parse() error = %v, want error %v", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestMethods(t *testing.T) {
+	name, methods := Archive{}.Methods()
+	if name != "archive" || len(methods) != 1 || methods[0] != "extracted" {
+		t.Fatalf("Methods() = %s, %v", name, methods)
+	}
+	props, err := Archive{}.PropertiesForMethod("extracted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"name", "source", "source_hash", "strip_components", "if_missing", "enforce_toplevel"} {
+		if _, ok := props[key]; !ok {
+			t.Errorf("missing property %s", key)
+		}
+	}
+}
+
+func TestUndefinedMethod(t *testing.T) {
+	a := Archive{id: "id", method: "compressed", params: map[string]interface{}{}}
+	if _, err := a.Test(context.Background()); !errors.Is(err, ErrArchiveMethodUndefined) {
+		t.Errorf("Test() error = %v", err)
+	}
+	if _, err := a.Apply(context.Background()); !errors.Is(err, ErrArchiveMethodUndefined) {
+		t.Errorf("Apply() error = %v", err)
+	}
+}
+
+func TestStripComponents(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  int
+		err   bool
+	}{
+		{value: nil, want: 0},
+		{value: 2, want: 2},
+		{value: float64(1), want: 1},
+		{value: "3", want: 3},
+		{value: "", want: 0},
+		{value: 1.5, err: true},
+		{value: -1, err: true},
+		{value: "one", err: true},
+		{value: true, err: true},
+	}
+	for _, tc := range tests {
+		got, err := stripComponents(tc.value)
+		if (err != nil) != tc.err || got != tc.want {
+			t.Errorf("stripComponents(%v) = %d, %v", tc.value, got, err)
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	dir := t.TempDir()
+	files := []fixture{{name: "app/bin/run", body: "#!/bin/sh\n"}}
+	tests := []struct {
+		kind      string
+		requested string
+		want      format
+		err       bool
+	}{
+		{kind: "tar", want: format{}},
+		{kind: "tar.gz", want: format{compression: "gzip"}},
+		{kind: "tar.xz", want: format{compression: "xz"}},
+		{kind: "tar.zst", want: format{compression: "zstd"}},
+		{kind: "zip", want: format{zip: true}},
+		{kind: "zip", requested: "zip", want: format{zip: true}},
+		{kind: "tar.gz", requested: "tar", want: format{compression: "gzip"}},
+		{kind: "tar", requested: "rar", err: true},
+	}
+	for _, tc := range tests {
+		path := writeArchive(t, dir, tc.kind, files)
+		got, err := detectFormat(path, tc.requested)
+		if (err != nil) != tc.err || got != tc.want {
+			t.Errorf("detectFormat(%s, %q) = %+v, %v", tc.kind, tc.requested, got, err)
+		}
+	}
+
+	plain := filepath.Join(dir, "plain.txt")
+	if err := os.WriteFile(plain, []byte("not an archive"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := detectFormat(plain, ""); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("detectFormat(plain) error = %v", err)
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	if _, err := ingredients.NewRecipeCooker("id", "archive", "extracted", map[string]interface{}{"name": "/opt/app", "source": "/tmp/app.zip"}); err != nil {
+		t.Fatalf("archive.extracted is not registered: %v", err)
+	}
+}
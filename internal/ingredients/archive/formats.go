@@ -0,0 +1,223 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+type entryKind int
+
+const (
+	kindFile entryKind = iota
+	kindDir
+	kindSymlink
+	kindHardlink
+	// kindOther covers devices, fifos and the like, which are not
+	// extracted.
+	kindOther
+)
+
+// entry is a member of an archive.
+type entry struct {
+	name     string
+	kind     entryKind
+	mode     fs.FileMode
+	size     int64
+	linkname string
+}
+
+// format is how an archive is packed: a tar, possibly compressed, or a
+// zip.
+type format struct {
+	zip         bool
+	compression string
+}
+
+var (
+	magicZip      = []byte("PK\x03\x04")
+	magicZipEmpty = []byte("PK\x05\x06")
+	magicGzip     = []byte{0x1f, 0x8b}
+	magicXz       = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	magicZstd     = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	magicBzip2    = []byte("BZh")
+	magicUstar    = []byte("ustar")
+)
+
+// detectFormat works out how the archive at path is packed from its
+// first bytes. requested, if set, is the archive_format property: `tar`
+// or `zip`.
+func detectFormat(path, requested string) (format, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return format{}, err
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return format{}, err
+	}
+	head = head[:n]
+
+	isZip := bytes.HasPrefix(head, magicZip) || bytes.HasPrefix(head, magicZipEmpty)
+	switch requested {
+	case "zip":
+		return format{zip: true}, nil
+	case "", "tar":
+	default:
+		return format{}, errors.Join(ErrUnknownFormat, fmt.Errorf("archive_format %q is not `tar` or `zip`", requested))
+	}
+	switch {
+	case isZip && requested == "":
+		return format{zip: true}, nil
+	case bytes.HasPrefix(head, magicGzip):
+		return format{compression: "gzip"}, nil
+	case bytes.HasPrefix(head, magicXz):
+		return format{compression: "xz"}, nil
+	case bytes.HasPrefix(head, magicZstd):
+		return format{compression: "zstd"}, nil
+	case bytes.HasPrefix(head, magicBzip2):
+		return format{compression: "bzip2"}, nil
+	case requested == "tar" || (len(head) >= 262 && bytes.Equal(head[257:262], magicUstar)):
+		return format{}, nil
+	}
+	return format{}, errors.Join(ErrUnknownFormat, fmt.Errorf("cannot tell how %s is packed", path))
+}
+
+// walk calls fn with each entry of the archive at path, along with the
+// content of regular files.
+func walk(path string, af format, fn func(entry, io.Reader) error) error {
+	if af.zip {
+		return walkZip(path, fn)
+	}
+	return walkTar(path, af.compression, fn)
+}
+
+func walkTar(path, compression string, fn func(entry, io.Reader) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = bufio.NewReader(f)
+	switch compression {
+	case "gzip":
+		gz, gzErr := gzip.NewReader(r)
+		if gzErr != nil {
+			return gzErr
+		}
+		defer gz.Close()
+		r = gz
+	case "xz":
+		xzr, xzErr := xz.NewReader(r)
+		if xzErr != nil {
+			return xzErr
+		}
+		r = xzr
+	case "zstd":
+		zr, zErr := zstd.NewReader(r)
+		if zErr != nil {
+			return zErr
+		}
+		defer zr.Close()
+		r = zr
+	case "bzip2":
+		r = bzip2.NewReader(r)
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, nextErr := tr.Next()
+		if errors.Is(nextErr, io.EOF) {
+			return nil
+		}
+		if nextErr != nil {
+			return nextErr
+		}
+		e := entry{
+			name:     hdr.Name,
+			mode:     hdr.FileInfo().Mode().Perm(),
+			size:     hdr.Size,
+			linkname: hdr.Linkname,
+		}
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			e.kind = kindFile
+		case tar.TypeDir:
+			e.kind = kindDir
+		case tar.TypeSymlink:
+			e.kind = kindSymlink
+		case tar.TypeLink:
+			e.kind = kindHardlink
+		case tar.TypeXGlobalHeader:
+			continue
+		default:
+			e.kind = kindOther
+		}
+		if err = fn(e, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func walkZip(path string, fn func(entry, io.Reader) error) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, zf := range zr.File {
+		mode := zf.Mode()
+		e := entry{
+			name: zf.Name,
+			mode: mode.Perm(),
+			size: int64(zf.UncompressedSize64),
+		}
+		switch {
+		case mode.IsDir():
+			e.kind = kindDir
+		case mode&fs.ModeSymlink != 0:
+			e.kind = kindSymlink
+		case mode.IsRegular():
+			e.kind = kindFile
+		default:
+			e.kind = kindOther
+		}
+		if err = walkZipFile(zf, e, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkZipFile(zf *zip.File, e entry, fn func(entry, io.Reader) error) error {
+	if e.kind != kindFile && e.kind != kindSymlink {
+		return fn(e, nil)
+	}
+	rc, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if e.kind == kindSymlink {
+		// A zip stores a symlink's target as its content.
+		target, readErr := io.ReadAll(io.LimitReader(rc, 4096))
+		if readErr != nil {
+			return readErr
+		}
+		e.linkname = string(target)
+		return fn(e, nil)
+	}
+	return fn(e, rc)
+}
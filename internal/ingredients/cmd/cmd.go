@@ -26,8 +26,11 @@ var cmdMethodProps = map[string]ingredients.MethodPropsSet{
 	},
 }
 
-// Compile-time interface check.
-var _ cook.RecipeCooker = Cmd{}
+// Compile-time interface checks.
+var (
+	_ cook.RecipeCooker = Cmd{}
+	_ cook.Watcher      = Cmd{}
+)
 
 type Cmd struct {
 	id     string
@@ -101,6 +104,14 @@ func (c Cmd) Apply(ctx context.Context) (cook.Result, error) {
 	}
 }
 
+// ModWatch re-runs the command when a step it watches made changes.
+func (c Cmd) ModWatch(ctx context.Context, test bool) (cook.Result, error) {
+	if test {
+		return c.Test(ctx)
+	}
+	return c.Apply(ctx)
+}
+
 func (c Cmd) PropertiesForMethod(method string) (map[string]string, error) {
 	props, ok := cmdMethodProps[method]
 	if !ok {
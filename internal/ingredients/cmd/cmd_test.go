@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"os"
 	"testing"
 	"time"
 )
@@ -245,3 +246,32 @@ func TestApply_UndefinedMethod(t *testing.T) {
 		t.Error("expected Failed=true for undefined method")
 	}
 }
+
+func TestModWatch_Run(t *testing.T) {
+	marker := t.TempDir() + "/ran"
+	c := Cmd{
+		id:     "step1",
+		method: "run",
+		params: map[string]interface{}{
+			"name": "touch " + marker,
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := c.ModWatch(ctx, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Error("expected test mode not to run the command")
+	}
+	result, err := c.ModWatch(ctx, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Succeeded {
+		t.Error("expected success")
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("expected the command to be re-run: %v", err)
+	}
+}
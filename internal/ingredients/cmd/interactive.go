@@ -29,6 +29,10 @@ func FRun(target pki.KeyManager, cmdRun apitypes.CmdRun) (apitypes.CmdRun, error
 	topic := "grlx.sprouts." + target.SproutID + ".cmd.run"
 	var results apitypes.CmdRun
 	b, _ := json.Marshal(cmdRun)
+	b, err := pki.SignMessage(topic, cmdRun.JobID, b)
+	if err != nil {
+		return results, err
+	}
 	msg, err := nc.Request(topic, b, time.Second*15+cmdRun.Timeout)
 	if err != nil {
 		return results, err
@@ -37,14 +41,14 @@ func FRun(target pki.KeyManager, cmdRun apitypes.CmdRun) (apitypes.CmdRun, error
 	return results, err
 }
 
-func SRun(cmd apitypes.CmdRun) (apitypes.CmdRun, error) {
+// SRun runs cmd on the sprout. Cancelling ctx kills the command.
+func SRun(ctx context.Context, cmd apitypes.CmdRun) (apitypes.CmdRun, error) {
 	// A zero (or negative) timeout means "no deadline"; WithTimeout would
 	// otherwise produce an already-expired context and kill the command
 	// immediately.
-	ctx := context.Background()
 	cancel := context.CancelFunc(func() {})
 	if cmd.Timeout > 0 {
-		ctx, cancel = context.WithTimeout(context.Background(), cmd.Timeout)
+		ctx, cancel = context.WithTimeout(ctx, cmd.Timeout)
 	}
 	defer cancel()
 	envMutex.Lock()
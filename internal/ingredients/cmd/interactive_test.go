@@ -1,10 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	apitypes "github.com/gogrlx/grlx/v2/internal/api/types"
+	"github.com/gogrlx/grlx/v2/internal/cook"
 	"github.com/gogrlx/grlx/v2/internal/pki"
 )
 
@@ -23,7 +25,7 @@ func TestSRunSimpleCommand(t *testing.T) {
 		Timeout: 5 * time.Second,
 		Env:     apitypes.EnvVar{},
 	}
-	result, err := SRun(cmd)
+	result, err := SRun(context.Background(), cmd)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -45,7 +47,7 @@ func TestSRunWithCwd(t *testing.T) {
 		Timeout: 5 * time.Second,
 		Env:     apitypes.EnvVar{},
 	}
-	result, err := SRun(cmd)
+	result, err := SRun(context.Background(), cmd)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -63,7 +65,7 @@ func TestSRunWithEnv(t *testing.T) {
 			"GRLX_TEST_SRUN": "test_value",
 		},
 	}
-	result, err := SRun(cmd)
+	result, err := SRun(context.Background(), cmd)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -80,7 +82,7 @@ func TestSRunWithCustomPath(t *testing.T) {
 		Timeout: 5 * time.Second,
 		Env:     apitypes.EnvVar{},
 	}
-	result, err := SRun(cmd)
+	result, err := SRun(context.Background(), cmd)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -98,7 +100,7 @@ func TestSRunWithEnvPath(t *testing.T) {
 			"PATH": "/usr/bin:/bin",
 		},
 	}
-	result, err := SRun(cmd)
+	result, err := SRun(context.Background(), cmd)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -113,7 +115,7 @@ func TestSRunNonexistentCommand(t *testing.T) {
 		Timeout: 5 * time.Second,
 		Env:     apitypes.EnvVar{},
 	}
-	_, err := SRun(cmd)
+	_, err := SRun(context.Background(), cmd)
 	if err == nil {
 		t.Error("expected error for nonexistent command")
 	}
@@ -125,7 +127,7 @@ func TestSRunFailingCommand(t *testing.T) {
 		Timeout: 5 * time.Second,
 		Env:     apitypes.EnvVar{},
 	}
-	result, err := SRun(cmd)
+	result, err := SRun(context.Background(), cmd)
 	if err == nil {
 		t.Log("'false' command may or may not return error depending on implementation")
 	}
@@ -142,7 +144,7 @@ func TestSRunNonexistentRunas(t *testing.T) {
 		Timeout: 5 * time.Second,
 		Env:     apitypes.EnvVar{},
 	}
-	_, err := SRun(cmd)
+	_, err := SRun(context.Background(), cmd)
 	if err == nil {
 		t.Error("expected error for nonexistent runas user")
 	}
@@ -157,7 +159,7 @@ func TestSRunWithStreamTopic(t *testing.T) {
 		Env:         apitypes.EnvVar{},
 		StreamTopic: "grlx.test.output",
 	}
-	result, err := SRun(cmd)
+	result, err := SRun(context.Background(), cmd)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -175,7 +177,7 @@ func TestSRunWithEmptyPathInEnv(t *testing.T) {
 			"PATH": "",
 		},
 	}
-	result, err := SRun(cmd)
+	result, err := SRun(context.Background(), cmd)
 	// With empty PATH in env but no cmd.Path, the existing PATH should be used
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -209,3 +211,30 @@ func TestFRunWithoutNATSConnection(t *testing.T) {
 		t.Error("expected error for nil NATS connection")
 	}
 }
+
+func TestSRunCancelJob(t *testing.T) {
+	ctx, done := cook.TrackJob(context.Background(), "cmd-run-cancel")
+	defer done()
+	finished := make(chan apitypes.CmdRun, 1)
+	go func() {
+		result, _ := SRun(ctx, apitypes.CmdRun{Command: "sleep", Args: []string{"30"}})
+		finished <- result
+	}()
+	// Give the command a moment to start before cancelling it.
+	time.Sleep(100 * time.Millisecond)
+	if !cook.CancelJob("cmd-run-cancel") {
+		t.Fatal("expected the tracked command to be cancellable")
+	}
+	select {
+	case result := <-finished:
+		if result.ErrCode == 0 {
+			t.Errorf("expected a killed command to fail, got %+v", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled command kept running")
+	}
+	done()
+	if cook.CancelJob("cmd-run-cancel") {
+		t.Error("expected a finished command to no longer be cancellable")
+	}
+}
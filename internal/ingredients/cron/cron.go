@@ -0,0 +1,155 @@
+// Package cron manages scheduled jobs and environment variables in user
+// crontabs and in files under /etc/cron.d. Each managed job is preceded by
+// a marker comment naming the recipe step's entry, so entries written by
+// hand or by other tools are left alone.
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gogrlx/grlx/v2/internal/cook"
+	"github.com/gogrlx/grlx/v2/internal/ingredients"
+)
+
+var (
+	ErrCronMethodUndefined = errors.New("cron method undefined")
+	ErrMissingCommand      = errors.New("recipe is missing a command")
+	ErrMissingValue        = errors.New("recipe is missing a value")
+	ErrInvalidCronFile     = errors.New("cron_file must be a plain file name of letters, digits, - and _")
+	ErrInvalidSpecial      = errors.New("special must be one of @reboot, @yearly, @annually, @monthly, @weekly, @daily, @midnight or @hourly")
+	ErrSpecialAndSchedule  = errors.New("special cannot be combined with minute, hour, daymonth, month or dayweek")
+	ErrInvalidField        = errors.New("cron fields must be non-empty and single-line")
+	ErrInvalidEnvName      = errors.New("environment variable name must be letters, digits and _ and not start with a digit")
+)
+
+// Compile-time interface check.
+var _ cook.RecipeCooker = Cron{}
+
+type Cron struct {
+	id     string
+	method string
+	params map[string]interface{}
+}
+
+func (c Cron) Parse(id, method string, params map[string]interface{}) (cook.RecipeCooker, error) {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	parsed := Cron{
+		id: id, method: method,
+		params: params,
+	}
+	if err := parsed.validate(); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+func (c Cron) validate() error {
+	set, err := c.PropertiesForMethod(c.method)
+	if err != nil {
+		return err
+	}
+	propSet, err := ingredients.PropMapToPropSet(set)
+	if err != nil {
+		return err
+	}
+	for _, v := range propSet {
+		if v.IsReq {
+			if v.Key == "name" {
+				name, ok := c.params[v.Key].(string)
+				if !ok || name == "" {
+					return ingredients.ErrMissingName
+				}
+			} else {
+				if _, ok := c.params[v.Key]; !ok {
+					return fmt.Errorf("missing required property %s", v.Key)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (c Cron) Test(ctx context.Context) (cook.Result, error) {
+	switch c.method {
+	case "present":
+		return c.present(ctx, true)
+	case "absent":
+		return c.absent(ctx, true)
+	case "env_present":
+		return c.envPresent(ctx, true)
+	default:
+		return cook.Result{Succeeded: false, Failed: true, Changed: false, Notes: nil},
+			errors.Join(ErrCronMethodUndefined, fmt.Errorf("method %s undefined", c.method))
+	}
+}
+
+func (c Cron) Apply(ctx context.Context) (cook.Result, error) {
+	switch c.method {
+	case "present":
+		return c.present(ctx, false)
+	case "absent":
+		return c.absent(ctx, false)
+	case "env_present":
+		return c.envPresent(ctx, false)
+	default:
+		return cook.Result{Succeeded: false, Failed: true, Changed: false, Notes: nil},
+			errors.Join(ErrCronMethodUndefined, fmt.Errorf("method %s undefined", c.method))
+	}
+}
+
+func (c Cron) PropertiesForMethod(method string) (map[string]string, error) {
+	switch method {
+	case "absent":
+		return ingredients.MethodPropsSet{
+			ingredients.MethodProps{Key: "name", Type: "string", IsReq: true, Description: "the identifier of the managed entry"},
+			ingredients.MethodProps{Key: "user", Type: "string", IsReq: false, Description: "the user whose crontab holds the entry; defaults to root"},
+			ingredients.MethodProps{Key: "cron_file", Type: "string", IsReq: false, Description: "a file in /etc/cron.d to use instead of the user's crontab"},
+		}.ToMap(), nil
+	case "env_present":
+		return ingredients.MethodPropsSet{
+			ingredients.MethodProps{Key: "name", Type: "string", IsReq: true, Description: "the environment variable to set"},
+			ingredients.MethodProps{Key: "value", Type: "string", IsReq: true},
+			ingredients.MethodProps{Key: "user", Type: "string", IsReq: false, Description: "the user whose crontab to edit; defaults to root"},
+			ingredients.MethodProps{Key: "cron_file", Type: "string", IsReq: false, Description: "a file in /etc/cron.d to use instead of the user's crontab"},
+		}.ToMap(), nil
+	case "present":
+		return ingredients.MethodPropsSet{
+			ingredients.MethodProps{Key: "name", Type: "string", IsReq: true, Description: "the identifier of the managed entry"},
+			ingredients.MethodProps{Key: "command", Type: "string", IsReq: true},
+			ingredients.MethodProps{Key: "user", Type: "string", IsReq: false, Description: "the user the job runs as; defaults to root"},
+			ingredients.MethodProps{Key: "minute", Type: "string", IsReq: false},
+			ingredients.MethodProps{Key: "hour", Type: "string", IsReq: false},
+			ingredients.MethodProps{Key: "daymonth", Type: "string", IsReq: false},
+			ingredients.MethodProps{Key: "month", Type: "string", IsReq: false},
+			ingredients.MethodProps{Key: "dayweek", Type: "string", IsReq: false},
+			ingredients.MethodProps{Key: "special", Type: "string", IsReq: false, Description: "a schedule such as @daily or @reboot instead of the time fields"},
+			ingredients.MethodProps{Key: "comment", Type: "string", IsReq: false},
+			ingredients.MethodProps{Key: "cron_file", Type: "string", IsReq: false, Description: "a file in /etc/cron.d to use instead of the user's crontab"},
+		}.ToMap(), nil
+	default:
+		return nil, fmt.Errorf("method %s undefined", method)
+	}
+}
+
+func (c Cron) Methods() (string, []string) {
+	return "cron", []string{"absent", "env_present", "present"}
+}
+
+func (c Cron) Properties() (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	b, err := json.Marshal(c.params)
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(b, &m)
+	return m, err
+}
+
+func init() {
+	ingredients.RegisterAllMethods(Cron{})
+}
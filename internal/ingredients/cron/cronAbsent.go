@@ -0,0 +1,23 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gogrlx/grlx/v2/internal/cook"
+	"github.com/gogrlx/grlx/v2/internal/ingredients"
+)
+
+func (c Cron) absent(ctx context.Context, test bool) (cook.Result, error) {
+	name, ok := c.params["name"].(string)
+	if !ok || name == "" {
+		return cook.Result{Succeeded: false, Failed: true}, ingredients.ErrMissingName
+	}
+	t, err := c.table()
+	if err != nil {
+		return cook.Result{Succeeded: false, Failed: true}, err
+	}
+	return c.edit(ctx, test, t, func(lines []string) []string {
+		return removeEntry(lines, name)
+	}, fmt.Sprintf("entry `%s`", name), "removed from")
+}
@@ -0,0 +1,35 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gogrlx/grlx/v2/internal/cook"
+	"github.com/gogrlx/grlx/v2/internal/ingredients"
+)
+
+func (c Cron) envPresent(ctx context.Context, test bool) (cook.Result, error) {
+	name, ok := c.params["name"].(string)
+	if !ok || name == "" {
+		return cook.Result{Succeeded: false, Failed: true}, ingredients.ErrMissingName
+	}
+	if !envNameRe.MatchString(name) {
+		return cook.Result{Succeeded: false, Failed: true}, errors.Join(ErrInvalidEnvName, fmt.Errorf("got %q", name))
+	}
+	if _, ok = c.params["value"]; !ok {
+		return cook.Result{Succeeded: false, Failed: true}, ErrMissingValue
+	}
+	value := stringParam(c.params, "value")
+	if strings.Contains(value, "\n") {
+		return cook.Result{Succeeded: false, Failed: true}, errors.Join(ErrInvalidField, fmt.Errorf("value %q", value))
+	}
+	t, err := c.table()
+	if err != nil {
+		return cook.Result{Succeeded: false, Failed: true}, err
+	}
+	return c.edit(ctx, test, t, func(lines []string) []string {
+		return setEnv(lines, name, value)
+	}, fmt.Sprintf("variable `%s`", name), "set in")
+}
@@ -0,0 +1,130 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gogrlx/grlx/v2/internal/cook"
+	"github.com/gogrlx/grlx/v2/internal/ingredients"
+	"github.com/gogrlx/grlx/v2/internal/ingredients/file"
+)
+
+func (c Cron) present(ctx context.Context, test bool) (cook.Result, error) {
+	name, ok := c.params["name"].(string)
+	if !ok || name == "" {
+		return cook.Result{Succeeded: false, Failed: true}, ingredients.ErrMissingName
+	}
+	if strings.Contains(name, "\n") {
+		return cook.Result{Succeeded: false, Failed: true}, errors.Join(ErrInvalidField, fmt.Errorf("name %q", name))
+	}
+	t, err := c.table()
+	if err != nil {
+		return cook.Result{Succeeded: false, Failed: true}, err
+	}
+	job, err := c.jobLine(t)
+	if err != nil {
+		return cook.Result{Succeeded: false, Failed: true}, err
+	}
+	entry := []string{markerPrefix + name}
+	if comment := stringParam(c.params, "comment"); comment != "" {
+		for _, line := range strings.Split(comment, "\n") {
+			entry = append(entry, strings.TrimRight("# "+line, " "))
+		}
+	}
+	entry = append(entry, job)
+	return c.edit(ctx, test, t, func(lines []string) []string {
+		return setEntry(lines, name, entry)
+	}, fmt.Sprintf("entry `%s`", name), "set in")
+}
+
+// jobLine renders the schedule and command as a crontab line.
+func (c Cron) jobLine(t crontab) (string, error) {
+	command := stringParam(c.params, "command")
+	if command == "" {
+		return "", ErrMissingCommand
+	}
+	if strings.Contains(command, "\n") {
+		return "", errors.Join(ErrInvalidField, fmt.Errorf("command %q", command))
+	}
+	var schedule []string
+	special := stringParam(c.params, "special")
+	if special != "" {
+		if !specials[special] {
+			return "", errors.Join(ErrInvalidSpecial, fmt.Errorf("got %q", special))
+		}
+		schedule = []string{special}
+	}
+	for _, key := range []string{"minute", "hour", "daymonth", "month", "dayweek"} {
+		v, set := c.params[key]
+		set = set && v != nil
+		if set && special != "" {
+			return "", ErrSpecialAndSchedule
+		}
+		if special != "" {
+			continue
+		}
+		field := "*"
+		if set {
+			field = fmt.Sprint(v)
+		}
+		if field == "" || strings.ContainsAny(field, " \t\n") {
+			return "", errors.Join(ErrInvalidField, fmt.Errorf("%s %q", key, field))
+		}
+		schedule = append(schedule, field)
+	}
+	if t.file != "" {
+		schedule = append(schedule, t.user)
+	}
+	return strings.Join(append(schedule, command), " "), nil
+}
+
+// edit applies change to the crontab's lines and writes the result if it
+// differs. In test mode the notes hold a diff of the crontab instead. verb
+// describes the change, e.g. "set in".
+func (c Cron) edit(ctx context.Context, test bool, t crontab, change func([]string) []string, what, verb string) (cook.Result, error) {
+	var notes []fmt.Stringer
+	before, err := t.read(ctx)
+	if err != nil {
+		return cook.Result{Succeeded: false, Failed: true}, err
+	}
+	after := joinTable(change(splitTable(before)))
+	if after == before {
+		notes = append(notes, cook.Snprintf("%s is already %s %s", what, verb, t))
+		return cook.Result{
+			Succeeded: true, Failed: false,
+			Changed: false, Notes: notes,
+		}, nil
+	}
+	if test {
+		notes = append(notes, cook.Snprintf("%s would be %s %s", what, verb, t))
+		notes = append(notes, cook.SimpleNote(file.UnifiedDiff(t.String(), []byte(before), []byte(after))))
+		return cook.Result{
+			Succeeded: true, Failed: false,
+			Changed: true, Notes: notes,
+		}, nil
+	}
+	if err = t.write(ctx, after); err != nil {
+		notes = append(notes, cook.Snprintf("failed to write %s: %s", t, err))
+		return cook.Result{Succeeded: false, Failed: true, Notes: notes}, err
+	}
+	notes = append(notes, cook.Snprintf("%s %s %s", what, verb, t))
+	return cook.Result{
+		Succeeded: true, Failed: false,
+		Changed: true, Notes: notes,
+	}, nil
+}
+
+// stringParam extracts a string parameter, formatting numbers as recipes
+// may give schedule fields unquoted.
+func stringParam(params map[string]interface{}, key string) string {
+	switch v := params[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,357 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogrlx/grlx/v2/internal/ingredients"
+)
+
+// useTempCronDir points cronDir at a temporary directory and stubs the
+// crontab binary with an in-memory table per user.
+func useTempCronDir(t *testing.T) map[string]string {
+	t.Helper()
+	origDir, origRead, origWrite := cronDir, readUserCrontab, writeUserCrontab
+	t.Cleanup(func() {
+		cronDir, readUserCrontab, writeUserCrontab = origDir, origRead, origWrite
+	})
+	cronDir = t.TempDir()
+	tabs := map[string]string{}
+	readUserCrontab = func(_ context.Context, user string) (string, error) {
+		return tabs[user], nil
+	}
+	writeUserCrontab = func(_ context.Context, user, content string) error {
+		tabs[user] = content
+		return nil
+	}
+	return tabs
+}
+
+func TestPresent(t *testing.T) {
+	const existing = "MAILTO=ops\n# hand written\n0 * * * * root /usr/bin/true\n"
+	tests := []struct {
+		name     string
+		params   map[string]interface{}
+		existing string
+		want     string
+		changed  bool
+		error    error
+	}{
+		{
+			name:    "add to new file",
+			params:  map[string]interface{}{"command": "/usr/local/bin/backup", "minute": 30, "hour": "2"},
+			want:    "# grlx-managed: backup\n30 2 * * * root /usr/local/bin/backup\n",
+			changed: true,
+		},
+		{
+			name:     "append with comment and user",
+			params:   map[string]interface{}{"command": "backup", "special": "@daily", "user": "www", "comment": "nightly\nsee runbook"},
+			existing: existing,
+			want:     existing + "# grlx-managed: backup\n# nightly\n# see runbook\n@daily www backup\n",
+			changed:  true,
+		},
+		{
+			name:     "update in place",
+			params:   map[string]interface{}{"command": "backup --full", "special": "@weekly"},
+			existing: "# grlx-managed: backup\n# old\n@daily root backup\n" + existing,
+			want:     "# grlx-managed: backup\n@weekly root backup --full\n" + existing,
+			changed:  true,
+		},
+		{
+			name:     "already present",
+			params:   map[string]interface{}{"command": "backup", "special": "@daily"},
+			existing: existing + "# grlx-managed: backup\n@daily root backup\n",
+			want:     existing + "# grlx-managed: backup\n@daily root backup\n",
+		},
+		{
+			name:   "invalid special",
+			params: map[string]interface{}{"command": "backup", "special": "@fortnightly"},
+			error:  ErrInvalidSpecial,
+		},
+		{
+			name:   "special with schedule",
+			params: map[string]interface{}{"command": "backup", "special": "@daily", "hour": "3"},
+			error:  ErrSpecialAndSchedule,
+		},
+		{
+			name:   "invalid field",
+			params: map[string]interface{}{"command": "backup", "minute": "1 2"},
+			error:  ErrInvalidField,
+		},
+		{
+			name:   "missing command",
+			params: map[string]interface{}{},
+			error:  ErrMissingCommand,
+		},
+	}
+	for _, tc := range tests {
+		for _, test := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s/test=%v", tc.name, test), func(t *testing.T) {
+				useTempCronDir(t)
+				path := filepath.Join(cronDir, "grlx")
+				if tc.existing != "" {
+					if err := os.WriteFile(path, []byte(tc.existing), 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				params := map[string]interface{}{"name": "backup", "cron_file": "grlx"}
+				for k, v := range tc.params {
+					params[k] = v
+				}
+				c := Cron{id: "backup", method: "present", params: params}
+				run := c.Apply
+				if test {
+					run = c.Test
+				}
+				res, err := run(context.Background())
+				if !errors.Is(err, tc.error) {
+					t.Fatalf("error = %v, want %v", err, tc.error)
+				}
+				if tc.error != nil {
+					if !res.Failed {
+						t.Errorf("expected failed result, got %+v", res)
+					}
+					return
+				}
+				if res.Changed != tc.changed {
+					t.Errorf("Changed = %v, want %v (notes %v)", res.Changed, tc.changed, res.Notes)
+				}
+				got, _ := os.ReadFile(path)
+				want := tc.want
+				if test {
+					want = tc.existing
+				}
+				if string(got) != want {
+					t.Errorf("content = %q, want %q", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestPresentTestModeDiff(t *testing.T) {
+	useTempCronDir(t)
+	c := Cron{id: "backup", method: "present", params: map[string]interface{}{
+		"name": "backup", "cron_file": "grlx", "command": "backup", "special": "@daily",
+	}}
+	res, err := c.Test(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var notes []string
+	for _, n := range res.Notes {
+		notes = append(notes, n.String())
+	}
+	joined := strings.Join(notes, "\n")
+	for _, want := range []string{"entry `backup` would be set in", "+# grlx-managed: backup", "+@daily root backup"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("notes %q do not contain %q", joined, want)
+		}
+	}
+}
+
+func TestAbsent(t *testing.T) {
+	const hand = "MAILTO=ops\n0 * * * * root /usr/bin/true\n"
+	tests := []struct {
+		name     string
+		existing string
+		want     string
+		changed  bool
+	}{
+		{
+			name:     "remove entry and comments",
+			existing: "# grlx-managed: backup\n# nightly\n@daily root backup\n" + hand,
+			want:     hand,
+			changed:  true,
+		},
+		{
+			name:     "leave other entries",
+			existing: hand + "# grlx-managed: other\n@hourly root other\n",
+			want:     hand + "# grlx-managed: other\n@hourly root other\n",
+		},
+		{
+			name:     "remove last entry removes file",
+			existing: "# grlx-managed: backup\n@daily root backup\n",
+			changed:  true,
+		},
+		{
+			name: "missing file",
+		},
+	}
+	for _, tc := range tests {
+		for _, test := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s/test=%v", tc.name, test), func(t *testing.T) {
+				useTempCronDir(t)
+				path := filepath.Join(cronDir, "grlx")
+				if tc.existing != "" {
+					if err := os.WriteFile(path, []byte(tc.existing), 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				c := Cron{id: "backup", method: "absent", params: map[string]interface{}{"name": "backup", "cron_file": "grlx"}}
+				run := c.Apply
+				if test {
+					run = c.Test
+				}
+				res, err := run(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if res.Changed != tc.changed {
+					t.Errorf("Changed = %v, want %v (notes %v)", res.Changed, tc.changed, res.Notes)
+				}
+				want := tc.want
+				if test {
+					want = tc.existing
+				}
+				got, err := os.ReadFile(path)
+				if want == "" {
+					if !os.IsNotExist(err) {
+						t.Errorf("expected %s to be absent, got %q", path, got)
+					}
+					return
+				}
+				if string(got) != want {
+					t.Errorf("content = %q, want %q", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestEnvPresent(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   map[string]interface{}
+		existing string
+		want     string
+		changed  bool
+		error    error
+	}{
+		{
+			name:     "insert before first job",
+			params:   map[string]interface{}{"name": "PATH", "value": "/usr/bin:/bin"},
+			existing: "# header\nMAILTO=ops\n# grlx-managed: backup\n@daily backup\n",
+			want:     "# header\nMAILTO=ops\nPATH=/usr/bin:/bin\n# grlx-managed: backup\n@daily backup\n",
+			changed:  true,
+		},
+		{
+			name:     "update in place",
+			params:   map[string]interface{}{"name": "MAILTO", "value": "root"},
+			existing: "MAILTO = ops\n@daily backup\n",
+			want:     "MAILTO=root\n@daily backup\n",
+			changed:  true,
+		},
+		{
+			name:     "already set",
+			params:   map[string]interface{}{"name": "MAILTO", "value": "ops"},
+			existing: "MAILTO=ops\n",
+			want:     "MAILTO=ops\n",
+		},
+		{
+			name:    "empty crontab",
+			params:  map[string]interface{}{"name": "SHELL", "value": "/bin/bash"},
+			want:    "SHELL=/bin/bash\n",
+			changed: true,
+		},
+		{
+			name:   "invalid name",
+			params: map[string]interface{}{"name": "1PATH", "value": "x"},
+			error:  ErrInvalidEnvName,
+		},
+		{
+			name:   "missing value",
+			params: map[string]interface{}{"name": "PATH"},
+			error:  ErrMissingValue,
+		},
+	}
+	for _, tc := range tests {
+		for _, test := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s/test=%v", tc.name, test), func(t *testing.T) {
+				tabs := useTempCronDir(t)
+				tabs["www"] = tc.existing
+				params := map[string]interface{}{"user": "www"}
+				for k, v := range tc.params {
+					params[k] = v
+				}
+				c := Cron{id: "env", method: "env_present", params: params}
+				run := c.Apply
+				if test {
+					run = c.Test
+				}
+				res, err := run(context.Background())
+				if !errors.Is(err, tc.error) {
+					t.Fatalf("error = %v, want %v", err, tc.error)
+				}
+				if tc.error != nil {
+					return
+				}
+				if res.Changed != tc.changed {
+					t.Errorf("Changed = %v, want %v (notes %v)", res.Changed, tc.changed, res.Notes)
+				}
+				want := tc.want
+				if test {
+					want = tc.existing
+				}
+				if tabs["www"] != want {
+					t.Errorf("crontab = %q, want %q", tabs["www"], want)
+				}
+			})
+		}
+	}
+}
+
+func TestUserCrontab(t *testing.T) {
+	tabs := useTempCronDir(t)
+	tabs["root"] = "MAILTO=ops\n"
+	c := Cron{id: "backup", method: "present", params: map[string]interface{}{
+		"name": "backup", "command": "backup", "minute": "5", "dayweek": "1-5",
+	}}
+	if _, err := c.Apply(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// User crontab lines have no user field.
+	if want := "MAILTO=ops\n# grlx-managed: backup\n5 * * * 1-5 backup\n"; tabs["root"] != want {
+		t.Errorf("crontab = %q, want %q", tabs["root"], want)
+	}
+	if entries, _ := os.ReadDir(cronDir); len(entries) != 0 {
+		t.Errorf("expected no cron.d files, got %v", entries)
+	}
+}
+
+func TestInvalidCronFile(t *testing.T) {
+	useTempCronDir(t)
+	for _, file := range []string{"../passwd", "grlx.conf", "a/b"} {
+		c := Cron{id: "x", method: "absent", params: map[string]interface{}{"name": "x", "cron_file": file}}
+		if _, err := c.Apply(context.Background()); !errors.Is(err, ErrInvalidCronFile) {
+			t.Errorf("cron_file %q: error = %v, want %v", file, err, ErrInvalidCronFile)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	if _, err := (Cron{}).Parse("x", "present", map[string]interface{}{"command": "true"}); !errors.Is(err, ingredients.ErrMissingName) {
+		t.Errorf("error = %v, want %v", err, ingredients.ErrMissingName)
+	}
+	if _, err := (Cron{}).Parse("x", "present", map[string]interface{}{"name": "x"}); err == nil {
+		t.Error("expected error for missing command")
+	}
+	if _, err := (Cron{}).Parse("x", "reboot", map[string]interface{}{"name": "x"}); err == nil {
+		t.Error("expected error for undefined method")
+	}
+	_, methods := Cron{}.Methods()
+	for _, m := range methods {
+		if _, err := (Cron{}).PropertiesForMethod(m); err != nil {
+			t.Errorf("PropertiesForMethod(%s): %v", m, err)
+		}
+	}
+	res, err := Cron{method: "reboot"}.Test(context.Background())
+	if !errors.Is(err, ErrCronMethodUndefined) || !res.Failed {
+		t.Errorf("Test() = %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,209 @@
+package cron
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// markerPrefix starts the comment line above each managed job. Comment
+// lines between the marker and the job belong to the entry.
+const markerPrefix = "# grlx-managed: "
+
+// cronDir is where cron_file entries are written; replaceable in tests.
+var cronDir = "/etc/cron.d"
+
+// Function variables for the crontab binary — replaceable in tests.
+var (
+	readUserCrontab = func(ctx context.Context, user string) (string, error) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "crontab", "-l", "-u", user)
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			if strings.Contains(stderr.String(), "no crontab for") {
+				return "", nil
+			}
+			return "", fmt.Errorf("crontab -l -u %s: %w: %s", user, err, strings.TrimSpace(stderr.String()))
+		}
+		return stdout.String(), nil
+	}
+	writeUserCrontab = func(ctx context.Context, user, content string) error {
+		cmd := exec.CommandContext(ctx, "crontab", "-u", user, "-")
+		cmd.Stdin = strings.NewReader(content)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("crontab -u %s: %w: %s", user, err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+)
+
+var (
+	cronFileRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	envNameRe  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	envLineRe  = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)\s*=`)
+	specials   = map[string]bool{
+		"@reboot": true, "@yearly": true, "@annually": true, "@monthly": true,
+		"@weekly": true, "@daily": true, "@midnight": true, "@hourly": true,
+	}
+)
+
+// crontab is either a user's crontab or a file in cronDir. Lines of a
+// cron.d file name the user a job runs as; lines of a user crontab don't.
+type crontab struct {
+	user string
+	file string
+}
+
+// table returns the crontab the step edits.
+func (c Cron) table() (crontab, error) {
+	user, _ := c.params["user"].(string)
+	if user == "" {
+		user = "root"
+	}
+	file, _ := c.params["cron_file"].(string)
+	if file != "" && !cronFileRe.MatchString(file) {
+		return crontab{}, errors.Join(ErrInvalidCronFile, fmt.Errorf("got %q", file))
+	}
+	if strings.ContainsAny(user, " \t\n") {
+		return crontab{}, errors.Join(ErrInvalidField, fmt.Errorf("user %q", user))
+	}
+	return crontab{user: user, file: file}, nil
+}
+
+func (t crontab) String() string {
+	if t.file != "" {
+		return filepath.Join(cronDir, t.file)
+	}
+	return "crontab of " + t.user
+}
+
+func (t crontab) read(ctx context.Context) (string, error) {
+	if t.file == "" {
+		return readUserCrontab(ctx, t.user)
+	}
+	b, err := os.ReadFile(filepath.Join(cronDir, t.file))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return string(b), err
+}
+
+// write replaces the crontab with content. A cron.d file left with
+// nothing in it is removed.
+func (t crontab) write(ctx context.Context, content string) error {
+	if t.file == "" {
+		return writeUserCrontab(ctx, t.user, content)
+	}
+	name := filepath.Join(cronDir, t.file)
+	if strings.TrimSpace(content) == "" {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(cronDir, 0o755); err != nil {
+		return err
+	}
+	// cron skips files with a dot in their name, so it never reads the
+	// temporary file.
+	tmp, err := os.CreateTemp(cronDir, "."+t.file+".grlx-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, writeErr := tmp.WriteString(content)
+	chmodErr := tmp.Chmod(0o644)
+	if err = errors.Join(writeErr, chmodErr, tmp.Close()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// splitTable splits crontab content into lines without their newlines.
+func splitTable(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+func joinTable(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// findEntry returns the lines [start, end) holding the managed entry for
+// name: its marker, any comment lines and the job. start is -1 if there
+// is no such entry.
+func findEntry(lines []string, name string) (int, int) {
+	for i, line := range lines {
+		if line != markerPrefix+name {
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && strings.HasPrefix(lines[end], "#") && !strings.HasPrefix(lines[end], markerPrefix) {
+			end++
+		}
+		if end < len(lines) && !strings.HasPrefix(lines[end], markerPrefix) {
+			end++
+		}
+		return i, end
+	}
+	return -1, -1
+}
+
+// setEntry replaces the entry for name with entry, or appends it.
+func setEntry(lines []string, name string, entry []string) []string {
+	start, end := findEntry(lines, name)
+	if start < 0 {
+		return append(append([]string(nil), lines...), entry...)
+	}
+	out := append([]string(nil), lines[:start]...)
+	out = append(out, entry...)
+	return append(out, lines[end:]...)
+}
+
+// removeEntry drops the entry for name, if present.
+func removeEntry(lines []string, name string) []string {
+	start, end := findEntry(lines, name)
+	if start < 0 {
+		return lines
+	}
+	return append(append([]string(nil), lines[:start]...), lines[end:]...)
+}
+
+// setEnv sets the variable name to value, in place if it is already set
+// and otherwise before the first job so it applies to every job.
+func setEnv(lines []string, name, value string) []string {
+	line := name + "=" + value
+	insert := len(lines)
+	for i, l := range lines {
+		if m := envLineRe.FindStringSubmatch(l); m != nil {
+			if m[1] == name {
+				out := append([]string(nil), lines...)
+				out[i] = line
+				return out
+			}
+			continue
+		}
+		trimmed := strings.TrimSpace(l)
+		if insert == len(lines) && trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			insert = i
+		}
+	}
+	// Keep a managed job's marker and comments together with the job.
+	for insert > 0 && insert < len(lines) && strings.HasPrefix(lines[insert-1], "#") {
+		insert--
+	}
+	out := append([]string(nil), lines[:insert]...)
+	out = append(out, line)
+	return append(out, lines[insert:]...)
+}
@@ -0,0 +1,54 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// writeAtomic replaces the existing file name with content by writing a
+// temporary file next to it and renaming it into place, so readers never
+// see a partly written file. The replacement keeps the mode and
+// ownership of the original, and a symlink is followed rather than
+// replaced.
+func writeAtomic(name string, content []byte) error {
+	name, err := filepath.EvalSymlinks(name)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	uid, gid := -1, -1
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		uid, gid = int(st.Uid), int(st.Gid)
+	}
+	return writeReplace(name, content, info.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky), uid, gid)
+}
+
+// writeReplace writes content to a temporary file next to name with the
+// given mode and ownership, then renames it over name. A uid or gid of -1
+// leaves that id as the sprout's own.
+func writeReplace(name string, content []byte, mode os.FileMode, uid, gid int) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".grlx-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %w", name, err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+	_, writeErr := tmp.Write(content)
+	// Chown before chmod: changing the owner clears setuid and setgid.
+	var chownErr error
+	if uid >= 0 || gid >= 0 {
+		chownErr = tmp.Chown(uid, gid)
+	}
+	chmodErr := tmp.Chmod(mode)
+	closeErr := tmp.Close()
+	if err = errors.Join(writeErr, chownErr, chmodErr, closeErr); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return os.Rename(tmpName, name)
+}
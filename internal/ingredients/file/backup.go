@@ -0,0 +1,79 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gogrlx/grlx/v2/internal/config"
+	"github.com/gogrlx/grlx/v2/internal/cook"
+)
+
+// backupTimeFormat is appended to backup file names. It sorts
+// chronologically and contains no characters that need quoting.
+const backupTimeFormat = "20060102T150405.000000000Z"
+
+// backupDir resolves the step's "backup" property. "minion" keeps backups
+// in the sprout's cache directory; anything else must be an absolute path.
+// An empty string means backups are disabled.
+func (f File) backupDir() (string, error) {
+	target, _ := f.params["backup"].(string)
+	switch {
+	case target == "":
+		return "", nil
+	case target == "minion":
+		return filepath.Join(config.CacheDir, "file_backup"), nil
+	case filepath.IsAbs(target):
+		return filepath.Clean(target), nil
+	default:
+		return "", errors.Join(ErrInvalidBackup, fmt.Errorf("got %q", target))
+	}
+}
+
+// backup copies name into the backup directory before it is replaced,
+// mirroring its path and adding a timestamp suffix, e.g.
+// <dir>/etc/hosts_20240102T030405.000000000Z. It does nothing if backups
+// are disabled or the file does not exist yet.
+func (f File) backup(name string, test bool) ([]fmt.Stringer, error) {
+	dir, err := f.backupDir()
+	if err != nil || dir == "" {
+		return nil, err
+	}
+	info, err := os.Stat(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+	dest := filepath.Join(dir, abs) + "_" + time.Now().UTC().Format(backupTimeFormat)
+	if test {
+		return []fmt.Stringer{cook.Snprintf("`%s` would be backed up to `%s`", name, filepath.Dir(dest))}, nil
+	}
+	if err = os.MkdirAll(filepath.Dir(dest), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory for %s: %w", name, err)
+	}
+	src, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s for backup: %w", name, err)
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup of %s: %w", name, err)
+	}
+	_, copyErr := io.Copy(dst, src)
+	closeErr := dst.Close()
+	if err = errors.Join(copyErr, closeErr); err != nil {
+		os.Remove(dest)
+		return nil, fmt.Errorf("failed to back up %s: %w", name, err)
+	}
+	return []fmt.Stringer{cook.Snprintf("backed up `%s` to `%s`", name, dest)}, nil
+}
@@ -0,0 +1,148 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogrlx/grlx/v2/internal/config"
+)
+
+func TestBackupDir(t *testing.T) {
+	origCache := config.CacheDir
+	defer func() { config.CacheDir = origCache }()
+	config.CacheDir = "/var/cache/grlx/sprout"
+
+	tests := []struct {
+		backup   interface{}
+		expected string
+		errorIs  error
+	}{
+		{backup: nil, expected: ""},
+		{backup: "", expected: ""},
+		{backup: "minion", expected: "/var/cache/grlx/sprout/file_backup"},
+		{backup: "/srv/backups/", expected: "/srv/backups"},
+		{backup: "relative/dir", errorIs: ErrInvalidBackup},
+	}
+	for _, tc := range tests {
+		f := File{params: map[string]interface{}{"backup": tc.backup}}
+		got, err := f.backupDir()
+		if tc.errorIs != nil {
+			if !errors.Is(err, tc.errorIs) {
+				t.Errorf("backup %v: expected error %v, got %v", tc.backup, tc.errorIs, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("backup %v: unexpected error %v", tc.backup, err)
+		}
+		if got != tc.expected {
+			t.Errorf("backup %v: expected %q, got %q", tc.backup, tc.expected, got)
+		}
+	}
+}
+
+func TestBackup(t *testing.T) {
+	tempDir := t.TempDir()
+	backupDir := filepath.Join(tempDir, "backups")
+	name := filepath.Join(tempDir, "etc", "app.conf")
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte("old\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f := File{
+		id:     "backup",
+		method: "content",
+		params: map[string]interface{}{
+			"name":   name,
+			"text":   "new",
+			"backup": backupDir,
+		},
+	}
+
+	res, err := f.content(context.Background(), true)
+	if err != nil {
+		t.Fatalf("test mode failed: %v", err)
+	}
+	if !strings.Contains(res.Notes[len(res.Notes)-1].String(), "would be backed up") {
+		t.Errorf("expected a backup note in test mode, got %v", res.Notes)
+	}
+	if _, err = os.Stat(backupDir); !os.IsNotExist(err) {
+		t.Errorf("test mode must not create backups")
+	}
+
+	if _, err = f.content(context.Background(), false); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	matches, err := filepath.Glob(filepath.Join(backupDir, name) + "_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("expected one backup, got %v", matches)
+	}
+	got, err := os.ReadFile(matches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "old\n" {
+		t.Errorf("expected backup to hold the replaced content, got %q", got)
+	}
+	info, err := os.Stat(matches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected backup to keep mode 0600, got %v", info.Mode().Perm())
+	}
+
+	// Nothing changes on a second run, so no new backup is taken.
+	if _, err = f.content(context.Background(), false); err != nil {
+		t.Fatalf("second apply failed: %v", err)
+	}
+	matches, _ = filepath.Glob(filepath.Join(backupDir, name) + "_*")
+	if len(matches) != 1 {
+		t.Errorf("expected unchanged file not to be backed up again, got %v", matches)
+	}
+}
+
+func TestBackupMissingFile(t *testing.T) {
+	tempDir := t.TempDir()
+	f := File{params: map[string]interface{}{"backup": tempDir}}
+	notes, err := f.backup(filepath.Join(tempDir, "nope"), false)
+	if err != nil || len(notes) != 0 {
+		t.Errorf("expected no backup for a missing file, got %v, %v", notes, err)
+	}
+}
+
+func TestBackupInvalid(t *testing.T) {
+	tempDir := t.TempDir()
+	name := filepath.Join(tempDir, "hosts")
+	if err := os.WriteFile(name, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f := File{
+		id:     "backup-invalid",
+		method: "prepend",
+		params: map[string]interface{}{
+			"name":   name,
+			"text":   "new",
+			"backup": "backups",
+		},
+	}
+	res, err := f.prepend(context.Background(), false)
+	if !errors.Is(err, ErrInvalidBackup) {
+		t.Errorf("expected %v, got %v", ErrInvalidBackup, err)
+	}
+	if !res.Failed {
+		t.Error("expected the step to fail")
+	}
+	if got, _ := os.ReadFile(name); string(got) != "old\n" {
+		t.Errorf("file must not be modified when the backup fails, got %q", got)
+	}
+}
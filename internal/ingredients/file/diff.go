@@ -0,0 +1,258 @@
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gogrlx/grlx/v2/internal/cook"
+)
+
+const (
+	// maxDiffBytes is the largest file (before or after) that show_changes
+	// will diff. Larger files only get a note saying they changed.
+	maxDiffBytes = 1 << 20
+	// maxDiffCells bounds the LCS table; beyond it the differing region is
+	// reported as a single replacement.
+	maxDiffCells = 1 << 22
+	// diffContext is the number of unchanged lines around each hunk.
+	diffContext = 3
+	// binarySniffLen is how much of a file is checked for NUL bytes.
+	binarySniffLen = 8000
+)
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// showChanges reports whether the step asked for diffs in its notes.
+func (f File) showChanges() bool {
+	show, _ := f.params["show_changes"].(bool)
+	return show
+}
+
+// diffNotes returns a note holding the unified diff from before to after
+// when show_changes is set. Binary and oversized files get a short note
+// instead of a diff.
+func (f File) diffNotes(name string, before, after []byte) []fmt.Stringer {
+	if !f.showChanges() || bytes.Equal(before, after) {
+		return nil
+	}
+	if isBinary(before) || isBinary(after) {
+		return []fmt.Stringer{cook.Snprintf("binary file `%s` differs", name)}
+	}
+	if len(before) > maxDiffBytes || len(after) > maxDiffBytes {
+		return []fmt.Stringer{cook.Snprintf("diff for `%s` suppressed: file is larger than %d bytes", name, maxDiffBytes)}
+	}
+	return []fmt.Stringer{cook.SimpleNote(unifiedDiff(name, before, after))}
+}
+
+// isBinary uses the same heuristic as git: a NUL byte near the start of
+// the file, or content that is not valid UTF-8.
+func isBinary(b []byte) bool {
+	sniff := b
+	if len(sniff) > binarySniffLen {
+		sniff = sniff[:binarySniffLen]
+	}
+	return bytes.IndexByte(sniff, 0) >= 0 || !utf8.Valid(b)
+}
+
+// splitLines splits b into lines, keeping each line's trailing newline so
+// that a missing newline at end of file shows up in the diff.
+func splitLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// UnifiedDiff renders the difference between before and after in unified
+// diff format, for ingredients that edit files of their own.
+func UnifiedDiff(name string, before, after []byte) string {
+	return unifiedDiff(name, before, after)
+}
+
+// unifiedDiff renders the difference between before and after in unified
+// diff format with diffContext lines of context.
+func unifiedDiff(name string, before, after []byte) string {
+	ops := diffLines(splitLines(before), splitLines(after))
+
+	// aPos[k] and bPos[k] are the number of lines of before and after
+	// consumed by ops[:k].
+	aPos := make([]int, len(ops)+1)
+	bPos := make([]int, len(ops)+1)
+	for k, op := range ops {
+		aPos[k+1], bPos[k+1] = aPos[k], bPos[k]
+		if op.kind != '+' {
+			aPos[k+1]++
+		}
+		if op.kind != '-' {
+			bPos[k+1]++
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", name, name)
+	prevEnd := 0
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		start := max(i-diffContext, prevEnd)
+		end := i
+		for {
+			for end < len(ops) && ops[end].kind != ' ' {
+				end++
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next < len(ops) && next-end <= 2*diffContext {
+				end = next
+				continue
+			}
+			end = min(end+diffContext, len(ops))
+			break
+		}
+		writeHunk(&out, ops[start:end], aPos[start], aPos[end]-aPos[start], bPos[start], bPos[end]-bPos[start])
+		prevEnd, i = end, end
+	}
+	return out.String()
+}
+
+func writeHunk(out *strings.Builder, ops []diffOp, aStart, aCount, bStart, bCount int) {
+	// Empty ranges point at the line before them, as in GNU diff.
+	if aCount > 0 {
+		aStart++
+	}
+	if bCount > 0 {
+		bStart++
+	}
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+	for _, op := range ops {
+		out.WriteByte(op.kind)
+		out.WriteString(op.line)
+		if !strings.HasSuffix(op.line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// diffLines returns an edit script turning a into b. Common prefixes and
+// suffixes are stripped first so typical edits stay cheap.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, lcsDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+func lcsDiff(a, b []string) []diffOp {
+	var ops []diffOp
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// maxChangedLineNotes bounds how many lines changedLineNotes lists.
+const maxChangedLineNotes = 50
+
+// changedLineNotes returns a note for each line removed from or added to
+// name between before and after, numbered as in before and after
+// respectively.
+func changedLineNotes(name string, before, after []byte) []fmt.Stringer {
+	var notes []fmt.Stringer
+	ops := diffLines(splitLines(before), splitLines(after))
+	aLine, bLine, changed := 0, 0, 0
+	for _, op := range ops {
+		switch op.kind {
+		case ' ':
+			aLine++
+			bLine++
+			continue
+		case '-':
+			aLine++
+		case '+':
+			bLine++
+		}
+		changed++
+		if changed > maxChangedLineNotes {
+			continue
+		}
+		line := strings.TrimSuffix(op.line, "\n")
+		if op.kind == '-' {
+			notes = append(notes, cook.Snprintf("`%s` line %d removed: %s", name, aLine, line))
+		} else {
+			notes = append(notes, cook.Snprintf("`%s` line %d added: %s", name, bLine, line))
+		}
+	}
+	if changed > maxChangedLineNotes {
+		notes = append(notes, cook.Snprintf("`%s`: %d more changed lines not listed", name, changed-maxChangedLineNotes))
+	}
+	return notes
+}
@@ -0,0 +1,228 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   string
+		after    string
+		expected string
+	}{
+		{
+			name:   "create",
+			before: "",
+			after:  "a\nb\n",
+			expected: "--- f\n+++ f\n" +
+				"@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name:   "delete",
+			before: "a\nb\n",
+			after:  "",
+			expected: "--- f\n+++ f\n" +
+				"@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name:   "change with context",
+			before: "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			after:  "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			expected: "--- f\n+++ f\n" +
+				"@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name:   "separate hunks",
+			before: "a\n1\n2\n3\n4\n5\n6\n7\n8\nb\n",
+			after:  "A\n1\n2\n3\n4\n5\n6\n7\n8\nB\n",
+			expected: "--- f\n+++ f\n" +
+				"@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n 3\n" +
+				"@@ -7,4 +7,4 @@\n 6\n 7\n 8\n-b\n+B\n",
+		},
+		{
+			name:   "merged hunks",
+			before: "a\n1\n2\nb\n",
+			after:  "A\n1\n2\nB\n",
+			expected: "--- f\n+++ f\n" +
+				"@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n-b\n+B\n",
+		},
+		{
+			name:   "append line",
+			before: "a\n",
+			after:  "a\nb\n",
+			expected: "--- f\n+++ f\n" +
+				"@@ -1,1 +1,2 @@\n a\n+b\n",
+		},
+		{
+			name:   "missing newline",
+			before: "a\nb",
+			after:  "a\nb\n",
+			expected: "--- f\n+++ f\n" +
+				"@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := unifiedDiff("f", []byte(tc.before), []byte(tc.after))
+			if got != tc.expected {
+				t.Errorf("unexpected diff:\n%s\nexpected:\n%s", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestDiffNotes(t *testing.T) {
+	show := File{params: map[string]interface{}{"show_changes": true}}
+	hide := File{params: map[string]interface{}{}}
+
+	if notes := hide.diffNotes("f", []byte("a\n"), []byte("b\n")); len(notes) != 0 {
+		t.Errorf("expected no notes without show_changes, got %v", notes)
+	}
+	if notes := show.diffNotes("f", []byte("a\n"), []byte("a\n")); len(notes) != 0 {
+		t.Errorf("expected no notes for identical content, got %v", notes)
+	}
+	notes := show.diffNotes("f", []byte("a\n"), []byte("b\n"))
+	if len(notes) != 1 || !strings.Contains(notes[0].String(), "-a\n+b\n") {
+		t.Errorf("expected a diff note, got %v", notes)
+	}
+	notes = show.diffNotes("f", []byte("a\n"), []byte{0x7f, 'E', 'L', 'F', 0})
+	if len(notes) != 1 || notes[0].String() != "binary file `f` differs" {
+		t.Errorf("expected a binary note, got %v", notes)
+	}
+	large := bytes.Repeat([]byte("x\n"), maxDiffBytes)
+	notes = show.diffNotes("f", []byte("a\n"), large)
+	if len(notes) != 1 || !strings.Contains(notes[0].String(), "suppressed") {
+		t.Errorf("expected a size limit note, got %v", notes)
+	}
+}
+
+func TestLCSDiffFallback(t *testing.T) {
+	// Past maxDiffCells the whole region is replaced rather than diffed.
+	n := 1<<11 + 1
+	a := make([]string, n)
+	b := make([]string, n)
+	for i := range a {
+		a[i] = fmt.Sprintf("a%d\n", i)
+		b[i] = fmt.Sprintf("b%d\n", i)
+	}
+	ops := lcsDiff(a, b)
+	if len(ops) != 2*n || ops[0].kind != '-' || ops[n].kind != '+' {
+		t.Errorf("expected %d removals followed by %d additions", n, n)
+	}
+}
+
+func TestShowChanges(t *testing.T) {
+	tempDir := t.TempDir()
+	name := filepath.Join(tempDir, "motd")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hasDiff := func(t *testing.T, notes []fmt.Stringer, want string) {
+		t.Helper()
+		for _, note := range notes {
+			if strings.HasPrefix(note.String(), "--- "+name) && strings.Contains(note.String(), want) {
+				return
+			}
+		}
+		t.Errorf("expected a diff containing %q, got %v", want, notes)
+	}
+
+	tests := []struct {
+		method string
+		text   string
+		run    func(File, bool) ([]fmt.Stringer, error)
+		want   string
+	}{
+		{
+			method: "content",
+			text:   "new",
+			run: func(f File, test bool) ([]fmt.Stringer, error) {
+				res, err := f.content(context.Background(), test)
+				return res.Notes, err
+			},
+			want: "-old\n+new\n",
+		},
+		{
+			method: "append",
+			text:   "new",
+			run: func(f File, test bool) ([]fmt.Stringer, error) {
+				res, err := f.append(context.Background(), test)
+				return res.Notes, err
+			},
+			want: " old\n+new\n",
+		},
+		{
+			method: "prepend",
+			text:   "new",
+			run: func(f File, test bool) ([]fmt.Stringer, error) {
+				res, err := f.prepend(context.Background(), test)
+				return res.Notes, err
+			},
+			want: "+new\n old\n",
+		},
+		{
+			method: "contains",
+			text:   "new",
+			run: func(f File, test bool) ([]fmt.Stringer, error) {
+				res, err := f.checkContains(context.Background(), test)
+				if err == ErrMissingContent {
+					err = nil
+				}
+				return res.Notes, err
+			},
+			want: " old\n+new\n",
+		},
+	}
+	for _, tc := range tests {
+		for _, test := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s/test=%v", tc.method, test), func(t *testing.T) {
+				write("old\n")
+				f := File{
+					id:     tc.method,
+					method: tc.method,
+					params: map[string]interface{}{
+						"name":         name,
+						"text":         tc.text,
+						"show_changes": true,
+					},
+				}
+				notes, err := tc.run(f, test)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				hasDiff(t, notes, tc.want)
+			})
+		}
+	}
+}
+
+func TestChangedLineNotes(t *testing.T) {
+	notes := changedLineNotes("f", []byte("a\nb\nc\n"), []byte("a\nB\nc\nd\n"))
+	var got []string
+	for _, n := range notes {
+		got = append(got, n.String())
+	}
+	want := []string{"`f` line 2 removed: b", "`f` line 2 added: B", "`f` line 4 added: d"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("changedLineNotes() = %q, want %q", got, want)
+	}
+
+	var many strings.Builder
+	for i := range maxChangedLineNotes + 5 {
+		fmt.Fprintf(&many, "%d\n", i)
+	}
+	notes = changedLineNotes("f", nil, []byte(many.String()))
+	if len(notes) != maxChangedLineNotes+1 || !strings.Contains(notes[maxChangedLineNotes].String(), "5 more") {
+		t.Errorf("expected %d notes ending with a summary, got %d", maxChangedLineNotes+1, len(notes))
+	}
+}
@@ -86,17 +86,26 @@ func (f File) Test(ctx context.Context) (cook.Result, error) {
 		return f.missing(ctx, true)
 	case "prepend":
 		return f.prepend(ctx, true)
+	case "recurse":
+		return f.recurse(ctx, true)
+	case "replace":
+		return f.replace(ctx, true)
 	case "touch":
 		return f.touch(ctx, true)
 	case "cached":
 		return f.cached(ctx, true)
 	case "contains":
-		res, _, err := f.contains(ctx, true)
-		return res, err
+		return f.checkContains(ctx, true)
 	case "content":
 		return f.content(ctx, true)
+	case "line":
+		return f.line(ctx, true)
+	case "keys_present":
+		return f.keysPresent(ctx, true)
 	case "managed":
 		return f.managed(ctx, true)
+	case "serialize":
+		return f.serialize(ctx, true)
 	case "symlink":
 		return f.symlink(ctx, true)
 	default:
@@ -161,17 +170,26 @@ func (f File) Apply(ctx context.Context) (cook.Result, error) {
 		return f.missing(ctx, false)
 	case "prepend":
 		return f.prepend(ctx, false)
+	case "recurse":
+		return f.recurse(ctx, false)
+	case "replace":
+		return f.replace(ctx, false)
 	case "touch":
 		return f.touch(ctx, false)
 	case "cached":
 		return f.cached(ctx, false)
 	case "contains":
-		res, _, err := f.contains(ctx, false)
-		return res, err
+		return f.checkContains(ctx, false)
 	case "content":
 		return f.content(ctx, false)
+	case "line":
+		return f.line(ctx, false)
+	case "keys_present":
+		return f.keysPresent(ctx, false)
 	case "managed":
 		return f.managed(ctx, false)
+	case "serialize":
+		return f.serialize(ctx, false)
 	case "symlink":
 		return f.symlink(ctx, false)
 	default:
@@ -199,6 +217,8 @@ func (f File) PropertiesForMethod(method string) (map[string]string, error) {
 			ingredients.MethodProps{Key: "sources", Type: "[]string", IsReq: false, Description: "source, but in list format"},
 			ingredients.MethodProps{Key: "template", Type: "bool", IsReq: false, Description: "whether to render the file as a template before appending (experimental)"},
 			ingredients.MethodProps{Key: "text", Type: "[]string", IsReq: false, Description: "the text to append to the file"},
+			ingredients.MethodProps{Key: "backup", Type: "string", IsReq: false, Description: "`minion` or an absolute directory to keep timestamped copies of replaced files"},
+			ingredients.MethodProps{Key: "show_changes", Type: "bool", IsReq: false, Description: "include a unified diff of the changes in the step notes"},
 		}.ToMap(), nil
 	case "cached":
 		return ingredients.MethodPropsSet{
@@ -215,7 +235,9 @@ func (f File) PropertiesForMethod(method string) (map[string]string, error) {
 			ingredients.MethodProps{Key: "source_hashes", Type: "[]string", IsReq: false},
 			ingredients.MethodProps{Key: "sources", Type: "[]string", IsReq: false},
 			ingredients.MethodProps{Key: "template", Type: "bool", IsReq: false},
+			ingredients.MethodProps{Key: "context", Type: "map", IsReq: false},
 			ingredients.MethodProps{Key: "text", Type: "[]string", IsReq: false},
+			ingredients.MethodProps{Key: "show_changes", Type: "bool", IsReq: false, Description: "include a unified diff of the changes in the step notes"},
 		}.ToMap(), nil
 	case "content":
 		return ingredients.MethodPropsSet{
@@ -225,8 +247,11 @@ func (f File) PropertiesForMethod(method string) (map[string]string, error) {
 			ingredients.MethodProps{Key: "source", Type: "string", IsReq: false},
 			ingredients.MethodProps{Key: "source_hash", Type: "string", IsReq: false},
 			ingredients.MethodProps{Key: "template", Type: "bool", IsReq: false},
+			ingredients.MethodProps{Key: "context", Type: "map", IsReq: false},
 			ingredients.MethodProps{Key: "sources", Type: "[]string", IsReq: false},
 			ingredients.MethodProps{Key: "source_hashes", Type: "[]string", IsReq: false},
+			ingredients.MethodProps{Key: "backup", Type: "string", IsReq: false, Description: "`minion` or an absolute directory to keep timestamped copies of replaced files"},
+			ingredients.MethodProps{Key: "show_changes", Type: "bool", IsReq: false, Description: "include a unified diff of the changes in the step notes"},
 		}.ToMap(), nil
 	case "directory":
 		return ingredients.MethodPropsSet{
@@ -238,6 +263,26 @@ func (f File) PropertiesForMethod(method string) (map[string]string, error) {
 			ingredients.MethodProps{Key: "file_mode", Type: "string", IsReq: false},
 			ingredients.MethodProps{Key: "makedirs", Type: "bool", IsReq: false},
 		}.ToMap(), nil
+	case "keys_present":
+		return ingredients.MethodPropsSet{
+			ingredients.MethodProps{Key: "name", Type: "string", IsReq: true, Description: "the name/path of the json, yaml, toml or ini file to edit"},
+			ingredients.MethodProps{Key: "keys", Type: "map", IsReq: false, Description: "values to set at dotted key paths; mapping values are merged key by key"},
+			ingredients.MethodProps{Key: "absent", Type: "[]string", IsReq: false, Description: "dotted key paths to remove"},
+			ingredients.MethodProps{Key: "format", Type: "string", IsReq: false, Description: "`json`, `yaml`, `toml` or `ini`; guessed from a `.json`, `.yaml`, `.yml`, `.toml` or `.ini` extension by default"},
+			ingredients.MethodProps{Key: "backup", Type: "string", IsReq: false, Description: "`minion` or an absolute directory to keep timestamped copies of replaced files"},
+			ingredients.MethodProps{Key: "show_changes", Type: "bool", IsReq: false, Description: "include a unified diff of the changes in the step notes"},
+		}.ToMap(), nil
+	case "line":
+		return ingredients.MethodPropsSet{
+			ingredients.MethodProps{Key: "name", Type: "string", IsReq: true, Description: "the name/path of the file to edit"},
+			ingredients.MethodProps{Key: "content", Type: "string", IsReq: false, Description: "the line to ensure or replace matching lines with"},
+			ingredients.MethodProps{Key: "match", Type: "string", IsReq: false, Description: "regular expression selecting the lines to act on; defaults to lines equal to content"},
+			ingredients.MethodProps{Key: "mode", Type: "string", IsReq: false, Description: "`ensure` (default), `replace` or `delete`"},
+			ingredients.MethodProps{Key: "after", Type: "string", IsReq: false, Description: "only act on lines after the first line matching this regular expression"},
+			ingredients.MethodProps{Key: "before", Type: "string", IsReq: false, Description: "only act on lines before the next line matching this regular expression"},
+			ingredients.MethodProps{Key: "backup", Type: "string", IsReq: false, Description: "`minion` or an absolute directory to keep timestamped copies of replaced files"},
+			ingredients.MethodProps{Key: "show_changes", Type: "bool", IsReq: false, Description: "include a unified diff of the changes in the step notes"},
+		}.ToMap(), nil
 	case "managed":
 		return ingredients.MethodPropsSet{
 			ingredients.MethodProps{Key: "name", Type: "string", IsReq: true},
@@ -247,10 +292,13 @@ func (f File) PropertiesForMethod(method string) (map[string]string, error) {
 			ingredients.MethodProps{Key: "group", Type: "string", IsReq: false},
 			ingredients.MethodProps{Key: "mode", Type: "string", IsReq: false},
 			ingredients.MethodProps{Key: "template", Type: "bool", IsReq: false},
+			ingredients.MethodProps{Key: "context", Type: "map", IsReq: false},
 			ingredients.MethodProps{Key: "makedirs", Type: "bool", IsReq: false},
 			ingredients.MethodProps{Key: "dir_mode", Type: "string", IsReq: false},
 			ingredients.MethodProps{Key: "sources", Type: "[]string", IsReq: false},
 			ingredients.MethodProps{Key: "source_hashes", Type: "[]string", IsReq: false},
+			ingredients.MethodProps{Key: "backup", Type: "string", IsReq: false, Description: "`minion` or an absolute directory to keep timestamped copies of replaced files"},
+			ingredients.MethodProps{Key: "show_changes", Type: "bool", IsReq: false, Description: "include a unified diff of the changes in the step notes"},
 		}.ToMap(), nil
 	case "missing":
 		return ingredients.MethodPropsSet{
@@ -264,8 +312,48 @@ func (f File) PropertiesForMethod(method string) (map[string]string, error) {
 			ingredients.MethodProps{Key: "source", Type: "string", IsReq: false},
 			ingredients.MethodProps{Key: "source_hash", Type: "string", IsReq: false},
 			ingredients.MethodProps{Key: "template", Type: "bool", IsReq: false},
+			ingredients.MethodProps{Key: "context", Type: "map", IsReq: false},
 			ingredients.MethodProps{Key: "sources", Type: "[]string", IsReq: false},
 			ingredients.MethodProps{Key: "source_hashes", Type: "[]string", IsReq: false},
+			ingredients.MethodProps{Key: "backup", Type: "string", IsReq: false, Description: "`minion` or an absolute directory to keep timestamped copies of replaced files"},
+			ingredients.MethodProps{Key: "show_changes", Type: "bool", IsReq: false, Description: "include a unified diff of the changes in the step notes"},
+		}.ToMap(), nil
+	case "recurse":
+		return ingredients.MethodPropsSet{
+			ingredients.MethodProps{Key: "name", Type: "string", IsReq: true, Description: "the directory to mirror the source into"},
+			ingredients.MethodProps{Key: "source", Type: "string", IsReq: true, Description: "the source directory, e.g. grlx://configs/app"},
+			ingredients.MethodProps{Key: "include", Type: "[]string", IsReq: false, Description: "only mirror files matching these globs; globs without a / match base names"},
+			ingredients.MethodProps{Key: "exclude", Type: "[]string", IsReq: false, Description: "skip files matching these globs; excluded files are never removed by clean"},
+			ingredients.MethodProps{Key: "clean", Type: "bool", IsReq: false, Description: "remove files and directories under name that are not in the source"},
+			ingredients.MethodProps{Key: "template", Type: "bool", IsReq: false, Description: "render each file as a template"},
+			ingredients.MethodProps{Key: "context", Type: "map", IsReq: false},
+			ingredients.MethodProps{Key: "dir_mode", Type: "string", IsReq: false, Description: "mode for directories; defaults to 0755 for new directories"},
+			ingredients.MethodProps{Key: "file_mode", Type: "string", IsReq: false, Description: "mode for files; defaults to the mode of the source file"},
+			ingredients.MethodProps{Key: "user", Type: "string", IsReq: false},
+			ingredients.MethodProps{Key: "group", Type: "string", IsReq: false},
+			ingredients.MethodProps{Key: "makedirs", Type: "bool", IsReq: false},
+		}.ToMap(), nil
+	case "replace":
+		return ingredients.MethodPropsSet{
+			ingredients.MethodProps{Key: "name", Type: "string", IsReq: true, Description: "the name/path of the file to edit"},
+			ingredients.MethodProps{Key: "pattern", Type: "string", IsReq: true, Description: "regular expression to search for; ^ and $ match at line boundaries"},
+			ingredients.MethodProps{Key: "repl", Type: "string", IsReq: true, Description: "the replacement, which may refer to submatches as $1 or ${name}"},
+			ingredients.MethodProps{Key: "count", Type: "string", IsReq: false, Description: "replace at most this many matches; 0 (default) replaces all"},
+			ingredients.MethodProps{Key: "append_if_not_found", Type: "bool", IsReq: false, Description: "append not_found_content if pattern does not match"},
+			ingredients.MethodProps{Key: "not_found_content", Type: "string", IsReq: false, Description: "content to append when pattern does not match; defaults to repl"},
+			ingredients.MethodProps{Key: "backup", Type: "string", IsReq: false, Description: "`minion` or an absolute directory to keep timestamped copies of replaced files"},
+			ingredients.MethodProps{Key: "show_changes", Type: "bool", IsReq: false, Description: "include a unified diff of the changes in the step notes"},
+		}.ToMap(), nil
+	case "serialize":
+		return ingredients.MethodPropsSet{
+			ingredients.MethodProps{Key: "name", Type: "string", IsReq: true, Description: "the name/path of the file to write"},
+			ingredients.MethodProps{Key: "dataset", Type: "map", IsReq: true, Description: "the data to serialize"},
+			ingredients.MethodProps{Key: "format", Type: "string", IsReq: false, Description: "`json`, `yaml` or `toml`; guessed from the file extension by default"},
+			ingredients.MethodProps{Key: "merge_if_exists", Type: "bool", IsReq: false, Description: "merge dataset into the data already in the file"},
+			ingredients.MethodProps{Key: "makedirs", Type: "bool", IsReq: false},
+			ingredients.MethodProps{Key: "mode", Type: "string", IsReq: false, Description: "mode for a new file; defaults to 0644"},
+			ingredients.MethodProps{Key: "backup", Type: "string", IsReq: false, Description: "`minion` or an absolute directory to keep timestamped copies of replaced files"},
+			ingredients.MethodProps{Key: "show_changes", Type: "bool", IsReq: false, Description: "include a unified diff of the changes in the step notes"},
 		}.ToMap(), nil
 	case "exists":
 		return ingredients.MethodPropsSet{
@@ -300,9 +388,14 @@ func (f File) Methods() (string, []string) {
 		"contains",
 		"content",
 		"directory",
+		"keys_present",
+		"line",
 		"managed",
 		"missing",
 		"prepend",
+		"recurse",
+		"replace",
+		"serialize",
 		"exists",
 		"symlink",
 		"touch",
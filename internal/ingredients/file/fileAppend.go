@@ -2,6 +2,7 @@ package file
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -12,6 +13,17 @@ import (
 	"github.com/gogrlx/grlx/v2/internal/ingredients"
 )
 
+// appendedLines returns content as newline-terminated lines, which is how
+// append writes it to an existing file.
+func appendedLines(content *bytes.Buffer) []byte {
+	var lines bytes.Buffer
+	scanner := bufio.NewScanner(content)
+	for scanner.Scan() {
+		lines.WriteString(scanner.Text() + "\n")
+	}
+	return lines.Bytes()
+}
+
 func (f File) append(ctx context.Context, test bool) (cook.Result, error) {
 	var notes []fmt.Stringer
 	name, ok := f.params["name"].(string)
@@ -71,12 +83,14 @@ func (f File) append(ctx context.Context, test bool) (cook.Result, error) {
 
 		if test {
 			notes = append(notes, cook.Snprintf("file `%s` would be created with appended content", name))
+			notes = append(notes, f.diffNotes(name, nil, missing.Bytes())...)
 			return cook.Result{
 				Succeeded: true, Failed: false,
 				Changed: true, Notes: notes,
 			}, nil
 		}
 
+		added := bytes.Clone(missing.Bytes())
 		newFile, createErr := os.Create(name)
 		if createErr != nil {
 			return cook.Result{
@@ -91,6 +105,7 @@ func (f File) append(ctx context.Context, test bool) (cook.Result, error) {
 			}, fmt.Errorf("failed to write to %s: %w", name, writeErr)
 		}
 		notes = append(notes, cook.Snprintf("created and appended to %s", name))
+		notes = append(notes, f.diffNotes(name, nil, added)...)
 		return cook.Result{
 			Succeeded: true, Failed: false,
 			Changed: true, Notes: notes,
@@ -98,8 +113,23 @@ func (f File) append(ctx context.Context, test bool) (cook.Result, error) {
 	}
 
 	if errors.Is(err, ErrMissingContent) {
+		added := bytes.NewBuffer(appendedLines(&missing))
+		var existing []byte
+		if f.showChanges() {
+			existing, _ = os.ReadFile(name)
+		}
+		diffNotes := f.diffNotes(name, existing, append(bytes.Clone(existing), added.Bytes()...))
+
+		backupNotes, backupErr := f.backup(name, test)
+		notes = append(notes, backupNotes...)
+		if backupErr != nil {
+			return cook.Result{
+				Succeeded: false, Failed: true, Notes: notes,
+			}, backupErr
+		}
 		if test {
 			notes = append(notes, cook.Snprintf("content would be appended to `%s`", name))
+			notes = append(notes, diffNotes...)
 			return cook.Result{
 				Succeeded: true, Failed: false,
 				Changed: true, Notes: notes,
@@ -113,17 +143,13 @@ func (f File) append(ctx context.Context, test bool) (cook.Result, error) {
 			}, fmt.Errorf("failed to open %s for appending: %w", name, openErr)
 		}
 		defer appendFile.Close()
-		scanner := bufio.NewScanner(&missing)
-		for scanner.Scan() {
-			line := scanner.Text()
-			_, writeErr := appendFile.WriteString(line + "\n")
-			if writeErr != nil {
-				return cook.Result{
-					Succeeded: false, Failed: true, Notes: notes,
-				}, writeErr
-			}
+		if _, writeErr := added.WriteTo(appendFile); writeErr != nil {
+			return cook.Result{
+				Succeeded: false, Failed: true, Notes: notes,
+			}, writeErr
 		}
 		notes = append(notes, cook.Snprintf("appended to %s", name))
+		notes = append(notes, diffNotes...)
 		return cook.Result{
 			Succeeded: true, Failed: false,
 			Changed: true, Notes: notes,
@@ -99,3 +99,9 @@ func (f File) cached(ctx context.Context, test bool) (cook.Result, error) {
 		Changed: false, Notes: notes,
 	}, nil
 }
+
+// CachePath returns the path the cached method keeps its file at, so
+// other ingredients can read a source they cached through it.
+func (f File) CachePath() (string, error) {
+	return f.dest()
+}
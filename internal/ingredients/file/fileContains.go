@@ -68,6 +68,18 @@ func (f File) contains(ctx context.Context, test bool) (cook.Result, bytes.Buffe
 		}, content, err
 	}
 
+	if f.isTemplate() {
+		rendered, renderErr := f.render(name, content.Bytes())
+		if renderErr != nil {
+			return cook.Result{
+				Succeeded: false, Failed: true,
+				Changed: false, Notes: notes,
+			}, content, renderErr
+		}
+		content.Reset()
+		content.Write(rendered)
+	}
+
 	// Read current file contents.
 	file, err := os.Open(name)
 	if err != nil {
@@ -86,7 +98,8 @@ func (f File) contains(ctx context.Context, test bool) (cook.Result, bytes.Buffe
 	sort.Strings(currentContents)
 
 	shouldContents := []string{}
-	scanner = bufio.NewScanner(&content)
+	// Scan a copy so content is still intact for callers that write it.
+	scanner = bufio.NewScanner(bytes.NewReader(content.Bytes()))
 	for scanner.Scan() {
 		shouldContents = append(shouldContents, scanner.Text())
 	}
@@ -105,6 +118,24 @@ func (f File) contains(ctx context.Context, test bool) (cook.Result, bytes.Buffe
 	}, content, ErrMissingContent
 }
 
+// checkContains implements the file.contains method. When show_changes is
+// set and content is missing, the diff shows the lines file.append would
+// add.
+func (f File) checkContains(ctx context.Context, test bool) (cook.Result, error) {
+	res, missing, err := f.contains(ctx, test)
+	if !errors.Is(err, ErrMissingContent) || !f.showChanges() {
+		return res, err
+	}
+	name := filepath.Clean(f.params["name"].(string))
+	existing, readErr := os.ReadFile(name)
+	if readErr != nil {
+		return res, err
+	}
+	after := append(bytes.Clone(existing), appendedLines(&missing)...)
+	res.Notes = append(res.Notes, f.diffNotes(name, existing, after)...)
+	return res, err
+}
+
 // gatherSourceBuf collects content from a single "source"/"source_hash" param pair into buf.
 func (f File) gatherSourceBuf(ctx context.Context, buf *bytes.Buffer, name string, skipVerify bool) ([]fmt.Stringer, error) {
 	var notes []fmt.Stringer
@@ -95,7 +95,13 @@ func (f File) content(ctx context.Context, test bool) (cook.Result, error) {
 		}, err
 	}
 
-	desiredBytes := desired.Bytes()
+	desiredBytes, err := f.render(name, desired.Bytes())
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true,
+			Changed: false, Notes: notes,
+		}, err
+	}
 
 	// Check if file already has the desired content (idempotency).
 	existing, readErr := os.ReadFile(name)
@@ -120,12 +126,30 @@ func (f File) content(ctx context.Context, test bool) (cook.Result, error) {
 		} else {
 			notes = append(notes, cook.Snprintf("file `%s` content would be updated", name))
 		}
+		backupNotes, backupErr := f.backup(name, true)
+		notes = append(notes, backupNotes...)
+		if backupErr != nil {
+			return cook.Result{
+				Succeeded: false, Failed: true,
+				Changed: false, Notes: notes,
+			}, backupErr
+		}
+		notes = append(notes, f.diffNotes(name, existing, desiredBytes)...)
 		return cook.Result{
 			Succeeded: true, Failed: false,
 			Changed: true, Notes: notes,
 		}, nil
 	}
 
+	backupNotes, err := f.backup(name, false)
+	notes = append(notes, backupNotes...)
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true,
+			Changed: false, Notes: notes,
+		}, err
+	}
+
 	// Write the content to the file.
 	if writeErr := os.WriteFile(name, desiredBytes, 0o644); writeErr != nil {
 		return cook.Result{
@@ -139,6 +163,7 @@ func (f File) content(ctx context.Context, test bool) (cook.Result, error) {
 	} else {
 		notes = append(notes, cook.Snprintf("updated content of `%s`", name))
 	}
+	notes = append(notes, f.diffNotes(name, existing, desiredBytes)...)
 
 	return cook.Result{
 		Succeeded: true, Failed: false,
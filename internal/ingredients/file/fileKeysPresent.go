@@ -0,0 +1,248 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/gogrlx/grlx/v2/internal/cook"
+	"github.com/gogrlx/grlx/v2/internal/ingredients"
+)
+
+func (f File) keysPresent(ctx context.Context, test bool) (cook.Result, error) {
+	// Params: "name", "keys", "absent", "format", "backup", "show_changes"
+
+	var notes []fmt.Stringer
+	name, ok := f.params["name"].(string)
+	if !ok || name == "" {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, ingredients.ErrMissingName
+	}
+	name = filepath.Clean(name)
+	if name == "/" {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, ErrModifyRoot
+	}
+	format, err := f.structuredFormat(name, formatJSON, formatYAML, formatTOML, formatINI)
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	keys, _ := f.params["keys"].(map[string]interface{})
+	set, err := flattenKeys(keys, nil)
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	absent, err := f.absentKeys()
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	if len(set) == 0 && len(absent) == 0 {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, ErrMissingKeys
+	}
+
+	before, err := os.ReadFile(name)
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	after, setKeys, deletedKeys, err := editKeys(format, before, set, absent)
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, fmt.Errorf("failed to edit %s as %s: %w", name, format, err)
+	}
+	if len(setKeys) == 0 && len(deletedKeys) == 0 {
+		return cook.Result{
+			Succeeded: true, Failed: false,
+			Changed: false, Notes: notes,
+		}, nil
+	}
+
+	backupNotes, err := f.backup(name, test)
+	notes = append(notes, backupNotes...)
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	if !test {
+		if err = writeAtomic(name, after); err != nil {
+			return cook.Result{
+				Succeeded: false, Failed: true, Notes: notes,
+			}, err
+		}
+	}
+	for _, k := range setKeys {
+		if test {
+			notes = append(notes, cook.Snprintf("key `%s` would be set in `%s`", k, name))
+		} else {
+			notes = append(notes, cook.Snprintf("set key `%s` in `%s`", k, name))
+		}
+	}
+	for _, k := range deletedKeys {
+		if test {
+			notes = append(notes, cook.Snprintf("key `%s` would be removed from `%s`", k, name))
+		} else {
+			notes = append(notes, cook.Snprintf("removed key `%s` from `%s`", k, name))
+		}
+	}
+	notes = append(notes, f.diffNotes(name, before, after)...)
+	return cook.Result{
+		Succeeded: true, Failed: false,
+		Changed: true, Notes: notes,
+	}, nil
+}
+
+// absentKeys reads the dotted key paths to remove.
+func (f File) absentKeys() ([][]string, error) {
+	var keys []string
+	switch v := f.params["absent"].(type) {
+	case nil:
+	case string:
+		keys = []string{v}
+	case []string:
+		keys = v
+	case []interface{}:
+		for _, k := range v {
+			keys = append(keys, fmt.Sprintf("%v", k))
+		}
+	default:
+		return nil, errors.Join(ErrInvalidProperty, errors.New("absent must be a key or a list of keys"))
+	}
+	var paths [][]string
+	for _, k := range sortedPaths(keys) {
+		path, err := splitKeyPath(k)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// editKeys sets and removes keys in content. It returns the edited
+// content and the keys that were set or removed; if none were, the content
+// is returned untouched, so a file is only reformatted when it changes.
+//
+// YAML keeps its comments and key order. JSON keeps its key order and is
+// written with two-space indents. TOML is decoded and encoded again, so
+// comments are lost and keys sorted. ini files are edited line by line.
+func editKeys(format string, content []byte, set []keyValue, absent [][]string) ([]byte, []string, []string, error) {
+	var setKeys, deletedKeys []string
+	switch format {
+	case formatYAML, formatJSON:
+		if format == formatJSON && len(bytes.TrimSpace(content)) > 0 && !json.Valid(content) {
+			return nil, nil, nil, errors.Join(ErrUnparsableContent, errors.New("invalid JSON"))
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(content, &doc); err != nil {
+			return nil, nil, nil, errors.Join(ErrUnparsableContent, err)
+		}
+		root, err := yamlRoot(&doc)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for _, kv := range set {
+			changed, err := yamlSet(root, kv.path, kv.value)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if changed {
+				setKeys = append(setKeys, kv.String())
+			}
+		}
+		for _, path := range absent {
+			if yamlDelete(root, path) {
+				deletedKeys = append(deletedKeys, strings.Join(path, "."))
+			}
+		}
+		if len(setKeys) == 0 && len(deletedKeys) == 0 {
+			return content, nil, nil, nil
+		}
+		var buf bytes.Buffer
+		if format == formatJSON {
+			if err = writeJSONNode(&buf, &doc, ""); err != nil {
+				return nil, nil, nil, err
+			}
+			buf.WriteString("\n")
+			return buf.Bytes(), setKeys, deletedKeys, nil
+		}
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err = enc.Encode(&doc); err != nil {
+			return nil, nil, nil, err
+		}
+		if err = enc.Close(); err != nil {
+			return nil, nil, nil, err
+		}
+		return buf.Bytes(), setKeys, deletedKeys, nil
+	case formatTOML:
+		data, err := decodeDataset(format, content)
+		if err != nil {
+			return nil, nil, nil, errors.Join(ErrUnparsableContent, err)
+		}
+		for _, kv := range set {
+			changed, err := mapSet(data, kv.path, kv.value)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if changed {
+				setKeys = append(setKeys, kv.String())
+			}
+		}
+		for _, path := range absent {
+			if mapDelete(data, path) {
+				deletedKeys = append(deletedKeys, strings.Join(path, "."))
+			}
+		}
+		if len(setKeys) == 0 && len(deletedKeys) == 0 {
+			return content, nil, nil, nil
+		}
+		out, err := encodeDataset(format, data)
+		return out, setKeys, deletedKeys, err
+	case formatINI:
+		lines := splitLines(content)
+		for _, kv := range set {
+			value, err := iniValue(kv)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			section, key := iniPath(kv.path)
+			var changed bool
+			if lines, changed = iniSet(lines, section, key, value); changed {
+				setKeys = append(setKeys, kv.String())
+			}
+		}
+		for _, path := range absent {
+			section, key := iniPath(path)
+			var deleted bool
+			if lines, deleted = iniDelete(lines, section, key); deleted {
+				deletedKeys = append(deletedKeys, strings.Join(path, "."))
+			}
+		}
+		if len(setKeys) == 0 && len(deletedKeys) == 0 {
+			return content, nil, nil, nil
+		}
+		return []byte(strings.Join(lines, "")), setKeys, deletedKeys, nil
+	default:
+		return nil, nil, nil, errors.Join(ErrUnsupportedFormat, fmt.Errorf("cannot edit %s", format))
+	}
+}
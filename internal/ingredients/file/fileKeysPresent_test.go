@@ -0,0 +1,201 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeysPresent(t *testing.T) {
+	const appYAML = "# app settings\nname: app # the app\nserver:\n  # listen port\n  port: 80\n  tls: false\n"
+	const appJSON = "{\n  \"name\": \"app\",\n  \"server\": {\"port\": 80, \"tls\": false},\n  \"html\": \"<b>\"\n}\n"
+	const appINI = "; global\nname = app\n\n[server]\nport=80\ntls = false\n\n[log]\nlevel = info\n"
+	tests := []struct {
+		name     string
+		file     string
+		params   map[string]interface{}
+		existing string
+		want     string
+		changed  bool
+		error    error
+	}{
+		{
+			name:     "yaml keeps comments",
+			file:     "app.yaml",
+			params:   map[string]interface{}{"keys": map[string]interface{}{"server.port": 8080, "server.bind": "0.0.0.0"}},
+			existing: appYAML,
+			want:     "# app settings\nname: app # the app\nserver:\n  # listen port\n  port: 8080\n  tls: false\n  bind: 0.0.0.0\n",
+			changed:  true,
+		},
+		{
+			name:     "yaml nested map merges",
+			file:     "app.yaml",
+			params:   map[string]interface{}{"keys": map[string]interface{}{"server": map[string]interface{}{"tls": true}}},
+			existing: appYAML,
+			want:     "# app settings\nname: app # the app\nserver:\n  # listen port\n  port: 80\n  tls: true\n",
+			changed:  true,
+		},
+		{
+			name:     "yaml present",
+			file:     "app.yaml",
+			params:   map[string]interface{}{"keys": map[string]interface{}{"server.port": 80.0}, "absent": "server.missing"},
+			existing: appYAML,
+			want:     appYAML,
+		},
+		{
+			name:     "yaml delete",
+			file:     "app.yaml",
+			params:   map[string]interface{}{"absent": []interface{}{"server.tls"}},
+			existing: appYAML,
+			want:     "# app settings\nname: app # the app\nserver:\n  # listen port\n  port: 80\n",
+			changed:  true,
+		},
+		{
+			name:     "yaml conflict",
+			file:     "app.yaml",
+			params:   map[string]interface{}{"keys": map[string]interface{}{"name.first": "x"}},
+			existing: appYAML,
+			want:     appYAML,
+			error:    ErrKeyConflict,
+		},
+		{
+			name:     "json keeps key order",
+			file:     "app.json",
+			params:   map[string]interface{}{"keys": map[string]interface{}{"server.port": 8080, "log.level": "debug"}},
+			existing: appJSON,
+			want:     "{\n  \"name\": \"app\",\n  \"server\": {\n    \"port\": 8080,\n    \"tls\": false\n  },\n  \"html\": \"<b>\",\n  \"log\": {\n    \"level\": \"debug\"\n  }\n}\n",
+			changed:  true,
+		},
+		{
+			name:     "json present",
+			file:     "app.json",
+			params:   map[string]interface{}{"keys": map[string]interface{}{"server.tls": false}},
+			existing: appJSON,
+			want:     appJSON,
+		},
+		{
+			name:     "json keeps literals",
+			file:     "app.json",
+			params:   map[string]interface{}{"keys": map[string]interface{}{"name": "web"}},
+			existing: "{\n  \"name\": \"app\",\n  \"ratio\": 1.0,\n  \"max\": 1e3,\n  \"big\": 12345678901234567890\n}\n",
+			want:     "{\n  \"name\": \"web\",\n  \"ratio\": 1.0,\n  \"max\": 1e3,\n  \"big\": 12345678901234567890\n}\n",
+			changed:  true,
+		},
+		{
+			name:     "cfg needs a format",
+			file:     "app.cfg",
+			params:   map[string]interface{}{"keys": map[string]interface{}{"name": "web"}},
+			existing: appINI,
+			want:     appINI,
+			error:    ErrUnsupportedFormat,
+		},
+		{
+			name:     "invalid json",
+			file:     "app.json",
+			params:   map[string]interface{}{"keys": map[string]interface{}{"a": 1}},
+			existing: "a: 1\n",
+			want:     "a: 1\n",
+			error:    ErrUnparsableContent,
+		},
+		{
+			name:     "toml",
+			file:     "app.toml",
+			params:   map[string]interface{}{"keys": map[string]interface{}{"server.port": 8080}, "absent": "server.tls"},
+			existing: "name = \"app\"\n\n[server]\nport = 80\ntls = false\n",
+			want:     "name = \"app\"\n\n[server]\n  port = 8080\n",
+			changed:  true,
+		},
+		{
+			name:     "toml present",
+			file:     "app.toml",
+			params:   map[string]interface{}{"keys": map[string]interface{}{"server.port": 80}},
+			existing: "name = \"app\"\n\n[server]\nport = 80\n",
+			want:     "name = \"app\"\n\n[server]\nport = 80\n",
+		},
+		{
+			name: "ini",
+			file: "app.ini",
+			params: map[string]interface{}{
+				"keys":   map[string]interface{}{"server.port": 8080, "server.bind": "0.0.0.0", "user": "grlx", "db.host": "localhost"},
+				"absent": []interface{}{"log.level"},
+			},
+			existing: appINI,
+			want:     "; global\nname = app\nuser = grlx\n\n[server]\nport=8080\ntls = false\nbind = 0.0.0.0\n\n[log]\n\n[db]\nhost = localhost\n",
+			changed:  true,
+		},
+		{
+			name:     "ini present",
+			file:     "app.cfg",
+			params:   map[string]interface{}{"format": "ini", "keys": map[string]interface{}{"server.tls": false, "name": "app"}},
+			existing: appINI,
+			want:     appINI,
+		},
+		{
+			name:     "ini rejects lists",
+			file:     "app.ini",
+			params:   map[string]interface{}{"keys": map[string]interface{}{"server.hosts": []interface{}{"a"}}},
+			existing: appINI,
+			want:     appINI,
+			error:    ErrInvalidProperty,
+		},
+		{
+			name:     "missing keys",
+			file:     "app.yaml",
+			existing: appYAML,
+			want:     appYAML,
+			error:    ErrMissingKeys,
+		},
+		{
+			name:     "invalid key path",
+			file:     "app.yaml",
+			params:   map[string]interface{}{"absent": "server..port"},
+			existing: appYAML,
+			want:     appYAML,
+			error:    ErrInvalidProperty,
+		},
+	}
+	for _, tc := range tests {
+		for _, test := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s/test=%v", tc.name, test), func(t *testing.T) {
+				name := filepath.Join(t.TempDir(), tc.file)
+				if err := os.WriteFile(name, []byte(tc.existing), 0o640); err != nil {
+					t.Fatal(err)
+				}
+				params := map[string]interface{}{"name": name}
+				for k, v := range tc.params {
+					params[k] = v
+				}
+				f := File{id: "keys", method: "keys_present", params: params}
+				run := f.Apply
+				if test {
+					run = f.Test
+				}
+				res, err := run(context.Background())
+				if tc.error != nil {
+					if !errors.Is(err, tc.error) {
+						t.Fatalf("error = %v, want %v", err, tc.error)
+					}
+					if !res.Failed {
+						t.Errorf("expected failed result, got %+v", res)
+					}
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				if res.Changed != tc.changed {
+					t.Errorf("Changed = %v, want %v (notes %v)", res.Changed, tc.changed, res.Notes)
+				}
+				got, _ := os.ReadFile(name)
+				want := tc.want
+				if test {
+					want = tc.existing
+				}
+				if string(got) != want {
+					t.Errorf("content = %q, want %q", got, want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gogrlx/grlx/v2/internal/cook"
+	"github.com/gogrlx/grlx/v2/internal/ingredients"
+)
+
+func (f File) line(ctx context.Context, test bool) (cook.Result, error) {
+	// Params: "name", "content", "match", "mode", "before", "after",
+	// "backup", "show_changes"
+
+	var notes []fmt.Stringer
+	name, ok := f.params["name"].(string)
+	if !ok || name == "" {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, ingredients.ErrMissingName
+	}
+	name = filepath.Clean(name)
+	if name == "/" {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, ErrModifyRoot
+	}
+	mode, _ := f.params["mode"].(string)
+	if mode == "" {
+		mode = "ensure"
+	}
+	if mode != "ensure" && mode != "replace" && mode != "delete" {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, errors.Join(ErrInvalidLineMode, fmt.Errorf("got %q", mode))
+	}
+	content, _ := f.params["content"].(string)
+	content = strings.TrimSuffix(content, "\n")
+	if strings.Contains(content, "\n") {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, errors.Join(ErrInvalidProperty, fmt.Errorf("content must be a single line"))
+	}
+	match, _ := f.params["match"].(string)
+	if content == "" && (mode != "delete" || match == "") {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, ErrMissingContent
+	}
+	// Without a match, the line to act on is the content itself.
+	if match == "" {
+		match = "^" + regexp.QuoteMeta(content) + "$"
+	}
+	matchRe, err := regexp.Compile(match)
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, errors.Join(ErrInvalidPattern, err)
+	}
+	var afterRe, beforeRe *regexp.Regexp
+	if after, _ := f.params["after"].(string); after != "" {
+		if afterRe, err = regexp.Compile(after); err != nil {
+			return cook.Result{
+				Succeeded: false, Failed: true, Notes: notes,
+			}, errors.Join(ErrInvalidPattern, err)
+		}
+	}
+	if before, _ := f.params["before"].(string); before != "" {
+		if beforeRe, err = regexp.Compile(before); err != nil {
+			return cook.Result{
+				Succeeded: false, Failed: true, Notes: notes,
+			}, errors.Join(ErrInvalidPattern, err)
+		}
+	}
+
+	original, err := os.ReadFile(name)
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	lines := splitLines(original)
+	lo, hi, err := lineRegion(lines, afterRe, beforeRe)
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	edited := editLines(lines, lo, hi, matchRe, content, mode, afterRe != nil, beforeRe != nil)
+	updated := []byte(strings.Join(edited, ""))
+	if bytes.Equal(original, updated) {
+		if mode == "replace" {
+			notes = append(notes, cook.Snprintf("no line in `%s` matches `%s`", name, match))
+		}
+		return cook.Result{
+			Succeeded: true, Failed: false,
+			Changed: false, Notes: notes,
+		}, nil
+	}
+
+	lineNotes := changedLineNotes(name, original, updated)
+	diffNotes := f.diffNotes(name, original, updated)
+	backupNotes, err := f.backup(name, test)
+	notes = append(notes, backupNotes...)
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	if test {
+		notes = append(notes, cook.Snprintf("`%s` would be edited (mode %s)", name, mode))
+		notes = append(notes, lineNotes...)
+		notes = append(notes, diffNotes...)
+		return cook.Result{
+			Succeeded: true, Failed: false,
+			Changed: true, Notes: notes,
+		}, nil
+	}
+	if err = writeAtomic(name, updated); err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	notes = append(notes, cook.Snprintf("edited `%s` (mode %s)", name, mode))
+	notes = append(notes, lineNotes...)
+	notes = append(notes, diffNotes...)
+	return cook.Result{
+		Succeeded: true, Failed: false,
+		Changed: true, Notes: notes,
+	}, nil
+}
+
+// lineRegion returns the lines [lo, hi) that file.line acts on: those
+// after the first line matching afterRe, up to the next line matching
+// beforeRe. Either anchor may be nil.
+func lineRegion(lines []string, afterRe, beforeRe *regexp.Regexp) (int, int, error) {
+	lo, hi := 0, len(lines)
+	if afterRe != nil {
+		found := false
+		for i, l := range lines {
+			if afterRe.MatchString(strings.TrimSuffix(l, "\n")) {
+				lo, found = i+1, true
+				break
+			}
+		}
+		if !found {
+			return 0, 0, errors.Join(ErrAnchorNotFound, fmt.Errorf("after: `%s`", afterRe))
+		}
+	}
+	if beforeRe != nil {
+		found := false
+		for i := lo; i < len(lines); i++ {
+			if beforeRe.MatchString(strings.TrimSuffix(lines[i], "\n")) {
+				hi, found = i, true
+				break
+			}
+		}
+		if !found {
+			return 0, 0, errors.Join(ErrAnchorNotFound, fmt.Errorf("before: `%s`", beforeRe))
+		}
+	}
+	return lo, hi, nil
+}
+
+// editLines applies a file.line mode to the lines in [lo, hi). Lines keep
+// their trailing newline. In ensure mode a missing line goes right after
+// the after anchor, else right before the before anchor, else at the end
+// of the file.
+func editLines(lines []string, lo, hi int, matchRe *regexp.Regexp, content, mode string, hasAfter, hasBefore bool) []string {
+	out := make([]string, 0, len(lines)+1)
+	out = append(out, lines[:lo]...)
+	matched := false
+	for _, l := range lines[lo:hi] {
+		text, eol := strings.CutSuffix(l, "\n")
+		if !matchRe.MatchString(text) {
+			out = append(out, l)
+			continue
+		}
+		matched = true
+		if mode == "delete" {
+			continue
+		}
+		if eol {
+			out = append(out, content+"\n")
+		} else {
+			out = append(out, content)
+		}
+	}
+	out = append(out, lines[hi:]...)
+	if matched || mode != "ensure" {
+		return out
+	}
+
+	at := len(lines)
+	switch {
+	case hasAfter:
+		at = lo
+	case hasBefore:
+		at = hi
+	}
+	out = append(out[:0:0], lines[:at]...)
+	if at > 0 && !strings.HasSuffix(out[at-1], "\n") {
+		out[at-1] += "\n"
+	}
+	out = append(out, content+"\n")
+	return append(out, lines[at:]...)
+}
@@ -0,0 +1,211 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLine(t *testing.T) {
+	const fstab = "# fstab\n/dev/sda1 / ext4 defaults 0 1\n[mounts]\nproc /proc proc defaults 0 0\n[end]\n"
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		content string
+		want    string
+		changed bool
+		error   error
+	}{
+		{
+			name:    "ensure appends",
+			params:  map[string]interface{}{"content": "tmpfs /tmp tmpfs defaults 0 0"},
+			content: fstab,
+			want:    fstab + "tmpfs /tmp tmpfs defaults 0 0\n",
+			changed: true,
+		},
+		{
+			name:    "ensure adds missing newline",
+			params:  map[string]interface{}{"content": "b"},
+			content: "a",
+			want:    "a\nb\n",
+			changed: true,
+		},
+		{
+			name:    "ensure present",
+			params:  map[string]interface{}{"content": "proc /proc proc defaults 0 0"},
+			content: fstab,
+			want:    fstab,
+		},
+		{
+			name:    "ensure replaces match",
+			params:  map[string]interface{}{"content": "proc /proc proc nosuid 0 0", "match": "^proc "},
+			content: fstab,
+			want:    strings.Replace(fstab, "proc /proc proc defaults 0 0", "proc /proc proc nosuid 0 0", 1),
+			changed: true,
+		},
+		{
+			name:    "ensure after anchor",
+			params:  map[string]interface{}{"content": "tmpfs /tmp tmpfs defaults 0 0", "after": `^\[mounts\]$`},
+			content: fstab,
+			want:    strings.Replace(fstab, "[mounts]\n", "[mounts]\ntmpfs /tmp tmpfs defaults 0 0\n", 1),
+			changed: true,
+		},
+		{
+			name:    "ensure before anchor",
+			params:  map[string]interface{}{"content": "tmpfs /tmp tmpfs defaults 0 0", "before": `^\[end\]$`},
+			content: fstab,
+			want:    strings.Replace(fstab, "[end]\n", "tmpfs /tmp tmpfs defaults 0 0\n[end]\n", 1),
+			changed: true,
+		},
+		{
+			name:    "ensure only looks between anchors",
+			params:  map[string]interface{}{"content": "# fstab", "after": `^\[mounts\]$`, "before": `^\[end\]$`},
+			content: fstab,
+			want:    strings.Replace(fstab, "[mounts]\n", "[mounts]\n# fstab\n", 1),
+			changed: true,
+		},
+		{
+			name:    "replace",
+			params:  map[string]interface{}{"mode": "replace", "content": "# managed by grlx", "match": "^#"},
+			content: fstab,
+			want:    strings.Replace(fstab, "# fstab", "# managed by grlx", 1),
+			changed: true,
+		},
+		{
+			name:    "replace without match",
+			params:  map[string]interface{}{"mode": "replace", "content": "x", "match": "^swap"},
+			content: fstab,
+			want:    fstab,
+		},
+		{
+			name:    "delete",
+			params:  map[string]interface{}{"mode": "delete", "match": "^proc "},
+			content: fstab,
+			want:    strings.Replace(fstab, "proc /proc proc defaults 0 0\n", "", 1),
+			changed: true,
+		},
+		{
+			name:    "delete by content",
+			params:  map[string]interface{}{"mode": "delete", "content": "[end]"},
+			content: fstab,
+			want:    strings.Replace(fstab, "[end]\n", "", 1),
+			changed: true,
+		},
+		{
+			name:    "delete after anchor",
+			params:  map[string]interface{}{"mode": "delete", "match": "^[#/]", "after": `^\[mounts\]$`},
+			content: fstab,
+			want:    fstab,
+		},
+		{
+			name:    "anchor not found",
+			params:  map[string]interface{}{"content": "x", "after": "^nope$"},
+			content: fstab,
+			want:    fstab,
+			error:   ErrAnchorNotFound,
+		},
+		{
+			name:    "invalid mode",
+			params:  map[string]interface{}{"content": "x", "mode": "insert"},
+			content: fstab,
+			want:    fstab,
+			error:   ErrInvalidLineMode,
+		},
+		{
+			name:    "missing content",
+			params:  map[string]interface{}{"match": "x"},
+			content: fstab,
+			want:    fstab,
+			error:   ErrMissingContent,
+		},
+		{
+			name:    "multi-line content",
+			params:  map[string]interface{}{"content": "a\nb"},
+			content: fstab,
+			want:    fstab,
+			error:   ErrInvalidProperty,
+		},
+		{
+			name:    "invalid match",
+			params:  map[string]interface{}{"content": "x", "match": "["},
+			content: fstab,
+			want:    fstab,
+			error:   ErrInvalidPattern,
+		},
+	}
+	for _, tc := range tests {
+		for _, test := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s/test=%v", tc.name, test), func(t *testing.T) {
+				name := filepath.Join(t.TempDir(), "fstab")
+				if err := os.WriteFile(name, []byte(tc.content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				params := map[string]interface{}{"name": name}
+				for k, v := range tc.params {
+					params[k] = v
+				}
+				f := File{id: "line", method: "line", params: params}
+				run := f.Apply
+				if test {
+					run = f.Test
+				}
+				res, err := run(context.Background())
+				if !errors.Is(err, tc.error) {
+					t.Fatalf("error = %v, want %v", err, tc.error)
+				}
+				if tc.error != nil {
+					if !res.Failed {
+						t.Errorf("expected failed result, got %+v", res)
+					}
+					return
+				}
+				if res.Changed != tc.changed {
+					t.Errorf("Changed = %v, want %v (notes %v)", res.Changed, tc.changed, res.Notes)
+				}
+				got, _ := os.ReadFile(name)
+				want := tc.want
+				if test {
+					want = tc.content
+				}
+				if string(got) != want {
+					t.Errorf("content = %q, want %q", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestLineNotesChangedLines(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "sudoers")
+	if err := os.WriteFile(name, []byte("root ALL=(ALL) ALL\n%wheel ALL=(ALL) ALL\n"), 0o440); err != nil {
+		t.Fatal(err)
+	}
+	f := File{id: "sudoers", method: "line", params: map[string]interface{}{
+		"name": name, "mode": "delete", "match": "^%wheel",
+	}}
+	res, err := f.Test(context.Background())
+	if err != nil || !res.Changed {
+		t.Fatalf("Test() = %+v, %v", res, err)
+	}
+	var notes []string
+	for _, n := range res.Notes {
+		notes = append(notes, n.String())
+	}
+	if joined := strings.Join(notes, "\n"); !strings.Contains(joined, "line 2 removed: %wheel ALL=(ALL) ALL") {
+		t.Errorf("notes %q do not list the removed line", joined)
+	}
+	if _, err = f.Apply(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o440 {
+		t.Errorf("mode = %v, want 0440", info.Mode().Perm())
+	}
+}
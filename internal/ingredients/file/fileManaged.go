@@ -1,10 +1,10 @@
 package file
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 
@@ -42,9 +42,6 @@ func (f File) managed(ctx context.Context, test bool) (cook.Result, error) {
 	if c, ok := f.params["create"].(bool); ok {
 		create = c
 	}
-	backup, _ := f.params["backup"].(string)
-	_ = backup
-
 	// Validate source hash requirement
 	if source != "" && sourceHash == "" && !skipVerify {
 		return cook.Result{
@@ -126,14 +123,48 @@ func (f File) managed(ctx context.Context, test bool) (cook.Result, error) {
 				Changed: false, Notes: notes,
 			}, errors.Join(err, ErrCacheFailure)
 		}
-		changed := !fileExists
-		if !changed {
+		// Until the source has been cached there is nothing to compare
+		// against, so assume the destination will change.
+		if cacheRes.Changed {
+			if fileExists {
+				notes = append(notes, cook.Snprintf("file `%s` would be updated from source", name))
+			}
+			return cook.Result{
+				Succeeded: true, Failed: false,
+				Changed: true, Notes: notes,
+			}, nil
+		}
+		desired, err := f.cachedContent(cacheFile, name)
+		if err != nil {
+			return cook.Result{
+				Succeeded: false, Failed: true,
+				Changed: false, Notes: notes,
+			}, err
+		}
+		existing, readErr := os.ReadFile(name)
+		if readErr == nil && bytes.Equal(existing, desired) {
+			return cook.Result{
+				Succeeded: true, Failed: false,
+				Changed: false, Notes: notes,
+			}, nil
+		}
+		backupNotes, err := f.backup(name, true)
+		notes = append(notes, backupNotes...)
+		if err != nil {
+			return cook.Result{
+				Succeeded: false, Failed: true,
+				Changed: false, Notes: notes,
+			}, err
+		}
+		if fileExists {
 			notes = append(notes, cook.Snprintf("file `%s` would be updated from source", name))
-			changed = true
+		} else {
+			notes = append(notes, cook.Snprintf("file `%s` would be created from source", name))
 		}
+		notes = append(notes, f.diffNotes(name, existing, desired)...)
 		return cook.Result{
 			Succeeded: true, Failed: false,
-			Changed: changed, Notes: notes,
+			Changed: true, Notes: notes,
 		}, nil
 	}
 
@@ -147,37 +178,31 @@ func (f File) managed(ctx context.Context, test bool) (cook.Result, error) {
 		}, errors.Join(err, ErrCacheFailure)
 	}
 
-	// Get the cached file path
-	cf := cacheFile.(File)
-	sourceDest, err := cf.dest()
+	desired, err := f.cachedContent(cacheFile, name)
 	if err != nil {
-		notes = append(notes, cook.Snprintf("failed to get cached source destination: %v", err))
 		return cook.Result{
 			Succeeded: false, Failed: true,
 			Changed: false, Notes: notes,
 		}, err
 	}
-
-	// Copy cached source to destination
-	srcFile, err := os.Open(sourceDest)
-	if err != nil {
+	existing, readErr := os.ReadFile(name)
+	if readErr == nil && bytes.Equal(existing, desired) {
 		return cook.Result{
-			Succeeded: false, Failed: true,
+			Succeeded: true, Failed: false,
 			Changed: false, Notes: notes,
-		}, err
+		}, nil
 	}
-	defer srcFile.Close()
 
-	dstFile, err := os.Create(name)
+	backupNotes, err := f.backup(name, false)
+	notes = append(notes, backupNotes...)
 	if err != nil {
 		return cook.Result{
 			Succeeded: false, Failed: true,
 			Changed: false, Notes: notes,
 		}, err
 	}
-	defer dstFile.Close()
 
-	if _, err := io.Copy(dstFile, srcFile); err != nil {
+	if err := os.WriteFile(name, desired, 0o644); err != nil {
 		return cook.Result{
 			Succeeded: false, Failed: true,
 			Changed: false, Notes: notes,
@@ -185,8 +210,27 @@ func (f File) managed(ctx context.Context, test bool) (cook.Result, error) {
 	}
 
 	notes = append(notes, cook.Snprintf("file `%s` managed from source `%s`", name, source))
+	notes = append(notes, f.diffNotes(name, existing, desired)...)
 	return cook.Result{
 		Succeeded: true, Failed: false,
 		Changed: true, Notes: notes,
 	}, nil
 }
+
+// cachedContent reads the cached copy of a source and renders it when the
+// step is a template.
+func (f File) cachedContent(cacheFile cook.RecipeCooker, name string) ([]byte, error) {
+	cf, ok := cacheFile.(File)
+	if !ok {
+		return nil, fmt.Errorf("cached source is not a File")
+	}
+	sourceDest, err := cf.dest()
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(sourceDest)
+	if err != nil {
+		return nil, err
+	}
+	return f.render(name, content)
+}
@@ -12,8 +12,9 @@ import (
 	"github.com/gogrlx/grlx/v2/internal/ingredients"
 )
 
-// prependContent extracts the text content to prepend from params.
-func (f File) prependContent() []byte {
+// prependContent extracts the text content to prepend from params,
+// rendering it when the step is a template.
+func (f File) prependContent(name string) ([]byte, error) {
 	var buf bytes.Buffer
 	if text, ok := f.params["text"].(string); ok && text != "" {
 		buf.WriteString(text + "\n")
@@ -22,7 +23,7 @@ func (f File) prependContent() []byte {
 			buf.WriteString(fmt.Sprintf("%v\n", v))
 		}
 	}
-	return buf.Bytes()
+	return f.render(name, buf.Bytes())
 }
 
 func (f File) prepend(ctx context.Context, test bool) (cook.Result, error) {
@@ -58,9 +59,15 @@ func (f File) prepend(ctx context.Context, test bool) (cook.Result, error) {
 		}, nil
 	}
 	if os.IsNotExist(err) {
-		content := f.prependContent()
+		content, renderErr := f.prependContent(name)
+		if renderErr != nil {
+			return cook.Result{
+				Succeeded: false, Failed: true, Notes: notes,
+			}, renderErr
+		}
 		if test {
 			notes = append(notes, cook.Snprintf("would create and prepend to %s", name))
+			notes = append(notes, f.diffNotes(name, nil, content)...)
 			return cook.Result{
 				Succeeded: true, Failed: false,
 				Changed: true, Notes: notes,
@@ -72,15 +79,32 @@ func (f File) prepend(ctx context.Context, test bool) (cook.Result, error) {
 			}, fmt.Errorf("failed to create %s: %w", name, createErr)
 		}
 		notes = append(notes, cook.Snprintf("prepended %v", name))
+		notes = append(notes, f.diffNotes(name, nil, content)...)
 		return cook.Result{
 			Succeeded: true, Failed: false,
 			Changed: true, Notes: notes,
 		}, nil
 	}
 	if errors.Is(err, ErrMissingContent) {
-		content := f.prependContent()
+		content, renderErr := f.prependContent(name)
+		if renderErr != nil {
+			return cook.Result{
+				Succeeded: false, Failed: true, Notes: notes,
+			}, renderErr
+		}
+		backupNotes, backupErr := f.backup(name, test)
+		notes = append(notes, backupNotes...)
+		if backupErr != nil {
+			return cook.Result{
+				Succeeded: false, Failed: true, Notes: notes,
+			}, backupErr
+		}
 		if test {
 			notes = append(notes, cook.Snprintf("would prepend to %s", name))
+			if f.showChanges() {
+				existing, _ := os.ReadFile(name)
+				notes = append(notes, f.diffNotes(name, existing, append(bytes.Clone(content), existing...))...)
+			}
 			return cook.Result{
 				Succeeded: true, Failed: false,
 				Changed: true, Notes: notes,
@@ -103,6 +127,7 @@ func (f File) prepend(ctx context.Context, test bool) (cook.Result, error) {
 			}, fmt.Errorf("failed to open %s for prepending: %w", name, writeErr)
 		}
 		notes = append(notes, cook.Snprintf("prepended %v", name))
+		notes = append(notes, f.diffNotes(name, existing, combined.Bytes())...)
 		return cook.Result{
 			Succeeded: true, Failed: false,
 			Changed: true, Notes: notes,
@@ -0,0 +1,485 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gogrlx/grlx/v2/internal/cook"
+	"github.com/gogrlx/grlx/v2/internal/ingredients"
+)
+
+func (f File) recurse(ctx context.Context, test bool) (cook.Result, error) {
+	// Params: "name", "source", "include", "exclude", "clean", "template",
+	// "context", "dir_mode", "file_mode", "user", "group", "makedirs"
+
+	var notes []fmt.Stringer
+	name, ok := f.params["name"].(string)
+	if !ok || name == "" {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, ingredients.ErrMissingName
+	}
+	name = filepath.Clean(name)
+	if name == "/" {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, ErrModifyRoot
+	}
+	source, _ := f.params["source"].(string)
+	if source == "" {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, ErrMissingSource
+	}
+	include, err := f.globList("include")
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	exclude, err := f.globList("exclude")
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	dirMode, setDirMode, err := f.modeParam("dir_mode")
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	if !setDirMode {
+		dirMode = 0o755
+	}
+	fileMode, setFileMode, err := f.modeParam("file_mode")
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	userName, _ := f.params["user"].(string)
+	groupName, _ := f.params["group"].(string)
+	uid, gid, err := LookupOwner(userName, groupName)
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	clean, _ := f.params["clean"].(bool)
+	makedirs, _ := f.params["makedirs"].(bool)
+
+	parent := filepath.Dir(name)
+	if _, statErr := os.Stat(parent); os.IsNotExist(statErr) && !makedirs {
+		return cook.Result{
+			Succeeded: false, Failed: true,
+			Changed: false, Notes: []fmt.Stringer{
+				cook.Snprintf("parent directory `%s` does not exist and makedirs is false", parent),
+			},
+		}, ErrPathNotFound
+	}
+
+	listing, err := ListSource(ctx, f.id, source, f.params)
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	r := recursion{
+		name: name, source: strings.TrimSuffix(source, "/"),
+		dirMode: dirMode, setDirMode: setDirMode,
+		fileMode: fileMode, setFileMode: setFileMode,
+		uid: uid, gid: gid,
+		files: map[string]SourceFile{},
+		dirs:  map[string]bool{".": true},
+	}
+	for _, sf := range listing {
+		rel := path.Clean(sf.Path)
+		if path.IsAbs(rel) || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+			return cook.Result{
+				Succeeded: false, Failed: true, Notes: notes,
+			}, errors.Join(ErrUnsafeSourcePath, fmt.Errorf("%q", sf.Path))
+		}
+		if (len(include) > 0 && !globMatch(include, rel)) || globMatch(exclude, rel) {
+			continue
+		}
+		r.files[rel] = sf
+		for d := path.Dir(rel); d != "."; d = path.Dir(d) {
+			r.dirs[d] = true
+		}
+	}
+
+	changed := false
+	for _, d := range sortedKeys(r.dirs) {
+		dirNotes, dirChanged, dirErr := r.syncDir(d, test)
+		notes = append(notes, dirNotes...)
+		changed = changed || dirChanged
+		if dirErr != nil {
+			return cook.Result{
+				Succeeded: false, Failed: true,
+				Changed: changed, Notes: notes,
+			}, dirErr
+		}
+	}
+	for _, rel := range sortedKeys(r.files) {
+		fileNotes, fileChanged, fileErr := f.syncFile(ctx, &r, rel, test)
+		notes = append(notes, fileNotes...)
+		changed = changed || fileChanged
+		if fileErr != nil {
+			return cook.Result{
+				Succeeded: false, Failed: true,
+				Changed: changed, Notes: notes,
+			}, fileErr
+		}
+	}
+	if clean {
+		cleanNotes, cleanChanged, cleanErr := r.clean(exclude, test)
+		notes = append(notes, cleanNotes...)
+		changed = changed || cleanChanged
+		if cleanErr != nil {
+			return cook.Result{
+				Succeeded: false, Failed: true,
+				Changed: changed, Notes: notes,
+			}, cleanErr
+		}
+	}
+	return cook.Result{
+		Succeeded: true, Failed: false,
+		Changed: changed, Notes: notes,
+	}, nil
+}
+
+// recursion holds what file.recurse mirrors into name: the source files
+// left after include and exclude, and the directories holding them, both
+// keyed by slash-separated paths relative to name.
+type recursion struct {
+	name, source string
+	dirMode      os.FileMode
+	setDirMode   bool
+	fileMode     os.FileMode
+	setFileMode  bool
+	uid, gid     int
+	files        map[string]SourceFile
+	dirs         map[string]bool
+}
+
+func (r *recursion) target(rel string) string {
+	return filepath.Join(r.name, filepath.FromSlash(rel))
+}
+
+// syncDir creates a directory of the tree or fixes its mode and
+// ownership. The mode of an existing directory is only changed when
+// dir_mode is set.
+func (r *recursion) syncDir(rel string, test bool) ([]fmt.Stringer, bool, error) {
+	target := r.target(rel)
+	var info fs.FileInfo
+	var err error
+	if rel == "." {
+		// The destination itself may be a symlink to a directory.
+		info, err = os.Stat(target)
+	} else {
+		info, err = os.Lstat(target)
+	}
+	if os.IsNotExist(err) {
+		if test {
+			return []fmt.Stringer{cook.Snprintf("would add directory `%s`", target)}, true, nil
+		}
+		if err = os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return nil, false, err
+		}
+		if err = os.Mkdir(target, r.dirMode); err != nil {
+			return nil, false, err
+		}
+		if err = r.setAttrs(target, r.dirMode); err != nil {
+			return nil, true, err
+		}
+		return []fmt.Stringer{cook.Snprintf("added directory `%s`", target)}, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if !info.IsDir() {
+		return nil, false, errors.Join(ErrNotDirectory, fmt.Errorf("`%s`", target))
+	}
+	if (!r.setDirMode || info.Mode().Perm() == r.dirMode) && !OwnerDiffers(info, r.uid, r.gid) {
+		return nil, false, nil
+	}
+	if test {
+		return []fmt.Stringer{cook.Snprintf("would update mode/ownership of `%s`", target)}, true, nil
+	}
+	mode := info.Mode().Perm()
+	if r.setDirMode {
+		mode = r.dirMode
+	}
+	if err = r.setAttrs(target, mode); err != nil {
+		return nil, false, err
+	}
+	return []fmt.Stringer{cook.Snprintf("updated mode/ownership of `%s`", target)}, true, nil
+}
+
+func (r *recursion) setAttrs(target string, mode os.FileMode) error {
+	if r.uid >= 0 || r.gid >= 0 {
+		if err := os.Lchown(target, r.uid, r.gid); err != nil {
+			return err
+		}
+	}
+	return os.Chmod(target, mode)
+}
+
+// syncFile brings one file of the tree in line with the source, caching
+// and rendering the source only when the content may have changed.
+func (f File) syncFile(ctx context.Context, r *recursion, rel string, test bool) ([]fmt.Stringer, bool, error) {
+	sf := r.files[rel]
+	target := r.target(rel)
+	mode := r.fileMode
+	if !r.setFileMode {
+		mode = sf.Mode.Perm()
+		if mode == 0 {
+			mode = 0o644
+		}
+	}
+	info, err := os.Lstat(target)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return nil, false, err
+	}
+	if exists && info.IsDir() {
+		return nil, false, errors.Join(ErrNotDirectory, fmt.Errorf("`%s` is a directory in the destination but a file in the source", target))
+	}
+
+	// Without templating the listed checksum says whether the content
+	// changed, so nothing needs fetching for an up to date file.
+	sameContent := false
+	if exists && info.Mode().IsRegular() && !f.isTemplate() {
+		sum, sumErr := sha256Sum(target)
+		if sumErr != nil {
+			return nil, false, sumErr
+		}
+		sameContent = sum == sf.SHA256
+	}
+
+	var desired []byte
+	if !sameContent {
+		cacheFile, parseErr := f.Parse(fmt.Sprintf("%s-%s", f.id, rel), "cached", map[string]interface{}{
+			"source": r.source + "/" + rel,
+			"hash":   "sha256:" + sf.SHA256,
+			"name":   fmt.Sprintf("%s-%s", f.id, rel),
+		})
+		if parseErr != nil {
+			return nil, false, parseErr
+		}
+		var cacheRes cook.Result
+		if test {
+			cacheRes, err = cacheFile.Test(ctx)
+		} else {
+			cacheRes, err = cacheFile.Apply(ctx)
+		}
+		if err != nil || !cacheRes.Succeeded {
+			return cacheRes.Notes, false, errors.Join(err, ErrCacheFailure)
+		}
+		if test && cacheRes.Changed {
+			// Not cached yet, so a template cannot be rendered to compare.
+			if exists {
+				return []fmt.Stringer{cook.Snprintf("would update `%s`", target)}, true, nil
+			}
+			return []fmt.Stringer{cook.Snprintf("would add `%s`", target)}, true, nil
+		}
+		if desired, err = f.cachedContent(cacheFile, target); err != nil {
+			return nil, false, err
+		}
+		if exists && info.Mode().IsRegular() {
+			existing, readErr := os.ReadFile(target)
+			if readErr != nil {
+				return nil, false, readErr
+			}
+			sameContent = bytes.Equal(existing, desired)
+		}
+	}
+
+	if sameContent {
+		if info.Mode().Perm() == mode && !OwnerDiffers(info, r.uid, r.gid) {
+			return nil, false, nil
+		}
+		if test {
+			return []fmt.Stringer{cook.Snprintf("would update mode/ownership of `%s`", target)}, true, nil
+		}
+		if err = r.setAttrs(target, mode); err != nil {
+			return nil, false, err
+		}
+		return []fmt.Stringer{cook.Snprintf("updated mode/ownership of `%s`", target)}, true, nil
+	}
+
+	verb := "add"
+	if exists {
+		verb = "update"
+	}
+	if test {
+		return []fmt.Stringer{cook.Snprintf("would %s `%s`", verb, target)}, true, nil
+	}
+	if err = writeReplace(target, desired, mode, r.uid, r.gid); err != nil {
+		return nil, false, err
+	}
+	return []fmt.Stringer{cook.Snprintf("%sed `%s`", strings.TrimSuffix(verb, "e"), target)}, true, nil
+}
+
+// clean removes what is under name but not in the source, leaving alone
+// anything matching an exclude pattern and the directories holding it.
+func (r *recursion) clean(exclude []string, test bool) ([]fmt.Stringer, bool, error) {
+	var files, dirs []string
+	kept := map[string]bool{}
+	err := filepath.WalkDir(r.name, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == r.name {
+			return nil
+		}
+		rel, err := filepath.Rel(r.name, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		_, managedFile := r.files[rel]
+		if managedFile || r.dirs[rel] {
+			return nil
+		}
+		if globMatch(exclude, rel) {
+			for a := path.Dir(rel); a != "."; a = path.Dir(a) {
+				kept[a] = true
+			}
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			dirs = append(dirs, rel)
+		} else {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	var notes []fmt.Stringer
+	changed := false
+	for _, rel := range files {
+		changed = true
+		if test {
+			notes = append(notes, cook.Snprintf("would remove `%s`", r.target(rel)))
+			continue
+		}
+		if err = os.Remove(r.target(rel)); err != nil {
+			return notes, changed, err
+		}
+		notes = append(notes, cook.Snprintf("removed `%s`", r.target(rel)))
+	}
+	// Deepest first, so each directory is empty by the time it is removed.
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+	for _, rel := range dirs {
+		if kept[rel] {
+			continue
+		}
+		changed = true
+		if test {
+			notes = append(notes, cook.Snprintf("would remove directory `%s`", r.target(rel)))
+			continue
+		}
+		if err = os.Remove(r.target(rel)); err != nil {
+			return notes, changed, err
+		}
+		notes = append(notes, cook.Snprintf("removed directory `%s`", r.target(rel)))
+	}
+	return notes, changed, nil
+}
+
+// globList reads a property holding one glob or a list of them and
+// checks that each is well formed.
+func (f File) globList(key string) ([]string, error) {
+	var globs []string
+	switch v := f.params[key].(type) {
+	case nil:
+	case string:
+		globs = []string{v}
+	case []string:
+		globs = v
+	case []interface{}:
+		for _, g := range v {
+			globs = append(globs, fmt.Sprintf("%v", g))
+		}
+	default:
+		return nil, errors.Join(ErrInvalidProperty, fmt.Errorf("%s must be a glob or a list of globs", key))
+	}
+	for _, g := range globs {
+		if _, err := path.Match(g, ""); err != nil {
+			return nil, errors.Join(ErrInvalidGlob, fmt.Errorf("%s: %q", key, g))
+		}
+	}
+	return globs, nil
+}
+
+// globMatch reports whether rel or one of its parent directories matches
+// a glob. Globs without a slash are matched against base names, so `*.conf`
+// matches at any depth.
+func globMatch(globs []string, rel string) bool {
+	for _, g := range globs {
+		for p := rel; p != "."; p = path.Dir(p) {
+			subject := p
+			if !strings.Contains(g, "/") {
+				subject = path.Base(p)
+			}
+			if ok, _ := path.Match(g, subject); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// modeParam reads an octal mode property such as "0644". ok is false when
+// the property is not set.
+func (f File) modeParam(key string) (mode os.FileMode, ok bool, err error) {
+	val, _ := f.params[key].(string)
+	if val == "" {
+		return 0, false, nil
+	}
+	parsed, err := strconv.ParseUint(val, 8, 32)
+	if err != nil || parsed > 0o777 {
+		return 0, false, errors.Join(ErrInvalidProperty, fmt.Errorf("invalid %s %q", key, val))
+	}
+	return os.FileMode(parsed), true, nil
+}
+
+func sha256Sum(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,254 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gogrlx/grlx/v2/internal/config"
+)
+
+// testTreeFile is a tree:// provider that copies and lists real files, as
+// file.recurse needs directory listings and real content.
+type testTreeFile struct {
+	Source      string
+	Destination string
+}
+
+func (tf testTreeFile) path() string {
+	return strings.TrimPrefix(tf.Source, "tree://")
+}
+
+func (tf testTreeFile) Download(ctx context.Context) error {
+	src, err := os.Open(tf.path())
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(tf.Destination)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return errors.Join(err, dst.Close())
+}
+
+func (tf testTreeFile) Properties() (map[string]interface{}, error) {
+	return nil, nil
+}
+
+func (tf testTreeFile) Parse(id, source, destination, hash string, properties map[string]interface{}) (FileProvider, error) {
+	return testTreeFile{Source: source, Destination: destination}, nil
+}
+
+func (tf testTreeFile) Protocols() []string {
+	return []string{"tree"}
+}
+
+func (tf testTreeFile) Verify(ctx context.Context) (bool, error) {
+	if _, err := os.Stat(tf.Destination); err != nil {
+		return false, ErrFileNotFound
+	}
+	return true, nil
+}
+
+func (tf testTreeFile) List(ctx context.Context) ([]SourceFile, error) {
+	var files []SourceFile
+	err := filepath.WalkDir(tf.path(), func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, _ := filepath.Rel(tf.path(), p)
+		info, _ := d.Info()
+		sum, err := sha256Sum(p)
+		files = append(files, SourceFile{Path: filepath.ToSlash(rel), Size: info.Size(), Mode: info.Mode().Perm(), SHA256: sum})
+		return err
+	})
+	return files, err
+}
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readTree returns the files under root, keyed by slash-separated path.
+func readTree(t *testing.T, root string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(p)
+		rel, _ := filepath.Rel(root, p)
+		files[filepath.ToSlash(rel)] = string(content)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestRecurse(t *testing.T) {
+	provMap["tree"] = testTreeFile{}
+	defer delete(provMap, "tree")
+
+	source := map[string]string{
+		"app.conf":        "port = {{ .port }}\n",
+		"conf.d/a.conf":   "a\n",
+		"conf.d/b.conf":   "b\n",
+		"drafts/new.conf": "draft\n",
+		"README":          "readme\n",
+	}
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+		dest   map[string]string
+		want   map[string]string
+		notes  []string
+		// testNotes overrides notes in test mode.
+		testNotes []string
+		error     error
+	}{
+		{
+			name: "mirror into empty destination",
+			want: source,
+			notes: []string{
+				"add `README`", "add `app.conf`", "add `conf.d/a.conf`",
+				"add `conf.d/b.conf`", "add `drafts/new.conf`",
+				"add directory `conf.d`", "add directory `drafts`",
+			},
+		},
+		{
+			name: "update and leave unmanaged files",
+			dest: map[string]string{"README": "readme\n", "conf.d/a.conf": "old\n", "local.conf": "mine\n"},
+			want: map[string]string{
+				"app.conf": source["app.conf"], "conf.d/a.conf": "a\n", "conf.d/b.conf": "b\n",
+				"drafts/new.conf": "draft\n", "README": "readme\n", "local.conf": "mine\n",
+			},
+			notes: []string{
+				"add `app.conf`", "update `conf.d/a.conf`", "add `conf.d/b.conf`",
+				"add `drafts/new.conf`", "add directory `drafts`",
+			},
+		},
+		{
+			name:   "include and exclude",
+			params: map[string]interface{}{"include": []interface{}{"*.conf"}, "exclude": "drafts"},
+			want:   map[string]string{"app.conf": source["app.conf"], "conf.d/a.conf": "a\n", "conf.d/b.conf": "b\n"},
+			notes: []string{
+				"add `app.conf`", "add `conf.d/a.conf`", "add `conf.d/b.conf`",
+				"add directory `conf.d`",
+			},
+		},
+		{
+			name:   "clean keeps excluded files",
+			params: map[string]interface{}{"clean": true, "exclude": []interface{}{"drafts", "*.local"}},
+			dest: map[string]string{
+				"app.conf": source["app.conf"], "conf.d/a.conf": "a\n", "conf.d/b.conf": "b\n", "README": "readme\n",
+				"conf.d/stale.conf": "x\n", "old/x/y": "y\n", "site.local": "keep\n", "drafts/mine": "keep\n",
+			},
+			want: map[string]string{
+				"app.conf": source["app.conf"], "conf.d/a.conf": "a\n", "conf.d/b.conf": "b\n", "README": "readme\n",
+				"site.local": "keep\n", "drafts/mine": "keep\n",
+			},
+			notes: []string{
+				"remove `conf.d/stale.conf`", "remove `old/x/y`",
+				"remove directory `old`", "remove directory `old/x`",
+			},
+		},
+		{
+			name:   "template",
+			params: map[string]interface{}{"include": "app.conf", "template": true, "context": map[string]interface{}{"port": 8080}},
+			dest:   map[string]string{"app.conf": "port = 8080\n"},
+			want:   map[string]string{"app.conf": "port = 8080\n"},
+			// An uncached template cannot be rendered to compare.
+			testNotes: []string{"update `app.conf`"},
+		},
+		{
+			name:   "invalid glob",
+			params: map[string]interface{}{"exclude": "["},
+			error:  ErrInvalidGlob,
+		},
+		{
+			name:   "invalid file_mode",
+			params: map[string]interface{}{"file_mode": "rw"},
+			error:  ErrInvalidProperty,
+		},
+	}
+	for _, tc := range tests {
+		for _, test := range []bool{true, false} {
+			t.Run(tc.name+map[bool]string{true: "/test", false: "/apply"}[test], func(t *testing.T) {
+				tempDir := t.TempDir()
+				origCache := config.CacheDir
+				config.CacheDir = filepath.Join(tempDir, "cache")
+				defer func() { config.CacheDir = origCache }()
+				if err := os.MkdirAll(config.CacheDir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				src := filepath.Join(tempDir, "src")
+				writeTree(t, src, source)
+				dest := filepath.Join(tempDir, "dest")
+				if err := os.MkdirAll(dest, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				writeTree(t, dest, tc.dest)
+
+				params := map[string]interface{}{"name": dest, "source": "tree://" + src}
+				for k, v := range tc.params {
+					params[k] = v
+				}
+				f := File{id: "recurse", method: "recurse", params: params}
+				res, err := f.recurse(context.Background(), test)
+				if !errors.Is(err, tc.error) {
+					t.Fatalf("error = %v, want %v", err, tc.error)
+				}
+				if tc.error != nil {
+					return
+				}
+				// Test and apply notes differ only in tense.
+				tense := strings.NewReplacer("would ", "", "added", "add", "updated", "update", "removed", "remove", dest+"/", "")
+				var notes []string
+				for _, n := range res.Notes {
+					notes = append(notes, tense.Replace(n.String()))
+				}
+				sort.Strings(notes)
+				wantNotes := append([]string(nil), tc.notes...)
+				if test && tc.testNotes != nil {
+					wantNotes = append([]string(nil), tc.testNotes...)
+				}
+				sort.Strings(wantNotes)
+				if len(notes) != len(wantNotes) || (len(notes) > 0 && !reflect.DeepEqual(notes, wantNotes)) {
+					t.Errorf("notes = %q, want %q", notes, wantNotes)
+				}
+				if res.Changed != (len(wantNotes) > 0) {
+					t.Errorf("Changed = %v with notes %q", res.Changed, notes)
+				}
+				want := tc.want
+				if test {
+					want = tc.dest
+				}
+				got := readTree(t, dest)
+				if len(got) != len(want) || (len(got) > 0 && !reflect.DeepEqual(got, want)) {
+					t.Errorf("tree = %v, want %v", got, want)
+				}
+			})
+		}
+	}
+}
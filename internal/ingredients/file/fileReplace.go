@@ -0,0 +1,188 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/gogrlx/grlx/v2/internal/cook"
+	"github.com/gogrlx/grlx/v2/internal/ingredients"
+)
+
+func (f File) replace(ctx context.Context, test bool) (cook.Result, error) {
+	// Params: "name", "pattern", "repl", "count", "append_if_not_found",
+	// "not_found_content", "backup", "show_changes"
+
+	var notes []fmt.Stringer
+	name, ok := f.params["name"].(string)
+	if !ok || name == "" {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, ingredients.ErrMissingName
+	}
+	name = filepath.Clean(name)
+	if name == "/" {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, ErrModifyRoot
+	}
+	pattern, ok := f.params["pattern"].(string)
+	if !ok || pattern == "" {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, ErrMissingPattern
+	}
+	repl, ok := f.params["repl"].(string)
+	if !ok {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, ErrMissingRepl
+	}
+	count, err := f.intParam("count")
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	appendIfNotFound, _ := f.params["append_if_not_found"].(bool)
+	notFoundContent, ok := f.params["not_found_content"].(string)
+	if !ok || notFoundContent == "" {
+		notFoundContent = repl
+	}
+	// ^ and $ match at line boundaries, as the edits are line-oriented.
+	re, err := regexp.Compile("(?m)" + pattern)
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, errors.Join(ErrInvalidPattern, err)
+	}
+
+	before, err := os.ReadFile(name)
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	after, replaced := replaceN(re, before, []byte(repl), count)
+	appended := false
+	if replaced == 0 && appendIfNotFound && !bytes.Contains(before, []byte(notFoundContent)) {
+		after = appendLine(before, notFoundContent)
+		appended = true
+	}
+	if bytes.Equal(before, after) {
+		return cook.Result{
+			Succeeded: true, Failed: false,
+			Changed: false, Notes: notes,
+		}, nil
+	}
+
+	lineNotes := changedLineNotes(name, before, after)
+	diffNotes := f.diffNotes(name, before, after)
+	backupNotes, err := f.backup(name, test)
+	notes = append(notes, backupNotes...)
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	if test {
+		if appended {
+			notes = append(notes, cook.Snprintf("`%s` does not match `%s`, content would be appended", name, pattern))
+		} else {
+			notes = append(notes, cook.Snprintf("%d occurrences of `%s` would be replaced in `%s`", replaced, pattern, name))
+		}
+		notes = append(notes, lineNotes...)
+		notes = append(notes, diffNotes...)
+		return cook.Result{
+			Succeeded: true, Failed: false,
+			Changed: true, Notes: notes,
+		}, nil
+	}
+
+	if err = writeAtomic(name, after); err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	if appended {
+		notes = append(notes, cook.Snprintf("`%s` did not match `%s`, appended content", name, pattern))
+	} else {
+		notes = append(notes, cook.Snprintf("replaced %d occurrences of `%s` in `%s`", replaced, pattern, name))
+	}
+	notes = append(notes, lineNotes...)
+	notes = append(notes, diffNotes...)
+	return cook.Result{
+		Succeeded: true, Failed: false,
+		Changed: true, Notes: notes,
+	}, nil
+}
+
+// replaceN replaces the first count matches of re in src with repl, or
+// every match if count is 0. repl may refer to submatches as in
+// regexp.Regexp.Expand. It returns the result and how many matches were
+// replaced.
+func replaceN(re *regexp.Regexp, src, repl []byte, count int) ([]byte, int) {
+	n := -1
+	if count > 0 {
+		n = count
+	}
+	matches := re.FindAllSubmatchIndex(src, n)
+	out := make([]byte, 0, len(src))
+	last := 0
+	for _, m := range matches {
+		out = append(out, src[last:m[0]]...)
+		out = re.Expand(out, repl, src, m)
+		last = m[1]
+	}
+	return append(out, src[last:]...), len(matches)
+}
+
+// appendLine adds line to the end of content on a line of its own.
+func appendLine(content []byte, line string) []byte {
+	out := bytes.Clone(content)
+	if len(out) > 0 && out[len(out)-1] != '\n' {
+		out = append(out, '\n')
+	}
+	out = append(out, line...)
+	if len(line) == 0 || line[len(line)-1] != '\n' {
+		out = append(out, '\n')
+	}
+	return out
+}
+
+// intParam reads a non-negative integer property, which recipes may give
+// as a number or a string. A missing property is 0.
+func (f File) intParam(key string) (int, error) {
+	var n int
+	switch v := f.params[key].(type) {
+	case nil:
+		return 0, nil
+	case int:
+		n = v
+	case float64:
+		if v != float64(int(v)) {
+			return 0, errors.Join(ErrInvalidProperty, fmt.Errorf("%s must be an integer, got %v", key, v))
+		}
+		n = int(v)
+	case string:
+		if v == "" {
+			return 0, nil
+		}
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, errors.Join(ErrInvalidProperty, fmt.Errorf("%s must be an integer, got %q", key, v))
+		}
+		n = parsed
+	default:
+		return 0, errors.Join(ErrInvalidProperty, fmt.Errorf("%s must be an integer, got %v", key, v))
+	}
+	if n < 0 {
+		return 0, errors.Join(ErrInvalidProperty, fmt.Errorf("%s must not be negative", key))
+	}
+	return n, nil
+}
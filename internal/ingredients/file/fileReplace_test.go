@@ -0,0 +1,233 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplace(t *testing.T) {
+	const config = "# sshd\nPort 22\nPermitRootLogin yes\n#PermitRootLogin no\n"
+	tests := []struct {
+		name     string
+		params   map[string]interface{}
+		content  string
+		want     string
+		changed  bool
+		error    error
+		noCreate bool
+	}{
+		{
+			name:    "replace all",
+			params:  map[string]interface{}{"pattern": "^#?PermitRootLogin .*$", "repl": "PermitRootLogin no"},
+			content: config,
+			want:    "# sshd\nPort 22\nPermitRootLogin no\nPermitRootLogin no\n",
+			changed: true,
+		},
+		{
+			name:    "count",
+			params:  map[string]interface{}{"pattern": "^#?PermitRootLogin .*$", "repl": "PermitRootLogin no", "count": 1},
+			content: config,
+			want:    "# sshd\nPort 22\nPermitRootLogin no\n#PermitRootLogin no\n",
+			changed: true,
+		},
+		{
+			name:    "count as string",
+			params:  map[string]interface{}{"pattern": "o", "repl": "0", "count": "2"},
+			content: "foo\n",
+			want:    "f00\n",
+			changed: true,
+		},
+		{
+			name:    "submatches",
+			params:  map[string]interface{}{"pattern": `^Port (\d+)$`, "repl": "Port 2${1}"},
+			content: config,
+			want:    "# sshd\nPort 222\nPermitRootLogin yes\n#PermitRootLogin no\n",
+			changed: true,
+		},
+		{
+			name:    "already replaced",
+			params:  map[string]interface{}{"pattern": "^Port .*$", "repl": "Port 22"},
+			content: config,
+			want:    config,
+		},
+		{
+			name:    "no match",
+			params:  map[string]interface{}{"pattern": "^ListenAddress .*$", "repl": "ListenAddress 0.0.0.0"},
+			content: config,
+			want:    config,
+		},
+		{
+			name:    "append if not found",
+			params:  map[string]interface{}{"pattern": "^ListenAddress .*$", "repl": "ListenAddress 0.0.0.0", "append_if_not_found": true},
+			content: "Port 22",
+			want:    "Port 22\nListenAddress 0.0.0.0\n",
+			changed: true,
+		},
+		{
+			name:    "not found content",
+			params:  map[string]interface{}{"pattern": "^ListenAddress .*$", "repl": "x", "append_if_not_found": true, "not_found_content": "ListenAddress ::"},
+			content: config,
+			want:    config + "ListenAddress ::\n",
+			changed: true,
+		},
+		{
+			name:    "missing pattern",
+			params:  map[string]interface{}{"repl": "x"},
+			content: config,
+			want:    config,
+			error:   ErrMissingPattern,
+		},
+		{
+			name:    "missing repl",
+			params:  map[string]interface{}{"pattern": "x"},
+			content: config,
+			want:    config,
+			error:   ErrMissingRepl,
+		},
+		{
+			name:    "invalid pattern",
+			params:  map[string]interface{}{"pattern": "(", "repl": "x"},
+			content: config,
+			want:    config,
+			error:   ErrInvalidPattern,
+		},
+		{
+			name:    "invalid count",
+			params:  map[string]interface{}{"pattern": "x", "repl": "y", "count": -1},
+			content: config,
+			want:    config,
+			error:   ErrInvalidProperty,
+		},
+		{
+			name:     "missing file",
+			params:   map[string]interface{}{"pattern": "x", "repl": "y"},
+			noCreate: true,
+			error:    os.ErrNotExist,
+		},
+	}
+	for _, tc := range tests {
+		for _, test := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s/test=%v", tc.name, test), func(t *testing.T) {
+				name := filepath.Join(t.TempDir(), "sshd_config")
+				if !tc.noCreate {
+					if err := os.WriteFile(name, []byte(tc.content), 0o600); err != nil {
+						t.Fatal(err)
+					}
+				}
+				params := map[string]interface{}{"name": name}
+				for k, v := range tc.params {
+					params[k] = v
+				}
+				f := File{id: "replace", method: "replace", params: params}
+				run := f.Apply
+				if test {
+					run = f.Test
+				}
+				res, err := run(context.Background())
+				if !errors.Is(err, tc.error) {
+					t.Fatalf("error = %v, want %v", err, tc.error)
+				}
+				if tc.error != nil {
+					if !res.Failed {
+						t.Errorf("expected failed result, got %+v", res)
+					}
+					return
+				}
+				if res.Changed != tc.changed {
+					t.Errorf("Changed = %v, want %v (notes %v)", res.Changed, tc.changed, res.Notes)
+				}
+				got, _ := os.ReadFile(name)
+				want := tc.want
+				if test {
+					want = tc.content
+				}
+				if string(got) != want {
+					t.Errorf("content = %q, want %q", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestReplaceKeepsModeAndNotesLines(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(name, []byte("127.0.0.1 localhost\n10.0.0.1 db\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	f := File{id: "hosts", method: "replace", params: map[string]interface{}{
+		"name": name, "pattern": `^10\.0\.0\.1 db$`, "repl": "10.0.0.2 db",
+	}}
+	res, err := f.Apply(context.Background())
+	if err != nil || !res.Changed {
+		t.Fatalf("Apply() = %+v, %v", res, err)
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o640 {
+		t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+	}
+	var notes []string
+	for _, n := range res.Notes {
+		notes = append(notes, n.String())
+	}
+	joined := strings.Join(notes, "\n")
+	for _, want := range []string{"line 2 removed: 10.0.0.1 db", "line 2 added: 10.0.0.2 db"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("notes %q do not contain %q", joined, want)
+		}
+	}
+	entries, _ := os.ReadDir(filepath.Dir(name))
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestReplaceFollowsSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "real")
+	link := filepath.Join(dir, "link")
+	if err := os.WriteFile(target, []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	f := File{id: "link", method: "replace", params: map[string]interface{}{"name": link, "pattern": "a", "repl": "b"}}
+	if _, err := f.Apply(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("link was replaced: %v", err)
+	}
+	if got, _ := os.ReadFile(target); string(got) != "b\n" {
+		t.Errorf("target = %q, want %q", got, "b\n")
+	}
+}
+
+func TestReplaceBackup(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "motd")
+	backupDir := t.TempDir()
+	if err := os.WriteFile(name, []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f := File{id: "motd", method: "replace", params: map[string]interface{}{
+		"name": name, "pattern": "hello", "repl": "goodbye", "backup": backupDir,
+	}}
+	if _, err := f.Apply(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(backupDir, name) + "_*")
+	if len(matches) != 1 {
+		t.Fatalf("expected one backup, got %v", matches)
+	}
+	if got, _ := os.ReadFile(matches[0]); string(got) != "hello\n" {
+		t.Errorf("backup = %q", got)
+	}
+}
@@ -0,0 +1,147 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gogrlx/grlx/v2/internal/cook"
+	"github.com/gogrlx/grlx/v2/internal/ingredients"
+)
+
+func (f File) serialize(ctx context.Context, test bool) (cook.Result, error) {
+	// Params: "name", "dataset", "format", "merge_if_exists", "makedirs",
+	// "mode", "backup", "show_changes"
+
+	var notes []fmt.Stringer
+	name, ok := f.params["name"].(string)
+	if !ok || name == "" {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, ingredients.ErrMissingName
+	}
+	name = filepath.Clean(name)
+	if name == "/" {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, ErrModifyRoot
+	}
+	dataset, ok := f.params["dataset"].(map[string]interface{})
+	if !ok {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, ErrMissingDataset
+	}
+	format, err := f.structuredFormat(name, formatJSON, formatYAML, formatTOML)
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	mode, setMode, err := f.modeParam("mode")
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	if !setMode {
+		mode = 0o644
+	}
+	mergeIfExists, _ := f.params["merge_if_exists"].(bool)
+	makedirs, _ := f.params["makedirs"].(bool)
+
+	existing, readErr := os.ReadFile(name)
+	exists := readErr == nil
+	if readErr != nil && !os.IsNotExist(readErr) {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, fmt.Errorf("failed to read existing file %s: %w", name, readErr)
+	}
+	if exists && mergeIfExists {
+		current, decodeErr := decodeDataset(format, existing)
+		if decodeErr != nil {
+			return cook.Result{
+				Succeeded: false, Failed: true, Notes: notes,
+			}, errors.Join(ErrUnparsableContent, fmt.Errorf("failed to parse %s as %s: %w", name, format, decodeErr))
+		}
+		dataset = mergeDataset(current, dataset)
+	}
+	desired, err := encodeDataset(format, dataset)
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	if exists && bytes.Equal(existing, desired) {
+		return cook.Result{
+			Succeeded: true, Failed: false,
+			Changed: false, Notes: notes,
+		}, nil
+	}
+
+	dir := filepath.Dir(name)
+	if _, statErr := os.Stat(dir); os.IsNotExist(statErr) {
+		if !makedirs {
+			return cook.Result{
+				Succeeded: false, Failed: true,
+				Changed: false, Notes: []fmt.Stringer{
+					cook.Snprintf("parent directory `%s` does not exist and makedirs is false", dir),
+				},
+			}, ErrPathNotFound
+		}
+		if test {
+			notes = append(notes, cook.Snprintf("directory `%s` would be created", dir))
+		} else {
+			if err = os.MkdirAll(dir, 0o755); err != nil {
+				return cook.Result{
+					Succeeded: false, Failed: true, Notes: notes,
+				}, err
+			}
+			notes = append(notes, cook.Snprintf("created directory `%s`", dir))
+		}
+	}
+	backupNotes, err := f.backup(name, test)
+	notes = append(notes, backupNotes...)
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	diffNotes := f.diffNotes(name, existing, desired)
+	if test {
+		if exists {
+			notes = append(notes, cook.Snprintf("file `%s` would be updated with the %s dataset", name, format))
+		} else {
+			notes = append(notes, cook.Snprintf("file `%s` would be created with the %s dataset", name, format))
+		}
+		notes = append(notes, diffNotes...)
+		return cook.Result{
+			Succeeded: true, Failed: false,
+			Changed: true, Notes: notes,
+		}, nil
+	}
+
+	if exists {
+		err = writeAtomic(name, desired)
+	} else {
+		err = writeReplace(name, desired, mode, -1, -1)
+	}
+	if err != nil {
+		return cook.Result{
+			Succeeded: false, Failed: true, Notes: notes,
+		}, err
+	}
+	if exists {
+		notes = append(notes, cook.Snprintf("updated `%s` with the %s dataset", name, format))
+	} else {
+		notes = append(notes, cook.Snprintf("created `%s` with the %s dataset", name, format))
+	}
+	notes = append(notes, diffNotes...)
+	return cook.Result{
+		Succeeded: true, Failed: false,
+		Changed: true, Notes: notes,
+	}, nil
+}
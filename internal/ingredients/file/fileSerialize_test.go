@@ -0,0 +1,135 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSerialize(t *testing.T) {
+	dataset := map[string]interface{}{
+		"name": "app",
+		"server": map[string]interface{}{
+			"port":  8080,
+			"hosts": []interface{}{"a", "b"},
+		},
+	}
+	tests := []struct {
+		name     string
+		file     string
+		params   map[string]interface{}
+		existing string
+		want     string
+		changed  bool
+		error    error
+	}{
+		{
+			name:    "json",
+			file:    "app.json",
+			want:    "{\n  \"name\": \"app\",\n  \"server\": {\n    \"hosts\": [\n      \"a\",\n      \"b\"\n    ],\n    \"port\": 8080\n  }\n}\n",
+			changed: true,
+		},
+		{
+			name:    "yaml",
+			file:    "app.yml",
+			want:    "name: app\nserver:\n  hosts:\n    - a\n    - b\n  port: 8080\n",
+			changed: true,
+		},
+		{
+			name:    "toml",
+			file:    "app.toml",
+			want:    "name = \"app\"\n\n[server]\n  hosts = [\"a\", \"b\"]\n  port = 8080\n",
+			changed: true,
+		},
+		{
+			name:     "unchanged",
+			file:     "app.yaml",
+			existing: "name: app\nserver:\n  hosts:\n    - a\n    - b\n  port: 8080\n",
+			want:     "name: app\nserver:\n  hosts:\n    - a\n    - b\n  port: 8080\n",
+		},
+		{
+			name:     "replace",
+			file:     "app.yaml",
+			existing: "debug: true\n",
+			want:     "name: app\nserver:\n  hosts:\n    - a\n    - b\n  port: 8080\n",
+			changed:  true,
+		},
+		{
+			name:     "merge",
+			file:     "app.yaml",
+			params:   map[string]interface{}{"merge_if_exists": true},
+			existing: "debug: true\nserver:\n  port: 80\n  tls: false\n",
+			want:     "debug: true\nname: app\nserver:\n  hosts:\n    - a\n    - b\n  port: 8080\n  tls: false\n",
+			changed:  true,
+		},
+		{
+			name:    "format overrides extension",
+			file:    "app.conf",
+			params:  map[string]interface{}{"format": "json", "dataset": map[string]interface{}{"a": 1}},
+			want:    "{\n  \"a\": 1\n}\n",
+			changed: true,
+		},
+		{
+			name:  "unknown format",
+			file:  "app.conf",
+			error: ErrUnsupportedFormat,
+		},
+		{
+			name:   "missing dataset",
+			file:   "app.json",
+			params: map[string]interface{}{"dataset": nil},
+			error:  ErrMissingDataset,
+		},
+	}
+	for _, tc := range tests {
+		for _, test := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s/test=%v", tc.name, test), func(t *testing.T) {
+				name := filepath.Join(t.TempDir(), tc.file)
+				if tc.existing != "" {
+					if err := os.WriteFile(name, []byte(tc.existing), 0o600); err != nil {
+						t.Fatal(err)
+					}
+				}
+				params := map[string]interface{}{"name": name, "dataset": dataset}
+				for k, v := range tc.params {
+					params[k] = v
+				}
+				f := File{id: "serialize", method: "serialize", params: params}
+				run := f.Apply
+				if test {
+					run = f.Test
+				}
+				res, err := run(context.Background())
+				if !errors.Is(err, tc.error) {
+					t.Fatalf("error = %v, want %v", err, tc.error)
+				}
+				if tc.error != nil {
+					return
+				}
+				if res.Changed != tc.changed {
+					t.Errorf("Changed = %v, want %v (notes %v)", res.Changed, tc.changed, res.Notes)
+				}
+				got, _ := os.ReadFile(name)
+				want := tc.want
+				if test {
+					want = tc.existing
+				}
+				if string(got) != want {
+					t.Errorf("content = %q, want %q", got, want)
+				}
+				if tc.existing != "" && !test {
+					info, err := os.Stat(name)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if info.Mode().Perm() != 0o600 {
+						t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+					}
+				}
+			})
+		}
+	}
+}
@@ -132,8 +132,8 @@ func TestApplyDispatch(t *testing.T) {
 func TestPropertiesForMethod(t *testing.T) {
 	allMethods := []string{
 		"absent", "append", "cached", "contains", "content",
-		"directory", "managed", "missing", "prepend", "exists",
-		"symlink", "touch",
+		"directory", "keys_present", "line", "managed", "missing", "prepend",
+		"recurse", "replace", "serialize", "exists", "symlink", "touch",
 	}
 	for _, method := range allMethods {
 		t.Run(method, func(t *testing.T) {
@@ -335,8 +335,8 @@ func TestMethods(t *testing.T) {
 	if prefix != "file" {
 		t.Errorf("expected prefix 'file', got %s", prefix)
 	}
-	if len(methods) != 12 {
-		t.Errorf("expected 12 methods, got %d", len(methods))
+	if len(methods) != 17 {
+		t.Errorf("expected 17 methods, got %d", len(methods))
 	}
 }
 
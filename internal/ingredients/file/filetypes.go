@@ -3,6 +3,7 @@ package file
 import (
 	"context"
 	"errors"
+	"os"
 
 	"github.com/gogrlx/grlx/v2/internal/ingredients/file/hashers"
 )
@@ -15,15 +16,46 @@ type FileProvider interface {
 	Verify(context.Context) (bool, error)
 }
 
+// DirLister is implemented by FileProviders that can list a source
+// directory, which file.recurse needs to mirror it.
+type DirLister interface {
+	List(context.Context) ([]SourceFile, error)
+}
+
+// SourceFile is a regular file found under a source directory.
+type SourceFile struct {
+	// Path is slash-separated and relative to the source directory.
+	Path   string      `json:"path"`
+	Size   int64       `json:"size"`
+	Mode   os.FileMode `json:"mode"`
+	SHA256 string      `json:"sha256"`
+}
+
 var (
-	ErrMissingSource  = errors.New("recipe is missing a source")
-	ErrMissingHash    = errors.New("file is missing a hash")
-	ErrCacheFailure   = errors.New("file caching failed")
-	ErrMissingContent = errors.New("file is missing content")
-	ErrFileNotFound   = hashers.ErrFileNotFound
-	ErrHashMismatch   = hashers.ErrHashMismatch
-	ErrDeleteRoot     = errors.New("cannot delete root directory")
-	ErrModifyRoot     = errors.New("cannot modify root directory")
-	ErrMissingTarget  = errors.New("target is missing")
-	ErrPathNotFound   = errors.New("path not found")
+	ErrMissingSource     = errors.New("recipe is missing a source")
+	ErrMissingHash       = errors.New("file is missing a hash")
+	ErrCacheFailure      = errors.New("file caching failed")
+	ErrMissingContent    = errors.New("file is missing content")
+	ErrFileNotFound      = hashers.ErrFileNotFound
+	ErrHashMismatch      = hashers.ErrHashMismatch
+	ErrDeleteRoot        = errors.New("cannot delete root directory")
+	ErrModifyRoot        = errors.New("cannot modify root directory")
+	ErrMissingTarget     = errors.New("target is missing")
+	ErrPathNotFound      = errors.New("path not found")
+	ErrInvalidBackup     = errors.New("backup must be `minion` or an absolute directory")
+	ErrMissingPattern    = errors.New("recipe is missing a pattern")
+	ErrInvalidPattern    = errors.New("pattern is not a valid regular expression")
+	ErrMissingRepl       = errors.New("recipe is missing a replacement")
+	ErrInvalidProperty   = errors.New("invalid property value")
+	ErrInvalidLineMode   = errors.New("mode must be `ensure`, `replace` or `delete`")
+	ErrAnchorNotFound    = errors.New("no line matches the anchor")
+	ErrListUnsupported   = errors.New("file provider cannot list directories")
+	ErrInvalidGlob       = errors.New("pattern is not a valid glob")
+	ErrNotDirectory      = errors.New("path is not a directory")
+	ErrUnsafeSourcePath  = errors.New("source file path leaves the source directory")
+	ErrUnsupportedFormat = errors.New("unsupported file format")
+	ErrMissingDataset    = errors.New("recipe is missing a dataset")
+	ErrMissingKeys       = errors.New("recipe is missing keys or absent")
+	ErrKeyConflict       = errors.New("key path crosses a value that is not a mapping")
+	ErrUnparsableContent = errors.New("file content cannot be parsed")
 )
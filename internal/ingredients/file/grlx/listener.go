@@ -0,0 +1,244 @@
+package grlx
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	nats "github.com/nats-io/nats.go"
+
+	"github.com/gogrlx/grlx/v2/internal/config"
+	"github.com/gogrlx/grlx/v2/internal/ingredients/file"
+	log "github.com/gogrlx/grlx/v2/internal/log"
+)
+
+const (
+	statSuffix = "files.stat"
+	getSuffix  = "files.get"
+	listSuffix = "files.list"
+)
+
+// ChunkSize is the largest number of bytes returned in a single FileChunk.
+// It is kept well below the NATS default max payload (1MB) to leave room
+// for the base64 and JSON overhead.
+var ChunkSize int64 = 512 * 1024
+
+// FileRequest asks the farmer for metadata or a byte range of a file under
+// its RecipeDir.
+type FileRequest struct {
+	Path   string `json:"path"`
+	Offset int64  `json:"offset,omitempty"`
+	Length int64  `json:"length,omitempty"`
+}
+
+// FileStat describes a file served by the farmer.
+type FileStat struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	Error  string `json:"error,omitempty"`
+}
+
+// FileChunk carries a byte range of a file served by the farmer.
+type FileChunk struct {
+	Data  []byte `json:"data"`
+	EOF   bool   `json:"eof"`
+	Error string `json:"error,omitempty"`
+}
+
+// FileList lists the regular files under a directory served by the
+// farmer.
+type FileList struct {
+	Files []file.SourceFile `json:"files"`
+	Error string            `json:"error,omitempty"`
+}
+
+var ErrOutsideRecipeDir = errors.New("path resolves outside of the recipe directory")
+
+func subjectFor(sproutID, suffix string) string {
+	return "grlx.sprouts." + sproutID + "." + suffix
+}
+
+// RegisterFarmerListener subscribes to sprout file requests and serves
+// files from config.RecipeDir.
+func RegisterFarmerListener(nc *nats.Conn) {
+	_, err := nc.Subscribe(subjectFor("*", statSuffix), func(msg *nats.Msg) {
+		var req FileRequest
+		var stat FileStat
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			stat.Error = err.Error()
+		} else {
+			stat = statFile(config.RecipeDir, req.Path)
+		}
+		b, _ := json.Marshal(stat)
+		msg.Respond(b)
+	})
+	if err != nil {
+		log.Errorf("grlx file provider: failed to subscribe: %v", err)
+	}
+	_, err = nc.Subscribe(subjectFor("*", listSuffix), func(msg *nats.Msg) {
+		var req FileRequest
+		var list FileList
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			list.Error = err.Error()
+		} else {
+			list = listDir(config.RecipeDir, req.Path)
+		}
+		b, _ := json.Marshal(list)
+		msg.Respond(b)
+	})
+	if err != nil {
+		log.Errorf("grlx file provider: failed to subscribe: %v", err)
+	}
+	_, err = nc.Subscribe(subjectFor("*", getSuffix), func(msg *nats.Msg) {
+		var req FileRequest
+		var chunk FileChunk
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			chunk.Error = err.Error()
+		} else {
+			chunk = readChunk(config.RecipeDir, req)
+		}
+		b, _ := json.Marshal(chunk)
+		msg.Respond(b)
+	})
+	if err != nil {
+		log.Errorf("grlx file provider: failed to subscribe: %v", err)
+	}
+}
+
+// resolvePath joins path onto root and ensures the result, including any
+// symlinks along the way, stays inside root.
+func resolvePath(root, path string) (string, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	if realRoot, evalErr := filepath.EvalSymlinks(root); evalErr == nil {
+		root = realRoot
+	}
+	full := filepath.Join(root, filepath.Clean("/"+path))
+	real, err := filepath.EvalSymlinks(full)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, real)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Join(ErrOutsideRecipeDir, fmt.Errorf("%s", path))
+	}
+	return real, nil
+}
+
+func statFile(root, path string) FileStat {
+	stat := FileStat{Path: path}
+	full, err := resolvePath(root, path)
+	if err != nil {
+		stat.Error = err.Error()
+		return stat
+	}
+	f, err := os.Open(full)
+	if err != nil {
+		stat.Error = err.Error()
+		return stat
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		stat.Error = err.Error()
+		return stat
+	}
+	if !info.Mode().IsRegular() {
+		stat.Error = fmt.Sprintf("%s is not a regular file", path)
+		return stat
+	}
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		stat.Error = err.Error()
+		return stat
+	}
+	stat.Size = n
+	stat.SHA256 = fmt.Sprintf("%x", h.Sum(nil))
+	log.Tracef("grlx file provider: serving %s (%d bytes)", full, n)
+	return stat
+}
+
+// listDir lists the regular files under path. Symlinks are skipped, so
+// the listing never leaves root.
+func listDir(root, path string) FileList {
+	var list FileList
+	full, err := resolvePath(root, path)
+	if err != nil {
+		list.Error = err.Error()
+		return list
+	}
+	err = filepath.WalkDir(full, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(full, p)
+		if err != nil {
+			return err
+		}
+		stat := statFile(full, rel)
+		if stat.Error != "" {
+			return errors.New(stat.Error)
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		list.Files = append(list.Files, file.SourceFile{
+			Path:   filepath.ToSlash(rel),
+			Size:   stat.Size,
+			Mode:   info.Mode().Perm(),
+			SHA256: stat.SHA256,
+		})
+		return nil
+	})
+	if err != nil {
+		list.Error = err.Error()
+		list.Files = nil
+	}
+	return list
+}
+
+func readChunk(root string, req FileRequest) FileChunk {
+	var chunk FileChunk
+	full, err := resolvePath(root, req.Path)
+	if err != nil {
+		chunk.Error = err.Error()
+		return chunk
+	}
+	length := req.Length
+	if length <= 0 || length > ChunkSize {
+		length = ChunkSize
+	}
+	if req.Offset < 0 {
+		chunk.Error = "negative offset"
+		return chunk
+	}
+	f, err := os.Open(full)
+	if err != nil {
+		chunk.Error = err.Error()
+		return chunk
+	}
+	defer f.Close()
+	buf := make([]byte, length)
+	n, err := f.ReadAt(buf, req.Offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		chunk.Error = err.Error()
+		return chunk
+	}
+	chunk.Data = buf[:n]
+	chunk.EOF = errors.Is(err, io.EOF)
+	return chunk
+}
@@ -0,0 +1,231 @@
+// Package grlx implements the grlx:// file provider. Sources such as
+// grlx://configs/app.yaml are resolved relative to the farmer's RecipeDir
+// and streamed to the sprout in chunks over the authenticated NATS bus.
+package grlx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+
+	"github.com/gogrlx/grlx/v2/internal/config"
+	"github.com/gogrlx/grlx/v2/internal/ingredients/file"
+	"github.com/gogrlx/grlx/v2/internal/ingredients/file/hashers"
+)
+
+var (
+	nc *nats.Conn
+
+	// requestTimeout bounds each individual stat/chunk request to the farmer.
+	requestTimeout = 30 * time.Second
+
+	ErrNoConnection      = errors.New("grlx file provider: no NATS connection registered")
+	ErrInvalidSource     = errors.New("grlx file provider: invalid source path")
+	ErrTransferMismatch  = errors.New("grlx file provider: transferred file does not match farmer checksum")
+	ErrUnexpectedEOF     = errors.New("grlx file provider: farmer ended transfer early")
+	ErrMissingSproutID   = errors.New("grlx file provider: sprout ID is not configured")
+	ErrFarmerFileRequest = errors.New("grlx file provider: farmer rejected file request")
+)
+
+// RegisterNatsConn sets the connection used by sprouts to fetch files from
+// the farmer.
+func RegisterNatsConn(conn *nats.Conn) {
+	nc = conn
+}
+
+type GRLXFile struct {
+	ID          string
+	Source      string
+	Destination string
+	Hash        string
+	Props       map[string]interface{}
+}
+
+// Compile-time interface checks.
+var (
+	_ file.FileProvider = GRLXFile{}
+	_ file.DirLister    = GRLXFile{}
+)
+
+func (gf GRLXFile) Download(ctx context.Context) error {
+	if gf.Hash != "" {
+		ok, err := gf.Verify(ctx)
+		if err != nil && !errors.Is(err, file.ErrFileNotFound) {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+	if nc == nil {
+		return ErrNoConnection
+	}
+	sproutID := config.SproutID
+	if sproutID == "" {
+		return ErrMissingSproutID
+	}
+	path, err := sourcePath(gf.Source)
+	if err != nil {
+		return err
+	}
+	var stat FileStat
+	if err = request(ctx, subjectFor(sproutID, statSuffix), FileRequest{Path: path}, &stat); err != nil {
+		return err
+	}
+	if stat.Error != "" {
+		return errors.Join(ErrFarmerFileRequest, fmt.Errorf("stat %s: %s", gf.Source, stat.Error))
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(gf.Destination), "."+filepath.Base(gf.Destination)+".part-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	hasher := sha256.New()
+	w := io.MultiWriter(tmp, hasher)
+	var offset int64
+	for offset < stat.Size {
+		var chunk FileChunk
+		req := FileRequest{Path: path, Offset: offset, Length: ChunkSize}
+		if err = request(ctx, subjectFor(sproutID, getSuffix), req, &chunk); err != nil {
+			tmp.Close()
+			return err
+		}
+		if chunk.Error != "" {
+			tmp.Close()
+			return errors.Join(ErrFarmerFileRequest, fmt.Errorf("read %s at offset %d: %s", gf.Source, offset, chunk.Error))
+		}
+		if len(chunk.Data) == 0 {
+			tmp.Close()
+			return errors.Join(ErrUnexpectedEOF, fmt.Errorf("%s: received %d of %d bytes", gf.Source, offset, stat.Size))
+		}
+		if _, err = w.Write(chunk.Data); err != nil {
+			tmp.Close()
+			return err
+		}
+		offset += int64(len(chunk.Data))
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if actual := fmt.Sprintf("%x", hasher.Sum(nil)); actual != stat.SHA256 {
+		return errors.Join(ErrTransferMismatch, fmt.Errorf("%s: expected sha256 %s but received %s", gf.Source, stat.SHA256, actual))
+	}
+	if err = os.Rename(tmpName, gf.Destination); err != nil {
+		return err
+	}
+	if gf.Hash == "" {
+		return nil
+	}
+	ok, err := gf.Verify(ctx)
+	if err != nil {
+		os.Remove(gf.Destination)
+		return err
+	}
+	if !ok {
+		os.Remove(gf.Destination)
+		return errors.Join(file.ErrHashMismatch, fmt.Errorf("recipe step %s: hash for %s does not match %s", gf.ID, gf.Source, gf.Hash))
+	}
+	return nil
+}
+
+// List asks the farmer for the regular files under the source directory.
+func (gf GRLXFile) List(ctx context.Context) ([]file.SourceFile, error) {
+	if nc == nil {
+		return nil, ErrNoConnection
+	}
+	sproutID := config.SproutID
+	if sproutID == "" {
+		return nil, ErrMissingSproutID
+	}
+	path, err := sourcePath(gf.Source)
+	if err != nil {
+		return nil, err
+	}
+	var list FileList
+	if err = request(ctx, subjectFor(sproutID, listSuffix), FileRequest{Path: path}, &list); err != nil {
+		return nil, err
+	}
+	if list.Error != "" {
+		return nil, errors.Join(ErrFarmerFileRequest, fmt.Errorf("list %s: %s", gf.Source, list.Error))
+	}
+	return list.Files, nil
+}
+
+// request sends a single JSON request to the farmer and decodes the reply
+// into out.
+func request(ctx context.Context, subject string, req FileRequest, out any) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	msg, err := nc.RequestWithContext(reqCtx, subject, b)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(msg.Data, out)
+}
+
+// sourcePath strips the grlx:// scheme from source and returns the path
+// relative to the farmer's RecipeDir.
+func sourcePath(source string) (string, error) {
+	path, ok := strings.CutPrefix(source, "grlx://")
+	if !ok {
+		return "", errors.Join(ErrInvalidSource, fmt.Errorf("source %s is not a grlx:// URL", source))
+	}
+	path = strings.TrimLeft(path, "/")
+	if path == "" {
+		return "", errors.Join(ErrInvalidSource, fmt.Errorf("source %s has an empty path", source))
+	}
+	return path, nil
+}
+
+func (gf GRLXFile) Properties() (map[string]interface{}, error) {
+	return gf.Props, nil
+}
+
+func (gf GRLXFile) Parse(id, source, destination, hash string, properties map[string]interface{}) (file.FileProvider, error) {
+	if properties == nil {
+		properties = make(map[string]interface{})
+	}
+	return GRLXFile{ID: id, Source: source, Destination: destination, Hash: hash, Props: properties}, nil
+}
+
+func (gf GRLXFile) Protocols() []string {
+	return []string{"grlx"}
+}
+
+func (gf GRLXFile) Verify(ctx context.Context) (bool, error) {
+	hashType := ""
+	if gf.Props["hashType"] == nil {
+		hashType = hashers.GuessHashType(gf.Hash)
+	} else if ht, ok := gf.Props["hashType"].(string); !ok {
+		hashType = hashers.GuessHashType(gf.Hash)
+	} else {
+		hashType = ht
+	}
+	cf := hashers.CacheFile{
+		ID:          gf.ID,
+		Destination: gf.Destination,
+		Hash:        gf.Hash,
+		HashType:    hashType,
+	}
+	return cf.Verify(ctx)
+}
+
+func init() {
+	file.RegisterProvider(GRLXFile{})
+}
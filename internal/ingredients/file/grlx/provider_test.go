@@ -0,0 +1,360 @@
+package grlx
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	nats "github.com/nats-io/nats.go"
+
+	"github.com/gogrlx/grlx/v2/internal/config"
+	"github.com/gogrlx/grlx/v2/internal/ingredients/file"
+)
+
+func startTestNATS(t *testing.T) *nats.Conn {
+	t.Helper()
+	opts := &server.Options{
+		Host: "127.0.0.1",
+		Port: -1,
+	}
+	ns, err := server.NewServer(opts)
+	if err != nil {
+		t.Fatalf("start test NATS server: %v", err)
+	}
+	go ns.Start()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server failed to become ready")
+	}
+	conn, err := nats.Connect(ns.ClientURL())
+	if err != nil {
+		ns.Shutdown()
+		t.Fatalf("connect to test NATS: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		ns.Shutdown()
+	})
+	return conn
+}
+
+// setupFarmer starts a NATS server, registers the farmer listener and the
+// sprout connection, and points RecipeDir at a fresh temp directory.
+func setupFarmer(t *testing.T) string {
+	t.Helper()
+	conn := startTestNATS(t)
+	recipeDir := t.TempDir()
+
+	origRecipeDir, origSproutID := config.RecipeDir, config.SproutID
+	config.RecipeDir = recipeDir
+	config.SproutID = "grlx-file-test"
+	RegisterFarmerListener(conn)
+	conn.Flush()
+	RegisterNatsConn(conn)
+	t.Cleanup(func() {
+		config.RecipeDir, config.SproutID = origRecipeDir, origSproutID
+		RegisterNatsConn(nil)
+	})
+	return recipeDir
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestProtocols(t *testing.T) {
+	protos := GRLXFile{}.Protocols()
+	if len(protos) != 1 || protos[0] != "grlx" {
+		t.Errorf("expected [\"grlx\"], got %v", protos)
+	}
+}
+
+func TestParse(t *testing.T) {
+	fp, err := GRLXFile{}.Parse("step", "grlx://configs/app.yaml", "/tmp/dst", "abc", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	gf, ok := fp.(GRLXFile)
+	if !ok {
+		t.Fatal("expected GRLXFile type")
+	}
+	if gf.ID != "step" || gf.Source != "grlx://configs/app.yaml" || gf.Destination != "/tmp/dst" || gf.Hash != "abc" {
+		t.Errorf("unexpected parse result: %+v", gf)
+	}
+	if gf.Props == nil {
+		t.Error("expected props to be initialized")
+	}
+}
+
+func TestNewFileProviderResolvesGRLX(t *testing.T) {
+	fp, err := file.NewFileProvider("step", "grlx://configs/app.yaml", "/tmp/dst", "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := fp.(GRLXFile); !ok {
+		t.Errorf("expected GRLXFile, got %T", fp)
+	}
+}
+
+func TestSourcePath(t *testing.T) {
+	cases := []struct {
+		source  string
+		want    string
+		wantErr bool
+	}{
+		{source: "grlx://configs/app.yaml", want: "configs/app.yaml"},
+		{source: "grlx:///configs/app.yaml", want: "configs/app.yaml"},
+		{source: "grlx://", wantErr: true},
+		{source: "http://example.com/a", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.source, func(t *testing.T) {
+			got, err := sourcePath(tc.source)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidSource) {
+					t.Errorf("expected ErrInvalidSource, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDownload(t *testing.T) {
+	recipeDir := setupFarmer(t)
+	data := []byte("listen: 8080\n")
+	writeFile(t, filepath.Join(recipeDir, "configs", "app.yaml"), data)
+
+	dst := filepath.Join(t.TempDir(), "app.yaml")
+	fp, _ := GRLXFile{}.Parse("step", "grlx://configs/app.yaml", dst, sha256Hex(data), nil)
+	if err := fp.Download(context.Background()); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(data) {
+		t.Errorf("want %q, got %q", data, got)
+	}
+	ok, err := fp.Verify(context.Background())
+	if err != nil || !ok {
+		t.Errorf("Verify: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestDownloadChunked(t *testing.T) {
+	recipeDir := setupFarmer(t)
+	origChunk := ChunkSize
+	ChunkSize = 7
+	t.Cleanup(func() { ChunkSize = origChunk })
+
+	data := []byte(strings.Repeat("0123456789abcdef", 10))
+	writeFile(t, filepath.Join(recipeDir, "big.bin"), data)
+	sum := md5.Sum(data)
+
+	dst := filepath.Join(t.TempDir(), "big.bin")
+	fp, _ := GRLXFile{}.Parse("step", "grlx://big.bin", dst, hex.EncodeToString(sum[:]), map[string]interface{}{"hashType": "md5"})
+	if err := fp.Download(context.Background()); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	got, _ := os.ReadFile(dst)
+	if string(got) != string(data) {
+		t.Errorf("chunked download produced %d bytes, want %d", len(got), len(data))
+	}
+}
+
+func TestDownloadEmptyFile(t *testing.T) {
+	recipeDir := setupFarmer(t)
+	writeFile(t, filepath.Join(recipeDir, "empty"), nil)
+
+	dst := filepath.Join(t.TempDir(), "empty")
+	fp, _ := GRLXFile{}.Parse("step", "grlx://empty", dst, sha256Hex(nil), nil)
+	if err := fp.Download(context.Background()); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("expected empty file, got %d bytes", info.Size())
+	}
+}
+
+func TestDownloadSkipVerify(t *testing.T) {
+	recipeDir := setupFarmer(t)
+	writeFile(t, filepath.Join(recipeDir, "motd"), []byte("hello"))
+
+	dst := filepath.Join(t.TempDir(), "motd")
+	fp, _ := GRLXFile{}.Parse("step", "grlx://motd", dst, "", nil)
+	if err := fp.Download(context.Background()); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if got, _ := os.ReadFile(dst); string(got) != "hello" {
+		t.Errorf("want hello, got %q", got)
+	}
+}
+
+func TestDownloadHashMismatch(t *testing.T) {
+	recipeDir := setupFarmer(t)
+	writeFile(t, filepath.Join(recipeDir, "motd"), []byte("hello"))
+
+	dst := filepath.Join(t.TempDir(), "motd")
+	fp, _ := GRLXFile{}.Parse("step", "grlx://motd", dst, sha256Hex([]byte("goodbye")), nil)
+	err := fp.Download(context.Background())
+	if !errors.Is(err, file.ErrHashMismatch) {
+		t.Fatalf("expected ErrHashMismatch, got %v", err)
+	}
+	if _, statErr := os.Stat(dst); !os.IsNotExist(statErr) {
+		t.Error("expected mismatched download to be removed")
+	}
+}
+
+func TestDownloadAlreadyCached(t *testing.T) {
+	setupFarmer(t)
+	data := []byte("cached")
+	dst := filepath.Join(t.TempDir(), "cached")
+	writeFile(t, dst, data)
+
+	// The source does not exist on the farmer, so this only succeeds if
+	// the cached copy is accepted without a transfer.
+	fp, _ := GRLXFile{}.Parse("step", "grlx://missing", dst, sha256Hex(data), nil)
+	if err := fp.Download(context.Background()); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+}
+
+func TestDownloadMissingSource(t *testing.T) {
+	setupFarmer(t)
+	dst := filepath.Join(t.TempDir(), "missing")
+	fp, _ := GRLXFile{}.Parse("step", "grlx://missing", dst, "", nil)
+	err := fp.Download(context.Background())
+	if !errors.Is(err, ErrFarmerFileRequest) {
+		t.Fatalf("expected ErrFarmerFileRequest, got %v", err)
+	}
+}
+
+func TestDownloadPathTraversal(t *testing.T) {
+	recipeDir := setupFarmer(t)
+	outside := filepath.Join(filepath.Dir(recipeDir), "secret")
+	writeFile(t, outside, []byte("secret"))
+	t.Cleanup(func() { os.Remove(outside) })
+	if err := os.Symlink(outside, filepath.Join(recipeDir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, src := range []string{"grlx://../secret", "grlx://link"} {
+		t.Run(src, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "secret")
+			fp, _ := GRLXFile{}.Parse("step", src, dst, "", nil)
+			err := fp.Download(context.Background())
+			if !errors.Is(err, ErrFarmerFileRequest) {
+				t.Fatalf("expected ErrFarmerFileRequest, got %v", err)
+			}
+			if _, statErr := os.Stat(dst); !os.IsNotExist(statErr) {
+				t.Error("expected no file to be written")
+			}
+		})
+	}
+}
+
+func TestDownloadNoConnection(t *testing.T) {
+	RegisterNatsConn(nil)
+	fp, _ := GRLXFile{}.Parse("step", "grlx://motd", filepath.Join(t.TempDir(), "motd"), "", nil)
+	if err := fp.Download(context.Background()); !errors.Is(err, ErrNoConnection) {
+		t.Fatalf("expected ErrNoConnection, got %v", err)
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a", "b.txt"), []byte("b"))
+	if _, err := resolvePath(root, "a/b.txt"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := resolvePath(root, "a/../a/b.txt"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// Cleaning against "/" keeps ".." from escaping the root, so this
+	// resolves to root/b.txt, which does not exist.
+	if _, err := resolvePath(root, "../../b.txt"); err == nil {
+		t.Error("expected error for path outside of root")
+	}
+}
+
+func TestReadChunk(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "f"), []byte("abcdef"))
+	chunk := readChunk(root, FileRequest{Path: "f", Offset: 2, Length: 3})
+	if chunk.Error != "" || string(chunk.Data) != "cde" || chunk.EOF {
+		t.Errorf("unexpected chunk: %+v", chunk)
+	}
+	chunk = readChunk(root, FileRequest{Path: "f", Offset: 4, Length: 10})
+	if chunk.Error != "" || string(chunk.Data) != "ef" || !chunk.EOF {
+		t.Errorf("unexpected chunk: %+v", chunk)
+	}
+	chunk = readChunk(root, FileRequest{Path: "f", Offset: -1})
+	if chunk.Error == "" {
+		t.Error("expected error for negative offset")
+	}
+}
+
+func TestList(t *testing.T) {
+	recipeDir := setupFarmer(t)
+	writeFile(t, filepath.Join(recipeDir, "nginx", "conf.d", "site.conf"), []byte("server {}\n"))
+	writeFile(t, filepath.Join(recipeDir, "nginx", "nginx.conf"), []byte("events {}\n"))
+	outside := filepath.Join(filepath.Dir(recipeDir), "secret")
+	writeFile(t, outside, []byte("secret"))
+	t.Cleanup(func() { os.Remove(outside) })
+	if err := os.Symlink(outside, filepath.Join(recipeDir, "nginx", "secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	fp, _ := GRLXFile{}.Parse("step", "grlx://nginx", "", "", nil)
+	files, err := fp.(file.DirLister).List(context.Background())
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %+v", files)
+	}
+	if files[0].Path != "conf.d/site.conf" || files[0].SHA256 != sha256Hex([]byte("server {}\n")) || files[0].Mode != 0o644 {
+		t.Errorf("unexpected entry %+v", files[0])
+	}
+	if files[1].Path != "nginx.conf" || files[1].Size != int64(len("events {}\n")) {
+		t.Errorf("unexpected entry %+v", files[1])
+	}
+
+	if err = os.Symlink(filepath.Dir(recipeDir), filepath.Join(recipeDir, "up")); err != nil {
+		t.Fatal(err)
+	}
+	fp, _ = GRLXFile{}.Parse("step", "grlx://up", "", "", nil)
+	if _, err = fp.(file.DirLister).List(context.Background()); !errors.Is(err, ErrFarmerFileRequest) {
+		t.Fatalf("expected ErrFarmerFileRequest, got %v", err)
+	}
+}
@@ -2,9 +2,13 @@ package local
 
 import (
 	"context"
+	"crypto/sha256"
 	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 
 	"github.com/gogrlx/grlx/v2/internal/ingredients/file"
 	"github.com/gogrlx/grlx/v2/internal/ingredients/file/hashers"
@@ -18,8 +22,11 @@ type LocalFile struct {
 	Props       map[string]interface{}
 }
 
-// Compile-time interface check.
-var _ file.FileProvider = LocalFile{}
+// Compile-time interface checks.
+var (
+	_ file.FileProvider = LocalFile{}
+	_ file.DirLister    = LocalFile{}
+)
 
 func (lf LocalFile) Download(ctx context.Context) error {
 	ok, err := lf.Verify(ctx)
@@ -62,6 +69,56 @@ func (lf LocalFile) Parse(id, source, destination, hash string, properties map[s
 	return LocalFile{ID: id, Source: source, Destination: destination, Hash: hash, Props: properties}, nil
 }
 
+// List returns the regular files under the source directory. Symlinks
+// and other special files are skipped.
+func (lf LocalFile) List(ctx context.Context) ([]file.SourceFile, error) {
+	var files []file.SourceFile
+	err := filepath.WalkDir(lf.Source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(lf.Source, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		sum, err := sha256File(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, file.SourceFile{
+			Path:   filepath.ToSlash(rel),
+			Size:   info.Size(),
+			Mode:   info.Mode().Perm(),
+			SHA256: sum,
+		})
+		return nil
+	})
+	return files, err
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
 func (lf LocalFile) Protocols() []string {
 	return []string{"file"}
 }
@@ -320,3 +320,27 @@ func TestDownloadWithSHA256(t *testing.T) {
 		t.Error("expected Verify to pass after Download")
 	}
 }
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "a.conf"), []byte("a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/etc/passwd", filepath.Join(dir, "passwd")); err != nil {
+		t.Fatal(err)
+	}
+	files, err := LocalFile{Source: dir}.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("%x", sha256.Sum256([]byte("a")))
+	if len(files) != 1 || files[0].Path != "sub/a.conf" || files[0].SHA256 != want || files[0].Mode != 0o600 || files[0].Size != 1 {
+		t.Fatalf("unexpected listing %+v", files)
+	}
+	if _, err = (LocalFile{Source: filepath.Join(dir, "missing")}).List(context.Background()); err == nil {
+		t.Error("expected error listing a missing directory")
+	}
+}
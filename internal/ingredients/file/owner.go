@@ -0,0 +1,47 @@
+package file
+
+import (
+	"io/fs"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// LookupOwner resolves a user and group name to ids, -1 meaning leave as
+// is. An empty name resolves to -1.
+func LookupOwner(userName, groupName string) (int, int, error) {
+	uid, gid := -1, -1
+	if userName != "" {
+		u, err := user.Lookup(userName)
+		if err != nil {
+			return 0, 0, err
+		}
+		id, err := strconv.ParseUint(u.Uid, 10, 32)
+		if err != nil {
+			return 0, 0, err
+		}
+		uid = int(id)
+	}
+	if groupName != "" {
+		g, err := user.LookupGroup(groupName)
+		if err != nil {
+			return 0, 0, err
+		}
+		id, err := strconv.ParseUint(g.Gid, 10, 32)
+		if err != nil {
+			return 0, 0, err
+		}
+		gid = int(id)
+	}
+	return uid, gid, nil
+}
+
+// OwnerDiffers reports whether info is not owned by uid and gid, either
+// of which may be -1 to ignore it.
+func OwnerDiffers(info fs.FileInfo, uid, gid int) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	return (uid >= 0 && int(st.Uid) != uid) || (gid >= 0 && int(st.Gid) != gid)
+}
@@ -0,0 +1,51 @@
+package file
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestLookupOwner(t *testing.T) {
+	uid, gid, err := LookupOwner("", "")
+	if err != nil || uid != -1 || gid != -1 {
+		t.Errorf("LookupOwner(\"\", \"\") = %d, %d, %v, want -1, -1, nil", uid, gid, err)
+	}
+	current, err := user.Current()
+	if err != nil {
+		t.Skip(err)
+	}
+	uid, _, err = LookupOwner(current.Username, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strconv.Itoa(uid) != current.Uid {
+		t.Errorf("uid = %d, want %s", uid, current.Uid)
+	}
+	if _, _, err = LookupOwner("grlx-no-such-user", ""); err == nil {
+		t.Error("expected an unknown user to fail")
+	}
+	if _, _, err = LookupOwner("", "grlx-no-such-group"); err == nil {
+		t.Error("expected an unknown group to fail")
+	}
+}
+
+func TestOwnerDiffers(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "owned")
+	if err := os.WriteFile(name, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, gid := os.Getuid(), os.Getgid()
+	if OwnerDiffers(info, uid, gid) || OwnerDiffers(info, -1, -1) {
+		t.Error("expected the file to be owned by the current user")
+	}
+	if !OwnerDiffers(info, uid+1, -1) || !OwnerDiffers(info, -1, gid+1) {
+		t.Error("expected another owner to differ")
+	}
+}
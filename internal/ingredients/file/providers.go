@@ -1,6 +1,7 @@
 package file
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -63,3 +64,17 @@ func NewFileProvider(id string, source, destination, hash string, params map[str
 	}
 	return r.Parse(id, source, destination, hash, params)
 }
+
+// ListSource lists the regular files under the source directory through
+// the provider for its protocol, if that provider can list directories.
+func ListSource(ctx context.Context, id, source string, params map[string]interface{}) ([]SourceFile, error) {
+	fp, err := NewFileProvider(id, source, "", "", params)
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := fp.(DirLister)
+	if !ok {
+		return nil, errors.Join(ErrListUnsupported, fmt.Errorf("source %s", source))
+	}
+	return lister.List(ctx)
+}
@@ -1,9 +1,33 @@
+// Package s3 implements the s3:// file provider. Objects are fetched with
+// SigV4-signed GET requests from AWS S3 or any S3-compatible endpoint such
+// as MinIO.
 package s3
 
 import (
 	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/gogrlx/grlx/v2/internal/config"
 	"github.com/gogrlx/grlx/v2/internal/ingredients/file"
+	"github.com/gogrlx/grlx/v2/internal/ingredients/file/hashers"
+)
+
+const defaultRegion = "us-east-1"
+
+var (
+	ErrInvalidSource   = errors.New("invalid s3 source")
+	ErrUnexpectedCode  = errors.New("unexpected S3 status code")
+	ErrETagMismatch    = errors.New("downloaded object does not match its ETag")
+	ErrPartialSettings = errors.New("s3 credentials require both access_key_id and secret_access_key")
 )
 
 type S3File struct {
@@ -14,13 +38,178 @@ type S3File struct {
 	Props       map[string]interface{}
 }
 
+// settings holds the resolved connection parameters for a single download.
+type settings struct {
+	Region    string
+	Endpoint  string
+	PathStyle bool
+	Creds     credentials
+}
+
 // Compile-time interface check.
 var _ file.FileProvider = S3File{}
 
-func (sf S3File) Download(context.Context) error {
+func (sf S3File) Download(ctx context.Context) error {
+	if sf.Hash != "" {
+		ok, err := sf.Verify(ctx)
+		if err != nil && !errors.Is(err, file.ErrFileNotFound) {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+	bucket, key, err := parseSource(sf.Source)
+	if err != nil {
+		return err
+	}
+	s, err := sf.settings()
+	if err != nil {
+		return err
+	}
+	objectURL, err := s.objectURL(bucket, key)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, objectURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	if s.Creds.AccessKeyID != "" {
+		signRequest(req, s.Creds, s.Region, time.Now())
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return errors.Join(ErrUnexpectedCode, fmt.Errorf("GET %s: status %d: %s", sf.Source, res.StatusCode, strings.TrimSpace(string(body))))
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(sf.Destination), "."+filepath.Base(sf.Destination)+".part-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+	md5sum := md5.New()
+	_, copyErr := io.Copy(io.MultiWriter(tmp, md5sum), res.Body)
+	closeErr := tmp.Close()
+	if copyErr != nil {
+		return copyErr
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	// Single-part uploads use the object's MD5 as ETag; multipart ETags
+	// contain a "-" and cannot be checked without the part sizes.
+	etag := strings.Trim(res.Header.Get("ETag"), `"`)
+	if etag != "" && !strings.Contains(etag, "-") && !encryptedETag(res.Header) {
+		if actual := fmt.Sprintf("%x", md5sum.Sum(nil)); actual != etag {
+			return errors.Join(ErrETagMismatch, fmt.Errorf("%s: ETag %s, downloaded %s", sf.Source, etag, actual))
+		}
+	}
+	if err = os.Rename(tmpName, sf.Destination); err != nil {
+		return err
+	}
+	if sf.Hash == "" {
+		return nil
+	}
+	ok, err := sf.Verify(ctx)
+	if err != nil {
+		os.Remove(sf.Destination)
+		return err
+	}
+	if !ok {
+		os.Remove(sf.Destination)
+		return errors.Join(file.ErrHashMismatch, fmt.Errorf("recipe step %s: hash for %s does not match %s", sf.ID, sf.Source, sf.Hash))
+	}
 	return nil
 }
 
+// parseSource splits s3://bucket/key into its bucket and key.
+func parseSource(source string) (string, string, error) {
+	rest, ok := strings.CutPrefix(source, "s3://")
+	if !ok {
+		return "", "", errors.Join(ErrInvalidSource, fmt.Errorf("%s is not an s3:// URL", source))
+	}
+	bucket, key, _ := strings.Cut(rest, "/")
+	if bucket == "" || key == "" {
+		return "", "", errors.Join(ErrInvalidSource, fmt.Errorf("%s must be of the form s3://bucket/key", source))
+	}
+	return bucket, key, nil
+}
+
+// settings resolves connection parameters from the step properties, then
+// the sprout's "s3" config section, then the standard AWS environment
+// variables.
+func (sf S3File) settings() (settings, error) {
+	cfg := config.S3Settings()
+	lookup := func(key string, envs ...string) string {
+		if v, ok := sf.Props[key].(string); ok && v != "" {
+			return v
+		}
+		if v, ok := cfg[key].(string); ok && v != "" {
+			return v
+		}
+		for _, env := range envs {
+			if v := os.Getenv(env); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	s := settings{
+		Region:   lookup("region", "AWS_REGION", "AWS_DEFAULT_REGION"),
+		Endpoint: lookup("endpoint", "AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"),
+		Creds: credentials{
+			AccessKeyID:     lookup("access_key_id", "AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: lookup("secret_access_key", "AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    lookup("session_token", "AWS_SESSION_TOKEN"),
+		},
+	}
+	if s.Region == "" {
+		s.Region = defaultRegion
+	}
+	// Custom endpoints (MinIO and friends) rarely support virtual-hosted
+	// buckets, so they default to path-style addressing.
+	s.PathStyle = s.Endpoint != ""
+	if v, ok := sf.Props["path_style"].(bool); ok {
+		s.PathStyle = v
+	} else if v, ok := cfg["path_style"].(bool); ok {
+		s.PathStyle = v
+	}
+	if (s.Creds.AccessKeyID == "") != (s.Creds.SecretAccessKey == "") {
+		return s, ErrPartialSettings
+	}
+	return s, nil
+}
+
+// objectURL builds the request URL for bucket/key.
+func (s settings) objectURL(bucket, key string) (*url.URL, error) {
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + s.Region + ".amazonaws.com"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, errors.Join(ErrInvalidSource, fmt.Errorf("invalid endpoint %s", endpoint))
+	}
+	base := strings.TrimSuffix(u.Path, "/")
+	if s.PathStyle {
+		u.Path = base + "/" + bucket + "/" + key
+	} else {
+		u.Host = bucket + "." + u.Host
+		u.Path = base + "/" + key
+	}
+	return u, nil
+}
+
 func (sf S3File) Properties() (map[string]interface{}, error) {
 	return sf.Props, nil
 }
@@ -33,9 +222,35 @@ func (sf S3File) Parse(id, source, destination, hash string, properties map[stri
 }
 
 func (sf S3File) Protocols() []string {
-	return []string{"file"}
+	return []string{"s3"}
+}
+
+func (sf S3File) Verify(ctx context.Context) (bool, error) {
+	hashType := ""
+	if sf.Props["hashType"] == nil {
+		hashType = hashers.GuessHashType(sf.Hash)
+	} else if ht, ok := sf.Props["hashType"].(string); !ok {
+		hashType = hashers.GuessHashType(sf.Hash)
+	} else {
+		hashType = ht
+	}
+	cf := hashers.CacheFile{
+		ID:          sf.ID,
+		Destination: sf.Destination,
+		Hash:        sf.Hash,
+		HashType:    hashType,
+	}
+	return cf.Verify(ctx)
+}
+
+func init() {
+	file.RegisterProvider(S3File{})
 }
 
-func (sf S3File) Verify(context.Context) (bool, error) {
-	return false, nil
+// encryptedETag reports whether the object in a response is encrypted with
+// SSE-KMS or a customer-provided key (SSE-C), whose ETags are not the MD5
+// of the object's data.
+func encryptedETag(h http.Header) bool {
+	return h.Get("X-Amz-Server-Side-Encryption") == "aws:kms" ||
+		h.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") != ""
 }
@@ -2,6 +2,17 @@ package s3
 
 import (
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/gogrlx/grlx/v2/internal/ingredients/file"
@@ -13,8 +24,8 @@ var _ file.FileProvider = S3File{}
 func TestProtocols(t *testing.T) {
 	sf := S3File{}
 	protos := sf.Protocols()
-	if len(protos) != 1 || protos[0] != "file" {
-		t.Errorf("expected [\"file\"], got %v", protos)
+	if len(protos) != 1 || protos[0] != "s3" {
+		t.Errorf("expected [\"s3\"], got %v", protos)
 	}
 }
 
@@ -105,114 +116,442 @@ func TestProperties(t *testing.T) {
 	}
 }
 
-func TestDownloadStub(t *testing.T) {
-	// S3 Download is currently a stub that returns nil.
-	// This test documents the current behavior and ensures it doesn't panic.
-	sf := S3File{
-		ID:          "test-s3-download",
-		Source:      "s3://bucket/key",
-		Destination: "/tmp/dst",
-		Hash:        "abc123",
-		Props:       map[string]interface{}{},
+func TestParsePreservesAllFields(t *testing.T) {
+	sf := S3File{}
+	props := map[string]interface{}{
+		"bucket":   "test-bucket",
+		"region":   "eu-west-1",
+		"endpoint": "https://minio.local:9000",
+		"hashType": "sha256",
+	}
+
+	fp, err := sf.Parse("full-test", "s3://test-bucket/path/to/file.tar.gz", "/var/cache/grlx/file.tar.gz", "abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890", props)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
 	}
 
-	ctx := context.Background()
-	err := sf.Download(ctx)
+	parsed := fp.(S3File)
+	gotProps, err := parsed.Properties()
 	if err != nil {
-		t.Fatalf("Download stub should return nil, got: %v", err)
+		t.Fatalf("Properties error: %v", err)
+	}
+
+	for key, want := range props {
+		got, exists := gotProps[key]
+		if !exists {
+			t.Errorf("property %q missing after Parse", key)
+			continue
+		}
+		if got != want {
+			t.Errorf("property %q: want %v, got %v", key, want, got)
+		}
+	}
+}
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+)
+
+// fakeS3 is a minimal path-style S3 server that verifies SigV4 signatures
+// for GET requests.
+type fakeS3 struct {
+	mu        sync.Mutex
+	objects   map[string][]byte
+	etags     map[string]string
+	headers   map[string]http.Header
+	anonymous bool
+	requests  int
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	t.Helper()
+	fs := &fakeS3{objects: map[string][]byte{}, etags: map[string]string{}, headers: map[string]http.Header{}}
+	srv := httptest.NewServer(fs)
+	t.Cleanup(srv.Close)
+	return fs, srv
+}
+
+func (fs *fakeS3) put(bucket, key string, data []byte) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.objects[bucket+"/"+key] = data
+}
+
+func (fs *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.requests++
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !fs.anonymous {
+		if err := verifySignature(r); err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprintf(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>%s</Message></Error>", err)
+			return
+		}
 	}
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	data, ok := fs.objects[path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "<Error><Code>NoSuchKey</Code></Error>")
+		return
+	}
+	etag, ok := fs.etags[path]
+	if !ok {
+		sum := md5.Sum(data)
+		etag = hex.EncodeToString(sum[:])
+	}
+	w.Header().Set("ETag", `"`+etag+`"`)
+	for name, values := range fs.headers[path] {
+		w.Header()[name] = values
+	}
+	w.Write(data)
+}
+
+// verifySignature recomputes the SigV4 signature of r from its
+// Authorization header and the shared test secret.
+func verifySignature(r *http.Request) error {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, sigAlgorithm+" ") {
+		return errors.New("missing or malformed Authorization header")
+	}
+	fields := map[string]string{}
+	for _, part := range strings.Split(strings.TrimPrefix(auth, sigAlgorithm+" "), ", ") {
+		k, v, _ := strings.Cut(part, "=")
+		fields[k] = v
+	}
+	credParts := strings.Split(fields["Credential"], "/")
+	if len(credParts) != 5 || credParts[0] != testAccessKey {
+		return fmt.Errorf("bad credential scope %q", fields["Credential"])
+	}
+	var headers strings.Builder
+	for _, name := range strings.Split(fields["SignedHeaders"], ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		headers.WriteString(name + ":" + value + "\n")
+	}
+	canonical := strings.Join([]string{
+		r.Method, canonicalURI(r.URL), canonicalQuery(r.URL),
+		headers.String(), fields["SignedHeaders"], r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	sum := sha256.Sum256([]byte(canonical))
+	stringToSign := strings.Join([]string{
+		sigAlgorithm, r.Header.Get("X-Amz-Date"),
+		strings.Join(credParts[1:], "/"), hex.EncodeToString(sum[:]),
+	}, "\n")
+	key := signingKey(testSecretKey, credParts[1], credParts[2], credParts[3])
+	want := hex.EncodeToString(hmacSHA256(key, []byte(stringToSign)))
+	if want != fields["Signature"] {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
 }
 
-func TestVerifyStub(t *testing.T) {
-	// S3 Verify is currently a stub that returns (false, nil).
-	// This test documents the current behavior.
-	sf := S3File{
-		ID:          "test-s3-verify",
-		Source:      "s3://bucket/key",
-		Destination: "/tmp/dst",
-		Hash:        "abc123",
-		Props:       map[string]interface{}{},
+func fakeProps(endpoint string) map[string]interface{} {
+	return map[string]interface{}{
+		"endpoint":          endpoint,
+		"region":            "us-west-2",
+		"access_key_id":     testAccessKey,
+		"secret_access_key": testSecretKey,
 	}
+}
+
+func TestDownload(t *testing.T) {
+	fs, srv := newFakeS3(t)
+	data := []byte("release-1.2.3 contents")
+	fs.put("releases", "app/v1.2.3.tar.gz", data)
+	sum := sha256.Sum256(data)
 
-	ctx := context.Background()
-	ok, err := sf.Verify(ctx)
+	dst := filepath.Join(t.TempDir(), "app.tar.gz")
+	fp, _ := S3File{}.Parse("s3-dl", "s3://releases/app/v1.2.3.tar.gz", dst, hex.EncodeToString(sum[:]), fakeProps(srv.URL))
+	if err := fp.Download(context.Background()); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	got, err := os.ReadFile(dst)
 	if err != nil {
-		t.Fatalf("Verify stub should return nil error, got: %v", err)
+		t.Fatal(err)
+	}
+	if string(got) != string(data) {
+		t.Errorf("want %q, got %q", data, got)
 	}
-	if ok {
-		t.Error("Verify stub should return false (not yet implemented)")
+	ok, err := fp.Verify(context.Background())
+	if err != nil || !ok {
+		t.Errorf("Verify: ok=%v err=%v", ok, err)
+	}
+
+	// A second download with a valid cached copy must not hit the server.
+	before := fs.requests
+	if err := fp.Download(context.Background()); err != nil {
+		t.Fatalf("second Download: %v", err)
+	}
+	if fs.requests != before {
+		t.Errorf("expected cached file to be reused, server saw %d new requests", fs.requests-before)
 	}
 }
 
-func TestDownloadWithCancelledContext(t *testing.T) {
-	sf := S3File{
-		ID:          "test-cancel",
-		Source:      "s3://bucket/key",
-		Destination: "/tmp/dst",
-		Hash:        "abc123",
-		Props:       map[string]interface{}{},
+func TestDownloadSpecialCharacterKey(t *testing.T) {
+	fs, srv := newFakeS3(t)
+	fs.put("bucket", "dir with spaces/file+name=1.txt", []byte("x"))
+
+	dst := filepath.Join(t.TempDir(), "file.txt")
+	fp, _ := S3File{}.Parse("s3-special", "s3://bucket/dir with spaces/file+name=1.txt", dst, "", fakeProps(srv.URL))
+	if err := fp.Download(context.Background()); err != nil {
+		t.Fatalf("Download: %v", err)
 	}
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	cancel()
+func TestDownloadSessionToken(t *testing.T) {
+	fs, srv := newFakeS3(t)
+	fs.put("bucket", "key", []byte("token"))
+	props := fakeProps(srv.URL)
+	props["session_token"] = "FQoGZXIvYXdzEXAMPLE"
 
-	// Current stub ignores context — this documents that behavior.
-	err := sf.Download(ctx)
-	if err != nil {
-		t.Fatalf("Download stub should return nil even with cancelled context, got: %v", err)
+	dst := filepath.Join(t.TempDir(), "key")
+	fp, _ := S3File{}.Parse("s3-token", "s3://bucket/key", dst, "", props)
+	if err := fp.Download(context.Background()); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+}
+
+func TestDownloadAnonymous(t *testing.T) {
+	fs, srv := newFakeS3(t)
+	fs.anonymous = true
+	fs.put("public", "readme", []byte("hi"))
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+
+	dst := filepath.Join(t.TempDir(), "readme")
+	fp, _ := S3File{}.Parse("s3-anon", "s3://public/readme", dst, "", map[string]interface{}{"endpoint": srv.URL})
+	if err := fp.Download(context.Background()); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+}
+
+func TestDownloadBadSignature(t *testing.T) {
+	fs, srv := newFakeS3(t)
+	fs.put("bucket", "key", []byte("secret"))
+	props := fakeProps(srv.URL)
+	props["secret_access_key"] = "wrong"
+
+	dst := filepath.Join(t.TempDir(), "key")
+	fp, _ := S3File{}.Parse("s3-badsig", "s3://bucket/key", dst, "", props)
+	err := fp.Download(context.Background())
+	if !errors.Is(err, ErrUnexpectedCode) {
+		t.Fatalf("expected ErrUnexpectedCode, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected 403 in error, got %v", err)
+	}
+	if _, statErr := os.Stat(dst); !os.IsNotExist(statErr) {
+		t.Error("expected no file to be written")
+	}
+}
+
+func TestDownloadNotFound(t *testing.T) {
+	_, srv := newFakeS3(t)
+	dst := filepath.Join(t.TempDir(), "missing")
+	fp, _ := S3File{}.Parse("s3-404", "s3://bucket/missing", dst, "", fakeProps(srv.URL))
+	if err := fp.Download(context.Background()); !errors.Is(err, ErrUnexpectedCode) {
+		t.Fatalf("expected ErrUnexpectedCode, got %v", err)
+	}
+}
+
+func TestDownloadHashMismatch(t *testing.T) {
+	fs, srv := newFakeS3(t)
+	fs.put("bucket", "key", []byte("actual"))
+	sum := sha256.Sum256([]byte("expected"))
+
+	dst := filepath.Join(t.TempDir(), "key")
+	fp, _ := S3File{}.Parse("s3-mismatch", "s3://bucket/key", dst, hex.EncodeToString(sum[:]), fakeProps(srv.URL))
+	if err := fp.Download(context.Background()); !errors.Is(err, file.ErrHashMismatch) {
+		t.Fatalf("expected ErrHashMismatch, got %v", err)
+	}
+	if _, statErr := os.Stat(dst); !os.IsNotExist(statErr) {
+		t.Error("expected mismatched download to be removed")
+	}
+}
+
+func TestDownloadETag(t *testing.T) {
+	fs, srv := newFakeS3(t)
+	fs.put("bucket", "corrupt", []byte("data"))
+	fs.etags["bucket/corrupt"] = "00000000000000000000000000000000"
+	fs.put("bucket", "multipart", []byte("data"))
+	fs.etags["bucket/multipart"] = "9b2cf535f27731c974343645a3985328-2"
+
+	dst := filepath.Join(t.TempDir(), "corrupt")
+	fp, _ := S3File{}.Parse("s3-etag", "s3://bucket/corrupt", dst, "", fakeProps(srv.URL))
+	if err := fp.Download(context.Background()); !errors.Is(err, ErrETagMismatch) {
+		t.Fatalf("expected ErrETagMismatch, got %v", err)
+	}
+
+	dst = filepath.Join(t.TempDir(), "multipart")
+	fp, _ = S3File{}.Parse("s3-etag-multi", "s3://bucket/multipart", dst, "", fakeProps(srv.URL))
+	if err := fp.Download(context.Background()); err != nil {
+		t.Fatalf("multipart ETag should be skipped, got %v", err)
 	}
 }
 
-func TestVerifyWithCancelledContext(t *testing.T) {
-	sf := S3File{
-		ID:          "test-cancel-verify",
-		Source:      "s3://bucket/key",
-		Destination: "/tmp/dst",
-		Hash:        "abc123",
-		Props:       map[string]interface{}{},
+func TestDownloadEncryptedETag(t *testing.T) {
+	fs, srv := newFakeS3(t)
+	// Encrypted objects have an ETag that is not the MD5 of their data.
+	tests := map[string]http.Header{
+		"kms":    {"X-Amz-Server-Side-Encryption": {"aws:kms"}},
+		"sse-c":  {"X-Amz-Server-Side-Encryption-Customer-Algorithm": {"AES256"}},
+		"sse-s3": {"X-Amz-Server-Side-Encryption": {"AES256"}},
+	}
+	for name, header := range tests {
+		t.Run(name, func(t *testing.T) {
+			fs.put("bucket", name, []byte("data"))
+			fs.etags["bucket/"+name] = "0123456789abcdef0123456789abcdef"
+			fs.headers["bucket/"+name] = header
+			dst := filepath.Join(t.TempDir(), name)
+			fp, _ := S3File{}.Parse("s3-"+name, "s3://bucket/"+name, dst, "", fakeProps(srv.URL))
+			err := fp.Download(context.Background())
+			if name == "sse-s3" {
+				// SSE-S3 ETags are still the MD5 of the data.
+				if !errors.Is(err, ErrETagMismatch) {
+					t.Fatalf("expected ErrETagMismatch, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected the ETag of an encrypted object to be skipped, got %v", err)
+			}
+			if got, _ := os.ReadFile(dst); string(got) != "data" {
+				t.Errorf("expected the object to be downloaded, got %q", got)
+			}
+		})
 	}
+}
 
+func TestDownloadCancelledContext(t *testing.T) {
+	fs, srv := newFakeS3(t)
+	fs.put("bucket", "key", []byte("data"))
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
-	// Current stub ignores context — this documents that behavior.
-	ok, err := sf.Verify(ctx)
-	if err != nil {
-		t.Fatalf("Verify stub should return nil error even with cancelled context, got: %v", err)
-	}
-	if ok {
-		t.Error("expected false from Verify stub")
+	fp, _ := S3File{}.Parse("s3-cancel", "s3://bucket/key", filepath.Join(t.TempDir(), "key"), "", fakeProps(srv.URL))
+	if err := fp.Download(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
 	}
 }
 
-func TestParsePreservesAllFields(t *testing.T) {
-	sf := S3File{}
-	props := map[string]interface{}{
-		"bucket":   "test-bucket",
-		"region":   "eu-west-1",
-		"endpoint": "https://minio.local:9000",
-		"hashType": "sha256",
+func TestParseSource(t *testing.T) {
+	cases := []struct {
+		source  string
+		bucket  string
+		key     string
+		wantErr bool
+	}{
+		{source: "s3://bucket/key", bucket: "bucket", key: "key"},
+		{source: "s3://bucket/a/b/c.tar.gz", bucket: "bucket", key: "a/b/c.tar.gz"},
+		{source: "s3://bucket", wantErr: true},
+		{source: "s3://bucket/", wantErr: true},
+		{source: "s3:///key", wantErr: true},
+		{source: "https://bucket/key", wantErr: true},
 	}
+	for _, tc := range cases {
+		t.Run(tc.source, func(t *testing.T) {
+			bucket, key, err := parseSource(tc.source)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidSource) {
+					t.Errorf("expected ErrInvalidSource, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if bucket != tc.bucket || key != tc.key {
+				t.Errorf("want %s/%s, got %s/%s", tc.bucket, tc.key, bucket, key)
+			}
+		})
+	}
+}
 
-	fp, err := sf.Parse("full-test", "s3://test-bucket/path/to/file.tar.gz", "/var/cache/grlx/file.tar.gz", "abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890", props)
+func TestSettings(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_ENDPOINT_URL_S3", "")
+	t.Setenv("AWS_ENDPOINT_URL", "")
+
+	s, err := S3File{Props: map[string]interface{}{}}.settings()
 	if err != nil {
-		t.Fatalf("Parse error: %v", err)
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Region != defaultRegion || s.PathStyle || s.Endpoint != "" {
+		t.Errorf("unexpected defaults: %+v", s)
 	}
 
-	parsed := fp.(S3File)
-	gotProps, err := parsed.Properties()
+	t.Setenv("AWS_REGION", "eu-central-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "envkey")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "envsecret")
+	s, err = S3File{Props: map[string]interface{}{"region": "ap-south-1"}}.settings()
 	if err != nil {
-		t.Fatalf("Properties error: %v", err)
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Region != "ap-south-1" {
+		t.Errorf("step property should override environment, got %s", s.Region)
+	}
+	if s.Creds.AccessKeyID != "envkey" || s.Creds.SecretAccessKey != "envsecret" {
+		t.Errorf("expected credentials from environment, got %+v", s.Creds)
 	}
 
-	for key, want := range props {
-		got, exists := gotProps[key]
-		if !exists {
-			t.Errorf("property %q missing after Parse", key)
-			continue
-		}
-		if got != want {
-			t.Errorf("property %q: want %v, got %v", key, want, got)
-		}
+	s, _ = S3File{Props: map[string]interface{}{"endpoint": "http://minio:9000"}}.settings()
+	if !s.PathStyle {
+		t.Error("custom endpoints should default to path-style")
+	}
+	s, _ = S3File{Props: map[string]interface{}{"endpoint": "http://minio:9000", "path_style": false}}.settings()
+	if s.PathStyle {
+		t.Error("path_style: false should be honored")
+	}
+
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	if _, err = (S3File{Props: map[string]interface{}{}}).settings(); !errors.Is(err, ErrPartialSettings) {
+		t.Errorf("expected ErrPartialSettings, got %v", err)
+	}
+}
+
+func TestObjectURL(t *testing.T) {
+	cases := []struct {
+		name string
+		s    settings
+		want string
+	}{
+		{name: "aws virtual-hosted", s: settings{Region: "us-west-2"}, want: "https://bucket.s3.us-west-2.amazonaws.com/a/b"},
+		{name: "aws path-style", s: settings{Region: "us-west-2", PathStyle: true}, want: "https://s3.us-west-2.amazonaws.com/bucket/a/b"},
+		{name: "minio", s: settings{Endpoint: "http://minio:9000/", PathStyle: true}, want: "http://minio:9000/bucket/a/b"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := tc.s.objectURL("bucket", "a/b")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if u.String() != tc.want {
+				t.Errorf("want %s, got %s", tc.want, u.String())
+			}
+		})
+	}
+	if _, err := (settings{Endpoint: "minio:9000"}).objectURL("b", "k"); err == nil {
+		t.Error("expected error for endpoint without scheme")
+	}
+}
+
+func TestNewFileProviderResolvesS3(t *testing.T) {
+	fp, err := file.NewFileProvider("step", "s3://bucket/key", "/tmp/dst", "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := fp.(S3File); !ok {
+		t.Errorf("expected S3File, got %T", fp)
 	}
 }
@@ -0,0 +1,476 @@
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	formatJSON = "json"
+	formatYAML = "yaml"
+	formatTOML = "toml"
+	formatINI  = "ini"
+)
+
+// structuredFormat returns the format property, or guesses it from the
+// extension of name. Only the given formats are accepted. Extensions such
+// as .conf and .cfg are used by too many formats to guess, so those files
+// need an explicit format.
+func (f File) structuredFormat(name string, formats ...string) (string, error) {
+	format, _ := f.params["format"].(string)
+	format = strings.ToLower(format)
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".json":
+			format = formatJSON
+		case ".yaml", ".yml":
+			format = formatYAML
+		case ".toml":
+			format = formatTOML
+		case ".ini":
+			format = formatINI
+		}
+	}
+	if format == "yml" {
+		format = formatYAML
+	}
+	for _, supported := range formats {
+		if format == supported {
+			return format, nil
+		}
+	}
+	if format == "" {
+		return "", errors.Join(ErrUnsupportedFormat, fmt.Errorf("cannot guess the format of %s, set format to one of %s", name, strings.Join(formats, ", ")))
+	}
+	return "", errors.Join(ErrUnsupportedFormat, fmt.Errorf("got %q, want one of %s", format, strings.Join(formats, ", ")))
+}
+
+// encodeDataset serializes data in a stable layout: keys are sorted and
+// nested values indented by two spaces.
+func encodeDataset(format string, data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(data); err != nil {
+			return nil, err
+		}
+	case formatYAML:
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(data); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+	case formatTOML:
+		if err := toml.NewEncoder(&buf).Encode(data); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Join(ErrUnsupportedFormat, fmt.Errorf("cannot serialize %s", format))
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeDataset parses content holding a mapping at the top level. Empty
+// content is an empty mapping.
+func decodeDataset(format string, content []byte) (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return data, nil
+	}
+	var err error
+	switch format {
+	case formatJSON:
+		err = json.Unmarshal(content, &data)
+	case formatYAML:
+		err = yaml.Unmarshal(content, &data)
+	case formatTOML:
+		err = toml.Unmarshal(content, &data)
+	default:
+		err = errors.Join(ErrUnsupportedFormat, fmt.Errorf("cannot parse %s", format))
+	}
+	return data, err
+}
+
+// mergeDataset merges src into dst. Mappings present in both are merged
+// key by key; anything else in src replaces the value in dst.
+func mergeDataset(dst, src map[string]interface{}) map[string]interface{} {
+	for k, v := range src {
+		srcMap, srcOK := v.(map[string]interface{})
+		dstMap, dstOK := dst[k].(map[string]interface{})
+		if srcOK && dstOK {
+			dst[k] = mergeDataset(dstMap, srcMap)
+			continue
+		}
+		dst[k] = v
+	}
+	return dst
+}
+
+// keyValue is a value to set at a key path.
+type keyValue struct {
+	path  []string
+	value interface{}
+}
+
+func (kv keyValue) String() string {
+	return strings.Join(kv.path, ".")
+}
+
+// flattenKeys turns the keys property into the leaf values to set. Keys
+// are dotted paths, and a mapping value is merged key by key rather than
+// replacing what is there, so {"a.b": 1} and {"a": {"b": 1}} are the same.
+func flattenKeys(keys map[string]interface{}, prefix []string) ([]keyValue, error) {
+	var out []keyValue
+	for _, k := range sortedKeys(keys) {
+		path, err := splitKeyPath(k)
+		if err != nil {
+			return nil, err
+		}
+		path = append(append([]string(nil), prefix...), path...)
+		if m, ok := keys[k].(map[string]interface{}); ok && len(m) > 0 {
+			nested, err := flattenKeys(m, path)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, nested...)
+			continue
+		}
+		out = append(out, keyValue{path: path, value: keys[k]})
+	}
+	return out, nil
+}
+
+func splitKeyPath(key string) ([]string, error) {
+	path := strings.Split(key, ".")
+	for _, seg := range path {
+		if seg == "" {
+			return nil, errors.Join(ErrInvalidProperty, fmt.Errorf("invalid key path %q", key))
+		}
+	}
+	return path, nil
+}
+
+// sameValue compares values decoded by different parsers, which may use
+// different number types for the same value.
+func sameValue(a, b interface{}) bool {
+	aj, aErr := json.Marshal(a)
+	bj, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && bytes.Equal(aj, bj)
+}
+
+// mapSet sets value at path in data, creating mappings on the way.
+func mapSet(data map[string]interface{}, path []string, value interface{}) (bool, error) {
+	for i, seg := range path[:len(path)-1] {
+		next, ok := data[seg]
+		if !ok {
+			m := map[string]interface{}{}
+			data[seg] = m
+			data = m
+			continue
+		}
+		if data, ok = next.(map[string]interface{}); !ok {
+			return false, errors.Join(ErrKeyConflict, fmt.Errorf("`%s`", strings.Join(path[:i+1], ".")))
+		}
+	}
+	last := path[len(path)-1]
+	if old, ok := data[last]; ok && sameValue(old, value) {
+		return false, nil
+	}
+	data[last] = value
+	return true, nil
+}
+
+// mapDelete removes path from data, if present.
+func mapDelete(data map[string]interface{}, path []string) bool {
+	for _, seg := range path[:len(path)-1] {
+		next, ok := data[seg].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		data = next
+	}
+	if _, ok := data[path[len(path)-1]]; !ok {
+		return false
+	}
+	delete(data, path[len(path)-1])
+	return true
+}
+
+// yamlRoot returns the top-level mapping of a parsed document, making
+// one if the document is empty.
+func yamlRoot(doc *yaml.Node) (*yaml.Node, error) {
+	if doc.Kind == 0 {
+		doc.Kind = yaml.DocumentNode
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, errors.Join(ErrKeyConflict, errors.New("the document is not a mapping"))
+	}
+	return root, nil
+}
+
+// yamlLookup returns the index of the value for key in a mapping node, or
+// -1.
+func yamlLookup(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// yamlSet sets value at path below the mapping root, keeping the comments
+// of a replaced value.
+func yamlSet(root *yaml.Node, path []string, value interface{}) (bool, error) {
+	m := root
+	for i, seg := range path[:len(path)-1] {
+		idx := yamlLookup(m, seg)
+		if idx < 0 {
+			next := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: seg}, next)
+			m = next
+			continue
+		}
+		if m.Content[idx].Kind != yaml.MappingNode {
+			return false, errors.Join(ErrKeyConflict, fmt.Errorf("`%s`", strings.Join(path[:i+1], ".")))
+		}
+		m = m.Content[idx]
+	}
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return false, err
+	}
+	last := path[len(path)-1]
+	idx := yamlLookup(m, last)
+	if idx < 0 {
+		m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: last}, &node)
+		return true, nil
+	}
+	old := m.Content[idx]
+	var oldValue interface{}
+	if err := old.Decode(&oldValue); err == nil && sameValue(oldValue, value) {
+		return false, nil
+	}
+	node.HeadComment, node.LineComment, node.FootComment = old.HeadComment, old.LineComment, old.FootComment
+	m.Content[idx] = &node
+	return true, nil
+}
+
+// yamlDelete removes path below the mapping root, if present.
+func yamlDelete(root *yaml.Node, path []string) bool {
+	m := root
+	for _, seg := range path[:len(path)-1] {
+		idx := yamlLookup(m, seg)
+		if idx < 0 || m.Content[idx].Kind != yaml.MappingNode {
+			return false
+		}
+		m = m.Content[idx]
+	}
+	idx := yamlLookup(m, path[len(path)-1])
+	if idx < 0 {
+		return false
+	}
+	m.Content = append(m.Content[:idx-1], m.Content[idx+1:]...)
+	return true
+}
+
+// writeJSONNode writes a node parsed from JSON back out as JSON, keeping
+// the order of object keys and the literals of numbers, booleans and
+// nulls.
+func writeJSONNode(buf *bytes.Buffer, node *yaml.Node, indent string) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return writeJSONNode(buf, node.Content[0], indent)
+	case yaml.AliasNode:
+		return writeJSONNode(buf, node.Alias, indent)
+	case yaml.MappingNode, yaml.SequenceNode:
+		open, close, step := "[", "]", 1
+		if node.Kind == yaml.MappingNode {
+			open, close, step = "{", "}", 2
+		}
+		if len(node.Content) == 0 {
+			buf.WriteString(open + close)
+			return nil
+		}
+		buf.WriteString(open + "\n")
+		for i := 0; i < len(node.Content); i += step {
+			buf.WriteString(indent + "  ")
+			if step == 2 {
+				key, err := jsonScalar(node.Content[i].Value)
+				if err != nil {
+					return err
+				}
+				buf.Write(key)
+				buf.WriteString(": ")
+			}
+			if err := writeJSONNode(buf, node.Content[i+step-1], indent+"  "); err != nil {
+				return err
+			}
+			if i+step < len(node.Content) {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n")
+		}
+		buf.WriteString(indent + close)
+		return nil
+	default:
+		if node.Tag != "!!str" && json.Valid([]byte(node.Value)) {
+			buf.WriteString(node.Value)
+			return nil
+		}
+		var v interface{}
+		if err := node.Decode(&v); err != nil {
+			return err
+		}
+		b, err := jsonScalar(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		return nil
+	}
+}
+
+func jsonScalar(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// iniLine is a key line in an ini file.
+type iniLine struct {
+	index int
+	sep   int // offset of the = or : in the line
+}
+
+// iniFile indexes the sections and keys of an ini file's lines. Keys
+// before the first section header belong to the section "".
+type iniFile struct {
+	lines    []string
+	keys     map[string]map[string]iniLine
+	sections map[string]int // index of the last non-blank line of the section
+}
+
+func parseINI(lines []string) iniFile {
+	ini := iniFile{
+		lines:    lines,
+		keys:     map[string]map[string]iniLine{"": {}},
+		sections: map[string]int{"": -1},
+	}
+	section := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			continue
+		case strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]"):
+			section = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			if _, ok := ini.keys[section]; !ok {
+				ini.keys[section] = map[string]iniLine{}
+			}
+		case strings.HasPrefix(trimmed, ";") || strings.HasPrefix(trimmed, "#"):
+		default:
+			if sep := strings.IndexAny(line, "=:"); sep > 0 {
+				ini.keys[section][strings.TrimSpace(line[:sep])] = iniLine{index: i, sep: sep}
+			}
+		}
+		ini.sections[section] = i
+	}
+	return ini
+}
+
+// iniSet sets key in section to value, replacing the value of an existing
+// key in place and otherwise adding the key at the end of the section.
+func iniSet(lines []string, section, key, value string) ([]string, bool) {
+	ini := parseINI(lines)
+	if kl, ok := ini.keys[section][key]; ok {
+		line := strings.TrimRight(lines[kl.index], "\r\n")
+		if strings.TrimSpace(line[kl.sep+1:]) == value {
+			return lines, false
+		}
+		prefix := line[:kl.sep+1]
+		if rest := line[kl.sep+1:]; strings.TrimLeft(rest, " \t") != rest || rest == "" {
+			prefix += " "
+		}
+		lines[kl.index] = prefix + value + "\n"
+		return lines, true
+	}
+	newLine := key + " = " + value + "\n"
+	if last, ok := ini.sections[section]; ok {
+		lines = ensureTrailingNewline(lines)
+		return append(lines[:last+1], append([]string{newLine}, lines[last+1:]...)...), true
+	}
+	lines = ensureTrailingNewline(lines)
+	if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+		lines = append(lines, "\n")
+	}
+	return append(lines, "["+section+"]\n", newLine), true
+}
+
+// iniDelete removes key from section, if present.
+func iniDelete(lines []string, section, key string) ([]string, bool) {
+	kl, ok := parseINI(lines).keys[section][key]
+	if !ok {
+		return lines, false
+	}
+	return append(lines[:kl.index], lines[kl.index+1:]...), true
+}
+
+func ensureTrailingNewline(lines []string) []string {
+	if n := len(lines); n > 0 && !strings.HasSuffix(lines[n-1], "\n") {
+		lines[n-1] += "\n"
+	}
+	return lines
+}
+
+// iniPath maps a key path to a section and key: the last element is the
+// key and the rest, joined by dots, the section.
+func iniPath(path []string) (string, string) {
+	return strings.Join(path[:len(path)-1], "."), path[len(path)-1]
+}
+
+// iniValue formats a scalar for an ini file.
+func iniValue(kv keyValue) (string, error) {
+	switch v := kv.value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case map[string]interface{}, []interface{}:
+		return "", errors.Join(ErrInvalidProperty, fmt.Errorf("ini value for `%s` must be a scalar", kv))
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// sortedPaths sorts the absent key paths for stable notes.
+func sortedPaths(paths []string) []string {
+	out := append([]string(nil), paths...)
+	sort.Strings(out)
+	return out
+}
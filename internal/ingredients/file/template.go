@@ -0,0 +1,66 @@
+package file
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"text/template"
+
+	"github.com/gogrlx/grlx/v2/internal/config"
+	"github.com/gogrlx/grlx/v2/internal/cook"
+)
+
+var ErrTemplateContext = errors.New("template context must be a map")
+
+// isTemplate reports whether the step asked for its content to be
+// rendered as a Go template.
+func (f File) isTemplate() bool {
+	t, _ := f.params["template"].(bool)
+	return t
+}
+
+// templateContext returns the step's "context" property, which is passed
+// to the template as its data (e.g. {{ .port }}).
+func (f File) templateContext() (map[string]interface{}, error) {
+	raw, ok := f.params["context"]
+	if !ok || raw == nil {
+		return map[string]interface{}{}, nil
+	}
+	switch c := raw.(type) {
+	case map[string]interface{}:
+		return c, nil
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(c))
+		for k, v := range c {
+			m[fmt.Sprintf("%v", k)] = v
+		}
+		return m, nil
+	default:
+		return nil, errors.Join(ErrTemplateContext, fmt.Errorf("got %T", raw))
+	}
+}
+
+// render returns content rendered as a template when the step has
+// template: true, and content unchanged otherwise. Templates get the same
+// functions as recipes (props, hostname, sproutID, string helpers).
+func (f File) render(name string, content []byte) ([]byte, error) {
+	if !f.isTemplate() {
+		return content, nil
+	}
+	data, err := f.templateContext()
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(name).
+		Funcs(cook.TemplateFuncMap(config.SproutID)).
+		Option("missingkey=error").
+		Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template for %s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render template for %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,246 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogrlx/grlx/v2/internal/config"
+	"github.com/gogrlx/grlx/v2/internal/props"
+)
+
+func TestRender(t *testing.T) {
+	origID := config.SproutID
+	defer func() { config.SproutID = origID }()
+	config.SproutID = "render-sprout"
+	props.SetProp("render-sprout", "role", "web")
+
+	tests := []struct {
+		name     string
+		params   map[string]interface{}
+		content  string
+		expected string
+		wantErr  bool
+		errorIs  error
+	}{
+		{
+			name:     "not a template",
+			params:   map[string]interface{}{},
+			content:  "port={{ .port }}",
+			expected: "port={{ .port }}",
+		},
+		{
+			name:     "template false",
+			params:   map[string]interface{}{"template": false},
+			content:  "port={{ .port }}",
+			expected: "port={{ .port }}",
+		},
+		{
+			name: "context variables",
+			params: map[string]interface{}{
+				"template": true,
+				"context":  map[string]interface{}{"port": 8080},
+			},
+			content:  "port={{ .port }}",
+			expected: "port=8080",
+		},
+		{
+			name: "yaml style context",
+			params: map[string]interface{}{
+				"template": true,
+				"context":  map[interface{}]interface{}{"port": 443},
+			},
+			content:  "port={{ .port }}",
+			expected: "port=443",
+		},
+		{
+			name:     "props and helpers",
+			params:   map[string]interface{}{"template": true},
+			content:  `role={{ props "role" | upper }}`,
+			expected: "role=WEB",
+		},
+		{
+			name:    "missing context key",
+			params:  map[string]interface{}{"template": true},
+			content: "port={{ .port }}",
+			wantErr: true,
+		},
+		{
+			name:    "invalid template",
+			params:  map[string]interface{}{"template": true},
+			content: "port={{ .port ",
+			wantErr: true,
+		},
+		{
+			name: "invalid context",
+			params: map[string]interface{}{
+				"template": true,
+				"context":  "port=80",
+			},
+			content: "port={{ .port }}",
+			wantErr: true,
+			errorIs: ErrTemplateContext,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := File{id: tc.name, method: "managed", params: tc.params}
+			got, err := f.render("/etc/app.conf", []byte(tc.content))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got output %q", got)
+				}
+				if tc.errorIs != nil && !errors.Is(err, tc.errorIs) {
+					t.Errorf("expected error %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestManagedTemplate(t *testing.T) {
+	tempDir := t.TempDir()
+	origCache := config.CacheDir
+	config.CacheDir = filepath.Join(tempDir, "cache")
+	defer func() { config.CacheDir = origCache }()
+	if err := os.MkdirAll(config.CacheDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The test file provider only touches its destination, so seed the
+	// cached copy of the source directly.
+	source := filepath.Join(tempDir, "app.conf.tmpl")
+	cached := filepath.Join(config.CacheDir, "skip_managed-template-source")
+	if err := os.WriteFile(cached, []byte("listen={{ .port }}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(tempDir, "app.conf")
+	newFile := func(port int) File {
+		return File{
+			id:     "managed-template",
+			method: "managed",
+			params: map[string]interface{}{
+				"name":        dest,
+				"source":      source,
+				"skip_verify": true,
+				"template":    true,
+				"context":     map[string]interface{}{"port": port},
+			},
+		}
+	}
+
+	res, err := newFile(80).managed(context.Background(), false)
+	if err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	if !res.Changed {
+		t.Error("expected first apply to change the file")
+	}
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "listen=80\n" {
+		t.Errorf("expected rendered content, got %q", got)
+	}
+
+	res, err = newFile(80).managed(context.Background(), false)
+	if err != nil {
+		t.Fatalf("second apply failed: %v", err)
+	}
+	if res.Changed {
+		t.Errorf("expected unchanged render to leave the file alone, notes: %v", res.Notes)
+	}
+
+	res, err = newFile(80).managed(context.Background(), true)
+	if err != nil {
+		t.Fatalf("test mode failed: %v", err)
+	}
+	if res.Changed {
+		t.Errorf("expected test mode to report no change, notes: %v", res.Notes)
+	}
+
+	res, err = newFile(8080).managed(context.Background(), true)
+	if err != nil {
+		t.Fatalf("test mode failed: %v", err)
+	}
+	if !res.Changed {
+		t.Error("expected test mode to report a change for a new context")
+	}
+	if got, _ := os.ReadFile(dest); string(got) != "listen=80\n" {
+		t.Errorf("test mode must not modify the file, got %q", got)
+	}
+
+	bad := newFile(80)
+	bad.params["context"] = map[string]interface{}{}
+	res, err = bad.managed(context.Background(), false)
+	if err == nil || !strings.Contains(err.Error(), "port") {
+		t.Errorf("expected a missing key error, got %v", err)
+	}
+	if !res.Failed {
+		t.Error("expected a failed result for a bad render")
+	}
+}
+
+func TestContentTemplate(t *testing.T) {
+	tempDir := t.TempDir()
+	dest := filepath.Join(tempDir, "motd")
+	f := File{
+		id:     "content-template",
+		method: "content",
+		params: map[string]interface{}{
+			"name":     dest,
+			"text":     "welcome to {{ .site }}",
+			"template": true,
+			"context":  map[string]interface{}{"site": "grlx"},
+		},
+	}
+	if _, err := f.content(context.Background(), false); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "welcome to grlx") {
+		t.Errorf("expected rendered content, got %q", got)
+	}
+}
+
+func TestPrependTemplate(t *testing.T) {
+	tempDir := t.TempDir()
+	dest := filepath.Join(tempDir, "hosts")
+	if err := os.WriteFile(dest, []byte("existing\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f := File{
+		id:     "prepend-template",
+		method: "prepend",
+		params: map[string]interface{}{
+			"name":     dest,
+			"text":     "# managed for {{ .owner }}",
+			"template": true,
+			"context":  map[string]interface{}{"owner": "ops"},
+		},
+	}
+	if _, err := f.prepend(context.Background(), false); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "# managed for ops\nexisting\n" {
+		t.Errorf("expected rendered prepend, got %q", got)
+	}
+}
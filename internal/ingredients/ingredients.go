@@ -63,6 +63,8 @@ func PropMapToPropSet(pmap map[string]string) (MethodPropsSet, error) {
 		case "[]string":
 			fallthrough
 		case "bool":
+			fallthrough
+		case "map":
 			propset = append(propset, MethodProps{Key: k, Type: split[0], IsReq: isReq})
 		default:
 			return nil, fmt.Errorf("invalid Type value for key %s", k)
@@ -8,8 +8,11 @@ import (
 	"github.com/gogrlx/grlx/v2/internal/ingredients"
 )
 
-// Compile-time interface check.
-var _ cook.RecipeCooker = Service{}
+// Compile-time interface checks.
+var (
+	_ cook.RecipeCooker = Service{}
+	_ cook.Watcher      = Service{}
+)
 
 type Service struct {
 	id         string
@@ -186,6 +189,44 @@ func (s Service) Test(ctx context.Context) (cook.Result, error) {
 	}
 }
 
+// ModWatch is cooked in place of Apply or Test when a step watched by a
+// service.running step made changes. A stopped service is started as
+// usual; a running one is restarted, or reloaded if the step sets
+// reload: true. Other methods ignore watch requisites.
+func (s Service) ModWatch(ctx context.Context, test bool) (cook.Result, error) {
+	if s.method != "running" {
+		if test {
+			return s.Test(ctx)
+		}
+		return s.Apply(ctx)
+	}
+	sp, err := NewServiceProvider(s.id, s.method, s.properties)
+	if err != nil {
+		return cook.Result{}, err
+	}
+	isRunning, err := sp.IsRunning(ctx)
+	if err != nil {
+		return cook.Result{Succeeded: false, Failed: true}, err
+	}
+	if !isRunning {
+		if test {
+			return s.Test(ctx)
+		}
+		return s.Apply(ctx)
+	}
+	action, verb := sp.Restart, "restarted"
+	if reload, _ := s.properties["reload"].(bool); reload {
+		action, verb = sp.Reload, "reloaded"
+	}
+	if test {
+		return cook.Result{Succeeded: true, Changed: true, Notes: []fmt.Stringer{cook.SimpleNote(fmt.Sprintf("%s would be %s", s.name, verb))}}, nil
+	}
+	if err = action(ctx); err != nil {
+		return cook.Result{Succeeded: false, Failed: true, Changed: false, Notes: nil}, err
+	}
+	return cook.Result{Succeeded: true, Failed: false, Changed: true, Notes: []fmt.Stringer{cook.SimpleNote(fmt.Sprintf("%s has been %s", s.name, verb))}}, nil
+}
+
 func (s Service) Properties() (map[string]interface{}, error) {
 	return s.properties, nil
 }
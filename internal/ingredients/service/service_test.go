@@ -118,6 +118,9 @@ type mockProvider struct {
 	enabled bool
 	masked  bool
 
+	restarts int
+	reloads  int
+
 	startErr     error
 	stopErr      error
 	enableErr    error
@@ -189,6 +192,7 @@ func (m *mockProvider) Restart(_ context.Context) error {
 	if m.restartErr != nil {
 		return m.restartErr
 	}
+	m.restarts++
 	m.running = true
 	return nil
 }
@@ -197,6 +201,7 @@ func (m *mockProvider) Reload(_ context.Context) error {
 	if m.reloadErr != nil {
 		return m.reloadErr
 	}
+	m.reloads++
 	return nil
 }
 
@@ -1061,3 +1066,58 @@ func (e *errParseProvider) Unmask(_ context.Context) error            { return n
 func (e *errParseProvider) IsMasked(_ context.Context) (bool, error)  { return false, nil }
 func (e *errParseProvider) InitName() string                          { return "errparse" }
 func (e *errParseProvider) IsInit() bool                              { return false }
+
+func TestModWatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		running  bool
+		reload   bool
+		test     bool
+		restarts int
+		reloads  int
+		note     string
+	}{
+		{name: "restart running service", method: "running", running: true, restarts: 1, note: "nginx has been restarted"},
+		{name: "reload running service", method: "running", running: true, reload: true, reloads: 1, note: "nginx has been reloaded"},
+		{name: "start stopped service", method: "running", running: false, note: "nginx has been started"},
+		{name: "test restart", method: "running", running: true, test: true, note: "nginx would be restarted"},
+		{name: "test reload", method: "running", running: true, reload: true, test: true, note: "nginx would be reloaded"},
+		{name: "other methods apply", method: "stopped", running: true, note: "nginx has been stopped"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mp := &mockProvider{running: tc.running}
+			cleanup := registerMockProvider(t, mp)
+			defer cleanup()
+
+			props := map[string]interface{}{"name": "nginx", "reload": tc.reload}
+			s := Service{id: "svc-1", name: "nginx", method: tc.method, properties: props}
+			result, err := s.ModWatch(context.Background(), tc.test)
+			if err != nil {
+				t.Fatalf("ModWatch() error: %v", err)
+			}
+			if !result.Succeeded || !result.Changed {
+				t.Errorf("expected a successful change, got %+v", result)
+			}
+			if len(result.Notes) != 1 || result.Notes[0].String() != tc.note {
+				t.Errorf("expected note %q, got %v", tc.note, result.Notes)
+			}
+			if mp.restarts != tc.restarts || mp.reloads != tc.reloads {
+				t.Errorf("expected %d restarts and %d reloads, got %d and %d", tc.restarts, tc.reloads, mp.restarts, mp.reloads)
+			}
+		})
+	}
+}
+
+func TestModWatchRestartError(t *testing.T) {
+	mp := &mockProvider{running: true, restartErr: errors.New("restart failed")}
+	cleanup := registerMockProvider(t, mp)
+	defer cleanup()
+
+	s := Service{id: "svc-1", name: "nginx", method: "running", properties: map[string]interface{}{"name": "nginx"}}
+	result, err := s.ModWatch(context.Background(), false)
+	if err == nil || !result.Failed {
+		t.Errorf("expected the restart error to fail the step, got %+v, %v", result, err)
+	}
+}
@@ -16,7 +16,7 @@ import (
 type CLIListener struct {
 	store   *CLIStore
 	nc      *nats.Conn
-	sub     *nats.Subscription
+	subs    []*nats.Subscription
 	userKey string
 }
 
@@ -37,21 +37,36 @@ func (l *CLIListener) SubscribeAll() error {
 	if err != nil {
 		return err
 	}
-	l.sub = sub
+	l.subs = append(l.subs, sub)
 	return nil
 }
 
-// SubscribeJob subscribes only to completion events for a specific JID.
-func (l *CLIListener) SubscribeJob(jid string) error {
-	topic := "grlx.cook.*." + jid
-	sub, err := l.nc.Subscribe(topic, l.handleStepCompletion)
-	if err != nil {
-		return err
+// SubscribeJob subscribes only to completion events for a specific JID on
+// the given sprouts. A user scoped to some sprouts may only subscribe to
+// their subjects, so the sprouts are named rather than matched with a
+// wildcard.
+func (l *CLIListener) SubscribeJob(jid string, sproutIDs ...string) error {
+	for _, topic := range CompletionSubjects(jid, sproutIDs) {
+		sub, err := l.nc.Subscribe(topic, l.handleStepCompletion)
+		if err != nil {
+			l.Stop()
+			return err
+		}
+		l.subs = append(l.subs, sub)
 	}
-	l.sub = sub
 	return nil
 }
 
+// CompletionSubjects returns the subjects the completions of job jid on
+// sproutIDs are published to.
+func CompletionSubjects(jid string, sproutIDs []string) []string {
+	subjects := make([]string, 0, len(sproutIDs))
+	for _, sproutID := range sproutIDs {
+		subjects = append(subjects, jobCompletionPrefix+sproutID+"."+jid)
+	}
+	return subjects
+}
+
 // RecordJobInit records the initial metadata for a job that the current
 // user just initiated (via cook command).
 func (l *CLIListener) RecordJobInit(jid, recipe string, sproutIDs []string) {
@@ -71,9 +86,10 @@ func (l *CLIListener) RecordJobInit(jid, recipe string, sproutIDs []string) {
 
 // Stop unsubscribes the listener from NATS.
 func (l *CLIListener) Stop() {
-	if l.sub != nil {
-		l.sub.Unsubscribe()
+	for _, sub := range l.subs {
+		sub.Unsubscribe()
 	}
+	l.subs = nil
 }
 
 func (l *CLIListener) handleStepCompletion(msg *nats.Msg) {
@@ -75,7 +75,7 @@ func TestCLIListener_SubscribeAll(t *testing.T) {
 	}
 	defer listener.Stop()
 
-	if listener.sub == nil {
+	if len(listener.subs) != 1 {
 		t.Fatal("expected subscription to be set")
 	}
 
@@ -113,7 +113,7 @@ func TestCLIListener_SubscribeJob(t *testing.T) {
 	_, conn := startTestNATSServer(t)
 	listener := NewCLIListener(store, conn, "UKEY2")
 
-	if err := listener.SubscribeJob("specific-jid"); err != nil {
+	if err := listener.SubscribeJob("specific-jid", "sprout-x"); err != nil {
 		t.Fatalf("SubscribeJob: %v", err)
 	}
 	defer listener.Stop()
@@ -179,10 +179,11 @@ func TestCLIListener_Stop(t *testing.T) {
 	if err := listener.SubscribeAll(); err != nil {
 		t.Fatal(err)
 	}
+	sub := listener.subs[0]
 	listener.Stop()
 
 	// After stop, subscription should be inactive.
-	if listener.sub.IsValid() {
+	if sub.IsValid() {
 		t.Error("expected subscription to be invalid after Stop")
 	}
 }
@@ -811,7 +811,7 @@ func TestCLIListener_SubscribeJob_ClosedConn(t *testing.T) {
 
 	conn.Close()
 
-	err = listener.SubscribeJob("some-jid")
+	err = listener.SubscribeJob("some-jid", "sprout-x")
 	if err == nil {
 		t.Error("expected error when subscribing on closed connection")
 	}
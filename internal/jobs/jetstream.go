@@ -0,0 +1,390 @@
+package jobs
+
+// JetStreamStore keeps job history in a JetStream stream on the farmer's
+// embedded nats-server instead of in per-sprout JSONL files. Completions
+// published on grlx.cook.<sprout>.<jid> are captured by the stream as they
+// are sent, so nothing needs to subscribe and write them out, and clients
+// can replay a job from the stream after a disconnect.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/gogrlx/grlx/v2/internal/cook"
+	"github.com/gogrlx/grlx/v2/internal/log"
+	"github.com/gogrlx/grlx/v2/internal/pki"
+)
+
+const (
+	// JobStreamName is the JetStream stream holding job history.
+	JobStreamName = "GRLX_JOBS"
+	// JobCreatedPrefix prefixes the subject a JobCreated record is stored
+	// under: grlx.jobs.created.<sprout>.<jid>.
+	JobCreatedPrefix = "grlx.jobs.created."
+	// JobSummaryPrefix prefixes the subject a job's latest summary is
+	// stored under: grlx.jobs.summary.<sprout>.<jid>. Each summary rolls
+	// up the one before it, so listing jobs reads one record per job.
+	JobSummaryPrefix = "grlx.jobs.summary."
+
+	// jobIndexConsumer is the durable consumer that keeps the summaries
+	// up to date with the job records.
+	jobIndexConsumer = "GRLX_JOBS_INDEX"
+
+	jobCompletionPrefix = "grlx.cook."
+	jsFetchBatch        = 256
+	jsRequestTimeout    = 10 * time.Second
+)
+
+// JobCreated is recorded when the farmer dispatches a recipe so that a job
+// shows up, with its step count and invoker, before any step completes.
+type JobCreated struct {
+	JobMeta
+	Steps []cook.StepID `json:"steps"`
+}
+
+// JetStreamStore serves job summaries from the GRLX_JOBS stream.
+type JetStreamStore struct {
+	nc     *nats.Conn
+	js     jetstream.JetStream
+	stream jetstream.Stream
+}
+
+// NewJetStreamStore creates or updates the GRLX_JOBS stream, keeping
+// messages for maxAge (0 keeps them forever).
+func NewJetStreamStore(ctx context.Context, conn *nats.Conn, maxAge time.Duration) (*JetStreamStore, error) {
+	js, err := jetstream.New(conn)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:        JobStreamName,
+		Description: "grlx job history",
+		Subjects:    []string{jobCompletionPrefix + ">", JobCreatedPrefix + ">", JobSummaryPrefix + ">"},
+		Storage:     jetstream.FileStorage,
+		MaxAge:      maxAge,
+		AllowRollup: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating job stream: %w", err)
+	}
+	return &JetStreamStore{nc: conn, js: js, stream: stream}, nil
+}
+
+// RecordJobCreation subscribes to recipe dispatches and stores a
+// JobCreated record for each one. It is the stream counterpart of the
+// creation marker RegisterNatsConn writes to disk.
+func (s *JetStreamStore) RecordJobCreation() error {
+	_, err := s.nc.Subscribe("grlx.sprouts.*.cook", s.logJobCreation)
+	if err != nil {
+		return err
+	}
+	_, err = s.nc.Subscribe("grlx.sprouts.*.cook.queued", s.logJobQueued)
+	return err
+}
+
+// logJobQueued records a job the farmer has queued for an unreachable
+// sprout, then replies so the farmer knows the job exists before it marks
+// the job deferred.
+func (s *JetStreamStore) logJobQueued(msg *nats.Msg) {
+	s.logJobCreation(msg)
+	if msg.Reply != "" {
+		msg.Respond(nil)
+	}
+}
+
+func (s *JetStreamStore) logJobCreation(msg *nats.Msg) {
+	// Subject: grlx.sprouts.<sproutID>.cook
+	tComponents := strings.Split(msg.Subject, ".")
+	if len(tComponents) < 4 {
+		log.Errorf("unexpected subject format for job creation: %s", msg.Subject)
+		return
+	}
+	sprout := tComponents[2]
+
+	var envelope cook.RecipeEnvelope
+	if err := json.Unmarshal(pki.MessagePayload(msg.Data), &envelope); err != nil {
+		log.Errorf("failed to unmarshal recipe envelope: %v", err)
+		return
+	}
+	if envelope.JobID == "" {
+		return
+	}
+	created := JobCreated{
+		JobMeta: JobMeta{
+			JID:       envelope.JobID,
+			InvokedBy: envelope.InvokedBy,
+			CreatedAt: time.Now().UTC(),
+		},
+	}
+	for _, step := range envelope.Steps {
+		created.Steps = append(created.Steps, step.ID)
+	}
+	b, err := json.Marshal(created)
+	if err != nil {
+		log.Errorf("failed to marshal job creation record: %v", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), jsRequestTimeout)
+	defer cancel()
+	subject := JobCreatedPrefix + sprout + "." + envelope.JobID
+	// A queued job is seen here when it is queued and again when it is
+	// delivered; keep the first record.
+	if _, err = s.stream.GetLastMsgForSubject(ctx, subject); err == nil {
+		return
+	}
+	if _, err = s.js.Publish(ctx, subject, b); err != nil {
+		log.Errorf("failed to record job %s: %v", envelope.JobID, err)
+		return
+	}
+	log.Noticef("job %s created for sprout %s (%d steps)", envelope.JobID, sprout, len(envelope.Steps))
+}
+
+// IndexJobs keeps a summary of each job in the stream as its records
+// arrive, for ListAllJobs and ListJobsForSprout to read. The index
+// consumer is durable, so a farmer restart picks up where it left off and
+// the first start summarizes the jobs already in the stream.
+func (s *JetStreamStore) IndexJobs(ctx context.Context) error {
+	cons, err := s.stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:        jobIndexConsumer,
+		FilterSubjects: jobSubjects("*", "*"),
+		DeliverPolicy:  jetstream.DeliverAllPolicy,
+		AckPolicy:      jetstream.AckExplicitPolicy,
+	})
+	if err != nil {
+		return fmt.Errorf("creating job index: %w", err)
+	}
+	_, err = cons.Consume(func(msg jetstream.Msg) {
+		if sproutID, jid, ok := parseJobSubject(msg.Subject()); ok {
+			if indexErr := s.indexJob(sproutID, jid); indexErr != nil {
+				log.Errorf("failed to index job %s: %v", jid, indexErr)
+				msg.Nak()
+				return
+			}
+		}
+		msg.Ack()
+	})
+	return err
+}
+
+// indexJob stores the current summary of the job jid on sproutID, without
+// its steps, in place of the previous one.
+func (s *JetStreamStore) indexJob(sproutID, jid string) error {
+	summaries, err := s.load(sproutID, jid)
+	if err != nil || len(summaries) == 0 {
+		// The job has been deleted.
+		return err
+	}
+	summary := summaries[0]
+	summary.Steps = nil
+	b, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), jsRequestTimeout)
+	defer cancel()
+	msg := nats.NewMsg(JobSummaryPrefix + sproutID + "." + jid)
+	msg.Header.Set(nats.MsgRollup, nats.MsgRollupSubject)
+	msg.Data = b
+	_, err = s.js.PublishMsg(ctx, msg)
+	return err
+}
+
+// parseJobSubject returns the sprout and JID of a job record's subject.
+func parseJobSubject(subject string) (string, string, bool) {
+	prefix := jobCompletionPrefix
+	if strings.HasPrefix(subject, JobCreatedPrefix) {
+		prefix = JobCreatedPrefix
+	}
+	return strings.Cut(strings.TrimPrefix(subject, prefix), ".")
+}
+
+// FindJob returns the job with the given JID, whichever sprout ran it.
+// Only that job's records are read from the stream.
+func (s *JetStreamStore) FindJob(jid string) (*JobSummary, error) {
+	summaries, err := s.load("*", jid)
+	if err != nil {
+		return nil, err
+	}
+	if len(summaries) == 0 {
+		return nil, ErrJobNotFound
+	}
+	return &summaries[0], nil
+}
+
+// ListJobsForSprout returns all jobs for a sprout, most recent first. The
+// summaries come from the job index and do not include the steps.
+func (s *JetStreamStore) ListJobsForSprout(sproutID string) ([]JobSummary, error) {
+	summaries, err := s.loadSummaries(sproutID)
+	if err != nil {
+		return nil, err
+	}
+	if len(summaries) == 0 {
+		return nil, ErrSproutNoJobs
+	}
+	return summaries, nil
+}
+
+// ListAllJobs returns jobs across all sprouts, most recent first. A limit
+// of 0 means no limit. The summaries come from the job index and do not
+// include the steps.
+func (s *JetStreamStore) ListAllJobs(limit int) ([]JobSummary, error) {
+	summaries, err := s.loadSummaries("*")
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(summaries) > limit {
+		summaries = summaries[:limit]
+	}
+	return summaries, nil
+}
+
+// DeleteJob purges a job's completions and creation record from the stream.
+func (s *JetStreamStore) DeleteJob(jid string) error {
+	if _, err := s.FindJob(jid); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), jsRequestTimeout)
+	defer cancel()
+	for _, subject := range append(jobSubjects("*", jid), JobSummaryPrefix+"*."+jid) {
+		if err := s.stream.Purge(ctx, jetstream.WithPurgeSubject(subject)); err != nil {
+			return fmt.Errorf("deleting job %s: %w", jid, err)
+		}
+	}
+	return nil
+}
+
+// jobSubjects returns the stream subjects holding a job's records. sprout
+// and jid may be "*" to match any.
+func jobSubjects(sprout, jid string) []string {
+	return []string{
+		jobCompletionPrefix + sprout + "." + jid,
+		JobCreatedPrefix + sprout + "." + jid,
+	}
+}
+
+// loadSummaries reads the latest indexed summary of each job on sprout,
+// which may be "*" to match any, most recent first.
+func (s *JetStreamStore) loadSummaries(sprout string) ([]JobSummary, error) {
+	summaries := []JobSummary{}
+	err := s.read([]string{JobSummaryPrefix + sprout + ".*"}, jetstream.DeliverLastPerSubjectPolicy, func(msg jetstream.Msg) {
+		var summary JobSummary
+		if json.Unmarshal(msg.Data(), &summary) == nil {
+			summaries = append(summaries, summary)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].StartedAt.After(summaries[j].StartedAt)
+	})
+	return summaries, nil
+}
+
+// read passes each record on subjects, from where policy starts, to
+// handle.
+func (s *JetStreamStore) read(subjects []string, policy jetstream.DeliverPolicy, handle func(jetstream.Msg)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), jsRequestTimeout)
+	defer cancel()
+	cons, err := s.stream.CreateConsumer(ctx, jetstream.ConsumerConfig{
+		FilterSubjects:    subjects,
+		DeliverPolicy:     policy,
+		AckPolicy:         jetstream.AckNonePolicy,
+		InactiveThreshold: time.Minute,
+	})
+	if err != nil {
+		return fmt.Errorf("reading job stream: %w", err)
+	}
+	defer s.stream.DeleteConsumer(context.Background(), cons.CachedInfo().Name)
+
+	pending := cons.CachedInfo().NumPending
+	for pending > 0 {
+		batch, fetchErr := cons.Fetch(int(min(pending, jsFetchBatch)), jetstream.FetchMaxWait(jsRequestTimeout))
+		if fetchErr != nil {
+			return fmt.Errorf("reading job stream: %w", fetchErr)
+		}
+		received := 0
+		for msg := range batch.Messages() {
+			received++
+			handle(msg)
+		}
+		if batchErr := batch.Error(); batchErr != nil && !errors.Is(batchErr, nats.ErrTimeout) {
+			return fmt.Errorf("reading job stream: %w", batchErr)
+		}
+		if received == 0 {
+			break
+		}
+		pending -= uint64(min(uint64(received), pending))
+	}
+	return nil
+}
+
+// load reads every record matching sprout and jid from the stream and
+// builds one summary per job, most recent first.
+func (s *JetStreamStore) load(sprout, jid string) ([]JobSummary, error) {
+	type jobKey struct{ sprout, jid string }
+	var (
+		order   []jobKey
+		seen    = map[jobKey]bool{}
+		created = map[jobKey]JobCreated{}
+		steps   = map[jobKey][]cook.StepCompletion{}
+	)
+	err := s.read(jobSubjects(sprout, jid), jetstream.DeliverAllPolicy, func(msg jetstream.Msg) {
+		subject := msg.Subject()
+		isCreated := strings.HasPrefix(subject, JobCreatedPrefix)
+		sproutID, jobID, ok := parseJobSubject(subject)
+		if !ok {
+			return
+		}
+		key := jobKey{sproutID, jobID}
+		if !seen[key] {
+			seen[key] = true
+			order = append(order, key)
+		}
+		if isCreated {
+			var rec JobCreated
+			if json.Unmarshal(msg.Data(), &rec) != nil {
+				return
+			}
+			created[key] = rec
+			// Placeholders go first, as they do in the job file.
+			placeholders := make([]cook.StepCompletion, 0, len(rec.Steps))
+			for _, id := range rec.Steps {
+				placeholders = append(placeholders, cook.StepCompletion{
+					ID:               id,
+					CompletionStatus: cook.StepNotStarted,
+					Started:          rec.CreatedAt,
+				})
+			}
+			steps[key] = append(placeholders, steps[key]...)
+			return
+		}
+		var step cook.StepCompletion
+		if json.Unmarshal(msg.Data(), &step) != nil {
+			return
+		}
+		steps[key] = append(steps[key], step)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]JobSummary, 0, len(order))
+	for _, key := range order {
+		summary := buildSummary(key.jid, key.sprout, steps[key])
+		summary.InvokedBy = created[key].InvokedBy
+		summaries = append(summaries, *summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].StartedAt.After(summaries[j].StartedAt)
+	})
+	return summaries, nil
+}
@@ -0,0 +1,175 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+
+	"github.com/gogrlx/grlx/v2/internal/cook"
+)
+
+func startTestJetStream(t *testing.T) (*JetStreamStore, *nats.Conn) {
+	t.Helper()
+	opts := &server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	}
+	ns, err := server.NewServer(opts)
+	if err != nil {
+		t.Fatalf("start test NATS server: %v", err)
+	}
+	go ns.Start()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server failed to become ready")
+	}
+	conn, err := nats.Connect(ns.ClientURL())
+	if err != nil {
+		ns.Shutdown()
+		t.Fatalf("connect to test NATS: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		ns.Shutdown()
+	})
+
+	store, err := NewJetStreamStore(context.Background(), conn, time.Hour)
+	if err != nil {
+		t.Fatalf("NewJetStreamStore: %v", err)
+	}
+	if err = store.RecordJobCreation(); err != nil {
+		t.Fatalf("RecordJobCreation: %v", err)
+	}
+	if err = store.IndexJobs(context.Background()); err != nil {
+		t.Fatalf("IndexJobs: %v", err)
+	}
+	return store, conn
+}
+
+func publishStep(t *testing.T, conn *nats.Conn, sprout, jid string, step cook.StepCompletion) {
+	t.Helper()
+	b, err := json.Marshal(step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.Publish("grlx.cook."+sprout+"."+jid, b); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitForJob polls until the stream holds want records for jid.
+func waitForJob(t *testing.T, store *JetStreamStore, jid string, want int) *JobSummary {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		summary, err := store.FindJob(jid)
+		if err == nil && summary.Total >= want {
+			return summary
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s did not reach %d records: %v, %v", jid, want, summary, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// waitForList polls until the job index lists want jobs, each with at
+// least min records.
+func waitForList(t *testing.T, store *JetStreamStore, want, min int) []JobSummary {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		all, err := store.ListAllJobs(0)
+		if err == nil && len(all) == want && (want == 0 || slices.MinFunc(all, func(a, b JobSummary) int { return a.Total - b.Total }).Total >= min) {
+			return all
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("index did not list %d jobs: %+v, %v", want, all, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestJetStreamStore(t *testing.T) {
+	store, conn := startTestJetStream(t)
+	now := time.Now()
+
+	envelope := cook.RecipeEnvelope{
+		JobID:     "js-job-1",
+		InvokedBy: "UADMIN",
+		Steps:     []cook.Step{{ID: "step-1"}, {ID: "step-2"}},
+	}
+	b, _ := json.Marshal(envelope)
+	if err := conn.Publish("grlx.sprouts.web-01.cook", b); err != nil {
+		t.Fatal(err)
+	}
+	summary := waitForJob(t, store, "js-job-1", 2)
+	if summary.Status != JobPartial && summary.Status != JobPending {
+		t.Errorf("expected a job with only placeholders to be pending, got %v", summary.Status)
+	}
+	if summary.InvokedBy != "UADMIN" {
+		t.Errorf("expected invoker UADMIN, got %q", summary.InvokedBy)
+	}
+
+	publishStep(t, conn, "web-01", "js-job-1", makeStep("start-js-job-1", cook.StepCompleted, now, 0))
+	publishStep(t, conn, "web-01", "js-job-1", makeStep("step-1", cook.StepCompleted, now, time.Second))
+	publishStep(t, conn, "web-01", "js-job-1", makeStep("step-2", cook.StepFailed, now, time.Second))
+	publishStep(t, conn, "web-01", "js-job-1", makeStep("completed-js-job-1", cook.StepCompleted, now, 0))
+	summary = waitForJob(t, store, "js-job-1", 6)
+	if summary.SproutID != "web-01" {
+		t.Errorf("expected sprout web-01, got %q", summary.SproutID)
+	}
+	if summary.Status != JobFailed {
+		t.Errorf("expected status failed, got %v", summary.Status)
+	}
+	if summary.Steps[0].ID != "step-1" || summary.Steps[0].CompletionStatus != cook.StepNotStarted {
+		t.Errorf("expected placeholders first, got %+v", summary.Steps[0])
+	}
+
+	publishStep(t, conn, "db-01", "js-job-2", makeStep("step-1", cook.StepCompleted, now.Add(time.Minute), time.Second))
+	waitForJob(t, store, "js-job-2", 1)
+
+	all := waitForList(t, store, 2, 1)
+	if all[0].JID != "js-job-2" || all[1].Total != 6 {
+		t.Errorf("expected 2 jobs, most recent first, got %+v", all)
+	}
+	if all[1].Steps != nil {
+		t.Errorf("expected listed jobs to leave out their steps, got %+v", all[1].Steps)
+	}
+	if all[1].InvokedBy != "UADMIN" {
+		t.Errorf("expected the index to keep the invoker, got %q", all[1].InvokedBy)
+	}
+	if limited, _ := store.ListAllJobs(1); len(limited) != 1 {
+		t.Errorf("expected limit to apply, got %d jobs", len(limited))
+	}
+	forSprout, err := store.ListJobsForSprout("web-01")
+	if err != nil || len(forSprout) != 1 || forSprout[0].JID != "js-job-1" {
+		t.Errorf("expected only js-job-1 for web-01, got %+v, %v", forSprout, err)
+	}
+	if _, err = store.ListJobsForSprout("nobody"); !errors.Is(err, ErrSproutNoJobs) {
+		t.Errorf("expected %v, got %v", ErrSproutNoJobs, err)
+	}
+
+	if err = store.DeleteJob("js-job-1"); err != nil {
+		t.Fatalf("DeleteJob: %v", err)
+	}
+	if _, err = store.FindJob("js-job-1"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected deleted job to be gone, got %v", err)
+	}
+	if err = store.DeleteJob("js-job-1"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected %v, got %v", ErrJobNotFound, err)
+	}
+	if _, err = store.FindJob("js-job-2"); err != nil {
+		t.Errorf("expected other jobs to survive a delete, got %v", err)
+	}
+	if all = waitForList(t, store, 1, 1); all[0].JID != "js-job-2" {
+		t.Errorf("expected a deleted job to leave the index, got %+v", all)
+	}
+}
@@ -14,6 +14,9 @@ package jobs
 // Job data expiration is configurable via the joblogttl setting on both the
 // farmer (Store.StartReaperCtx) and the sprout (StartSproutReaper).
 
+// When the farmer's jobstore setting is "jetstream", this listener is not
+// used; see JetStreamStore.
+
 import (
 	"encoding/json"
 	"errors"
@@ -28,6 +31,7 @@ import (
 
 	"github.com/gogrlx/grlx/v2/internal/config"
 	"github.com/gogrlx/grlx/v2/internal/cook"
+	"github.com/gogrlx/grlx/v2/internal/pki"
 )
 
 // Job represents a job
@@ -44,6 +48,10 @@ func RegisterNatsConn(conn *nats.Conn) {
 	if err != nil {
 		log.Error(err)
 	}
+	_, err = nc.Subscribe("grlx.sprouts.*.cook.queued", logJobQueued)
+	if err != nil {
+		log.Error(err)
+	}
 
 	// Create the jobs directory if it does not exist
 	// this cannot run in init, as the config is not yet loaded
@@ -69,7 +77,7 @@ func logJobCreation(msg *nats.Msg) {
 	sprout := tComponents[2]
 
 	var envelope cook.RecipeEnvelope
-	if err := json.Unmarshal(msg.Data, &envelope); err != nil {
+	if err := json.Unmarshal(pki.MessagePayload(msg.Data), &envelope); err != nil {
 		log.Errorf("failed to unmarshal recipe envelope: %v", err)
 		return
 	}
@@ -131,6 +139,16 @@ func logJobCreation(msg *nats.Msg) {
 	log.Noticef("job %s created for sprout %s (%d steps)", envelope.JobID, sprout, len(envelope.Steps))
 }
 
+// logJobQueued records a job the farmer has queued for an unreachable
+// sprout, then replies so the farmer knows the job exists before it marks
+// the job deferred.
+func logJobQueued(msg *nats.Msg) {
+	logJobCreation(msg)
+	if msg.Reply != "" {
+		msg.Respond(nil)
+	}
+}
+
 func logJobs(msg *nats.Msg) {
 	// Subscribe to the jobs topic
 	tComponents := strings.Split(msg.Subject, ".")
@@ -194,6 +194,35 @@ func TestLogJobCreation(t *testing.T) {
 	}
 }
 
+func TestLogJobQueued(t *testing.T) {
+	dir := t.TempDir()
+	origJobLogDir := config.JobLogDir
+	config.JobLogDir = dir
+	t.Cleanup(func() { config.JobLogDir = origJobLogDir })
+
+	_, conn := startTestNATSServer(t)
+	RegisterNatsConn(conn)
+
+	envelope := cook.RecipeEnvelope{
+		JobID: "queued-job-1",
+		Steps: []cook.Step{{ID: "step-a"}},
+	}
+	data, _ := json.Marshal(envelope)
+
+	// The farmer waits for the reply before marking the job deferred.
+	if _, err := conn.Request("grlx.sprouts.sprout-queued.cook.queued", data, 2*time.Second); err != nil {
+		t.Fatalf("expected a reply once the job was recorded: %v", err)
+	}
+	jobFile := filepath.Join(dir, "sprout-queued", "queued-job-1.jsonl")
+	steps, err := readJobFile(jobFile)
+	if err != nil {
+		t.Fatalf("expected job file to exist: %v", err)
+	}
+	if len(steps) != 1 || steps[0].CompletionStatus != cook.StepNotStarted {
+		t.Errorf("expected 1 placeholder step, got %+v", steps)
+	}
+}
+
 func TestLogJobCreation_EmptyJobID(t *testing.T) {
 	dir := t.TempDir()
 	origJobLogDir := config.JobLogDir
@@ -30,6 +30,8 @@ const (
 	JobSucceeded                  // All steps completed successfully
 	JobFailed                     // At least one step failed
 	JobPartial                    // Mix of completed and not-started steps
+	JobQueued                     // Waiting for a free cook slot on the sprout
+	JobCancelled                  // Stopped by a cancel request
 )
 
 func (s JobStatus) String() string {
@@ -44,6 +46,10 @@ func (s JobStatus) String() string {
 		return "failed"
 	case JobPartial:
 		return "partial"
+	case JobQueued:
+		return "queued"
+	case JobCancelled:
+		return "cancelled"
 	default:
 		return "unknown"
 	}
@@ -71,6 +77,10 @@ func (s *JobStatus) UnmarshalJSON(data []byte) error {
 		*s = JobFailed
 	case "partial":
 		*s = JobPartial
+	case "queued":
+		*s = JobQueued
+	case "cancelled":
+		*s = JobCancelled
 	default:
 		return fmt.Errorf("unknown job status: %s", str)
 	}
@@ -88,10 +98,21 @@ type JobSummary struct {
 	Succeeded int                   `json:"succeeded"`
 	Failed    int                   `json:"failed"`
 	Skipped   int                   `json:"skipped"`
+	TimedOut  int                   `json:"timed_out,omitempty"` // also counted in Failed
+	Cancelled int                   `json:"cancelled,omitempty"`
 	Total     int                   `json:"total"`
 	InvokedBy string                `json:"invoked_by,omitempty"`
 }
 
+// Backend is the read/delete interface shared by the flat-file Store and
+// the JetStreamStore, so the farmer's API can serve jobs from either.
+type Backend interface {
+	FindJob(jid string) (*JobSummary, error)
+	ListJobsForSprout(sproutID string) ([]JobSummary, error)
+	ListAllJobs(limit int) ([]JobSummary, error)
+	DeleteJob(jid string) error
+}
+
 // Store provides methods for retrieving job data from the flat-file store.
 type Store struct {
 	mu     sync.RWMutex
@@ -383,8 +404,15 @@ func buildSummary(jid, sproutID string, steps []cook.StepCompletion) *JobSummary
 			summary.Succeeded++
 		case cook.StepFailed:
 			summary.Failed++
+		case cook.StepTimedOut:
+			summary.Failed++
+			summary.TimedOut++
 		case cook.StepSkipped:
 			summary.Skipped++
+		case cook.StepCancelled:
+			if !isLifecycleMarker(jid, step.ID) {
+				summary.Cancelled++
+			}
 		}
 
 		if !step.Started.IsZero() {
@@ -405,10 +433,53 @@ func buildSummary(jid, sproutID string, steps []cook.StepCompletion) *JobSummary
 
 	// Determine overall status
 	summary.Status = determineJobStatus(steps)
+	if status, ok := lifecycleStatus(jid, steps); ok {
+		summary.Status = status
+	}
 
 	return summary
 }
 
+// isLifecycleMarker reports whether id is one of the synthetic completions
+// that track a job rather than one of its steps.
+func isLifecycleMarker(jid string, id cook.StepID) bool {
+	switch string(id) {
+	case "batch-" + jid, "deferred-" + jid, "queued-" + jid, "start-" + jid, "completed-" + jid, "timeout-" + jid, "cancelled-" + jid:
+		return true
+	}
+	return false
+}
+
+// lifecycleStatus derives a job's status from the synthetic queued-,
+// start-, completed- and cancelled- markers the sprout scheduler
+// publishes, and the deferred- marker the farmer publishes for a job held
+// until its sprout reconnects. It reports false once the job has finished
+// normally (or if no markers were seen), in which case the step results
+// decide the status.
+func lifecycleStatus(jid string, steps []cook.StepCompletion) (JobStatus, bool) {
+	var queued, started bool
+	for _, step := range steps {
+		switch string(step.ID) {
+		case "deferred-" + jid, "queued-" + jid:
+			queued = true
+		case "start-" + jid:
+			started = true
+		case "completed-" + jid, "timeout-" + jid:
+			return 0, false
+		case "cancelled-" + jid:
+			return JobCancelled, true
+		}
+	}
+	switch {
+	case started:
+		return JobRunning, true
+	case queued:
+		return JobQueued, true
+	default:
+		return 0, false
+	}
+}
+
 // determineJobStatus computes the aggregate job status from step completions.
 func determineJobStatus(steps []cook.StepCompletion) JobStatus {
 	if len(steps) == 0 {
@@ -419,6 +490,7 @@ func determineJobStatus(steps []cook.StepCompletion) JobStatus {
 	hasFailed := false
 	hasNotStarted := false
 	hasCompleted := false
+	hasCancelled := false
 
 	for _, step := range steps {
 		switch step.CompletionStatus {
@@ -428,14 +500,19 @@ func determineJobStatus(steps []cook.StepCompletion) JobStatus {
 			hasInProgress = true
 		case cook.StepCompleted, cook.StepSkipped:
 			hasCompleted = true
-		case cook.StepFailed:
+		case cook.StepFailed, cook.StepTimedOut:
 			hasFailed = true
+		case cook.StepCancelled:
+			hasCancelled = true
 		}
 	}
 
 	if hasInProgress {
 		return JobRunning
 	}
+	if hasCancelled {
+		return JobCancelled
+	}
 	if hasFailed {
 		return JobFailed
 	}
@@ -289,6 +289,14 @@ func TestDetermineJobStatus(t *testing.T) {
 			},
 			expected: JobSucceeded,
 		},
+		{
+			name: "timed out returns failed",
+			steps: []cook.StepCompletion{
+				makeStep("s1", cook.StepCompleted, time.Now(), time.Second),
+				makeStep("s2", cook.StepTimedOut, time.Now(), time.Minute),
+			},
+			expected: JobFailed,
+		},
 		{
 			name: "any in progress returns running",
 			steps: []cook.StepCompletion{
@@ -351,6 +359,8 @@ func TestJobStatusJSON(t *testing.T) {
 		{JobSucceeded, `"succeeded"`},
 		{JobFailed, `"failed"`},
 		{JobPartial, `"partial"`},
+		{JobQueued, `"queued"`},
+		{JobCancelled, `"cancelled"`},
 	}
 
 	for _, tt := range tests {
@@ -374,6 +384,129 @@ func TestJobStatusJSON(t *testing.T) {
 	}
 }
 
+func TestBuildSummary_Lifecycle(t *testing.T) {
+	now := time.Now()
+	jid := "lifecycle-jid"
+	tests := []struct {
+		name     string
+		steps    []cook.StepCompletion
+		expected JobStatus
+	}{
+		{
+			name:     "queued",
+			steps:    []cook.StepCompletion{makeStep("queued-"+jid, cook.StepCompleted, now, 0)},
+			expected: JobQueued,
+		},
+		{
+			name: "started after queueing",
+			steps: []cook.StepCompletion{
+				makeStep("queued-"+jid, cook.StepCompleted, now, 0),
+				makeStep("start-"+jid, cook.StepCompleted, now, 0),
+			},
+			expected: JobRunning,
+		},
+		{
+			name: "completed",
+			steps: []cook.StepCompletion{
+				makeStep("queued-"+jid, cook.StepCompleted, now, 0),
+				makeStep("start-"+jid, cook.StepCompleted, now, 0),
+				makeStep("step1", cook.StepFailed, now, time.Second),
+				makeStep("completed-"+jid, cook.StepCompleted, now, 0),
+			},
+			expected: JobFailed,
+		},
+		{
+			name: "cancelled",
+			steps: []cook.StepCompletion{
+				makeStep("start-"+jid, cook.StepCompleted, now, 0),
+				makeStep("step1", cook.StepCompleted, now, time.Second),
+				makeStep("step2", cook.StepCancelled, now, 0),
+				makeStep("cancelled-"+jid, cook.StepCancelled, now, 0),
+			},
+			expected: JobCancelled,
+		},
+		{
+			name: "sent in a batch of a rolling cook",
+			steps: []cook.StepCompletion{
+				makeStep("step1", cook.StepNotStarted, now, 0),
+				makeStep("start-"+jid, cook.StepCompleted, now, 0),
+				makeStep("batch-"+jid, cook.StepNotStarted, now, 0),
+			},
+			expected: JobRunning,
+		},
+		{
+			name: "skipped by a stopped rolling cook",
+			steps: []cook.StepCompletion{
+				makeStep("cancelled-"+jid, cook.StepCancelled, now, 0),
+			},
+			expected: JobCancelled,
+		},
+		{
+			name: "deferred for an offline sprout",
+			steps: []cook.StepCompletion{
+				makeStep("step1", cook.StepNotStarted, now, 0),
+				makeStep("deferred-"+jid, cook.StepNotStarted, now, 0),
+			},
+			expected: JobQueued,
+		},
+		{
+			name: "delivered after deferral",
+			steps: []cook.StepCompletion{
+				makeStep("step1", cook.StepNotStarted, now, 0),
+				makeStep("deferred-"+jid, cook.StepNotStarted, now, 0),
+				makeStep("start-"+jid, cook.StepCompleted, now, 0),
+			},
+			expected: JobRunning,
+		},
+		{
+			name: "deferral expired",
+			steps: []cook.StepCompletion{
+				makeStep("step1", cook.StepNotStarted, now, 0),
+				makeStep("deferred-"+jid, cook.StepNotStarted, now, 0),
+				makeStep("cancelled-"+jid, cook.StepCancelled, now, 0),
+			},
+			expected: JobCancelled,
+		},
+		{
+			name: "cancelled while queued",
+			steps: []cook.StepCompletion{
+				makeStep("queued-"+jid, cook.StepCompleted, now, 0),
+				makeStep("step1", cook.StepCancelled, now, 0),
+				makeStep("cancelled-"+jid, cook.StepCancelled, now, 0),
+			},
+			expected: JobCancelled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := buildSummary(jid, "test-sprout", tt.steps)
+			if summary.Status != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, summary.Status)
+			}
+		})
+	}
+}
+
+func TestBuildSummary_Cancelled(t *testing.T) {
+	now := time.Now()
+	summary := buildSummary("jid", "sprout", []cook.StepCompletion{
+		makeStep("s1", cook.StepCompleted, now, time.Second),
+		makeStep("s2", cook.StepCancelled, now, 0),
+		makeStep("s3", cook.StepCancelled, now, 0),
+		makeStep("cancelled-jid", cook.StepCancelled, now, 0),
+	})
+	if summary.Cancelled != 2 {
+		t.Errorf("expected 2 cancelled steps not counting the marker, got %d", summary.Cancelled)
+	}
+	if summary.Status != JobCancelled {
+		t.Errorf("expected status cancelled, got %v", summary.Status)
+	}
+	// Without the marker the cancelled steps still decide the status.
+	if status := determineJobStatus(summary.Steps[:3]); status != JobCancelled {
+		t.Errorf("expected cancelled steps to mark the job cancelled, got %v", status)
+	}
+}
+
 func TestBuildSummary_Duration(t *testing.T) {
 	now := time.Now().Truncate(time.Second)
 	steps := []cook.StepCompletion{
@@ -400,6 +533,17 @@ func TestBuildSummary_Duration(t *testing.T) {
 	}
 }
 
+func TestBuildSummary_TimedOut(t *testing.T) {
+	now := time.Now()
+	summary := buildSummary("jid", "sprout", []cook.StepCompletion{
+		makeStep("s1", cook.StepFailed, now, time.Second),
+		makeStep("s2", cook.StepTimedOut, now, time.Minute),
+	})
+	if summary.Failed != 2 || summary.TimedOut != 1 {
+		t.Errorf("expected 2 failed with 1 timed out, got %d and %d", summary.Failed, summary.TimedOut)
+	}
+}
+
 func TestReadJobFile_EmptyLines(t *testing.T) {
 	dir := t.TempDir()
 	jobFile := filepath.Join(dir, "test.jsonl")
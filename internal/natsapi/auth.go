@@ -100,6 +100,9 @@ func handleAuthWhoAmI(params json.RawMessage) (any, error) {
 	if err != nil {
 		return nil, fmt.Errorf("unauthorized")
 	}
+	if _, err = intauth.VerifyRequestToken(p.Token, MethodAuthWhoAmI, params); err != nil {
+		return nil, fmt.Errorf("unauthorized: %w", err)
+	}
 
 	return apitypes.UserInfo{
 		Pubkey:   pubkey,
@@ -132,14 +135,20 @@ func handleAuthExplain(params json.RawMessage) (any, error) {
 	if err != nil {
 		return nil, fmt.Errorf("unauthorized")
 	}
+	format, err := intauth.VerifyRequestToken(p.Token, MethodAuthExplain, params)
+	if err != nil {
+		return nil, fmt.Errorf("unauthorized: %w", err)
+	}
 
 	summary := rbac.ExplainAccess(policy, pubkey)
 
 	resp := apitypes.ExplainResponse{
-		Pubkey:   pubkey,
-		RoleName: roleName,
-		IsAdmin:  summary.IsAdmin,
-		Warnings: summary.Warnings,
+		Pubkey:            pubkey,
+		RoleName:          roleName,
+		IsAdmin:           summary.IsAdmin,
+		Warnings:          summary.Warnings,
+		TokenFormat:       format,
+		LegacyTokensUntil: intauth.LegacyTokensUntil(),
 	}
 	for _, a := range summary.Actions {
 		resp.Actions = append(resp.Actions, apitypes.ActionExplain{
@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	apitypes "github.com/gogrlx/grlx/v2/internal/api/types"
+	"github.com/gogrlx/grlx/v2/internal/cook"
 	"github.com/gogrlx/grlx/v2/internal/ingredients/cmd"
 	"github.com/gogrlx/grlx/v2/internal/pki"
 )
@@ -33,6 +34,11 @@ func handleCmdRun(params json.RawMessage) (any, error) {
 		}
 	}
 
+	// The job ID lets jobs.cancel stop the command on each sprout.
+	if command.JobID == "" {
+		command.JobID = cook.GenerateJobID()
+	}
+
 	results := apitypes.TargetedResults{
 		Results: make(map[string]interface{}),
 	}
@@ -4,12 +4,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	apitypes "github.com/gogrlx/grlx/v2/internal/api/types"
 	"github.com/gogrlx/grlx/v2/internal/auth"
+	"github.com/gogrlx/grlx/v2/internal/config"
 	"github.com/gogrlx/grlx/v2/internal/cook"
 	log "github.com/gogrlx/grlx/v2/internal/log"
 	"github.com/gogrlx/grlx/v2/internal/pki"
@@ -28,7 +30,8 @@ func handleCook(params json.RawMessage) (any, error) {
 		return nil, fmt.Errorf("invalid cook command: %w", err)
 	}
 
-	for _, target := range ta.Target {
+	sproutIDs := make([]string, len(ta.Target))
+	for i, target := range ta.Target {
 		if !pki.IsValidSproutID(target.SproutID) || strings.Contains(target.SproutID, "_") {
 			return nil, fmt.Errorf("invalid sprout ID: %s", target.SproutID)
 		}
@@ -36,6 +39,27 @@ func handleCook(params json.RawMessage) (any, error) {
 		if !registered {
 			return nil, fmt.Errorf("unknown sprout: %s", target.SproutID)
 		}
+		sproutIDs[i] = target.SproutID
+	}
+
+	if command.Top {
+		assignments, topErr := assignTopRecipes(command, sproutIDs)
+		if topErr != nil {
+			return nil, topErr
+		}
+		command.TopMatches = assignments
+		// Only sprouts the top file gives recipes to are cooked.
+		sproutIDs = sproutIDs[:0]
+		for _, target := range ta.Target {
+			if len(assignments[target.SproutID].Recipes) > 0 {
+				sproutIDs = append(sproutIDs, target.SproutID)
+			}
+		}
+	}
+
+	size, maxFail, err := batchLimits(command, len(sproutIDs))
+	if err != nil {
+		return nil, err
 	}
 
 	if natsConn == nil {
@@ -43,14 +67,14 @@ func handleCook(params json.RawMessage) (any, error) {
 	}
 
 	// Resolve the invoker's identity for job attribution.
-	var invokerPubkey string
+	var invoker cookInvoker
 	var tp tokenParams
 	if len(params) > 0 {
 		json.Unmarshal(params, &tp)
 	}
 	if tp.Token != "" {
-		if pk, _, _, resolveErr := auth.WhoAmI(tp.Token); resolveErr == nil {
-			invokerPubkey = pk
+		if pk, role, name, resolveErr := auth.WhoAmI(tp.Token); resolveErr == nil {
+			invoker = cookInvoker{pubkey: pk, roleName: role, username: name}
 		}
 	}
 
@@ -71,34 +95,55 @@ func handleCook(params json.RawMessage) (any, error) {
 			return
 		}
 
-		sproutIDs := make([]string, len(ta.Target))
-		for i, t := range ta.Target {
-			sproutIDs[i] = t.SproutID
-		}
 		replyData, _ := json.Marshal(sproutIDs)
 		if err := msg.Respond(replyData); err != nil {
 			log.Errorf("error replying to cook trigger: %v", err)
 		}
 
+		send := func(sproutID string) (time.Duration, error) {
+			var timeout time.Duration
+			cookOpts := []cook.CookOption{cook.WithInvoker(invoker.pubkey), cook.WithTimeoutReport(&timeout)}
+			if command.State != "" {
+				cookOpts = append(cookOpts, cook.WithTargetStep(cook.StepID(command.State)))
+			}
+			if command.Top {
+				cookOpts = append(cookOpts, cook.WithRecipes(command.TopMatches[sproutID].Recipes...))
+			}
+			var err error
+			switch {
+			case command.Queue && sproutOffline(sproutID):
+				err = cook.QueueCookEvent(sproutID, command.Recipe, jid, command.Test, config.CookQueueTTL, cookOpts...)
+				return timeout, err
+			case command.Queue:
+				cookOpts = append(cookOpts, cook.WithQueue(config.CookQueueTTL))
+			}
+			err = cook.SendCookEvent(sproutID, command.Recipe, jid, command.Test, cookOpts...)
+			return timeout, err
+		}
+
+		if size > 0 {
+			recipes := []cook.RecipeName{command.Recipe}
+			if command.Top {
+				recipes = topRecipes(command.TopMatches, sproutIDs)
+			}
+			runBatches(jid, recipes, planBatches(sproutIDs, size), command.BatchWait, maxFail, invoker, send)
+			return
+		}
+
 		var wg sync.WaitGroup
 		var mu sync.Mutex
 		errs := make(map[string]error)
-		wg.Add(len(ta.Target))
+		wg.Add(len(sproutIDs))
 
-		for _, target := range ta.Target {
-			go func(t pki.KeyManager) {
+		for _, sproutID := range sproutIDs {
+			go func(sproutID string) {
 				defer wg.Done()
-				cookOpts := []cook.CookOption{cook.WithInvoker(invokerPubkey)}
-				if command.State != "" {
-					cookOpts = append(cookOpts, cook.WithTargetStep(cook.StepID(command.State)))
-				}
-				err := cook.SendCookEvent(t.SproutID, command.Recipe, jid, command.Test, cookOpts...)
-				if err != nil {
+				if _, err := send(sproutID); err != nil {
 					mu.Lock()
-					errs[t.SproutID] = err
+					errs[sproutID] = err
 					mu.Unlock()
 				}
-			}(target)
+			}(sproutID)
 		}
 		wg.Wait()
 		for sproutID, err := range errs {
@@ -107,5 +152,82 @@ func handleCook(params json.RawMessage) (any, error) {
 	}(jid, sub)
 
 	command.JID = jid
+	command.Sprouts = sproutIDs
 	return command, nil
 }
+
+// topRecipes returns every recipe the top file assigns to sproutIDs,
+// sorted and without duplicates.
+func topRecipes(assignments map[string]cook.TopAssignment, sproutIDs []string) []cook.RecipeName {
+	seen := map[cook.RecipeName]bool{}
+	var recipes []cook.RecipeName
+	for _, sproutID := range sproutIDs {
+		for _, recipe := range assignments[sproutID].Recipes {
+			if !seen[recipe] {
+				seen[recipe] = true
+				recipes = append(recipes, recipe)
+			}
+		}
+	}
+	sort.Slice(recipes, func(i, j int) bool { return recipes[i] < recipes[j] })
+	return recipes
+}
+
+// assignTopRecipes works out each target's recipes from the top file,
+// resolving cohorts against every accepted sprout.
+func assignTopRecipes(command apitypes.CmdCook, sproutIDs []string) (map[string]cook.TopAssignment, error) {
+	if command.Recipe != "" {
+		return nil, fmt.Errorf("a full-state cook takes its recipes from the top file, not %q", command.Recipe)
+	}
+	top, err := cook.LoadTopFile()
+	if err != nil {
+		return nil, err
+	}
+	var resolve func(string) (map[string]bool, error)
+	if cohortRegistry != nil {
+		allKeys := pki.ListNKeysByType()
+		allSproutIDs := make([]string, 0, len(allKeys.Accepted.Sprouts))
+		for _, km := range allKeys.Accepted.Sprouts {
+			allSproutIDs = append(allSproutIDs, km.SproutID)
+		}
+		resolve = func(name string) (map[string]bool, error) {
+			return cohortRegistry.Resolve(name, allSproutIDs)
+		}
+	}
+	assignments, err := top.Assign(sproutIDs, resolve)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range assignments {
+		if len(a.Recipes) > 0 {
+			return assignments, nil
+		}
+	}
+	return nil, fmt.Errorf("no top file entries match the targeted sprouts")
+}
+
+// batchLimits returns the batch size and failure limit for a cook on total
+// sprouts. A zero size sends to every sprout at once, and a negative limit
+// lets every batch run regardless of failures.
+func batchLimits(command apitypes.CmdCook, total int) (size, maxFail int, err error) {
+	maxFail = -1
+	if command.Batch == "" {
+		if command.MaxFail != "" {
+			return 0, 0, fmt.Errorf("max-fail requires a batch size")
+		}
+		return 0, maxFail, nil
+	}
+	size, err = parseBatchCount(command.Batch, total)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid batch size: %w", err)
+	}
+	if size < 1 {
+		return 0, 0, fmt.Errorf("invalid batch size %q: %w", command.Batch, errNoSprouts)
+	}
+	if command.MaxFail != "" {
+		if maxFail, err = parseBatchCount(command.MaxFail, total); err != nil {
+			return 0, 0, fmt.Errorf("invalid max-fail: %w", err)
+		}
+	}
+	return size, maxFail, nil
+}
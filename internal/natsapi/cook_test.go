@@ -0,0 +1,53 @@
+package natsapi
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	apitypes "github.com/gogrlx/grlx/v2/internal/api/types"
+	"github.com/gogrlx/grlx/v2/internal/cook"
+	"github.com/gogrlx/grlx/v2/internal/rbac"
+)
+
+func TestAssignTopRecipes(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(cook.RecipeDirEnvVar, dir)
+	top := []byte("web:\n  cohort: web\n  recipes: [nginx]\nbase:\n  sprouts: 'db-.*'\n  recipes: [base]\n")
+
+	if _, err := assignTopRecipes(apitypes.CmdCook{Top: true}, []string{"db-01"}); !errors.Is(err, cook.ErrNoTopFile) {
+		t.Errorf("expected %v, got %v", cook.ErrNoTopFile, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "top.grlx"), top, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	old := cohortRegistry
+	defer func() { cohortRegistry = old }()
+	cohortRegistry = rbac.NewRegistry()
+	if err := cohortRegistry.Register(&rbac.Cohort{Name: "web", Type: rbac.CohortTypeStatic, Members: []string{"web-01"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := assignTopRecipes(apitypes.CmdCook{Top: true}, []string{"web-01", "db-01", "cache-01"})
+	if err != nil {
+		t.Fatalf("assignTopRecipes: %v", err)
+	}
+	if r := got["web-01"].Recipes; len(r) != 1 || r[0] != "nginx" {
+		t.Errorf("expected web-01 to get nginx, got %v", r)
+	}
+	if r := got["db-01"].Recipes; len(r) != 1 || r[0] != "base" {
+		t.Errorf("expected db-01 to get base, got %v", r)
+	}
+	if a, ok := got["cache-01"]; !ok || len(a.Entries) != 0 {
+		t.Errorf("expected cache-01 to be reported with no entries, got %+v", a)
+	}
+
+	if _, err = assignTopRecipes(apitypes.CmdCook{Top: true}, []string{"cache-01"}); err == nil {
+		t.Error("expected an error when no entries match")
+	}
+	if _, err = assignTopRecipes(apitypes.CmdCook{Top: true, Recipe: "nginx"}, []string{"web-01"}); err == nil {
+		t.Error("expected an error when a recipe is named as well")
+	}
+}
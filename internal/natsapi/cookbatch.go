@@ -0,0 +1,304 @@
+package natsapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+
+	"github.com/gogrlx/grlx/v2/internal/audit"
+	"github.com/gogrlx/grlx/v2/internal/cook"
+	log "github.com/gogrlx/grlx/v2/internal/log"
+)
+
+// batchQueueAllowance is added to a sprout's recipe timeout when a rolling
+// cook waits for it, for the time the job may spend queued behind others.
+var batchQueueAllowance = 5 * time.Minute
+
+// batchSproutTimeout bounds how long a rolling cook waits for a sprout
+// cooking an envelope with recipeTimeout before counting it as failed.
+// A zero recipeTimeout means the sprout's DefaultCookTimeout.
+func batchSproutTimeout(recipeTimeout time.Duration) time.Duration {
+	if recipeTimeout <= 0 {
+		recipeTimeout = cook.DefaultCookTimeout
+	}
+	return recipeTimeout + batchQueueAllowance
+}
+
+var errNoSprouts = errors.New("must be at least one sprout")
+
+// parseBatchCount converts a batch or max-fail value, either a number of
+// sprouts or a percentage, into a number of sprouts out of total.
+// Percentages round up, so 10% of five sprouts is one.
+func parseBatchCount(spec string, total int) (int, error) {
+	if pct, ok := strings.CutSuffix(spec, "%"); ok {
+		p, err := strconv.ParseFloat(pct, 64)
+		if err != nil || p < 0 || p > 100 {
+			return 0, fmt.Errorf("%q is not a percentage between 0 and 100", spec)
+		}
+		return int(math.Ceil(p * float64(total) / 100)), nil
+	}
+	n, err := strconv.Atoi(spec)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a number of sprouts or a percentage", spec)
+	}
+	return n, nil
+}
+
+// planBatches splits sproutIDs into batches of size, keeping their order.
+func planBatches(sproutIDs []string, size int) [][]string {
+	var batches [][]string
+	for len(sproutIDs) > size {
+		batches = append(batches, sproutIDs[:size])
+		sproutIDs = sproutIDs[size:]
+	}
+	if len(sproutIDs) > 0 {
+		batches = append(batches, sproutIDs)
+	}
+	return batches
+}
+
+// batchProgress is recorded in the audit log after each batch of a
+// rolling cook.
+type batchProgress struct {
+	JID         string   `json:"jid"`
+	Recipes     []string `json:"recipes"`
+	Batch       int      `json:"batch"`
+	Batches     int      `json:"batches"`
+	Failed      []string `json:"failed,omitempty"`
+	TotalFailed int      `json:"total_failed"`
+	MaxFail     int      `json:"max_fail"`
+	Aborted     bool     `json:"aborted,omitempty"`
+}
+
+// cookInvoker identifies the user who started a cook, for audit entries
+// written after the request itself has been logged.
+type cookInvoker struct {
+	pubkey   string
+	roleName string
+	username string
+}
+
+// runBatches cooks jid on each batch in turn, sending a batch only once
+// every sprout in the previous one has finished and wait has passed.
+// When more than maxFail sprouts have failed, the remaining batches are
+// cancelled without being sent. A negative maxFail never stops the cook.
+// send returns the timeout of the envelope it sent, which bounds the wait
+// for that sprout. recipes are recorded with each batch's progress.
+func runBatches(jid string, recipes []cook.RecipeName, batches [][]string, wait time.Duration, maxFail int, invoker cookInvoker, send func(sproutID string) (time.Duration, error)) {
+	var all []string
+	for _, batch := range batches {
+		all = append(all, batch...)
+	}
+	names := make([]string, len(recipes))
+	for i, recipe := range recipes {
+		names[i] = string(recipe)
+	}
+	watcher, err := watchBatchJob(jid, all)
+	if err != nil {
+		log.Errorf("cannot follow rolling cook %s: %v", jid, err)
+		for _, sproutID := range all {
+			cook.CancelUndelivered(sproutID, jid, "rolling cook could not follow job progress")
+		}
+		return
+	}
+	defer watcher.stop()
+
+	failed := 0
+	for i, batch := range batches {
+		if i > 0 && wait > 0 {
+			time.Sleep(wait)
+		}
+		log.Noticef("rolling cook %s: starting batch %d of %d (%s)", jid, i+1, len(batches), strings.Join(batch, ", "))
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var timeout time.Duration
+		wg.Add(len(batch))
+		for _, sproutID := range batch {
+			go func(sproutID string) {
+				defer wg.Done()
+				// The marker goes out first so that the job's progress
+				// shows the batch before any of the sprout's steps.
+				cook.MarkBatchStarted(sproutID, jid, i+1, len(batches))
+				recipeTimeout, sendErr := send(sproutID)
+				if sendErr != nil {
+					log.Errorf("error cooking recipe for %s: %v", sproutID, sendErr)
+					watcher.finish(sproutID, true)
+					return
+				}
+				mu.Lock()
+				timeout = max(timeout, batchSproutTimeout(recipeTimeout))
+				mu.Unlock()
+			}(sproutID)
+		}
+		wg.Wait()
+
+		batchFailed := watcher.wait(batch, timeout)
+		failed += len(batchFailed)
+		progress := batchProgress{
+			JID:         jid,
+			Recipes:     names,
+			Batch:       i + 1,
+			Batches:     len(batches),
+			Failed:      batchFailed,
+			TotalFailed: failed,
+			MaxFail:     maxFail,
+			Aborted:     maxFail >= 0 && failed > maxFail && i < len(batches)-1,
+		}
+		logBatch(invoker, batch, progress)
+		if !progress.Aborted {
+			continue
+		}
+		reason := fmt.Sprintf("rolling cook stopped after batch %d of %d: %d sprouts failed, more than the %d allowed", i+1, len(batches), failed, maxFail)
+		log.Noticef("rolling cook %s: %s", jid, reason)
+		for _, rest := range batches[i+1:] {
+			for _, sproutID := range rest {
+				cook.CancelUndelivered(sproutID, jid, reason)
+			}
+		}
+		return
+	}
+}
+
+// logBatch records the outcome of one batch of a rolling cook.
+func logBatch(invoker cookInvoker, batch []string, progress batchProgress) {
+	logger := audit.Global()
+	if logger == nil {
+		return
+	}
+	params, _ := json.Marshal(progress)
+	entry := audit.Entry{
+		Timestamp:  time.Now().UTC(),
+		Username:   invoker.username,
+		Pubkey:     invoker.pubkey,
+		RoleName:   invoker.roleName,
+		Action:     "cook.batch",
+		Targets:    batch,
+		Parameters: params,
+		Success:    len(progress.Failed) == 0,
+	}
+	if progress.Aborted {
+		entry.Error = fmt.Sprintf("%d sprouts failed, more than the %d allowed", progress.TotalFailed, progress.MaxFail)
+	}
+	if err := logger.Log(entry); err != nil {
+		log.Errorf("cook audit: failed to log cook.batch: %v", err)
+	}
+}
+
+// batchWatcher follows the completions of a rolling cook, noting when
+// each sprout finishes and whether it failed.
+type batchWatcher struct {
+	jid string
+	sub *nats.Subscription
+
+	mu       sync.Mutex
+	failed   map[string]bool
+	done     map[string]chan struct{}
+	finished map[string]bool
+}
+
+func watchBatchJob(jid string, sproutIDs []string) (*batchWatcher, error) {
+	if natsConn == nil {
+		return nil, fmt.Errorf("NATS connection not available")
+	}
+	w := &batchWatcher{
+		jid:      jid,
+		failed:   map[string]bool{},
+		done:     map[string]chan struct{}{},
+		finished: map[string]bool{},
+	}
+	for _, sproutID := range sproutIDs {
+		w.done[sproutID] = make(chan struct{})
+	}
+	sub, err := natsConn.Subscribe("grlx.cook.*."+jid, w.handle)
+	if err != nil {
+		return nil, err
+	}
+	w.sub = sub
+	return w, natsConn.Flush()
+}
+
+func (w *batchWatcher) handle(m *nats.Msg) {
+	parts := strings.Split(m.Subject, ".")
+	if len(parts) != 4 {
+		return
+	}
+	sproutID := parts[2]
+	var step cook.StepCompletion
+	if err := json.Unmarshal(m.Data, &step); err != nil {
+		return
+	}
+	switch string(step.ID) {
+	case "completed-" + w.jid, "deferred-" + w.jid:
+		// A sprout that is offline will get the job later; it neither
+		// holds up the batch nor counts as a failure.
+		w.finish(sproutID, false)
+	case "timeout-" + w.jid, "cancelled-" + w.jid:
+		w.finish(sproutID, true)
+	default:
+		if step.CompletionStatus == cook.StepFailed || step.CompletionStatus == cook.StepTimedOut {
+			w.mu.Lock()
+			w.failed[sproutID] = true
+			w.mu.Unlock()
+		}
+	}
+}
+
+// finish marks sproutID as done with the job, and as failed if failed is
+// set.
+func (w *batchWatcher) finish(sproutID string, failed bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	done, ok := w.done[sproutID]
+	if !ok || w.finished[sproutID] {
+		return
+	}
+	if failed {
+		w.failed[sproutID] = true
+	}
+	w.finished[sproutID] = true
+	close(done)
+}
+
+// wait blocks until every sprout in batch has finished or timeout has
+// passed, and returns the sprouts that failed or did not finish in time.
+func (w *batchWatcher) wait(batch []string, timeout time.Duration) []string {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	expired := false
+	var failed []string
+	for _, sproutID := range batch {
+		w.mu.Lock()
+		done := w.done[sproutID]
+		w.mu.Unlock()
+		if !expired {
+			select {
+			case <-done:
+			case <-timer.C:
+				expired = true
+			}
+		}
+		select {
+		case <-done:
+		default:
+			log.Errorf("rolling cook %s: %s did not finish within %v", w.jid, sproutID, timeout)
+			w.finish(sproutID, true)
+		}
+		w.mu.Lock()
+		if w.failed[sproutID] {
+			failed = append(failed, sproutID)
+		}
+		w.mu.Unlock()
+	}
+	return failed
+}
+
+func (w *batchWatcher) stop() {
+	w.sub.Unsubscribe()
+}
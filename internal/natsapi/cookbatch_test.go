@@ -0,0 +1,239 @@
+package natsapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	apitypes "github.com/gogrlx/grlx/v2/internal/api/types"
+	"github.com/gogrlx/grlx/v2/internal/cook"
+)
+
+func TestParseBatchCount(t *testing.T) {
+	tests := []struct {
+		spec    string
+		total   int
+		want    int
+		wantErr bool
+	}{
+		{"5", 20, 5, false},
+		{"0", 20, 0, false},
+		{"20%", 20, 4, false},
+		{"10%", 5, 1, false},
+		{"100%", 7, 7, false},
+		{"0%", 7, 0, false},
+		{"", 20, 0, true},
+		{"-1", 20, 0, true},
+		{"five", 20, 0, true},
+		{"150%", 20, 0, true},
+		{"x%", 20, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseBatchCount(tt.spec, tt.total)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseBatchCount(%q, %d) error = %v, wantErr %v", tt.spec, tt.total, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseBatchCount(%q, %d) = %d, want %d", tt.spec, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestPlanBatches(t *testing.T) {
+	ids := []string{"a", "b", "c", "d", "e"}
+	tests := []struct {
+		size int
+		want [][]string
+	}{
+		{1, [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}},
+		{2, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}},
+		{5, [][]string{{"a", "b", "c", "d", "e"}}},
+		{10, [][]string{{"a", "b", "c", "d", "e"}}},
+	}
+	for _, tt := range tests {
+		if got := planBatches(ids, tt.size); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("planBatches(size %d) = %v, want %v", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestBatchLimits(t *testing.T) {
+	tests := []struct {
+		name        string
+		command     apitypes.CmdCook
+		wantSize    int
+		wantMaxFail int
+		wantErr     bool
+	}{
+		{"unbatched", apitypes.CmdCook{}, 0, -1, false},
+		{"batch without limit", apitypes.CmdCook{Batch: "3"}, 3, -1, false},
+		{"percentages", apitypes.CmdCook{Batch: "25%", MaxFail: "10%"}, 3, 1, false},
+		{"zero failures allowed", apitypes.CmdCook{Batch: "2", MaxFail: "0"}, 2, 0, false},
+		{"empty batch", apitypes.CmdCook{Batch: "0"}, 0, 0, true},
+		{"invalid batch", apitypes.CmdCook{Batch: "many"}, 0, 0, true},
+		{"invalid max-fail", apitypes.CmdCook{Batch: "2", MaxFail: "some"}, 0, 0, true},
+		{"max-fail without batch", apitypes.CmdCook{MaxFail: "1"}, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size, maxFail, err := batchLimits(tt.command, 10)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("batchLimits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (size != tt.wantSize || maxFail != tt.wantMaxFail) {
+				t.Errorf("batchLimits() = %d, %d, want %d, %d", size, maxFail, tt.wantSize, tt.wantMaxFail)
+			}
+		})
+	}
+}
+
+// fakeBatchSprouts stands in for sprouts in a rolling cook: each send
+// publishes a finished job, failing on the sprouts in fail. It returns
+// the sprouts sent to, in order, and the cancelled markers seen.
+func fakeBatchSprouts(t *testing.T, nc *nats.Conn, jid string, fail map[string]bool) (send func(string) (time.Duration, error), sent func() []string, cancelled <-chan string) {
+	t.Helper()
+	var mu sync.Mutex
+	var order []string
+	cancelledCh := make(chan string, 16)
+	sub, err := nc.Subscribe("grlx.cook.*."+jid, func(m *nats.Msg) {
+		var step cook.StepCompletion
+		json.Unmarshal(m.Data, &step)
+		if string(step.ID) == "cancelled-"+jid {
+			cancelledCh <- m.Subject
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sub.Unsubscribe() })
+
+	publish := func(sproutID string, step cook.StepCompletion) {
+		b, _ := json.Marshal(step)
+		nc.Publish("grlx.cook."+sproutID+"."+jid, b)
+	}
+	send = func(sproutID string) (time.Duration, error) {
+		mu.Lock()
+		order = append(order, sproutID)
+		mu.Unlock()
+		status := cook.StepCompleted
+		if fail[sproutID] {
+			status = cook.StepFailed
+		}
+		publish(sproutID, cook.StepCompletion{ID: "step-1", CompletionStatus: status})
+		publish(sproutID, cook.StepCompletion{ID: cook.StepID("completed-" + jid), CompletionStatus: cook.StepCompleted})
+		return 0, nil
+	}
+	sent = func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), order...)
+	}
+	return send, sent, cancelledCh
+}
+
+func TestRunBatches(t *testing.T) {
+	nc, cleanup := startEmbeddedNATS(t)
+	defer cleanup()
+	old := natsConn
+	natsConn = nc
+	defer func() { natsConn = old }()
+	cook.RegisterNatsConn(nc)
+	defer cook.RegisterNatsConn(nil)
+
+	sprouts := []string{"web-01", "web-02", "web-03", "web-04", "web-05"}
+
+	t.Run("all batches run within the limit", func(t *testing.T) {
+		jid := cook.GenerateJobID()
+		send, sent, _ := fakeBatchSprouts(t, nc, jid, map[string]bool{"web-02": true})
+		first := map[string]string{}
+		var firstMu sync.Mutex
+		sub, err := nc.Subscribe("grlx.cook.*."+jid, func(m *nats.Msg) {
+			var step cook.StepCompletion
+			json.Unmarshal(m.Data, &step)
+			firstMu.Lock()
+			if _, ok := first[m.Subject]; !ok {
+				first[m.Subject] = string(step.ID)
+			}
+			firstMu.Unlock()
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sub.Unsubscribe()
+		runBatches(jid, []cook.RecipeName{"deploy"}, planBatches(sprouts, 2), 0, 1, cookInvoker{}, send)
+		nc.Flush()
+		firstMu.Lock()
+		for _, id := range sprouts {
+			if got := first["grlx.cook."+id+"."+jid]; got != "batch-"+jid {
+				t.Errorf("expected %s's batch marker before its steps, got %q first", id, got)
+			}
+		}
+		firstMu.Unlock()
+		if got := sent(); len(got) != len(sprouts) {
+			t.Errorf("expected every sprout to be cooked, got %v", got)
+		}
+	})
+
+	t.Run("failures past the limit stop the rollout", func(t *testing.T) {
+		jid := cook.GenerateJobID()
+		send, sent, cancelled := fakeBatchSprouts(t, nc, jid, map[string]bool{"web-01": true, "web-02": true})
+		runBatches(jid, []cook.RecipeName{"deploy"}, planBatches(sprouts, 2), 0, 1, cookInvoker{}, send)
+		if got := sent(); !reflect.DeepEqual(got, []string{"web-01", "web-02"}) && !reflect.DeepEqual(got, []string{"web-02", "web-01"}) {
+			t.Errorf("expected only the first batch to be cooked, got %v", got)
+		}
+		seen := map[string]bool{}
+		for range sprouts[2:] {
+			select {
+			case subject := <-cancelled:
+				seen[subject] = true
+			case <-time.After(2 * time.Second):
+				t.Fatalf("expected the unsent sprouts to be cancelled, got %v", seen)
+			}
+		}
+		for _, id := range sprouts[2:] {
+			if !seen["grlx.cook."+id+"."+jid] {
+				t.Errorf("expected %s to be cancelled, got %v", id, seen)
+			}
+		}
+	})
+
+	t.Run("sprouts that never finish count as failed", func(t *testing.T) {
+		orig := batchQueueAllowance
+		batchQueueAllowance = 50 * time.Millisecond
+		defer func() { batchQueueAllowance = orig }()
+
+		jid := cook.GenerateJobID()
+		var calls []string
+		// The wait follows the envelope's timeout rather than the
+		// sprout's default.
+		silent := func(sproutID string) (time.Duration, error) {
+			calls = append(calls, sproutID)
+			return 50 * time.Millisecond, nil
+		}
+		start := time.Now()
+		runBatches(jid, []cook.RecipeName{"deploy"}, planBatches(sprouts[:2], 1), 0, 0, cookInvoker{}, silent)
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("expected the wait to follow the envelope timeout, took %v", elapsed)
+		}
+		if !reflect.DeepEqual(calls, []string{"web-01"}) {
+			t.Errorf("expected the rollout to stop after web-01 timed out, got %v", calls)
+		}
+	})
+}
+
+func TestTopRecipes(t *testing.T) {
+	assignments := map[string]cook.TopAssignment{
+		"web-01": {Recipes: []cook.RecipeName{"nginx", "base"}},
+		"web-02": {Recipes: []cook.RecipeName{"base"}},
+		"db-01":  {Recipes: []cook.RecipeName{"postgres", "base"}},
+	}
+	got := topRecipes(assignments, []string{"web-01", "web-02"})
+	if want := []cook.RecipeName{"base", "nginx"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("topRecipes() = %v, want %v", got, want)
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/nats-io/nkeys"
 
+	apitypes "github.com/gogrlx/grlx/v2/internal/api/types"
 	"github.com/gogrlx/grlx/v2/internal/audit"
 	intauth "github.com/gogrlx/grlx/v2/internal/auth"
 	"github.com/gogrlx/grlx/v2/internal/config"
@@ -837,18 +838,6 @@ func TestSubscribeNilConn(t *testing.T) {
 	}
 }
 
-// --- probeSprout with nil conn ---
-
-func TestProbeSproutNilConn(t *testing.T) {
-	old := natsConn
-	natsConn = nil
-	defer func() { natsConn = old }()
-
-	if probeSprout("any-sprout") {
-		t.Error("expected false for nil NATS conn")
-	}
-}
-
 // --- Cohorts handler tests with invalid JSON ---
 
 func TestHandleCohortsGetInvalidJSON(t *testing.T) {
@@ -950,8 +939,11 @@ func setupAuthWithToken(t *testing.T, roleName string, rules []rbac.Rule) (strin
 	if err != nil {
 		t.Fatal(err)
 	}
+	// The token is a legacy one, so open the migration window for it.
+	jety.Set("legacy_tokens_until", "9999-12-31")
 
 	cleanup := func() {
+		jety.Set("legacy_tokens_until", "")
 		intauth.SetPolicy(nil, nil, nil)
 		jety.Set("privkey", "")
 		jety.Set("pubkeys", nil)
@@ -1050,6 +1042,61 @@ func TestAuthMiddleware_ValidTokenScopeCheck(t *testing.T) {
 	}
 }
 
+func TestAuthMiddleware_RequestBoundToken(t *testing.T) {
+	legacy, cleanup := setupAuthWithToken(t, "operator", []rbac.Rule{
+		{Action: rbac.ActionView, Scope: "*"},
+	})
+	defer cleanup()
+
+	inner := func(params json.RawMessage) (any, error) { return "ok", nil }
+	withToken := func(method string, body map[string]any) json.RawMessage {
+		t.Helper()
+		b, _ := json.Marshal(body)
+		token, err := intauth.NewRequestToken(method, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body["token"] = token
+		b, _ = json.Marshal(body)
+		return b
+	}
+	tamper := func(params json.RawMessage) json.RawMessage {
+		var body map[string]any
+		json.Unmarshal(params, &body)
+		body["limit"] = 1000
+		b, _ := json.Marshal(body)
+		return b
+	}
+
+	wrapped := authMiddleware(MethodJobsList, inner)
+	params := withToken(MethodJobsList, map[string]any{"limit": 10})
+	if _, err := wrapped(params); err != nil {
+		t.Fatalf("expected a bound token to be accepted, got %v", err)
+	}
+	if _, err := wrapped(params); err != rbac.ErrAccessDenied {
+		t.Errorf("expected a replayed token to be denied, got %v", err)
+	}
+	if _, err := wrapped(withToken(MethodSproutsList, map[string]any{"limit": 10})); err != rbac.ErrAccessDenied {
+		t.Errorf("expected a token for another method to be denied, got %v", err)
+	}
+	if _, err := wrapped(tamper(withToken(MethodJobsList, map[string]any{"limit": 10}))); err != rbac.ErrAccessDenied {
+		t.Errorf("expected a token for other params to be denied, got %v", err)
+	}
+
+	legacyParams, _ := json.Marshal(map[string]string{"token": legacy})
+	if _, err := wrapped(legacyParams); err != nil {
+		t.Errorf("expected legacy tokens to be accepted before the deadline, got %v", err)
+	}
+	jety.Set("legacy_tokens_until", "2000-01-01")
+	if _, err := wrapped(legacyParams); err != rbac.ErrAccessDenied {
+		t.Errorf("expected legacy tokens to be denied after the deadline, got %v", err)
+	}
+	jety.Set("legacy_tokens_until", "")
+	if _, err := wrapped(legacyParams); err != rbac.ErrAccessDenied {
+		t.Errorf("expected legacy tokens to be denied without a deadline, got %v", err)
+	}
+}
+
 // --- handleAuthExplain with valid token ---
 
 func TestHandleAuthExplainWithToken(t *testing.T) {
@@ -1072,6 +1119,22 @@ func TestHandleAuthExplainWithToken(t *testing.T) {
 	if resp["role"] != "operator" {
 		t.Errorf("role = %v, want operator", resp["role"])
 	}
+	if resp["tokenFormat"] != "legacy" {
+		t.Errorf("tokenFormat = %v, want legacy", resp["tokenFormat"])
+	}
+
+	bound, err := intauth.NewRequestToken(MethodAuthExplain, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, _ = json.Marshal(map[string]string{"token": bound})
+	result, err = handleAuthExplain(params)
+	if err != nil {
+		t.Fatalf("handleAuthExplain: %v", err)
+	}
+	if got := result.(apitypes.ExplainResponse).TokenFormat; got != "request-bound" {
+		t.Errorf("tokenFormat = %v, want request-bound", got)
+	}
 }
 
 // --- handleAuthWhoAmI with valid token ---
@@ -1098,6 +1161,24 @@ func TestHandleAuthWhoAmIWithToken(t *testing.T) {
 	if resp["pubkey"] == "" || resp["pubkey"] == "(dangerously_allow_root)" {
 		t.Errorf("expected real pubkey, got %v", resp["pubkey"])
 	}
+
+	bound, err := intauth.NewRequestToken(MethodAuthWhoAmI, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, _ = json.Marshal(map[string]string{"token": bound})
+	if _, err = handleAuthWhoAmI(params); err != nil {
+		t.Fatalf("handleAuthWhoAmI: %v", err)
+	}
+	if _, err = handleAuthWhoAmI(params); err == nil {
+		t.Error("expected a replayed token to be rejected")
+	}
+
+	jety.Set("legacy_tokens_until", "")
+	params, _ = json.Marshal(map[string]string{"token": token})
+	if _, err = handleAuthWhoAmI(params); err == nil {
+		t.Error("expected a legacy token to be rejected without a deadline")
+	}
 }
 
 // --- handleAuthListUsers with configured users ---
@@ -0,0 +1,385 @@
+package natsapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	intauth "github.com/gogrlx/grlx/v2/internal/auth"
+	"github.com/gogrlx/grlx/v2/internal/cook"
+	"github.com/gogrlx/grlx/v2/internal/drift"
+	log "github.com/gogrlx/grlx/v2/internal/log"
+	"github.com/gogrlx/grlx/v2/internal/pki"
+	"github.com/gogrlx/grlx/v2/internal/rbac"
+	nats "github.com/nats-io/nats.go"
+)
+
+var driftStore *drift.Store
+
+// SetDriftStore assigns the store for drift reports.
+func SetDriftStore(s *drift.Store) {
+	driftStore = s
+}
+
+// driftCheckTimeout bounds how long a drift check waits for a sprout's
+// test cook to finish.
+var driftCheckTimeout = cook.DefaultCookTimeout + 5*time.Minute
+
+var (
+	errNoDriftStore      = errors.New("drift detection is not configured")
+	errDriftCheckRunning = errors.New("a drift check is already running")
+	errNoDriftTargets    = errors.New("no online sprouts get recipes from the top file")
+)
+
+// driftCheck guards against overlapping drift checks.
+var driftCheck struct {
+	sync.Mutex
+	running bool
+}
+
+// StartDriftChecker launches a background goroutine that checks every
+// sprout for drift each interval. Pass a zero or negative interval to
+// disable scheduled checks. Cancel the context to stop the goroutine.
+// The returned channel is closed when the goroutine exits.
+func StartDriftChecker(ctx context.Context, interval time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	if interval <= 0 || driftStore == nil {
+		log.Info("Scheduled drift checks disabled")
+		close(done)
+		return done
+	}
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		log.Infof("Scheduled drift checks started (every %s)", interval)
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info("Scheduled drift checks stopped")
+				return
+			case <-ticker.C:
+				jid, sproutIDs, err := checkDrift(allAcceptedSproutIDs())
+				// Farmers without a top file have nothing to check.
+				if errors.Is(err, errDriftCheckRunning) || errors.Is(err, errNoDriftTargets) || errors.Is(err, cook.ErrNoTopFile) {
+					log.Debugf("Skipping scheduled drift check: %v", err)
+				} else if err != nil {
+					log.Errorf("Scheduled drift check error: %v", err)
+				} else {
+					log.Debugf("Scheduled drift check %s started on %d sprout(s)", jid, len(sproutIDs))
+				}
+			}
+		}
+	}()
+
+	return done
+}
+
+// checkDrift starts a drift check of sproutIDs: each sprout that is
+// online and gets recipes from the top file cooks them in test mode, and
+// its report is recorded once the cook finishes. It returns the job ID of
+// the check and the sprouts being checked.
+func checkDrift(sproutIDs []string) (string, []string, error) {
+	if driftStore == nil {
+		return "", nil, errNoDriftStore
+	}
+	if natsConn == nil {
+		return "", nil, fmt.Errorf("NATS connection not available")
+	}
+	top, err := cook.LoadTopFile()
+	if err != nil {
+		return "", nil, err
+	}
+	var resolve func(string) (map[string]bool, error)
+	if cohortRegistry != nil {
+		allIDs := allAcceptedSproutIDs()
+		resolve = func(name string) (map[string]bool, error) {
+			return cohortRegistry.Resolve(name, allIDs)
+		}
+	}
+	assignments, err := top.Assign(sproutIDs, resolve)
+	if err != nil {
+		return "", nil, err
+	}
+	var checked []string
+	for _, sproutID := range sproutIDs {
+		if len(assignments[sproutID].Recipes) > 0 && !sproutOffline(sproutID) {
+			checked = append(checked, sproutID)
+		}
+	}
+	if len(checked) == 0 {
+		return "", nil, errNoDriftTargets
+	}
+	sort.Strings(checked)
+
+	driftCheck.Lock()
+	if driftCheck.running {
+		driftCheck.Unlock()
+		return "", nil, errDriftCheckRunning
+	}
+	driftCheck.running = true
+	driftCheck.Unlock()
+
+	jid := cook.GenerateJobID()
+	w, err := watchDriftJob(jid, checked)
+	if err != nil {
+		driftCheck.Lock()
+		driftCheck.running = false
+		driftCheck.Unlock()
+		return "", nil, err
+	}
+	go func() {
+		defer func() {
+			driftCheck.Lock()
+			driftCheck.running = false
+			driftCheck.Unlock()
+		}()
+		defer w.stop()
+		started := time.Now()
+		var wg sync.WaitGroup
+		wg.Add(len(checked))
+		for _, sproutID := range checked {
+			go func(sproutID string) {
+				defer wg.Done()
+				opts := []cook.CookOption{cook.WithInvoker(drift.InvokedBy), cook.WithRecipes(assignments[sproutID].Recipes...)}
+				if sendErr := cook.SendCookEvent(sproutID, "", jid, true, opts...); sendErr != nil {
+					log.Errorf("drift check %s: error starting test cook on %s: %v", jid, sproutID, sendErr)
+					w.finish(sproutID, false)
+				}
+			}(sproutID)
+		}
+		wg.Wait()
+		w.wait(driftCheckTimeout)
+		for _, sproutID := range checked {
+			completions, ok := w.result(sproutID)
+			if !ok {
+				continue
+			}
+			r, recErr := driftStore.Record(sproutID, jid, assignments[sproutID].Recipes, started, completions)
+			if recErr != nil {
+				log.Errorf("drift check %s: failed to record the report for %s: %v", jid, sproutID, recErr)
+			}
+			if len(r.Appeared) > 0 {
+				log.Infof("drift check %s: %s drifted in %s", jid, sproutID, strings.Join(r.Appeared, ", "))
+			}
+			if len(r.Resolved) > 0 {
+				log.Infof("drift check %s: %s no longer drifts in %s", jid, sproutID, strings.Join(r.Resolved, ", "))
+			}
+		}
+	}()
+	return jid, checked, nil
+}
+
+// driftWatcher collects the step completions of a drift check's test
+// cooks.
+type driftWatcher struct {
+	jid string
+	sub *nats.Subscription
+
+	mu          sync.Mutex
+	completions map[string][]cook.StepCompletion
+	complete    map[string]bool
+	pending     map[string]bool
+	done        chan struct{}
+}
+
+func watchDriftJob(jid string, sproutIDs []string) (*driftWatcher, error) {
+	w := &driftWatcher{
+		jid:         jid,
+		completions: map[string][]cook.StepCompletion{},
+		complete:    map[string]bool{},
+		pending:     map[string]bool{},
+		done:        make(chan struct{}),
+	}
+	for _, sproutID := range sproutIDs {
+		w.pending[sproutID] = true
+	}
+	sub, err := natsConn.Subscribe("grlx.cook.*."+jid, w.handle)
+	if err != nil {
+		return nil, err
+	}
+	w.sub = sub
+	return w, natsConn.Flush()
+}
+
+func (w *driftWatcher) handle(m *nats.Msg) {
+	parts := strings.Split(m.Subject, ".")
+	if len(parts) != 4 {
+		return
+	}
+	sproutID := parts[2]
+	var step cook.StepCompletion
+	if err := json.Unmarshal(m.Data, &step); err != nil {
+		return
+	}
+	switch id := string(step.ID); {
+	case id == "completed-"+w.jid:
+		w.finish(sproutID, true)
+	case id == "timeout-"+w.jid, id == "cancelled-"+w.jid, id == "deferred-"+w.jid:
+		// An unfinished test cook says nothing about what is left to
+		// change, so the previous report stands.
+		w.finish(sproutID, false)
+	case strings.HasSuffix(id, "-"+w.jid):
+		// Other lifecycle markers, such as start-<jid>, are not steps.
+	default:
+		w.mu.Lock()
+		w.completions[sproutID] = append(w.completions[sproutID], step)
+		w.mu.Unlock()
+	}
+}
+
+// finish marks sproutID as done with the test cook, and as having
+// completed it if complete is set.
+func (w *driftWatcher) finish(sproutID string, complete bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.pending[sproutID] {
+		return
+	}
+	delete(w.pending, sproutID)
+	w.complete[sproutID] = complete
+	if len(w.pending) == 0 {
+		close(w.done)
+	}
+}
+
+// wait blocks until every sprout has finished or timeout has passed.
+func (w *driftWatcher) wait(timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-w.done:
+	case <-timer.C:
+		w.mu.Lock()
+		for sproutID := range w.pending {
+			log.Errorf("drift check %s: %s did not finish within %v", w.jid, sproutID, timeout)
+		}
+		w.mu.Unlock()
+	}
+}
+
+// result returns the step completions of sproutID's test cook, and
+// whether it completed.
+func (w *driftWatcher) result(sproutID string) ([]cook.StepCompletion, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.completions[sproutID], w.complete[sproutID]
+}
+
+func (w *driftWatcher) stop() {
+	w.sub.Unsubscribe()
+}
+
+// DriftSproutParams identifies a sprout whose drift report is wanted.
+type DriftSproutParams struct {
+	SproutID string `json:"sprout_id"`
+}
+
+// DriftCheckParams selects the sprouts of a drift check. No sprout IDs
+// checks every sprout the caller may cook on.
+type DriftCheckParams struct {
+	SproutIDs []string `json:"sprout_ids,omitempty"`
+}
+
+// handleDriftList returns the drift reports of the sprouts the caller may
+// view.
+func handleDriftList(params json.RawMessage) (any, error) {
+	if driftStore == nil {
+		return DriftListResponse{Reports: []drift.Report{}}, nil
+	}
+	reports := driftStore.List()
+	if !intauth.DangerouslyAllowRoot() {
+		var tp tokenParams
+		if len(params) > 0 {
+			json.Unmarshal(params, &tp)
+		}
+		if tp.Token != "" {
+			sproutIDs := make([]string, len(reports))
+			for i, r := range reports {
+				sproutIDs[i] = r.SproutID
+			}
+			allowed := filterSproutsByScope(tp.Token, rbac.ActionView, sproutIDs)
+			if allowed != nil {
+				allowedSet := make(map[string]bool, len(allowed))
+				for _, id := range allowed {
+					allowedSet[id] = true
+				}
+				filtered := make([]drift.Report, 0, len(allowed))
+				for _, r := range reports {
+					if allowedSet[r.SproutID] {
+						filtered = append(filtered, r)
+					}
+				}
+				reports = filtered
+			}
+		}
+	}
+	return DriftListResponse{Reports: reports}, nil
+}
+
+func handleDriftGet(params json.RawMessage) (any, error) {
+	if driftStore == nil {
+		return nil, errNoDriftStore
+	}
+	var p DriftSproutParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if !pki.IsValidSproutID(p.SproutID) {
+		return nil, fmt.Errorf("invalid sprout ID: %s", p.SproutID)
+	}
+	return driftStore.Get(p.SproutID)
+}
+
+// handleDriftCheck starts a drift check of the requested sprouts, or of
+// every sprout the caller may cook on. The check runs in the background;
+// its reports replace the current ones as each sprout finishes.
+func handleDriftCheck(params json.RawMessage) (any, error) {
+	var p DriftCheckParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+	}
+	var tp tokenParams
+	if len(params) > 0 {
+		json.Unmarshal(params, &tp)
+	}
+	sproutIDs := p.SproutIDs
+	if len(sproutIDs) == 0 {
+		sproutIDs = allAcceptedSproutIDs()
+		if !intauth.DangerouslyAllowRoot() {
+			sproutIDs = filterSproutsByScope(tp.Token, rbac.ActionCook, sproutIDs)
+		}
+	} else {
+		for _, sproutID := range sproutIDs {
+			if !pki.IsValidSproutID(sproutID) {
+				return nil, fmt.Errorf("invalid sprout ID: %s", sproutID)
+			}
+			if registered, _ := pki.NKeyExists(sproutID, ""); !registered {
+				return nil, fmt.Errorf("unknown sprout: %s", sproutID)
+			}
+		}
+		if !intauth.DangerouslyAllowRoot() {
+			if err := checkScopedAccess(tp.Token, rbac.ActionCook, sproutIDs); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(sproutIDs) == 0 {
+		return nil, fmt.Errorf("no sprouts to check")
+	}
+	jid, checked, err := checkDrift(sproutIDs)
+	if err != nil {
+		return nil, err
+	}
+	return DriftCheckResponse{JID: jid, SproutIDs: checked}, nil
+}
@@ -0,0 +1,118 @@
+package natsapi
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gogrlx/grlx/v2/internal/cook"
+	"github.com/gogrlx/grlx/v2/internal/drift"
+	"github.com/gogrlx/grlx/v2/internal/rbac"
+)
+
+// useTestDriftStore installs an in-memory drift store.
+func useTestDriftStore(t *testing.T) *drift.Store {
+	t.Helper()
+	s := drift.New("")
+	orig := driftStore
+	t.Cleanup(func() { driftStore = orig })
+	driftStore = s
+	return s
+}
+
+func TestDriftWatcher(t *testing.T) {
+	nc, cleanup := startEmbeddedNATS(t)
+	defer cleanup()
+	old := natsConn
+	natsConn = nc
+	defer func() { natsConn = old }()
+
+	jid := cook.GenerateJobID()
+	w, err := watchDriftJob(jid, []string{"web-01", "web-02", "web-03"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.stop()
+
+	publish := func(sproutID string, step cook.StepCompletion) {
+		b, _ := json.Marshal(step)
+		nc.Publish("grlx.cook."+sproutID+"."+jid, b)
+	}
+	publish("web-01", cook.StepCompletion{ID: cook.StepID("start-" + jid), CompletionStatus: cook.StepInProgress})
+	publish("web-01", cook.StepCompletion{ID: "motd", CompletionStatus: cook.StepCompleted, WouldChange: true})
+	publish("web-01", cook.StepCompletion{ID: "sshd", CompletionStatus: cook.StepCompleted})
+	publish("web-01", cook.StepCompletion{ID: cook.StepID("completed-" + jid), CompletionStatus: cook.StepCompleted})
+	publish("web-02", cook.StepCompletion{ID: "motd", CompletionStatus: cook.StepCompleted, WouldChange: true})
+	publish("web-02", cook.StepCompletion{ID: cook.StepID("timeout-" + jid), CompletionStatus: cook.StepFailed})
+	nc.Flush()
+
+	// web-03 never answers, so the wait runs out.
+	w.wait(500 * time.Millisecond)
+	completions, ok := w.result("web-01")
+	if !ok || len(completions) != 2 || !completions[0].WouldChange {
+		t.Errorf("expected web-01's two steps, got %+v, %v", completions, ok)
+	}
+	if _, ok = w.result("web-02"); ok {
+		t.Error("expected a timed out test cook not to count as complete")
+	}
+	if _, ok = w.result("web-03"); ok {
+		t.Error("expected an unanswered test cook not to count as complete")
+	}
+}
+
+func TestHandleDriftReports(t *testing.T) {
+	token, cleanup := setupAuthWithToken(t, "viewer", []rbac.Rule{
+		{Action: rbac.ActionView, Scope: "*"},
+	})
+	defer cleanup()
+
+	if _, err := handleDriftGet(scheduleParams(t, token, DriftSproutParams{SproutID: "web-01"})); !errors.Is(err, errNoDriftStore) {
+		t.Errorf("expected %v, got %v", errNoDriftStore, err)
+	}
+
+	s := useTestDriftStore(t)
+	at := time.Now()
+	for _, id := range []string{"web-01", "db-01"} {
+		if _, err := s.Record(id, "jid-1", nil, at, []cook.StepCompletion{{ID: "motd", CompletionStatus: cook.StepCompleted, WouldChange: true}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := handleDriftList(scheduleParams(t, token, struct{}{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reports := result.(DriftListResponse).Reports; len(reports) != 2 || reports[0].SproutID != "db-01" {
+		t.Errorf("expected both reports, got %+v", reports)
+	}
+
+	result, err = handleDriftGet(scheduleParams(t, token, DriftSproutParams{SproutID: "web-01"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := result.(drift.Report); !r.Drifted() || r.Steps[0].ID != "motd" {
+		t.Errorf("unexpected report %+v", r)
+	}
+	if _, err = handleDriftGet(scheduleParams(t, token, DriftSproutParams{SproutID: "cache-01"})); !errors.Is(err, drift.ErrNoReport) {
+		t.Errorf("expected %v, got %v", drift.ErrNoReport, err)
+	}
+	if _, err = handleDriftGet(scheduleParams(t, token, DriftSproutParams{SproutID: "bad id"})); err == nil {
+		t.Error("expected an invalid sprout ID to be rejected")
+	}
+}
+
+func TestHandleDriftCheckRejectsBadTargets(t *testing.T) {
+	token, cleanup := setupAuthWithToken(t, "cook-only", []rbac.Rule{
+		{Action: rbac.ActionCook, Scope: "*"},
+	})
+	defer cleanup()
+	useTestDriftStore(t)
+
+	if _, err := handleDriftCheck(scheduleParams(t, token, DriftCheckParams{SproutIDs: []string{"bad id"}})); err == nil {
+		t.Error("expected an invalid sprout ID to be rejected")
+	}
+	if _, err := handleDriftCheck(scheduleParams(t, token, DriftCheckParams{SproutIDs: []string{"unknown-sprout"}})); err == nil {
+		t.Error("expected an unknown sprout to be rejected")
+	}
+}
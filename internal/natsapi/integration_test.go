@@ -2,11 +2,14 @@ package natsapi
 
 import (
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/nats-io/nats-server/v2/server"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
 
 	apitypes "github.com/gogrlx/grlx/v2/internal/api/types"
 	"github.com/gogrlx/grlx/v2/internal/audit"
@@ -271,84 +274,6 @@ func TestSubscribeWithAuditLogging(t *testing.T) {
 	}
 }
 
-// --- probeSprout integration test ---
-
-func TestProbeSproutSuccess(t *testing.T) {
-	nc, cleanup := startEmbeddedNATS(t)
-	defer cleanup()
-
-	old := natsConn
-	natsConn = nc
-	defer func() { natsConn = old }()
-
-	// Subscribe a mock sprout that responds to ping.
-	_, err := nc.Subscribe("grlx.sprouts.test-sprout.test.ping", func(msg *nats.Msg) {
-		resp, _ := json.Marshal(apitypes.PingPong{Pong: true})
-		msg.Respond(resp)
-	})
-	if err != nil {
-		t.Fatalf("subscribe mock sprout: %v", err)
-	}
-	nc.Flush()
-
-	if !probeSprout("test-sprout") {
-		t.Error("expected probeSprout to return true for responding sprout")
-	}
-}
-
-func TestProbeSproutTimeout(t *testing.T) {
-	nc, cleanup := startEmbeddedNATS(t)
-	defer cleanup()
-
-	old := natsConn
-	natsConn = nc
-	defer func() { natsConn = old }()
-
-	// No subscriber for this sprout — should timeout and return false.
-	if probeSprout("nonexistent-sprout") {
-		t.Error("expected probeSprout to return false for unresponsive sprout")
-	}
-}
-
-func TestProbeSproutBadResponse(t *testing.T) {
-	nc, cleanup := startEmbeddedNATS(t)
-	defer cleanup()
-
-	old := natsConn
-	natsConn = nc
-	defer func() { natsConn = old }()
-
-	// Subscribe a mock sprout that returns invalid JSON.
-	nc.Subscribe("grlx.sprouts.bad-json-sprout.test.ping", func(msg *nats.Msg) {
-		msg.Respond([]byte(`{invalid json`))
-	})
-	nc.Flush()
-
-	if probeSprout("bad-json-sprout") {
-		t.Error("expected probeSprout to return false for bad JSON response")
-	}
-}
-
-func TestProbeSproutNoPong(t *testing.T) {
-	nc, cleanup := startEmbeddedNATS(t)
-	defer cleanup()
-
-	old := natsConn
-	natsConn = nc
-	defer func() { natsConn = old }()
-
-	// Responds with valid JSON but Pong=false.
-	nc.Subscribe("grlx.sprouts.no-pong.test.ping", func(msg *nats.Msg) {
-		resp, _ := json.Marshal(apitypes.PingPong{Pong: false})
-		msg.Respond(resp)
-	})
-	nc.Flush()
-
-	if probeSprout("no-pong") {
-		t.Error("expected probeSprout to return false when Pong is false")
-	}
-}
-
 // --- handleCook integration test ---
 
 func TestHandleCookSuccessWithNATS(t *testing.T) {
@@ -782,7 +707,7 @@ func TestSubscribeSessionDoneWithError(t *testing.T) {
 	}
 }
 
-// --- handleSproutsList with NATS (probeSprout path) ---
+// --- handleSproutsList with NATS (presence path) ---
 
 func TestHandleSproutsListWithConnectedSprout(t *testing.T) {
 	nc, cleanup := startEmbeddedNATS(t)
@@ -798,12 +723,8 @@ func TestHandleSproutsListWithConnectedSprout(t *testing.T) {
 	jetyCleanup := setupJetyDangerouslyAllowRoot(t, true)
 	defer jetyCleanup()
 
-	// Subscribe mock sprout to respond to ping.
-	nc.Subscribe("grlx.sprouts.sprout-connected.test.ping", func(msg *nats.Msg) {
-		resp, _ := json.Marshal(apitypes.PingPong{Pong: true})
-		msg.Respond(resp)
-	})
-	nc.Flush()
+	startTestPresence(t, nc)
+	publishHeartbeat(t, nc, "sprout-connected")
 
 	result, err := handleSproutsList(nil)
 	if err != nil {
@@ -816,7 +737,7 @@ func TestHandleSproutsListWithConnectedSprout(t *testing.T) {
 		if s.ID == "sprout-connected" {
 			found = true
 			if !s.Connected {
-				t.Error("expected Connected=true for responding sprout")
+				t.Error("expected Connected=true for a sprout sending heartbeats")
 			}
 			break
 		}
@@ -837,12 +758,8 @@ func TestHandleSproutsGetWithNATS(t *testing.T) {
 	natsConn = nc
 	defer func() { natsConn = old }()
 
-	// Mock sprout responds to ping.
-	nc.Subscribe("grlx.sprouts.sprout-get-int.test.ping", func(msg *nats.Msg) {
-		resp, _ := json.Marshal(apitypes.PingPong{Pong: true})
-		msg.Respond(resp)
-	})
-	nc.Flush()
+	startTestPresence(t, nc)
+	publishHeartbeat(t, nc, "sprout-get-int")
 
 	params, _ := json.Marshal(pki.KeyManager{SproutID: "sprout-get-int"})
 	result, err := handleSproutsGet(params)
@@ -884,10 +801,17 @@ func TestHandleJobsCancelWithNATS(t *testing.T) {
 	}
 	writeTestJob(t, dir, "sprout-cancel-int", "jid-cancel-int", steps)
 
-	// Subscribe to capture the cancel message.
+	// Subscribe to capture the cancel message, checking it is signed
+	// by the farmer for the job.
+	verifier := pki.NewVerifier(useTestFarmerNKey(t))
 	cancelReceived := make(chan bool, 1)
 	nc.Subscribe("grlx.sprouts.sprout-cancel-int.cancel", func(msg *nats.Msg) {
-		cancelReceived <- true
+		signed, err := verifier.Verify(msg.Subject, msg.Data)
+		var req struct {
+			JID string `json:"jid"`
+		}
+		json.Unmarshal(signed.Payload, &req)
+		cancelReceived <- err == nil && signed.JobID == "jid-cancel-int" && req.JID == "jid-cancel-int"
 	})
 	nc.Flush()
 
@@ -907,13 +831,62 @@ func TestHandleJobsCancelWithNATS(t *testing.T) {
 
 	// Verify cancel was published.
 	select {
-	case <-cancelReceived:
-		// OK
+	case ok := <-cancelReceived:
+		if !ok {
+			t.Error("expected a cancel request signed by the farmer for the job")
+		}
 	case <-time.After(2 * time.Second):
 		t.Error("cancel message not received within timeout")
 	}
 }
 
+func TestHandleJobsQueueSigned(t *testing.T) {
+	nc, cleanup := startEmbeddedNATS(t)
+	defer cleanup()
+
+	old := natsConn
+	natsConn = nc
+	defer func() { natsConn = old }()
+
+	verifier := pki.NewVerifier(useTestFarmerNKey(t))
+	nc.Subscribe("grlx.sprouts.sprout-queue.jobs.queue", func(msg *nats.Msg) {
+		if _, err := verifier.Verify(msg.Subject, msg.Data); err != nil {
+			return
+		}
+		b, _ := json.Marshal([]cook.ScheduledJob{{JobID: "jid-queued"}})
+		msg.Respond(b)
+	})
+	nc.Flush()
+
+	params, _ := json.Marshal(JobsForSproutParams{SproutID: "sprout-queue"})
+	result, err := handleJobsQueue(params)
+	if err != nil {
+		t.Fatalf("expected the sprout to accept a signed queue request, got %v", err)
+	}
+	if queue := result.([]cook.ScheduledJob); len(queue) != 1 || queue[0].JobID != "jid-queued" {
+		t.Errorf("unexpected queue %+v", queue)
+	}
+}
+
+// useTestFarmerNKey writes a farmer NKey for signing sprout messages, and
+// returns its public key.
+func useTestFarmerNKey(t *testing.T) string {
+	t.Helper()
+	kp, err := nkeys.CreateUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed, _ := kp.Seed()
+	pubkey, _ := kp.PublicKey()
+	orig := config.NKeyFarmerPrivFile
+	t.Cleanup(func() { config.NKeyFarmerPrivFile = orig })
+	config.NKeyFarmerPrivFile = filepath.Join(t.TempDir(), "farmer.nkey")
+	if err = os.WriteFile(config.NKeyFarmerPrivFile, seed, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return pubkey
+}
+
 // --- Unique tests from PR branch ---
 
 func TestHandleCookTriggerAndSendEvents(t *testing.T) {
@@ -3,18 +3,27 @@ package natsapi
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	intauth "github.com/gogrlx/grlx/v2/internal/auth"
+	"github.com/gogrlx/grlx/v2/internal/cook"
 	"github.com/gogrlx/grlx/v2/internal/jobs"
+	"github.com/gogrlx/grlx/v2/internal/pki"
 	"github.com/gogrlx/grlx/v2/internal/rbac"
 )
 
-var jobStore *jobs.Store
+var jobStore jobs.Backend
 
 func init() {
 	jobStore = jobs.NewStore()
 }
 
+// SetJobStore replaces the store the jobs.* methods read from. The farmer
+// calls it when jobs are kept in JetStream rather than on disk.
+func SetJobStore(store jobs.Backend) {
+	jobStore = store
+}
+
 // JobsListParams holds optional parameters for listing jobs.
 type JobsListParams struct {
 	Limit int    `json:"limit,omitempty"`
@@ -183,10 +192,18 @@ func handleJobsCancel(params json.RawMessage) (any, error) {
 		}
 	}
 
-	if summary.Status != jobs.JobRunning && summary.Status != jobs.JobPending {
+	if summary.Status != jobs.JobRunning && summary.Status != jobs.JobPending && summary.Status != jobs.JobQueued {
 		return nil, fmt.Errorf("job cannot be cancelled: status is %s", summary.Status)
 	}
 
+	if cook.CancelQueuedCook(summary.SproutID, p.JID) {
+		return map[string]string{
+			"jid":     p.JID,
+			"sprout":  summary.SproutID,
+			"message": "queued cook cancelled before delivery",
+		}, nil
+	}
+
 	subject := SproutSubject(summary.SproutID, SproutCancel)
 	cancelMsg, _ := json.Marshal(map[string]string{"jid": p.JID})
 
@@ -194,7 +211,12 @@ func handleJobsCancel(params json.RawMessage) (any, error) {
 		return nil, fmt.Errorf("NATS connection not available")
 	}
 
-	if err := natsConn.Publish(subject, cancelMsg); err != nil {
+	// Sprouts only act on instructions signed by the farmer.
+	signed, err := pki.SignMessage(subject, p.JID, cancelMsg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign cancel: %w", err)
+	}
+	if err := natsConn.Publish(subject, signed); err != nil {
 		return nil, fmt.Errorf("failed to publish cancel: %w", err)
 	}
 
@@ -223,3 +245,35 @@ func handleJobsListForSprout(params json.RawMessage) (any, error) {
 	}
 	return summaries, nil
 }
+
+// sproutQueueTimeout bounds how long jobs.queue waits for a sprout.
+const sproutQueueTimeout = 5 * time.Second
+
+// handleJobsQueue asks a sprout for its scheduler state: the jobs it is
+// cooking and the ones waiting for a free slot.
+func handleJobsQueue(params json.RawMessage) (any, error) {
+	var p JobsForSproutParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	if p.SproutID == "" {
+		return nil, fmt.Errorf("sprout_id is required")
+	}
+	if natsConn == nil {
+		return nil, fmt.Errorf("NATS connection not available")
+	}
+	subject := SproutSubject(p.SproutID, SproutJobsQueue)
+	signed, err := pki.SignMessage(subject, "", []byte("{}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign queue request: %w", err)
+	}
+	msg, err := natsConn.Request(subject, signed, sproutQueueTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("sprout %s did not respond: %w", p.SproutID, err)
+	}
+	queue := []cook.ScheduledJob{}
+	if err := json.Unmarshal(msg.Data, &queue); err != nil {
+		return nil, fmt.Errorf("invalid queue from sprout %s: %w", p.SproutID, err)
+	}
+	return queue, nil
+}
@@ -19,6 +19,7 @@ var natsActionMap = map[string]rbac.Action{
 	MethodJobsList:        rbac.ActionView,
 	MethodJobsGet:         rbac.ActionView,
 	MethodJobsForSprout:   rbac.ActionView,
+	MethodJobsQueue:       rbac.ActionView,
 	MethodPropsGetAll:     rbac.ActionView,
 	MethodPropsGet:        rbac.ActionView,
 	MethodCohortsList:     rbac.ActionView,
@@ -60,6 +61,21 @@ var natsActionMap = map[string]rbac.Action{
 	// Audit
 	MethodAuditDates: rbac.ActionAdmin,
 	MethodAuditQuery: rbac.ActionAdmin,
+
+	// Schedules: creating a command schedule also needs ActionCmd, and
+	// the handlers check scope against the target cohort and ownership.
+	MethodScheduleList:   rbac.ActionView,
+	MethodScheduleGet:    rbac.ActionView,
+	MethodScheduleCreate: rbac.ActionCook,
+	MethodScheduleDelete: rbac.ActionCook,
+	MethodSchedulePause:  rbac.ActionCook,
+	MethodScheduleResume: rbac.ActionCook,
+
+	// Drift: a check cooks in test mode, and the handler checks scope
+	// against the sprouts it checks.
+	MethodDriftList:  rbac.ActionView,
+	MethodDriftGet:   rbac.ActionView,
+	MethodDriftCheck: rbac.ActionCook,
 }
 
 // publicMethods are accessible without a token.
@@ -117,6 +133,12 @@ func authMiddleware(method string, next handler) handler {
 			return nil, rbac.ErrAccessDenied
 		}
 
+		// The token must have been issued for this request.
+		if _, err := intauth.VerifyRequestToken(tp.Token, method, params); err != nil {
+			log.Warnf("rejected auth token for NATS method %s: %v", method, err)
+			return nil, rbac.ErrAccessDenied
+		}
+
 		// Check route-level access (does this role have the required action?).
 		if !intauth.TokenHasAction(tp.Token, requiredAction) {
 			return nil, rbac.ErrAccessDenied
@@ -529,18 +529,6 @@ func TestResolveKeyState(t *testing.T) {
 	}
 }
 
-// --- probeSprout tests ---
-
-func TestProbeSproutNoConn(t *testing.T) {
-	old := natsConn
-	natsConn = nil
-	defer func() { natsConn = old }()
-
-	if probeSprout("any-sprout") {
-		t.Error("expected false when natsConn is nil")
-	}
-}
-
 // --- SetNatsConn test ---
 
 func TestSetNatsConn(t *testing.T) {
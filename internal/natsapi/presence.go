@@ -0,0 +1,199 @@
+package natsapi
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/gogrlx/grlx/v2/internal/config"
+	"github.com/gogrlx/grlx/v2/internal/log"
+	"github.com/gogrlx/grlx/v2/internal/pki"
+)
+
+const (
+	// DefaultHeartbeatInterval is assumed for sprouts that have announced
+	// themselves but not yet said how often they will send heartbeats.
+	DefaultHeartbeatInterval = 30 * time.Second
+
+	// missedHeartbeats is how many heartbeat intervals may pass without
+	// word from a sprout before it is marked offline.
+	missedHeartbeats = 3
+
+	presenceSweepInterval = 5 * time.Second
+)
+
+// SproutPresence is what the farmer has seen of a sprout's connection.
+// ConnectedSince is when the farmer first heard from the sprout in its
+// current session, which is later than the sprout's real connection time
+// if the farmer itself restarted in the meantime.
+type SproutPresence struct {
+	ID             string         `json:"id"`
+	Online         bool           `json:"online"`
+	LastSeen       time.Time      `json:"last_seen"`
+	ConnectedSince time.Time      `json:"connected_since"`
+	Version        config.Version `json:"version"`
+	Reconnects     int            `json:"reconnects"`
+
+	interval time.Duration
+}
+
+// presenceTable tracks every sprout that has sent an announce or a
+// heartbeat since the farmer started.
+type presenceTable struct {
+	mu      sync.Mutex
+	sprouts map[string]*SproutPresence
+}
+
+var presence = newPresenceTable()
+
+func newPresenceTable() *presenceTable {
+	return &presenceTable{sprouts: map[string]*SproutPresence{}}
+}
+
+// seen records word from a sprout at now. announce is true for the
+// message a sprout sends when it starts. It reports whether the sprout
+// has just come online.
+func (p *presenceTable) seen(id string, version config.Version, interval time.Duration, announce bool, now time.Time) (SproutPresence, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sp, known := p.sprouts[id]
+	if !known {
+		sp = &SproutPresence{ID: id, interval: DefaultHeartbeatInterval}
+		p.sprouts[id] = sp
+	}
+	cameOnline := !sp.Online
+	switch {
+	case cameOnline:
+		if known {
+			sp.Reconnects++
+		}
+		sp.Online = true
+		sp.ConnectedSince = now
+	case announce:
+		// The sprout restarted before it was missed.
+		sp.Reconnects++
+		sp.ConnectedSince = now
+	}
+	sp.LastSeen = now
+	if interval > 0 {
+		sp.interval = interval
+	}
+	if version != (config.Version{}) {
+		sp.Version = version
+	}
+	return *sp, cameOnline
+}
+
+// sweep marks offline every sprout that has missed too many heartbeats
+// at now, and returns them.
+func (p *presenceTable) sweep(now time.Time) []SproutPresence {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var offline []SproutPresence
+	for _, sp := range p.sprouts {
+		if sp.Online && now.Sub(sp.LastSeen) > missedHeartbeats*sp.interval {
+			sp.Online = false
+			offline = append(offline, *sp)
+		}
+	}
+	return offline
+}
+
+// get returns the presence of a sprout, if it has been seen.
+func (p *presenceTable) get(id string) (SproutPresence, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sp, ok := p.sprouts[id]
+	if !ok {
+		return SproutPresence{}, false
+	}
+	return *sp, true
+}
+
+// sproutOffline reports whether sproutID has been seen but is now
+// offline. Sprouts the farmer has not heard from yet are not counted as
+// offline, as the farmer may simply have restarted.
+func sproutOffline(sproutID string) bool {
+	sp, ok := presence.get(sproutID)
+	return ok && !sp.Online
+}
+
+// StartPresenceTracker listens for sprout announcements and heartbeats on
+// nc, keeping the presence table that sprouts.list and sprouts.get read.
+// Each time a sprout comes online or is marked offline, its presence is
+// published on PresenceSubject. The tracker stops when ctx is cancelled.
+func StartPresenceTracker(ctx context.Context, nc *nats.Conn) error {
+	_, err := nc.Subscribe(SproutAnnouncePrefix+">", func(m *nats.Msg) {
+		var startup config.Startup
+		if err := json.Unmarshal(m.Data, &startup); err != nil {
+			log.Errorf("invalid sprout announcement on %s: %v", m.Subject, err)
+			return
+		}
+		id := strings.TrimPrefix(m.Subject, SproutAnnouncePrefix)
+		log.Infof("Received a join event from %s (%s)", id, startup.Version.Tag)
+		recordPresence(nc, id, startup.Version, 0, true)
+	})
+	if err != nil {
+		return err
+	}
+	_, err = nc.Subscribe(SproutSubject("*", SproutHeartbeat), func(m *nats.Msg) {
+		var hb config.Heartbeat
+		if err := json.Unmarshal(m.Data, &hb); err != nil {
+			log.Errorf("invalid sprout heartbeat on %s: %v", m.Subject, err)
+			return
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(m.Subject, SproutSubjectPrefix), "."+SproutHeartbeat)
+		recordPresence(nc, id, hb.Version, hb.Interval, false)
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(presenceSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				for _, sp := range presence.sweep(now) {
+					log.Noticef("Sprout %s is offline, last seen %s", sp.ID, sp.LastSeen.Format(time.RFC3339))
+					publishPresence(nc, sp)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// recordPresence updates the presence table for a message from sprout id,
+// publishing an event if the sprout has just come online. The sprout ID
+// is taken from the subject, which NATS permissions restrict each sprout
+// to publishing under its own ID.
+func recordPresence(nc *nats.Conn, id string, version config.Version, interval time.Duration, announce bool) {
+	if !pki.IsValidSproutID(id) {
+		log.Errorf("ignoring presence for invalid sprout ID %q", id)
+		return
+	}
+	sp, cameOnline := presence.seen(id, version, interval, announce, time.Now())
+	if cameOnline {
+		log.Noticef("Sprout %s is online", id)
+		publishPresence(nc, sp)
+	}
+}
+
+func publishPresence(nc *nats.Conn, sp SproutPresence) {
+	b, err := json.Marshal(sp)
+	if err != nil {
+		log.Errorf("failed to marshal presence for %s: %v", sp.ID, err)
+		return
+	}
+	if err = nc.Publish(PresenceSubject(sp.ID), b); err != nil {
+		log.Errorf("failed to publish presence for %s: %v", sp.ID, err)
+	}
+}
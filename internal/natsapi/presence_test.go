@@ -0,0 +1,141 @@
+package natsapi
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/gogrlx/grlx/v2/internal/config"
+)
+
+// startTestPresence gives the test an empty presence table and a tracker
+// listening on nc.
+func startTestPresence(t *testing.T, nc *nats.Conn) {
+	t.Helper()
+	old := presence
+	presence = newPresenceTable()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		presence = old
+	})
+	if err := StartPresenceTracker(ctx, nc); err != nil {
+		t.Fatalf("StartPresenceTracker: %v", err)
+	}
+	nc.Flush()
+}
+
+// publishHeartbeat sends a heartbeat for id and waits for the tracker to
+// record it.
+func publishHeartbeat(t *testing.T, nc *nats.Conn, id string) {
+	t.Helper()
+	b, _ := json.Marshal(config.Heartbeat{SproutID: id, Interval: time.Minute})
+	if err := nc.Publish(SproutSubject(id, SproutHeartbeat), b); err != nil {
+		t.Fatalf("publish heartbeat: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if sp, ok := presence.get(id); ok && sp.Online {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("heartbeat from %s was not recorded", id)
+}
+
+func TestPresenceTable(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	v1 := config.Version{Tag: "v2.0.0"}
+	v2 := config.Version{Tag: "v2.1.0"}
+
+	p := newPresenceTable()
+	sp, cameOnline := p.seen("web-01", v1, 0, true, start)
+	if !cameOnline || !sp.Online || sp.Reconnects != 0 || sp.Version != v1 {
+		t.Fatalf("first announce: got %+v, cameOnline %v", sp, cameOnline)
+	}
+
+	// Heartbeats keep the sprout online without changing when it connected.
+	sp, cameOnline = p.seen("web-01", config.Version{}, 10*time.Second, false, start.Add(10*time.Second))
+	if cameOnline || !sp.ConnectedSince.Equal(start) || sp.Version != v1 {
+		t.Fatalf("heartbeat: got %+v, cameOnline %v", sp, cameOnline)
+	}
+	if !sp.LastSeen.Equal(start.Add(10 * time.Second)) {
+		t.Errorf("LastSeen = %v, want %v", sp.LastSeen, start.Add(10*time.Second))
+	}
+
+	// Two missed heartbeats are tolerated, three are not.
+	if offline := p.sweep(start.Add(40 * time.Second)); len(offline) != 0 {
+		t.Fatalf("expected no sprouts offline yet, got %+v", offline)
+	}
+	offline := p.sweep(start.Add(41 * time.Second))
+	if len(offline) != 1 || offline[0].ID != "web-01" || offline[0].Online {
+		t.Fatalf("expected web-01 to go offline, got %+v", offline)
+	}
+	if offline = p.sweep(start.Add(time.Minute)); len(offline) != 0 {
+		t.Errorf("expected an offline sprout to be reported once, got %+v", offline)
+	}
+
+	// Coming back counts as a reconnect.
+	back := start.Add(2 * time.Minute)
+	sp, cameOnline = p.seen("web-01", config.Version{}, 10*time.Second, false, back)
+	if !cameOnline || sp.Reconnects != 1 || !sp.ConnectedSince.Equal(back) {
+		t.Fatalf("reconnect: got %+v, cameOnline %v", sp, cameOnline)
+	}
+
+	// So does a restart that was quicker than the offline timeout.
+	restart := back.Add(5 * time.Second)
+	sp, cameOnline = p.seen("web-01", v2, 0, true, restart)
+	if cameOnline || sp.Reconnects != 2 || !sp.ConnectedSince.Equal(restart) || sp.Version != v2 {
+		t.Fatalf("restart: got %+v, cameOnline %v", sp, cameOnline)
+	}
+
+	if _, ok := p.get("db-01"); ok {
+		t.Error("expected an unseen sprout to have no presence")
+	}
+}
+
+func TestPresenceEvents(t *testing.T) {
+	nc, cleanup := startEmbeddedNATS(t)
+	defer cleanup()
+	startTestPresence(t, nc)
+
+	events := make(chan SproutPresence, 4)
+	sub, err := nc.Subscribe(PresenceSubject("*"), func(m *nats.Msg) {
+		var sp SproutPresence
+		json.Unmarshal(m.Data, &sp)
+		events <- sp
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+	nc.Flush()
+
+	announce, _ := json.Marshal(config.Startup{SproutID: "web-01", Version: config.Version{Tag: "v2.0.0"}})
+	nc.Publish(SproutAnnouncePrefix+"web-01", announce)
+	select {
+	case sp := <-events:
+		if sp.ID != "web-01" || !sp.Online || sp.Version.Tag != "v2.0.0" {
+			t.Errorf("unexpected online event %+v", sp)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no online event published")
+	}
+
+	// A heartbeat from a sprout that is already online is not an event.
+	publishHeartbeat(t, nc, "web-01")
+	select {
+	case sp := <-events:
+		t.Errorf("unexpected event %+v", sp)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	info := SproutInfo{ID: "web-01"}
+	addPresence(&info)
+	if !info.Connected || info.LastSeen == nil || info.ConnectedSince == nil || info.Version == nil {
+		t.Errorf("expected presence in sprout info, got %+v", info)
+	}
+}
@@ -64,6 +64,7 @@ var routes = map[string]handler{
 	MethodJobsDelete:    handleJobsDelete,
 	MethodJobsCancel:    handleJobsCancel,
 	MethodJobsForSprout: handleJobsListForSprout,
+	MethodJobsQueue:     handleJobsQueue,
 
 	// Props
 	MethodPropsGetAll: handlePropsGetAll,
@@ -96,6 +97,19 @@ var routes = map[string]handler{
 	// Audit
 	MethodAuditDates: handleAuditList,
 	MethodAuditQuery: handleAuditQuery,
+
+	// Schedules
+	MethodScheduleList:   handleScheduleList,
+	MethodScheduleGet:    handleScheduleGet,
+	MethodScheduleCreate: handleScheduleCreate,
+	MethodScheduleDelete: handleScheduleDelete,
+	MethodSchedulePause:  handleSchedulePause,
+	MethodScheduleResume: handleScheduleResume,
+
+	// Drift
+	MethodDriftList:  handleDriftList,
+	MethodDriftGet:   handleDriftGet,
+	MethodDriftCheck: handleDriftCheck,
 }
 
 // Subscribe registers all NATS API handlers on the given connection.
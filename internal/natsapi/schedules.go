@@ -0,0 +1,378 @@
+package natsapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogrlx/grlx/v2/internal/audit"
+	intauth "github.com/gogrlx/grlx/v2/internal/auth"
+	"github.com/gogrlx/grlx/v2/internal/cook"
+	log "github.com/gogrlx/grlx/v2/internal/log"
+	"github.com/gogrlx/grlx/v2/internal/rbac"
+	"github.com/gogrlx/grlx/v2/internal/schedule"
+)
+
+var scheduler *schedule.Scheduler
+
+// SetScheduler assigns the scheduler for the schedule.* handlers.
+func SetScheduler(s *schedule.Scheduler) {
+	scheduler = s
+}
+
+// ScheduleNameParams identifies a schedule by name.
+type ScheduleNameParams struct {
+	Name string `json:"name"`
+}
+
+var errNoScheduler = errors.New("scheduler is not running")
+
+// scheduleAction is the RBAC action a schedule's runs need: cmd for
+// commands and cook for recipes.
+func scheduleAction(e schedule.Entry) rbac.Action {
+	if e.Command != "" {
+		return rbac.ActionCmd
+	}
+	return rbac.ActionCook
+}
+
+// handleScheduleList returns the schedules whose cohort the caller may
+// view.
+func handleScheduleList(params json.RawMessage) (any, error) {
+	if scheduler == nil {
+		return ScheduleListResponse{Schedules: []schedule.Entry{}}, nil
+	}
+	return ScheduleListResponse{Schedules: viewableSchedules(params, scheduler.List())}, nil
+}
+
+func handleScheduleGet(params json.RawMessage) (any, error) {
+	if scheduler == nil {
+		return nil, errNoScheduler
+	}
+	var p ScheduleNameParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	e, err := scheduler.Get(p.Name)
+	if err != nil {
+		return nil, err
+	}
+	if len(viewableSchedules(params, []schedule.Entry{e})) == 0 {
+		return nil, rbac.ErrAccessDenied
+	}
+	return e, nil
+}
+
+// viewableSchedules returns the entries the caller may view: those whose
+// cohort resolves only to sprouts the caller may view. A scoped caller
+// does not see schedules whose cohort cannot be resolved.
+func viewableSchedules(params json.RawMessage, entries []schedule.Entry) []schedule.Entry {
+	if intauth.DangerouslyAllowRoot() {
+		return entries
+	}
+	var tp tokenParams
+	if len(params) > 0 {
+		json.Unmarshal(params, &tp)
+	}
+	if tp.Token == "" {
+		return entries
+	}
+	allIDs := allAcceptedSproutIDs()
+	members := make([]map[string]bool, len(entries))
+	resolved := make([]bool, len(entries))
+	var sproutIDs []string
+	seen := make(map[string]bool)
+	for i, e := range entries {
+		if cohortRegistry == nil {
+			break
+		}
+		cohort, err := cohortRegistry.Resolve(e.Cohort, allIDs)
+		if err != nil {
+			continue
+		}
+		members[i], resolved[i] = cohort, true
+		for id := range cohort {
+			if !seen[id] {
+				seen[id] = true
+				sproutIDs = append(sproutIDs, id)
+			}
+		}
+	}
+	allowedSet := make(map[string]bool, len(sproutIDs))
+	for _, id := range filterSproutsByScope(tp.Token, rbac.ActionView, sproutIDs) {
+		allowedSet[id] = true
+	}
+	filtered := make([]schedule.Entry, 0, len(entries))
+	for i, e := range entries {
+		if !resolved[i] {
+			continue
+		}
+		viewable := true
+		for id := range members[i] {
+			if !allowedSet[id] {
+				viewable = false
+				break
+			}
+		}
+		if viewable {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// handleScheduleCreate adds a schedule owned by the caller. The caller
+// needs the action the schedule's runs will take on every sprout
+// currently in the target cohort; each run is filtered again by the
+// caller's role, so later changes to the role or the cohort are honoured.
+func handleScheduleCreate(params json.RawMessage) (any, error) {
+	if scheduler == nil {
+		return nil, errNoScheduler
+	}
+	var e schedule.Entry
+	if err := json.Unmarshal(params, &e); err != nil {
+		return nil, fmt.Errorf("invalid schedule: %w", err)
+	}
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	if cohortRegistry == nil {
+		return nil, fmt.Errorf("no cohort registry configured")
+	}
+	members, err := cohortRegistry.Resolve(e.Cohort, allAcceptedSproutIDs())
+	if err != nil {
+		return nil, err
+	}
+
+	var tp tokenParams
+	json.Unmarshal(params, &tp)
+	e.CreatedBy = ""
+	if tp.Token != "" {
+		if pk, _, _, whoErr := intauth.WhoAmI(tp.Token); whoErr == nil {
+			e.CreatedBy = pk
+		}
+	}
+	if !intauth.DangerouslyAllowRoot() {
+		action := scheduleAction(e)
+		if e.CreatedBy == "" || !intauth.TokenHasAction(tp.Token, action) {
+			return nil, rbac.ErrAccessDenied
+		}
+		sproutIDs := make([]string, 0, len(members))
+		for id := range members {
+			sproutIDs = append(sproutIDs, id)
+		}
+		if len(sproutIDs) > 0 {
+			if err = checkScopedAccess(tp.Token, action, sproutIDs); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return scheduler.Add(e)
+}
+
+func handleScheduleDelete(params json.RawMessage) (any, error) {
+	name, err := manageableSchedule(params)
+	if err != nil {
+		return nil, err
+	}
+	if err = scheduler.Remove(name); err != nil {
+		return nil, err
+	}
+	return ScheduleDeleteResponse{Name: name, Message: "schedule deleted"}, nil
+}
+
+func handleSchedulePause(params json.RawMessage) (any, error) {
+	name, err := manageableSchedule(params)
+	if err != nil {
+		return nil, err
+	}
+	return scheduler.SetPaused(name, true)
+}
+
+func handleScheduleResume(params json.RawMessage) (any, error) {
+	name, err := manageableSchedule(params)
+	if err != nil {
+		return nil, err
+	}
+	return scheduler.SetPaused(name, false)
+}
+
+// manageableSchedule returns the name of the schedule in params if the
+// caller may change it: schedules can be changed by their creator or by
+// an admin, and config schedules only by an admin.
+func manageableSchedule(params json.RawMessage) (string, error) {
+	if scheduler == nil {
+		return "", errNoScheduler
+	}
+	var p ScheduleNameParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "", fmt.Errorf("invalid request: %w", err)
+	}
+	e, err := scheduler.Get(p.Name)
+	if err != nil {
+		return "", err
+	}
+	if intauth.DangerouslyAllowRoot() {
+		return e.Name, nil
+	}
+	var tp tokenParams
+	json.Unmarshal(params, &tp)
+	if intauth.TokenHasAction(tp.Token, rbac.ActionAdmin) {
+		return e.Name, nil
+	}
+	if pk, _, _, whoErr := intauth.WhoAmI(tp.Token); whoErr == nil && e.CreatedBy != "" && pk == e.CreatedBy {
+		return e.Name, nil
+	}
+	return "", rbac.ErrAccessDenied
+}
+
+// RunSchedule starts one run of e as a job on the sprouts of its cohort,
+// attributed to the schedule. A schedule created through the API only
+// reaches the sprouts its creator may currently cook on, or run commands
+// on; a config schedule reaches the whole cohort. A command runs as a
+// single cmd.run step. Each sprout's job is sent after its own random
+// delay of up to the schedule's splay; sprouts still waiting when ctx is
+// cancelled are not sent the job.
+func RunSchedule(ctx context.Context, e schedule.Entry) (string, error) {
+	if natsConn == nil {
+		return "", fmt.Errorf("NATS connection not available")
+	}
+	if cohortRegistry == nil {
+		return "", fmt.Errorf("no cohort registry configured")
+	}
+	allIDs := allAcceptedSproutIDs()
+	members, err := cohortRegistry.Resolve(e.Cohort, allIDs)
+	if err != nil {
+		return "", err
+	}
+	sproutIDs := make([]string, 0, len(members))
+	for id := range members {
+		sproutIDs = append(sproutIDs, id)
+	}
+	sort.Strings(sproutIDs)
+	if e.CreatedBy != "" {
+		sproutIDs = intauth.PubkeyScopeFilter(e.CreatedBy, scheduleAction(e), sproutIDs, allIDs)
+	}
+	if len(sproutIDs) == 0 {
+		return "", fmt.Errorf("cohort %s has no sprouts the schedule may run on", e.Cohort)
+	}
+
+	jid := cook.GenerateJobID()
+	opts := []cook.CookOption{cook.WithInvoker(e.Invoker())}
+	if e.Command != "" {
+		opts = append(opts, cook.WithSteps(commandStep(e)))
+	}
+	failed := splaySends(ctx, sproutIDs, e.Splay, func(sproutID string) error {
+		sendErr := cook.SendCookEvent(sproutID, cook.RecipeName(e.Recipe), jid, e.Test, opts...)
+		if sendErr != nil {
+			log.Errorf("schedule %s: error starting job on %s: %v", e.Name, sproutID, sendErr)
+		}
+		return sendErr
+	})
+
+	if len(failed) > 0 {
+		err = fmt.Errorf("job %s could not be started on %d of %d sprouts: %s", jid, len(failed), len(sproutIDs), strings.Join(failed, ", "))
+	}
+	logScheduleRun(e, jid, sproutIDs, err)
+	return jid, err
+}
+
+// scheduleSplay picks a sprout's delay within a run splayed by up to
+// limit; replaceable in tests.
+var scheduleSplay = func(limit time.Duration) time.Duration {
+	if limit <= 0 {
+		return 0
+	}
+	return rand.N(limit)
+}
+
+// splaySends calls send for each sprout after its own delay of up to
+// splay, and returns the sorted sprouts whose send failed or that were
+// still waiting when ctx was cancelled.
+func splaySends(ctx context.Context, sproutIDs []string, splay time.Duration, send func(sproutID string) error) []string {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed []string
+	wg.Add(len(sproutIDs))
+	for _, sproutID := range sproutIDs {
+		go func(sproutID string) {
+			defer wg.Done()
+			timer := time.NewTimer(scheduleSplay(splay))
+			defer timer.Stop()
+			var err error
+			select {
+			case <-timer.C:
+				err = send(sproutID)
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+			if err != nil {
+				mu.Lock()
+				failed = append(failed, sproutID)
+				mu.Unlock()
+			}
+		}(sproutID)
+	}
+	wg.Wait()
+	sort.Strings(failed)
+	return failed
+}
+
+// commandStep is the cmd.run step a command schedule cooks. Its ID is the
+// schedule's name, which is short and stays the same from run to run.
+func commandStep(e schedule.Entry) cook.Step {
+	return cook.Step{
+		Ingredient: "cmd",
+		Method:     "run",
+		ID:         cook.StepID(e.Name),
+		Properties: map[string]interface{}{"name": e.Command},
+	}
+}
+
+// scheduleRun is recorded in the audit log for each run of a schedule.
+type scheduleRun struct {
+	Name    string `json:"name"`
+	JID     string `json:"jid"`
+	Cohort  string `json:"cohort"`
+	Recipe  string `json:"recipe,omitempty"`
+	Command string `json:"command,omitempty"`
+	Test    bool   `json:"test,omitempty"`
+}
+
+// logScheduleRun records a run of e in the audit log, attributed to the
+// schedule and carrying its creator's pubkey.
+func logScheduleRun(e schedule.Entry, jid string, targets []string, runErr error) {
+	logger := audit.Global()
+	if logger == nil {
+		return
+	}
+	params, _ := json.Marshal(scheduleRun{
+		Name:    e.Name,
+		JID:     jid,
+		Cohort:  e.Cohort,
+		Recipe:  e.Recipe,
+		Command: e.Command,
+		Test:    e.Test,
+	})
+	entry := audit.Entry{
+		Timestamp:  time.Now().UTC(),
+		Username:   e.Invoker(),
+		Pubkey:     e.CreatedBy,
+		Action:     "schedule.run",
+		Targets:    targets,
+		Parameters: params,
+		Success:    runErr == nil,
+	}
+	if runErr != nil {
+		entry.Error = runErr.Error()
+	}
+	if err := logger.Log(entry); err != nil {
+		log.Errorf("schedule audit: failed to log schedule.run: %v", err)
+	}
+}
@@ -0,0 +1,271 @@
+package natsapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	intauth "github.com/gogrlx/grlx/v2/internal/auth"
+	"github.com/gogrlx/grlx/v2/internal/rbac"
+	"github.com/gogrlx/grlx/v2/internal/schedule"
+)
+
+// useTestScheduler installs an in-memory scheduler and a registry with a
+// static "web" cohort for the schedule handlers.
+func useTestScheduler(t *testing.T) *schedule.Scheduler {
+	t.Helper()
+	s := schedule.New("", func(context.Context, schedule.Entry) (string, error) { return "", nil })
+	origScheduler, origRegistry := scheduler, cohortRegistry
+	t.Cleanup(func() { scheduler, cohortRegistry = origScheduler, origRegistry })
+	scheduler = s
+	reg := rbac.NewRegistry()
+	reg.Register(&rbac.Cohort{Name: "web", Type: rbac.CohortTypeStatic, Members: []string{"web-01"}})
+	cohortRegistry = reg
+	return s
+}
+
+func scheduleParams(t *testing.T, token string, v any) json.RawMessage {
+	t.Helper()
+	b, _ := json.Marshal(v)
+	var m map[string]any
+	json.Unmarshal(b, &m)
+	m["token"] = token
+	b, _ = json.Marshal(m)
+	return b
+}
+
+func TestHandleScheduleCreate(t *testing.T) {
+	token, cleanup := setupAuthWithToken(t, "cook-only", []rbac.Rule{
+		{Action: rbac.ActionCook, Scope: "*"},
+	})
+	defer cleanup()
+	useTestScheduler(t)
+	pk, _, _, err := intauth.WhoAmI(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recipe := schedule.Entry{Name: "nightly", Cron: "0 3 * * *", Cohort: "web", Recipe: "patch", CreatedBy: "USPOOFED"}
+	result, err := handleScheduleCreate(scheduleParams(t, token, recipe))
+	if err != nil {
+		t.Fatalf("handleScheduleCreate: %v", err)
+	}
+	created := result.(schedule.Entry)
+	if created.CreatedBy != pk || created.Source != schedule.SourceAPI {
+		t.Errorf("expected the schedule to belong to the caller, got %+v", created)
+	}
+
+	tests := map[string]struct {
+		entry schedule.Entry
+		want  error
+	}{
+		"command without cmd":  {schedule.Entry{Name: "uptime", Interval: time.Hour, Cohort: "web", Command: "uptime"}, rbac.ErrAccessDenied},
+		"invalid schedule":     {schedule.Entry{Name: "broken", Cohort: "web", Recipe: "patch"}, schedule.ErrInvalidSchedule},
+		"duplicate name":       {recipe, schedule.ErrScheduleExists},
+		"unknown cohort":       {schedule.Entry{Name: "other", Interval: time.Hour, Cohort: "nope", Recipe: "patch"}, nil},
+		"cron and no recipe":   {schedule.Entry{Name: "empty", Cron: "@daily", Cohort: "web"}, schedule.ErrInvalidSchedule},
+		"interval too short":   {schedule.Entry{Name: "fast", Interval: time.Second, Cohort: "web", Recipe: "patch"}, schedule.ErrInvalidSchedule},
+		"cron and an interval": {schedule.Entry{Name: "both", Cron: "@daily", Interval: time.Hour, Cohort: "web", Recipe: "patch"}, schedule.ErrInvalidSchedule},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := handleScheduleCreate(scheduleParams(t, token, tt.entry))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestHandleScheduleManage(t *testing.T) {
+	token, cleanup := setupAuthWithToken(t, "cook-only", []rbac.Rule{
+		{Action: rbac.ActionCook, Scope: "*"},
+	})
+	defer cleanup()
+	s := useTestScheduler(t)
+	pk, _, _, err := intauth.WhoAmI(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Add(schedule.Entry{Name: "mine", Interval: time.Hour, Cohort: "web", Recipe: "patch", CreatedBy: pk}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Add(schedule.Entry{Name: "theirs", Interval: time.Hour, Cohort: "web", Recipe: "patch", CreatedBy: "UOTHERUSER"}); err != nil {
+		t.Fatal(err)
+	}
+
+	name := func(n string) json.RawMessage {
+		return scheduleParams(t, token, ScheduleNameParams{Name: n})
+	}
+	if _, err = handleSchedulePause(name("theirs")); !errors.Is(err, rbac.ErrAccessDenied) {
+		t.Errorf("expected another user's schedule to be off limits, got %v", err)
+	}
+	if _, err = handleScheduleDelete(name("theirs")); !errors.Is(err, rbac.ErrAccessDenied) {
+		t.Errorf("expected another user's schedule to be off limits, got %v", err)
+	}
+	if _, err = handleSchedulePause(name("missing")); !errors.Is(err, schedule.ErrNoSchedule) {
+		t.Errorf("expected %v, got %v", schedule.ErrNoSchedule, err)
+	}
+
+	result, err := handleSchedulePause(name("mine"))
+	if err != nil || !result.(schedule.Entry).Paused {
+		t.Fatalf("expected the schedule to be paused, got %+v, %v", result, err)
+	}
+	result, err = handleScheduleResume(name("mine"))
+	if err != nil || result.(schedule.Entry).Paused {
+		t.Fatalf("expected the schedule to be resumed, got %+v, %v", result, err)
+	}
+	if _, err = handleScheduleDelete(name("mine")); err != nil {
+		t.Fatalf("handleScheduleDelete: %v", err)
+	}
+
+	result, err = handleScheduleList(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := result.(ScheduleListResponse).Schedules; len(list) != 1 || list[0].Name != "theirs" {
+		t.Errorf("expected only the other user's schedule to be left, got %+v", list)
+	}
+}
+
+func TestHandleScheduleListScope(t *testing.T) {
+	token, cleanup := setupAuthWithToken(t, "web-viewer", []rbac.Rule{
+		{Action: rbac.ActionView, Scope: "sprout:web-01"},
+	})
+	defer cleanup()
+	s := useTestScheduler(t)
+	cohortRegistry.Register(&rbac.Cohort{Name: "db", Type: rbac.CohortTypeStatic, Members: []string{"db-01"}})
+	cohortRegistry.Register(&rbac.Cohort{Name: "all", Type: rbac.CohortTypeStatic, Members: []string{"web-01", "db-01"}})
+	for _, e := range []schedule.Entry{
+		{Name: "web-patch", Interval: time.Hour, Cohort: "web", Recipe: "patch"},
+		{Name: "db-backup", Interval: time.Hour, Cohort: "db", Command: "pg_dumpall"},
+		{Name: "everywhere", Interval: time.Hour, Cohort: "all", Recipe: "patch"},
+	} {
+		if _, err := s.Add(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := handleScheduleList(scheduleParams(t, token, struct{}{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := result.(ScheduleListResponse).Schedules; len(list) != 1 || list[0].Name != "web-patch" {
+		t.Errorf("expected only the schedule on viewable sprouts, got %+v", list)
+	}
+
+	name := func(n string) json.RawMessage {
+		return scheduleParams(t, token, ScheduleNameParams{Name: n})
+	}
+	if _, err = handleScheduleGet(name("web-patch")); err != nil {
+		t.Errorf("expected a schedule on viewable sprouts, got %v", err)
+	}
+	for _, n := range []string{"db-backup", "everywhere"} {
+		if _, err = handleScheduleGet(name(n)); !errors.Is(err, rbac.ErrAccessDenied) {
+			t.Errorf("expected %s to be hidden, got %v", n, err)
+		}
+	}
+}
+
+func TestRunScheduleRespectsCreatorScope(t *testing.T) {
+	nc, cleanup := startEmbeddedNATS(t)
+	defer cleanup()
+	old := natsConn
+	natsConn = nc
+	defer func() { natsConn = old }()
+	_, authCleanup := setupAuthWithToken(t, "viewer", []rbac.Rule{
+		{Action: rbac.ActionView, Scope: "*"},
+	})
+	defer authCleanup()
+	useTestScheduler(t)
+
+	// The creator is no longer allowed to cook anywhere.
+	jid, err := RunSchedule(context.Background(), schedule.Entry{Name: "nightly", Cron: "@daily", Cohort: "web", Recipe: "patch", CreatedBy: "UFORMERUSER"})
+	if err == nil || jid != "" {
+		t.Fatalf("expected no job without any permitted sprouts, got %q, %v", jid, err)
+	}
+	if !strings.Contains(err.Error(), "no sprouts") {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if _, err = RunSchedule(context.Background(), schedule.Entry{Name: "nightly", Cron: "@daily", Cohort: "missing", Recipe: "patch"}); err == nil {
+		t.Error("expected an unknown cohort to fail the run")
+	}
+}
+
+func TestSplaySends(t *testing.T) {
+	orig := scheduleSplay
+	defer func() { scheduleSplay = orig }()
+	var limits []time.Duration
+	var mu sync.Mutex
+	delays := map[string]time.Duration{}
+	next := []time.Duration{0, 50 * time.Millisecond}
+	scheduleSplay = func(limit time.Duration) time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		limits = append(limits, limit)
+		d := next[0]
+		next = next[1:]
+		return d
+	}
+
+	start := time.Now()
+	failed := splaySends(context.Background(), []string{"web-01", "web-02"}, time.Minute, func(sproutID string) error {
+		mu.Lock()
+		delays[sproutID] = time.Since(start)
+		mu.Unlock()
+		if sproutID == "web-02" {
+			return errors.New("unreachable")
+		}
+		return nil
+	})
+	if !reflect.DeepEqual(failed, []string{"web-02"}) {
+		t.Errorf("expected only the failed send to be reported, got %v", failed)
+	}
+	if len(limits) != 2 || limits[0] != time.Minute || limits[1] != time.Minute {
+		t.Errorf("expected a splay for each sprout, got %v", limits)
+	}
+	var quick, slow time.Duration
+	for _, d := range delays {
+		if quick == 0 || d < quick {
+			quick = d
+		}
+		slow = max(slow, d)
+	}
+	if len(delays) != 2 || slow < 50*time.Millisecond || quick >= 50*time.Millisecond {
+		t.Errorf("expected each sprout to be sent after its own delay, got %v", delays)
+	}
+
+	// Sprouts still waiting out their splay when the run is cancelled are
+	// not sent the job.
+	scheduleSplay = func(time.Duration) time.Duration { return time.Hour }
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sent := false
+	failed = splaySends(ctx, []string{"web-01"}, time.Hour, func(string) error {
+		sent = true
+		return nil
+	})
+	if sent || !reflect.DeepEqual(failed, []string{"web-01"}) {
+		t.Errorf("expected a cancelled run to send nothing, sent=%v failed=%v", sent, failed)
+	}
+}
+
+func TestCommandStep(t *testing.T) {
+	e := schedule.Entry{Name: "uptime-check", Interval: time.Hour, Cohort: "web", Command: "uptime && df -h /var/lib/something/quite/long"}
+	step := commandStep(e)
+	if step.ID != "uptime-check" {
+		t.Errorf("expected the step to be named after the schedule, got %q", step.ID)
+	}
+	if step.Properties["name"] != e.Command {
+		t.Errorf("expected the command as the step's name, got %v", step.Properties["name"])
+	}
+}
@@ -32,9 +32,13 @@ var scopeExtractors = map[string]scopeExtractor{
 
 	// Jobs scoped to a sprout
 	"jobs.forsprout": extractJobsForSproutID,
+	"jobs.queue":     extractJobsForSproutID,
 
 	// Sprout detail
 	"sprouts.get": extractSproutsGetID,
+
+	// Drift report of a sprout
+	"drift.get": extractSproutsGetID,
 }
 
 // targetedParams extracts sprout IDs from TargetedAction-style params.
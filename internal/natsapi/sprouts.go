@@ -5,20 +5,41 @@ import (
 	"fmt"
 	"time"
 
-	apitypes "github.com/gogrlx/grlx/v2/internal/api/types"
 	intauth "github.com/gogrlx/grlx/v2/internal/auth"
+	"github.com/gogrlx/grlx/v2/internal/config"
 	"github.com/gogrlx/grlx/v2/internal/pki"
 	"github.com/gogrlx/grlx/v2/internal/rbac"
 )
 
-const sproutPingTimeout = 3 * time.Second
-
-// SproutInfo represents a sprout with its key state and connectivity status.
+// SproutInfo represents a sprout with its key state and connectivity
+// status. The presence fields are set once the farmer has heard from the
+// sprout; ConnectedSince only while it is online.
 type SproutInfo struct {
-	ID        string `json:"id"`
-	KeyState  string `json:"key_state"`
-	Connected bool   `json:"connected"`
-	NKey      string `json:"nkey,omitempty"`
+	ID             string          `json:"id"`
+	KeyState       string          `json:"key_state"`
+	Connected      bool            `json:"connected"`
+	NKey           string          `json:"nkey,omitempty"`
+	LastSeen       *time.Time      `json:"last_seen,omitempty"`
+	ConnectedSince *time.Time      `json:"connected_since,omitempty"`
+	Version        *config.Version `json:"version,omitempty"`
+	Reconnects     int             `json:"reconnects,omitempty"`
+}
+
+// addPresence fills in info from the presence table.
+func addPresence(info *SproutInfo) {
+	sp, ok := presence.get(info.ID)
+	if !ok {
+		return
+	}
+	info.Connected = sp.Online
+	info.LastSeen = &sp.LastSeen
+	if sp.Online {
+		info.ConnectedSince = &sp.ConnectedSince
+	}
+	if sp.Version != (config.Version{}) {
+		info.Version = &sp.Version
+	}
+	info.Reconnects = sp.Reconnects
 }
 
 func handleSproutsList(params json.RawMessage) (any, error) {
@@ -52,8 +73,8 @@ func handleSproutsList(params json.RawMessage) (any, error) {
 		if err == nil {
 			info.NKey = nkey
 		}
-		if e.state == "accepted" && natsConn != nil {
-			info.Connected = probeSprout(e.id)
+		if e.state == "accepted" {
+			addPresence(&info)
 		}
 		sprouts = append(sprouts, info)
 	}
@@ -115,34 +136,13 @@ func handleSproutsGet(params json.RawMessage) (any, error) {
 		NKey:     nkey,
 	}
 
-	if keyState == "accepted" && natsConn != nil {
-		info.Connected = probeSprout(km.SproutID)
+	if keyState == "accepted" {
+		addPresence(&info)
 	}
 
 	return info, nil
 }
 
-func probeSprout(sproutID string) bool {
-	if natsConn == nil {
-		return false
-	}
-	topic := SproutSubject(sproutID, SproutTestPing)
-	ping := apitypes.PingPong{Ping: true}
-	data, err := json.Marshal(ping)
-	if err != nil {
-		return false
-	}
-	msg, err := natsConn.Request(topic, data, sproutPingTimeout)
-	if err != nil {
-		return false
-	}
-	var pong apitypes.PingPong
-	if err := json.Unmarshal(msg.Data, &pong); err != nil {
-		return false
-	}
-	return pong.Pong
-}
-
 func resolveKeyState(sproutID string) string {
 	allKeys := pki.ListNKeysByType()
 	for _, km := range allKeys.Accepted.Sprouts {
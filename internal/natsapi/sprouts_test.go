@@ -35,7 +35,6 @@ func TestHandleSproutsList_Empty(t *testing.T) {
 func TestHandleSproutsList_AcceptedSprouts(t *testing.T) {
 	pkiDir := setupNatsAPIPKI(t)
 	setDangerouslyAllowRoot(t, true)
-	// Ensure natsConn is nil so probeSprout is skipped.
 	SetNatsConn(nil)
 
 	nkey := generateTestNKey(t)
@@ -255,16 +254,6 @@ func TestResolveKeyState_Unknown(t *testing.T) {
 	}
 }
 
-// --- probeSprout tests ---
-
-func TestProbeSprout_NilConn(t *testing.T) {
-	SetNatsConn(nil)
-
-	if probeSprout("any-sprout") {
-		t.Error("expected false when natsConn is nil")
-	}
-}
-
 // --- Test helpers ---
 
 func writeTestSproutKey(t *testing.T, pkiDir, state, id, nkey string) {
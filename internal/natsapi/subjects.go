@@ -9,8 +9,11 @@ import (
 	apitypes "github.com/gogrlx/grlx/v2/internal/api/types"
 	"github.com/gogrlx/grlx/v2/internal/audit"
 	"github.com/gogrlx/grlx/v2/internal/config"
+	"github.com/gogrlx/grlx/v2/internal/cook"
+	"github.com/gogrlx/grlx/v2/internal/drift"
 	"github.com/gogrlx/grlx/v2/internal/jobs"
 	"github.com/gogrlx/grlx/v2/internal/pki"
+	"github.com/gogrlx/grlx/v2/internal/schedule"
 	"github.com/gogrlx/grlx/v2/internal/shell"
 )
 
@@ -24,6 +27,14 @@ const SubjectPrefix = "grlx.api."
 // SproutSubjectPrefix is the root prefix for sprout-facing subjects.
 const SproutSubjectPrefix = "grlx.sprouts."
 
+// SproutAnnouncePrefix is the prefix sprouts announce themselves on when
+// they start. Full subject: grlx.sprouts.announce.<sproutID>
+const SproutAnnouncePrefix = "grlx.sprouts.announce."
+
+// PresenceSubjectPrefix is the prefix for sprout online/offline events.
+// Full subject: grlx.presence.<sproutID>
+const PresenceSubjectPrefix = "grlx.presence."
+
 // ──────────────────────────────────────────────
 // API method constants (suffix after SubjectPrefix)
 // ──────────────────────────────────────────────
@@ -62,6 +73,7 @@ const (
 	MethodJobsDelete    = "jobs.delete"
 	MethodJobsCancel    = "jobs.cancel"
 	MethodJobsForSprout = "jobs.forsprout"
+	MethodJobsQueue     = "jobs.queue"
 
 	// Props
 	MethodPropsGetAll = "props.getall"
@@ -94,6 +106,19 @@ const (
 	// Audit
 	MethodAuditDates = "audit.dates"
 	MethodAuditQuery = "audit.query"
+
+	// Schedules
+	MethodScheduleList   = "schedule.list"
+	MethodScheduleGet    = "schedule.get"
+	MethodScheduleCreate = "schedule.create"
+	MethodScheduleDelete = "schedule.delete"
+	MethodSchedulePause  = "schedule.pause"
+	MethodScheduleResume = "schedule.resume"
+
+	// Drift
+	MethodDriftList  = "drift.list"
+	MethodDriftGet   = "drift.get"
+	MethodDriftCheck = "drift.check"
 )
 
 // Subject returns the full NATS subject for a given API method.
@@ -107,6 +132,12 @@ func SproutSubject(sproutID, suffix string) string {
 	return SproutSubjectPrefix + sproutID + "." + suffix
 }
 
+// PresenceSubject returns the subject a sprout's presence events are
+// published on.
+func PresenceSubject(sproutID string) string {
+	return PresenceSubjectPrefix + sproutID
+}
+
 // ──────────────────────────────────────────────
 // Sprout-facing subject suffixes
 // ──────────────────────────────────────────────
@@ -115,9 +146,16 @@ const (
 	// SproutTestPing is the suffix for ping probes to a sprout.
 	SproutTestPing = "test.ping"
 
+	// SproutHeartbeat is the suffix sprouts publish heartbeats on.
+	SproutHeartbeat = "heartbeat"
+
 	// SproutCancel is the suffix for job cancel messages to a sprout.
 	SproutCancel = "cancel"
 
+	// SproutJobsQueue is the suffix for listing a sprout's running and
+	// queued jobs.
+	SproutJobsQueue = "jobs.queue"
+
 	// SproutShellStart is the suffix for starting a shell session on a sprout.
 	SproutShellStart = "shell.start"
 
@@ -175,6 +213,18 @@ type RecipesGetRequest struct {
 // AuditQueryRequest holds query parameters for audit log searches.
 type AuditQueryRequest = audit.QueryParams
 
+// ScheduleCreateRequest is the schedule to create.
+type ScheduleCreateRequest = schedule.Entry
+
+// ScheduleRequest identifies a schedule to get, delete, pause or resume.
+type ScheduleRequest = ScheduleNameParams
+
+// DriftGetRequest identifies the sprout whose drift report is wanted.
+type DriftGetRequest = DriftSproutParams
+
+// DriftCheckRequest selects the sprouts to check for drift.
+type DriftCheckRequest = DriftCheckParams
+
 // ──────────────────────────────────────────────
 // Response types
 // ──────────────────────────────────────────────
@@ -199,6 +249,10 @@ type JobsListResponse = []jobs.JobSummary
 // JobsGetResponse is a single job detail.
 type JobsGetResponse = jobs.JobSummary
 
+// JobsQueueResponse lists a sprout's running jobs, then its queued jobs
+// in the order they will start.
+type JobsQueueResponse = []cook.ScheduledJob
+
 // JobsDeleteResponse confirms a job was deleted from the farmer-side store.
 type JobsDeleteResponse struct {
 	JID     string `json:"jid"`
@@ -264,6 +318,35 @@ type AuditDatesResponse = []string
 // AuditQueryResponse is the result of an audit log query.
 type AuditQueryResponse = audit.QueryResult
 
+// ScheduleListResponse wraps the schedule list.
+type ScheduleListResponse struct {
+	Schedules []schedule.Entry `json:"schedules"`
+}
+
+// ScheduleResponse is a single schedule, as returned by get, create,
+// pause and resume.
+type ScheduleResponse = schedule.Entry
+
+// ScheduleDeleteResponse confirms a schedule was deleted.
+type ScheduleDeleteResponse struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+// DriftListResponse wraps the latest drift report of each sprout.
+type DriftListResponse struct {
+	Reports []drift.Report `json:"reports"`
+}
+
+// DriftGetResponse is the latest drift report of a sprout.
+type DriftGetResponse = drift.Report
+
+// DriftCheckResponse identifies a drift check that was started.
+type DriftCheckResponse struct {
+	JID       string   `json:"jid"`
+	SproutIDs []string `json:"sprout_ids"`
+}
+
 // ──────────────────────────────────────────────
 // Generic response envelope
 // ──────────────────────────────────────────────
@@ -284,12 +367,15 @@ func AllMethods() []string {
 		MethodTestPing,
 		MethodCmdRun,
 		MethodCook,
-		MethodJobsList, MethodJobsGet, MethodJobsDelete, MethodJobsCancel, MethodJobsForSprout,
+		MethodJobsList, MethodJobsGet, MethodJobsDelete, MethodJobsCancel, MethodJobsForSprout, MethodJobsQueue,
 		MethodPropsGetAll, MethodPropsGet, MethodPropsSet, MethodPropsDelete,
 		MethodCohortsList, MethodCohortsGet, MethodCohortsResolve, MethodCohortsRefresh, MethodCohortsValidate,
 		MethodAuthLogin, MethodAuthWhoAmI, MethodAuthListUsers, MethodAuthAddUser, MethodAuthRemoveUser, MethodAuthExplain,
 		MethodShellStart,
 		MethodRecipesList, MethodRecipesGet,
 		MethodAuditDates, MethodAuditQuery,
+		MethodScheduleList, MethodScheduleGet, MethodScheduleCreate,
+		MethodScheduleDelete, MethodSchedulePause, MethodScheduleResume,
+		MethodDriftList, MethodDriftGet, MethodDriftCheck,
 	}
 }
@@ -0,0 +1,188 @@
+package pki
+
+// Signed envelopes let a sprout check that a recipe or command really came
+// from its farmer rather than from anything else able to publish on the
+// bus. The farmer signs each message with its NKey; the sprout verifies the
+// signature against the farmer key it pinned at enrollment, and rejects
+// messages that are stale or that it has already seen.
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nkeys"
+
+	"github.com/gogrlx/grlx/v2/internal/config"
+)
+
+// MaxMessageAge is how far a signed message's timestamp may be from the
+// sprout's clock, in either direction, before the message is rejected.
+const MaxMessageAge = 5 * time.Minute
+
+var (
+	ErrUnsignedMessage   = errors.New("message is not signed by the farmer")
+	ErrBadSignature      = errors.New("message signature does not match the pinned farmer key")
+	ErrWrongSubject      = errors.New("message was signed for a different subject")
+	ErrStaleMessage      = errors.New("message timestamp is outside the allowed window")
+	ErrReplayedMessage   = errors.New("message has already been received")
+	ErrNoPinnedFarmerKey = errors.New("no farmer NKey has been pinned")
+	ErrFarmerKeyChanged  = errors.New("farmer presented an NKey that differs from the pinned key")
+)
+
+// SignedMessage wraps a payload the farmer sends to a sprout. The
+// signature covers every other field, so a message cannot be redirected to
+// another subject, re-dated or re-used under a different job.
+type SignedMessage struct {
+	Subject   string          `json:"subject"`
+	JobID     string          `json:"jid,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+	Nonce     string          `json:"nonce"`
+	Payload   json.RawMessage `json:"payload"`
+	Signature string          `json:"signature"`
+}
+
+// signedBytes returns the bytes the signature is computed over.
+func (m SignedMessage) signedBytes() []byte {
+	return []byte(strings.Join([]string{
+		m.Subject,
+		m.JobID,
+		strconv.FormatInt(m.Timestamp.UnixNano(), 10),
+		m.Nonce,
+		string(m.Payload),
+	}, "\x00"))
+}
+
+// SignMessage wraps payload, which must be JSON, in a SignedMessage for
+// subject, signed with the farmer's NKey.
+func SignMessage(subject, jobID string, payload []byte) ([]byte, error) {
+	seed, err := os.ReadFile(config.NKeyFarmerPrivFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading farmer NKey: %w", err)
+	}
+	kp, err := nkeys.FromSeed(seed)
+	if err != nil {
+		return nil, fmt.Errorf("loading farmer NKey: %w", err)
+	}
+	defer kp.Wipe()
+	return signMessage(kp, subject, jobID, payload, time.Now())
+}
+
+func signMessage(kp nkeys.KeyPair, subject, jobID string, payload []byte, now time.Time) ([]byte, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	msg := SignedMessage{
+		Subject:   subject,
+		JobID:     jobID,
+		Timestamp: now.UTC(),
+		Nonce:     base64.RawURLEncoding.EncodeToString(nonce),
+		Payload:   payload,
+	}
+	sig, err := kp.Sign(msg.signedBytes())
+	if err != nil {
+		return nil, err
+	}
+	msg.Signature = base64.RawURLEncoding.EncodeToString(sig)
+	return json.Marshal(msg)
+}
+
+// MessagePayload returns the payload of a signed message without verifying
+// it, or data unchanged if it is not a signed message. It is for the
+// farmer's own listeners, which only record what was sent.
+func MessagePayload(data []byte) []byte {
+	var msg SignedMessage
+	if json.Unmarshal(data, &msg) != nil || msg.Signature == "" || len(msg.Payload) == 0 {
+		return data
+	}
+	return msg.Payload
+}
+
+// Verifier checks signed messages against a pinned farmer key and
+// remembers the nonces it has accepted until they are too old to pass the
+// timestamp check anyway.
+type Verifier struct {
+	mu     sync.Mutex
+	pubkey string
+	seen   map[string]time.Time
+	now    func() time.Time
+}
+
+// NewVerifier returns a Verifier for messages signed by pubkey.
+func NewVerifier(pubkey string) *Verifier {
+	return &Verifier{
+		pubkey: pubkey,
+		seen:   map[string]time.Time{},
+		now:    time.Now,
+	}
+}
+
+// Verify checks that data is a message signed by the farmer for subject,
+// recent, and not seen before, and returns it.
+func (v *Verifier) Verify(subject string, data []byte) (SignedMessage, error) {
+	var msg SignedMessage
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Signature == "" {
+		return msg, ErrUnsignedMessage
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(msg.Signature)
+	if err != nil {
+		return msg, ErrBadSignature
+	}
+	kp, err := nkeys.FromPublicKey(v.pubkey)
+	if err != nil {
+		return msg, errors.Join(ErrBadSignature, err)
+	}
+	if kp.Verify(msg.signedBytes(), sig) != nil {
+		return msg, ErrBadSignature
+	}
+	if msg.Subject != subject {
+		return msg, errors.Join(ErrWrongSubject, fmt.Errorf("signed for %s, received on %s", msg.Subject, subject))
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	now := v.now()
+	for nonce, ts := range v.seen {
+		if now.Sub(ts) > MaxMessageAge {
+			delete(v.seen, nonce)
+		}
+	}
+	if age := now.Sub(msg.Timestamp); age > MaxMessageAge || age < -MaxMessageAge {
+		return msg, errors.Join(ErrStaleMessage, fmt.Errorf("signed at %s", msg.Timestamp.Format(time.RFC3339)))
+	}
+	if _, ok := v.seen[msg.Nonce]; ok {
+		return msg, ErrReplayedMessage
+	}
+	v.seen[msg.Nonce] = msg.Timestamp
+	return msg, nil
+}
+
+var (
+	farmerVerifier   *Verifier
+	farmerVerifierMu sync.Mutex
+)
+
+// VerifyFarmerMessage verifies data received on subject against the
+// farmer key pinned on this sprout.
+func VerifyFarmerMessage(subject string, data []byte) (SignedMessage, error) {
+	farmerVerifierMu.Lock()
+	if farmerVerifier == nil {
+		pubkey, err := os.ReadFile(config.SproutFarmerNKeyFile)
+		if err != nil {
+			farmerVerifierMu.Unlock()
+			return SignedMessage{}, errors.Join(ErrNoPinnedFarmerKey, err)
+		}
+		farmerVerifier = NewVerifier(strings.TrimSpace(string(pubkey)))
+	}
+	v := farmerVerifier
+	farmerVerifierMu.Unlock()
+	return v.Verify(subject, data)
+}
@@ -0,0 +1,173 @@
+package pki
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nkeys"
+
+	"github.com/gogrlx/grlx/v2/internal/config"
+)
+
+func newTestKey(t *testing.T) (nkeys.KeyPair, string) {
+	t.Helper()
+	kp, err := nkeys.CreateUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubkey, err := kp.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kp, pubkey
+}
+
+func TestVerifier(t *testing.T) {
+	farmer, farmerPub := newTestKey(t)
+	other, _ := newTestKey(t)
+	now := time.Now()
+	subject := "grlx.sprouts.web-01.cook"
+	payload := []byte(`{"JobID":"jid-1"}`)
+
+	sign := func(kp nkeys.KeyPair, subject string, at time.Time) []byte {
+		b, err := signMessage(kp, subject, "jid-1", payload, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	tamper := func(b []byte) []byte {
+		var msg SignedMessage
+		json.Unmarshal(b, &msg)
+		msg.Payload = json.RawMessage(`{"JobID":"jid-2"}`)
+		out, _ := json.Marshal(msg)
+		return out
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		subject string
+		errorIs error
+	}{
+		{name: "valid", data: sign(farmer, subject, now), subject: subject},
+		{name: "slightly ahead", data: sign(farmer, subject, now.Add(time.Minute)), subject: subject},
+		{name: "unsigned", data: payload, subject: subject, errorIs: ErrUnsignedMessage},
+		{name: "not json", data: []byte("nope"), subject: subject, errorIs: ErrUnsignedMessage},
+		{name: "other key", data: sign(other, subject, now), subject: subject, errorIs: ErrBadSignature},
+		{name: "tampered", data: tamper(sign(farmer, subject, now)), subject: subject, errorIs: ErrBadSignature},
+		{name: "other subject", data: sign(farmer, "grlx.sprouts.db-01.cook", now), subject: subject, errorIs: ErrWrongSubject},
+		{name: "stale", data: sign(farmer, subject, now.Add(-MaxMessageAge-time.Second)), subject: subject, errorIs: ErrStaleMessage},
+		{name: "future", data: sign(farmer, subject, now.Add(MaxMessageAge+time.Second)), subject: subject, errorIs: ErrStaleMessage},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := NewVerifier(farmerPub)
+			v.now = func() time.Time { return now }
+			msg, err := v.Verify(tc.subject, tc.data)
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("expected %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if msg.JobID != "jid-1" || string(msg.Payload) != string(payload) {
+				t.Errorf("unexpected message %+v", msg)
+			}
+		})
+	}
+}
+
+func TestVerifierReplay(t *testing.T) {
+	farmer, farmerPub := newTestKey(t)
+	now := time.Now()
+	v := NewVerifier(farmerPub)
+	v.now = func() time.Time { return now }
+	subject := "grlx.sprouts.web-01.cmd.run"
+
+	first, _ := signMessage(farmer, subject, "", []byte(`{}`), now)
+	second, _ := signMessage(farmer, subject, "", []byte(`{}`), now)
+	if _, err := v.Verify(subject, first); err != nil {
+		t.Fatalf("first message: %v", err)
+	}
+	if _, err := v.Verify(subject, first); !errors.Is(err, ErrReplayedMessage) {
+		t.Errorf("expected replay to be rejected, got %v", err)
+	}
+	if _, err := v.Verify(subject, second); err != nil {
+		t.Errorf("expected a new nonce to be accepted, got %v", err)
+	}
+
+	// Once the nonce is old enough to be stale it is forgotten, and the
+	// replay is rejected for its age instead.
+	now = now.Add(MaxMessageAge + time.Second)
+	if _, err := v.Verify(subject, first); !errors.Is(err, ErrStaleMessage) {
+		t.Errorf("expected stale replay to be rejected, got %v", err)
+	}
+	if len(v.seen) != 0 {
+		t.Errorf("expected expired nonces to be dropped, got %d", len(v.seen))
+	}
+}
+
+func TestMessagePayload(t *testing.T) {
+	farmer, _ := newTestKey(t)
+	payload := []byte(`{"JobID":"jid-1"}`)
+	signed, err := signMessage(farmer, "grlx.sprouts.web-01.cook", "jid-1", payload, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := MessagePayload(signed); string(got) != string(payload) {
+		t.Errorf("expected the signed payload, got %s", got)
+	}
+	if got := MessagePayload(payload); string(got) != string(payload) {
+		t.Errorf("expected unsigned data unchanged, got %s", got)
+	}
+}
+
+func TestPinFarmerNKey(t *testing.T) {
+	_, farmerPub := newTestKey(t)
+	served := farmerPub
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pki/farmernkey" {
+			t.Errorf("expected /pki/farmernkey, got %s", r.URL.Path)
+		}
+		w.Write([]byte(served))
+	}))
+	defer ts.Close()
+	config.FarmerURL = ts.URL
+	nkeyClient = ts.Client()
+	config.SproutFarmerNKeyFile = filepath.Join(t.TempDir(), "farmer.nkey.pub")
+
+	if err := PinFarmerNKey(); err != nil {
+		t.Fatalf("PinFarmerNKey failed: %v", err)
+	}
+	pinned, err := os.ReadFile(config.SproutFarmerNKeyFile)
+	if err != nil || string(pinned) != farmerPub {
+		t.Fatalf("expected %s to be pinned, got %q, %v", farmerPub, pinned, err)
+	}
+	if err = PinFarmerNKey(); err != nil {
+		t.Errorf("expected the same key to be accepted again, got %v", err)
+	}
+
+	_, served = newTestKey(t)
+	if err = PinFarmerNKey(); !errors.Is(err, ErrFarmerKeyChanged) {
+		t.Errorf("expected %v, got %v", ErrFarmerKeyChanged, err)
+	}
+	if pinned, _ = os.ReadFile(config.SproutFarmerNKeyFile); string(pinned) != farmerPub {
+		t.Errorf("expected the original pin to be kept, got %q", pinned)
+	}
+
+	served = "not-a-key"
+	config.SproutFarmerNKeyFile = filepath.Join(t.TempDir(), "farmer.nkey.pub")
+	if err = PinFarmerNKey(); err == nil {
+		t.Error("expected an invalid key to be refused")
+	}
+}
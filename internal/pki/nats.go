@@ -10,6 +10,7 @@ import (
 
 	"github.com/gogrlx/grlx/v2/internal/auth"
 	"github.com/gogrlx/grlx/v2/internal/config"
+	"github.com/gogrlx/grlx/v2/internal/rbac"
 
 	nats_server "github.com/nats-io/nats-server/v2/server"
 )
@@ -45,6 +46,10 @@ func ConfigureNats() nats_server.Options {
 		LogFile:               "nats.log",
 		AuthTimeout:           10,
 	}
+	if JetStreamEnabled() {
+		NatsConfig.JetStream = true
+		NatsConfig.StoreDir = config.JetStreamDir
+	}
 	certPool = x509.NewCertPool()
 	rootPEM, err := os.ReadFile(RootCA)
 	if err != nil || rootPEM == nil {
@@ -68,6 +73,12 @@ func ConfigureNats() nats_server.Options {
 	return NatsConfig
 }
 
+// JetStreamEnabled reports whether the farmer keeps job history in
+// JetStream, which must then be enabled on the embedded server.
+func JetStreamEnabled() bool {
+	return config.JobStore == config.JobStoreJetStream
+}
+
 func SetNATSServer(s *nats_server.Server) {
 	NatsServer = s
 }
@@ -80,14 +91,10 @@ func ReloadNKeys() error {
 		log.Fatalf("Could not load the Farmer's NKey, aborting")
 	}
 	log.Tracef("Loaded farmer's public key: %s", farmerKey)
-	grlxKeys, err := auth.GetPubkeysByRole("admin")
-	if err != nil {
-		log.Errorf("Could not load the grlx cli's NKey(s), please edit the config")
-	} else {
-		log.Tracef("Loaded grlx cli's public key(s): %v", grlxKeys)
-	}
 	nkeyUsers := []*nats_server.NkeyUser{}
-	allowAll := nats_server.SubjectPermission{Allow: []string{"grlx.>", "_INBOX.>"}}
+	// $JS.API.> lets the farmer read the job stream. Without JetStream
+	// those requests simply have no responders.
+	allowAll := nats_server.SubjectPermission{Allow: []string{"grlx.>", "_INBOX.>", "$JS.API.>"}}
 
 	farmerPermissions := nats_server.Permissions{}
 	farmerPermissions.Publish = &allowAll
@@ -96,16 +103,11 @@ func ReloadNKeys() error {
 	farmerUser.Permissions = &farmerPermissions
 	farmerUser.Nkey = farmerKey
 
-	grlxPermissions := nats_server.Permissions{}
-	grlxPermissions.Publish = &allowAll
-	grlxPermissions.Subscribe = &allowAll
-	for _, key := range grlxKeys {
-		grlxUser := nats_server.NkeyUser{}
-		grlxUser.Permissions = &grlxPermissions
-		grlxUser.Nkey = key
-		nkeyUsers = append(nkeyUsers, &grlxUser)
+	sproutIDs := make([]string, 0, len(authorizedKeys.Sprouts))
+	for _, account := range authorizedKeys.Sprouts {
+		sproutIDs = append(sproutIDs, account.SproutID)
 	}
-
+	nkeyUsers = append(nkeyUsers, userNKeys(sproutIDs)...)
 	nkeyUsers = append(nkeyUsers, &farmerUser)
 	for _, account := range authorizedKeys.Sprouts {
 		log.Tracef("Adding accepted key `%s` to NATS", account.SproutID)
@@ -116,7 +118,7 @@ func ReloadNKeys() error {
 			continue
 		}
 		accountSubscribe := nats_server.SubjectPermission{Allow: []string{"grlx.sprouts." + account.SproutID + ".>"}}
-		accountPublish := nats_server.SubjectPermission{Allow: []string{"grlx.sprouts.announce." + account.SproutID, "_INBOX.>", "grlx.cook." + account.SproutID + ".>", "grlx.sprouts." + account.SproutID + ".facts"}}
+		accountPublish := nats_server.SubjectPermission{Allow: []string{"grlx.sprouts.announce." + account.SproutID, "_INBOX.>", "grlx.cook." + account.SproutID + ".>", "grlx.sprouts." + account.SproutID + ".facts", "grlx.sprouts." + account.SproutID + ".heartbeat", "grlx.sprouts." + account.SproutID + ".files.>"}}
 		sproutPermissions := nats_server.Permissions{}
 		sproutPermissions.Publish = &accountPublish
 		sproutPermissions.Subscribe = &accountSubscribe
@@ -146,3 +148,99 @@ func ReloadNKeys() error {
 	}
 	return err
 }
+
+// userNKeys provisions an NKey user for every user in the RBAC policy,
+// with permissions derived from the user's role. Users whose role is not
+// defined get no NATS access at all.
+func userNKeys(sproutIDs []string) []*nats_server.NkeyUser {
+	policy := auth.CurrentPolicy()
+	if policy.Users == nil || policy.Roles == nil {
+		log.Errorf("no auth policy loaded, grlx cli users cannot connect")
+		return nil
+	}
+	resolver := auth.CohortResolver(sproutIDs)
+	users := []*nats_server.NkeyUser{}
+	for pubkey, roleName := range policy.Users.All() {
+		role, err := policy.Roles.Get(roleName)
+		if err != nil {
+			log.Errorf("user %s has unknown role %q, not adding to NATS", pubkey, roleName)
+			continue
+		}
+		log.Tracef("Adding user `%s` with role %q to NATS", pubkey, roleName)
+		users = append(users, &nats_server.NkeyUser{
+			Nkey:        pubkey,
+			Permissions: userPermissions(pubkey, role, sproutIDs, resolver),
+		})
+	}
+	return users
+}
+
+// userPermissions returns the NATS permissions for a CLI user holding
+// role. Every user may publish API requests to grlx.api.> and receive the
+// replies in its own inbox. Beyond that a user only gets the subjects the
+// CLI uses directly for the actions its role grants:
+//
+//   - job completions (grlx.cook.<sprout>.<jid>) for the sprouts it may
+//     view or cook on, and the cook trigger if it may cook
+//   - shell session I/O if it may open shells; the farmer only hands out
+//     session IDs after checking the sprout is in scope
+//   - read-only access to the job stream, for replaying jobs, if it may
+//     view every sprout, since a stream consumer is not limited by scope
+//
+// Admins may additionally subscribe to everything, which grlx tail needs.
+func userPermissions(pubkey string, role *rbac.Role, sproutIDs []string, resolver func(string) (map[string]bool, error)) *nats_server.Permissions {
+	publish := []string{"grlx.api.>"}
+	subscribe := []string{auth.InboxPrefix(pubkey) + ".>"}
+
+	switch {
+	case hasGlobalScope(role, rbac.ActionView) || hasGlobalScope(role, rbac.ActionCook):
+		subscribe = append(subscribe, "grlx.cook.>", "grlx.presence.>")
+	default:
+		seen := map[string]bool{}
+		for _, action := range []rbac.Action{rbac.ActionView, rbac.ActionCook} {
+			for _, id := range role.ScopeFilter(action, sproutIDs, resolver) {
+				if !seen[id] {
+					seen[id] = true
+					subscribe = append(subscribe, "grlx.cook."+id+".>", "grlx.presence."+id)
+				}
+			}
+		}
+	}
+	if role.HasAction(rbac.ActionCook) {
+		publish = append(publish, "grlx.farmer.cook.trigger.*")
+	}
+	if role.HasAction(rbac.ActionShell) {
+		publish = append(publish, "grlx.shell.*.input", "grlx.shell.*.resize")
+		subscribe = append(subscribe, "grlx.shell.*.output", "grlx.shell.*.done")
+	}
+	if JetStreamEnabled() && hasGlobalScope(role, rbac.ActionView) {
+		// GRLX_JOBS is jobs.JobStreamName, which cannot be imported here.
+		publish = append(publish,
+			"$JS.API.STREAM.INFO.GRLX_JOBS",
+			"$JS.API.CONSUMER.CREATE.GRLX_JOBS.>",
+			"$JS.API.CONSUMER.INFO.GRLX_JOBS.>",
+			"$JS.API.CONSUMER.DELETE.GRLX_JOBS.>",
+			"$JS.API.CONSUMER.MSG.NEXT.GRLX_JOBS.>",
+		)
+	}
+	if role.HasAction(rbac.ActionAdmin) {
+		subscribe = append(subscribe, "grlx.>", "_INBOX.>")
+	}
+	return &nats_server.Permissions{
+		Publish:   &nats_server.SubjectPermission{Allow: publish},
+		Subscribe: &nats_server.SubjectPermission{Allow: subscribe},
+	}
+}
+
+// hasGlobalScope reports whether role grants action on every sprout.
+func hasGlobalScope(role *rbac.Role, action rbac.Action) bool {
+	for _, rule := range role.Rules {
+		if rule.Action != rbac.ActionAdmin && rule.Action != action {
+			continue
+		}
+		if rule.Scope == "" || rule.Scope == "*" {
+			return true
+		}
+	}
+	return false
+}
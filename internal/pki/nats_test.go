@@ -0,0 +1,173 @@
+package pki
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	nats_server "github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+
+	"github.com/gogrlx/grlx/v2/internal/auth"
+	"github.com/gogrlx/grlx/v2/internal/config"
+	"github.com/gogrlx/grlx/v2/internal/rbac"
+)
+
+// connectWithRole starts an embedded nats-server holding a single user
+// with the permissions userPermissions gives role, and connects as that
+// user. Permission violations the server reports are sent on the
+// returned channel. Like the CLI, it takes replies in the user's own
+// inbox.
+func connectWithRole(t *testing.T, role *rbac.Role, sproutIDs []string, resolver func(string) (map[string]bool, error)) (*nats.Conn, <-chan error) {
+	t.Helper()
+	kp, err := nkeys.CreateUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubkey, _ := kp.PublicKey()
+	ns, err := nats_server.NewServer(&nats_server.Options{
+		Host: "127.0.0.1",
+		Port: -1,
+		Nkeys: []*nats_server.NkeyUser{{
+			Nkey:        pubkey,
+			Permissions: userPermissions(pubkey, role, sproutIDs, resolver),
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go ns.Start()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server failed to become ready")
+	}
+	violations := make(chan error, 16)
+	nc, err := nats.Connect(ns.ClientURL(),
+		nats.Nkey(pubkey, kp.Sign),
+		nats.CustomInboxPrefix(auth.InboxPrefix(pubkey)),
+		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
+			violations <- err
+		}),
+	)
+	if err != nil {
+		ns.Shutdown()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		nc.Close()
+		ns.Shutdown()
+	})
+	return nc, violations
+}
+
+func TestUserPermissions(t *testing.T) {
+	origStore := config.JobStore
+	defer func() { config.JobStore = origStore }()
+	config.JobStore = config.JobStoreJetStream
+
+	resolver := func(name string) (map[string]bool, error) {
+		if name == "web" {
+			return map[string]bool{"web-01": true, "web-02": true}, nil
+		}
+		return nil, rbac.ErrCohortNotFound
+	}
+	sprouts := []string{"db-01", "web-01", "web-02"}
+	tests := []struct {
+		name     string
+		rules    []rbac.Rule
+		pubAllow []string
+		pubDeny  []string
+		subAllow []string
+		subDeny  []string
+	}{
+		{
+			name:     "viewer",
+			rules:    []rbac.Rule{{Action: rbac.ActionView, Scope: "*"}},
+			pubAllow: []string{"grlx.api.jobs.list", "$JS.API.CONSUMER.CREATE.GRLX_JOBS.x"},
+			pubDeny:  []string{"grlx.farmer.cook.trigger.abc", "grlx.shell.s1.input"},
+			subAllow: []string{"grlx.cook.*.abc", "grlx.cook.db-01.abc", "grlx.presence.db-01"},
+			subDeny:  []string{"_INBOX.other", "grlx.sprouts.db-01.cook", "grlx.shell.s1.output"},
+		},
+		{
+			// grlx cook and grlx jobs watch subscribe to a job on each
+			// sprout by name, since a scoped user may not use a wildcard.
+			name: "cohort cook",
+			rules: []rbac.Rule{
+				{Action: rbac.ActionCook, Scope: "cohort:web"},
+				{Action: rbac.ActionView, Scope: "sprout:db-01"},
+			},
+			pubAllow: []string{"grlx.api.cook", "grlx.farmer.cook.trigger.abc"},
+			pubDeny:  []string{"$JS.API.CONSUMER.CREATE.GRLX_JOBS.x"},
+			subAllow: []string{"grlx.cook.db-01.abc", "grlx.cook.web-01.abc", "grlx.cook.web-02.abc", "grlx.presence.db-01"},
+			subDeny:  []string{"grlx.cook.*.abc", "grlx.cook.other-01.abc", "grlx.presence.>"},
+		},
+		{
+			name:     "shell",
+			rules:    []rbac.Rule{{Action: rbac.ActionShell, Scope: "sprout:web-01"}},
+			pubAllow: []string{"grlx.api.shell.start", "grlx.shell.s1.input", "grlx.shell.s1.resize"},
+			subAllow: []string{"grlx.shell.s1.output", "grlx.shell.s1.done"},
+			subDeny:  []string{"grlx.cook.*.abc", "grlx.cook.web-01.abc"},
+		},
+		{
+			name:     "admin",
+			rules:    []rbac.Rule{{Action: rbac.ActionAdmin, Scope: "*"}},
+			pubAllow: []string{"grlx.api.auth.users", "grlx.farmer.cook.trigger.abc", "grlx.shell.s1.input", "$JS.API.STREAM.INFO.GRLX_JOBS"},
+			pubDeny:  []string{"grlx.sprouts.db-01.cook", "$JS.API.STREAM.DELETE.GRLX_JOBS"},
+			subAllow: []string{"grlx.cook.*.abc", "grlx.sprouts.>", "_INBOX.other"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			role := &rbac.Role{Name: tc.name, Rules: tc.rules}
+			nc, violations := connectWithRole(t, role, sprouts, resolver)
+
+			// Everything allowed is done at once; the server reports
+			// violations before it answers the flush.
+			for _, s := range tc.pubAllow {
+				nc.Publish(s, nil)
+			}
+			for _, s := range tc.subAllow {
+				if _, err := nc.SubscribeSync(s); err != nil {
+					t.Fatalf("subscribe to %s: %v", s, err)
+				}
+			}
+			if _, err := nc.SubscribeSync(nc.NewInbox()); err != nil {
+				t.Fatal(err)
+			}
+			if err := nc.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case err := <-violations:
+				t.Fatalf("expected every allowed subject to be permitted, got %v", err)
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			expectViolation := func(kind, subject string) {
+				t.Helper()
+				select {
+				case err := <-violations:
+					if !strings.Contains(err.Error(), "Permissions Violation for "+kind+" to \""+subject+"\"") {
+						t.Errorf("expected a violation for %s, got %v", subject, err)
+					}
+				case <-time.After(2 * time.Second):
+					t.Errorf("expected %s to %s to be denied", kind, subject)
+				}
+			}
+			for _, s := range tc.pubDeny {
+				nc.Publish(s, nil)
+				nc.Flush()
+				expectViolation("Publish", s)
+			}
+			for _, s := range tc.subDeny {
+				sub, err := nc.SubscribeSync(s)
+				if err != nil {
+					t.Fatalf("subscribe to %s: %v", s, err)
+				}
+				nc.Flush()
+				expectViolation("Subscription", s)
+				sub.Unsubscribe()
+			}
+		})
+	}
+}
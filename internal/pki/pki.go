@@ -18,6 +18,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/nats-io/nkeys"
+
 	log "github.com/gogrlx/grlx/v2/internal/log"
 
 	"github.com/gogrlx/grlx/v2/internal/config"
@@ -481,6 +483,59 @@ func PutNKey(id string) error {
 	return nil
 }
 
+// PinFarmerNKey fetches the farmer's public NKey over the verified TLS
+// connection and pins it on first enrollment. Signed messages are checked
+// against the pinned key from then on; if the farmer later presents a
+// different key, ErrFarmerKeyChanged is returned and the pin is kept.
+func PinFarmerNKey() error {
+	pinned, _ := os.ReadFile(config.SproutFarmerNKeyFile)
+	pinnedKey := strings.TrimSpace(string(pinned))
+	key, err := fetchFarmerNKey()
+	if err != nil {
+		if pinnedKey != "" {
+			return nil
+		}
+		return err
+	}
+	if pinnedKey != "" {
+		if key != pinnedKey {
+			return ErrFarmerKeyChanged
+		}
+		return nil
+	}
+	if err = os.WriteFile(config.SproutFarmerNKeyFile, []byte(key), 0o600); err != nil {
+		return fmt.Errorf("failed to pin farmer NKey: %w", err)
+	}
+	log.Noticef("Pinned farmer NKey %s", key)
+	return nil
+}
+
+func fetchFarmerNKey() (string, error) {
+	nkeyClientMu.RLock()
+	client := nkeyClient
+	nkeyClientMu.RUnlock()
+	if client == nil {
+		return "", ErrNKeyClientNotReady
+	}
+	resp, err := client.Get(config.FarmerURL + "/pki/farmernkey")
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch farmer NKey: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch farmer NKey: %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch farmer NKey: %w", err)
+	}
+	key := strings.TrimSpace(string(body))
+	if !nkeys.IsValidPublicUserKey(key) {
+		return "", fmt.Errorf("farmer returned an invalid NKey %q", key)
+	}
+	return key, nil
+}
+
 func GetPubNKey(keyType PubKeyType) (string, error) {
 	var pubFile string
 	switch keyType {
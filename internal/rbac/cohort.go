@@ -383,16 +383,17 @@ func resolveDynamic(c *Cohort, allSproutIDs []string) map[string]bool {
 	for _, sproutID := range allSproutIDs {
 		getProp := props.GetStringPropFunc(sproutID)
 		val := getProp(c.Match.PropName)
-		if matchesPropValue(val, c.Match.PropValue) {
+		if MatchesPropValue(val, c.Match.PropValue) {
 			result[sproutID] = true
 		}
 	}
 	return result
 }
 
-// matchesPropValue checks if a sprout's property value matches the expected value.
-// It supports exact match and glob-style prefix/suffix wildcards.
-func matchesPropValue(actual, expected string) bool {
+// MatchesPropValue reports whether a sprout's property value matches the
+// expected value. It supports exact match and glob-style prefix/suffix
+// wildcards.
+func MatchesPropValue(actual, expected string) bool {
 	if expected == "*" {
 		return actual != ""
 	}
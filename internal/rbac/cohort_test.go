@@ -375,9 +375,9 @@ func TestMatchesPropValue(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.actual+"_"+tt.expected, func(t *testing.T) {
-			got := matchesPropValue(tt.actual, tt.expected)
+			got := MatchesPropValue(tt.actual, tt.expected)
 			if got != tt.want {
-				t.Errorf("matchesPropValue(%q, %q) = %v, want %v", tt.actual, tt.expected, got, tt.want)
+				t.Errorf("MatchesPropValue(%q, %q) = %v, want %v", tt.actual, tt.expected, got, tt.want)
 			}
 		})
 	}
@@ -0,0 +1,87 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/taigrr/jety"
+)
+
+// LoadFromConfig reads the "schedules" section of the farmer config. Each
+// key names a schedule:
+//
+//	schedules:
+//	  nightly-patch:
+//	    cron: "0 3 * * *"
+//	    cohort: webservers
+//	    recipe: patch
+//	    splay: 10m
+//	  disk-check:
+//	    interval: 15m
+//	    cohort: all
+//	    command: df -h
+//
+// A missing section yields no schedules. Invalid schedules are left out
+// and reported together in the error, so that one mistake does not stop
+// the others from running.
+func LoadFromConfig() ([]Entry, error) {
+	raw := jety.GetStringMap("schedules")
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	entries := make([]Entry, 0, len(names))
+	var errs []error
+	for _, name := range names {
+		e, err := parseConfigEntry(name, raw[name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("parsing schedule %q: %w", name, err))
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, errors.Join(errs...)
+}
+
+func parseConfigEntry(name string, raw any) (Entry, error) {
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return Entry{}, fmt.Errorf("%w: schedule %q value is not a map", ErrInvalidSchedule, name)
+	}
+	e := Entry{Name: name, Source: SourceConfig}
+	e.Cron, _ = m["cron"].(string)
+	e.Cohort, _ = m["cohort"].(string)
+	e.Recipe, _ = m["recipe"].(string)
+	e.Command, _ = m["command"].(string)
+	e.Test, _ = m["test"].(bool)
+	var err error
+	if e.Interval, err = parseDuration(m["interval"]); err != nil {
+		return Entry{}, fmt.Errorf("%w: interval: %w", ErrInvalidSchedule, err)
+	}
+	if e.Splay, err = parseDuration(m["splay"]); err != nil {
+		return Entry{}, fmt.Errorf("%w: splay: %w", ErrInvalidSchedule, err)
+	}
+	return e, e.Validate()
+}
+
+// parseDuration accepts a duration string such as "15m", or a number of
+// seconds.
+func parseDuration(v any) (time.Duration, error) {
+	switch d := v.(type) {
+	case nil:
+		return 0, nil
+	case string:
+		return time.ParseDuration(d)
+	case int:
+		return time.Duration(d) * time.Second, nil
+	case int64:
+		return time.Duration(d) * time.Second, nil
+	case float64:
+		return time.Duration(d * float64(time.Second)), nil
+	default:
+		return 0, fmt.Errorf("unexpected value %v", v)
+	}
+}
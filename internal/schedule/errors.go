@@ -0,0 +1,11 @@
+package schedule
+
+import "errors"
+
+var (
+	ErrInvalidCron     = errors.New("invalid cron expression")
+	ErrInvalidSchedule = errors.New("invalid schedule")
+	ErrScheduleExists  = errors.New("a schedule with that name already exists")
+	ErrNoSchedule      = errors.New("no such schedule")
+	ErrConfigSchedule  = errors.New("schedule is defined in the farmer config")
+)
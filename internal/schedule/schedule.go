@@ -0,0 +1,440 @@
+// Package schedule runs cooks and commands on the farmer at set times.
+//
+// A schedule targets a cohort with either a recipe or a shell command,
+// and fires on a cron expression or at a fixed interval. A run may be
+// spread out by a splay, each sprout's job starting after its own random
+// delay, so that the sprouts of a large cohort are not all started in the
+// same instant. Schedules come from the schedules
+// section of the farmer config or from the schedule.* API methods. The
+// API schedules, and the run state and paused flag of every schedule, are
+// kept in a JSON file so that they survive farmer restarts.
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gogrlx/grlx/v2/internal/log"
+)
+
+const (
+	// SourceConfig marks schedules defined in the farmer config.
+	SourceConfig = "config"
+	// SourceAPI marks schedules created through the API.
+	SourceAPI = "api"
+
+	// MinInterval is the shortest interval a schedule may run at.
+	MinInterval = time.Minute
+
+	// InvokerPrefix prefixes the schedule name in the invoker recorded on
+	// the jobs a schedule creates.
+	InvokerPrefix = "schedule:"
+)
+
+// tickInterval is how often a running scheduler checks for due entries.
+var tickInterval = time.Second
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// Entry is a single schedule. Exactly one of Cron and Interval sets when
+// it runs, and exactly one of Recipe and Command sets what it runs.
+type Entry struct {
+	Name     string        `json:"name"`
+	Cron     string        `json:"cron,omitempty"`
+	Interval time.Duration `json:"interval,omitempty"`
+	Cohort   string        `json:"cohort"`
+	Recipe   string        `json:"recipe,omitempty"`
+	Command  string        `json:"command,omitempty"`
+	Test     bool          `json:"test,omitempty"`
+	// Splay delays each sprout's job in a run by its own random duration
+	// of up to Splay.
+	Splay  time.Duration `json:"splay,omitempty"`
+	Paused bool          `json:"paused,omitempty"`
+
+	// Source is SourceConfig or SourceAPI. CreatedBy is the pubkey of the
+	// user who created an API schedule; its runs only reach the sprouts
+	// that user may cook on, or run commands on. Config schedules have no
+	// creator and run with the farmer's authority.
+	Source    string    `json:"source"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	NextRun   time.Time `json:"next_run"`
+	LastRun   time.Time `json:"last_run"`
+	LastJID   string    `json:"last_jid,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Validate checks that the entry is complete and consistent.
+func (e Entry) Validate() error {
+	var problems []error
+	if !validName.MatchString(e.Name) {
+		problems = append(problems, fmt.Errorf("name %q must be 1-64 letters, digits, dots, dashes or underscores", e.Name))
+	}
+	switch {
+	case e.Cron == "" && e.Interval == 0:
+		problems = append(problems, errors.New("one of cron or interval is required"))
+	case e.Cron != "" && e.Interval != 0:
+		problems = append(problems, errors.New("cron and interval cannot both be set"))
+	case e.Cron != "":
+		if _, err := ParseCron(e.Cron); err != nil {
+			problems = append(problems, err)
+		}
+	case e.Interval < MinInterval:
+		problems = append(problems, fmt.Errorf("interval %v is shorter than the minimum of %v", e.Interval, MinInterval))
+	}
+	switch {
+	case e.Recipe == "" && e.Command == "":
+		problems = append(problems, errors.New("one of recipe or command is required"))
+	case e.Recipe != "" && e.Command != "":
+		problems = append(problems, errors.New("recipe and command cannot both be set"))
+	}
+	if e.Cohort == "" {
+		problems = append(problems, errors.New("a target cohort is required"))
+	}
+	if e.Splay < 0 {
+		problems = append(problems, errors.New("splay cannot be negative"))
+	}
+	if len(problems) > 0 {
+		return errors.Join(append([]error{ErrInvalidSchedule}, problems...)...)
+	}
+	return nil
+}
+
+// Invoker is the identity recorded on the jobs the schedule creates.
+func (e Entry) Invoker() string {
+	return InvokerPrefix + e.Name
+}
+
+// RunFunc starts one run of a schedule and returns the job it created.
+// ctx is cancelled when the scheduler stops.
+type RunFunc func(ctx context.Context, e Entry) (jid string, err error)
+
+// Scheduler holds the schedules and starts their runs when they are due.
+type Scheduler struct {
+	path string
+	run  RunFunc
+
+	mu      sync.Mutex
+	entries map[string]*scheduled
+	runs    sync.WaitGroup
+}
+
+// scheduled is an entry along with whether a run of it is in progress.
+type scheduled struct {
+	Entry
+	running bool
+}
+
+type stateFile struct {
+	Schedules []Entry `json:"schedules"`
+}
+
+// New returns a scheduler that keeps its state in the file at path and
+// starts runs with run.
+func New(path string, run RunFunc) *Scheduler {
+	return &Scheduler{
+		path:    path,
+		run:     run,
+		entries: map[string]*scheduled{},
+	}
+}
+
+// Load replaces the config schedules with configEntries, keeps the API
+// schedules from the state file, and restores the run state of both. A
+// config schedule takes the place of an API schedule of the same name.
+// Load may be called again to pick up config changes; entries whose
+// timing has not changed keep their next run, and a run in progress is
+// kept track of either way.
+func (s *Scheduler) Load(configEntries []Entry) error {
+	saved, err := s.readState()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	loaded := map[string]*scheduled{}
+	for _, e := range configEntries {
+		if err = e.Validate(); err != nil {
+			return fmt.Errorf("schedule %s: %w", e.Name, err)
+		}
+		e.Source = SourceConfig
+		e.CreatedBy = ""
+		if prev, ok := saved[e.Name]; ok {
+			if prev.Source == SourceAPI {
+				log.Warnf("config schedule %s replaces the API schedule of the same name", e.Name)
+			}
+			e.Paused = prev.Paused
+			e.CreatedAt = prev.CreatedAt
+			e.LastRun, e.LastJID, e.LastError = prev.LastRun, prev.LastJID, prev.LastError
+		}
+		if e.CreatedAt.IsZero() {
+			e.CreatedAt = now.UTC()
+		}
+		loaded[e.Name] = &scheduled{Entry: e}
+	}
+	for name, e := range saved {
+		if _, ok := loaded[name]; ok || e.Source != SourceAPI {
+			continue
+		}
+		if err = e.Validate(); err != nil {
+			log.Errorf("dropping invalid saved schedule %s: %v", name, err)
+			continue
+		}
+		loaded[name] = &scheduled{Entry: e}
+	}
+	for name, e := range loaded {
+		// A run still in flight carries over whatever the new timing,
+		// so the schedule cannot start a second, overlapping run.
+		prev, ok := s.entries[name]
+		if ok {
+			e.running = prev.running
+		}
+		if ok && sameTiming(prev.Entry, e.Entry) {
+			e.NextRun = prev.NextRun
+			continue
+		}
+		s.reschedule(e, now)
+	}
+	s.entries = loaded
+	return s.writeStateLocked()
+}
+
+func sameTiming(a, b Entry) bool {
+	return a.Cron == b.Cron && a.Interval == b.Interval && a.Splay == b.Splay
+}
+
+// Add creates an API schedule.
+func (s *Scheduler) Add(e Entry) (Entry, error) {
+	if err := e.Validate(); err != nil {
+		return Entry{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[e.Name]; ok {
+		return Entry{}, errors.Join(ErrScheduleExists, fmt.Errorf("schedule %s", e.Name))
+	}
+	now := time.Now()
+	e.Source = SourceAPI
+	e.CreatedAt = now.UTC()
+	e.LastRun, e.LastJID, e.LastError = time.Time{}, "", ""
+	se := &scheduled{Entry: e}
+	s.reschedule(se, now)
+	s.entries[e.Name] = se
+	if err := s.writeStateLocked(); err != nil {
+		delete(s.entries, e.Name)
+		return Entry{}, err
+	}
+	return se.Entry, nil
+}
+
+// Remove deletes an API schedule. Config schedules can only be removed
+// from the config.
+func (s *Scheduler) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	se, ok := s.entries[name]
+	if !ok {
+		return errors.Join(ErrNoSchedule, fmt.Errorf("schedule %s", name))
+	}
+	if se.Source == SourceConfig {
+		return errors.Join(ErrConfigSchedule, fmt.Errorf("schedule %s cannot be deleted through the API", name))
+	}
+	delete(s.entries, name)
+	return s.writeStateLocked()
+}
+
+// SetPaused pauses or resumes a schedule. A resumed schedule runs at its
+// next time from now rather than making up for runs missed while paused.
+func (s *Scheduler) SetPaused(name string, paused bool) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	se, ok := s.entries[name]
+	if !ok {
+		return Entry{}, errors.Join(ErrNoSchedule, fmt.Errorf("schedule %s", name))
+	}
+	if se.Paused == paused {
+		return se.Entry, nil
+	}
+	se.Paused = paused
+	if !paused {
+		s.reschedule(se, time.Now())
+	}
+	return se.Entry, s.writeStateLocked()
+}
+
+// Get returns the named schedule.
+func (s *Scheduler) Get(name string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	se, ok := s.entries[name]
+	if !ok {
+		return Entry{}, errors.Join(ErrNoSchedule, fmt.Errorf("schedule %s", name))
+	}
+	return se.Entry, nil
+}
+
+// List returns every schedule, sorted by name.
+func (s *Scheduler) List() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]Entry, 0, len(s.entries))
+	for _, se := range s.entries {
+		list = append(list, se.Entry)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Start checks for due schedules until ctx is cancelled. The returned
+// channel is closed when the scheduler has stopped and its runs have
+// finished.
+func (s *Scheduler) Start(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(tickInterval)
+		defer ticker.Stop()
+		log.Infof("Scheduler started with %d schedule(s)", len(s.List()))
+		for {
+			select {
+			case <-ctx.Done():
+				s.runs.Wait()
+				log.Info("Scheduler stopped")
+				return
+			case now := <-ticker.C:
+				s.runDue(ctx, now)
+			}
+		}
+	}()
+	return done
+}
+
+// runDue starts every unpaused schedule whose next run is at or before
+// now. A schedule whose previous run is still starting is skipped.
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, se := range s.entries {
+		if se.Paused || se.running || se.NextRun.IsZero() || se.NextRun.After(now) {
+			continue
+		}
+		se.running = true
+		run := se.Entry
+		s.advance(se, now)
+		s.runs.Add(1)
+		go s.start(ctx, run, now)
+	}
+}
+
+func (s *Scheduler) start(ctx context.Context, e Entry, at time.Time) {
+	defer s.runs.Done()
+	log.Noticef("running schedule %s", e.Name)
+	jid, err := s.run(ctx, e)
+	if err != nil {
+		log.Errorf("schedule %s: %v", e.Name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	se, ok := s.entries[e.Name]
+	if !ok {
+		return
+	}
+	se.running = false
+	se.LastRun = at.UTC()
+	se.LastJID = jid
+	se.LastError = ""
+	if err != nil {
+		se.LastError = err.Error()
+	}
+	if writeErr := s.writeStateLocked(); writeErr != nil {
+		log.Errorf("failed to save schedule state: %v", writeErr)
+	}
+}
+
+// reschedule sets the entry's next run from now, as for a new entry.
+func (s *Scheduler) reschedule(se *scheduled, now time.Time) {
+	if se.Interval > 0 {
+		se.NextRun = now.Add(se.Interval)
+	} else {
+		se.NextRun = nextCron(se.Cron, now)
+	}
+}
+
+// advance moves the entry past a run at now. Interval schedules keep their
+// cadence; runs missed while the farmer was down are skipped.
+func (s *Scheduler) advance(se *scheduled, now time.Time) {
+	if se.Interval > 0 {
+		se.NextRun = se.NextRun.Add(se.Interval)
+		if !se.NextRun.After(now) {
+			se.NextRun = now.Add(se.Interval)
+		}
+	} else {
+		se.NextRun = nextCron(se.Cron, now)
+	}
+}
+
+func nextCron(expr string, now time.Time) time.Time {
+	c, err := ParseCron(expr)
+	if err != nil {
+		return time.Time{}
+	}
+	return c.Next(now)
+}
+
+func (s *Scheduler) readState() (map[string]Entry, error) {
+	saved := map[string]Entry{}
+	if s.path == "" {
+		return saved, nil
+	}
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return saved, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state stateFile
+	if err = json.Unmarshal(b, &state); err != nil {
+		return nil, fmt.Errorf("reading schedules from %s: %w", s.path, err)
+	}
+	for _, e := range state.Schedules {
+		saved[e.Name] = e
+	}
+	return saved, nil
+}
+
+// writeStateLocked saves every schedule to the state file. The caller
+// must hold s.mu.
+func (s *Scheduler) writeStateLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	state := stateFile{Schedules: make([]Entry, 0, len(s.entries))}
+	for _, se := range s.entries {
+		state.Schedules = append(state.Schedules, se.Entry)
+	}
+	sort.Slice(state.Schedules, func(i, j int) bool { return state.Schedules[i].Name < state.Schedules[j].Name })
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err = os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
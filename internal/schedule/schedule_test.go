@@ -0,0 +1,229 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/taigrr/jety"
+)
+
+func TestEntryValidate(t *testing.T) {
+	valid := Entry{Name: "nightly", Cron: "0 3 * * *", Cohort: "web", Recipe: "patch"}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected a valid entry, got %v", err)
+	}
+	tests := map[string]func(e *Entry){
+		"bad name":          func(e *Entry) { e.Name = "bad name" },
+		"no timing":         func(e *Entry) { e.Cron = "" },
+		"cron and interval": func(e *Entry) { e.Interval = time.Hour },
+		"bad cron":          func(e *Entry) { e.Cron = "every day" },
+		"short interval":    func(e *Entry) { e.Cron, e.Interval = "", time.Second },
+		"no work":           func(e *Entry) { e.Recipe = "" },
+		"recipe and cmd":    func(e *Entry) { e.Command = "uptime" },
+		"no cohort":         func(e *Entry) { e.Cohort = "" },
+		"negative splay":    func(e *Entry) { e.Splay = -time.Second },
+	}
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := valid
+			mutate(&e)
+			if err := e.Validate(); !errors.Is(err, ErrInvalidSchedule) {
+				t.Errorf("expected %v, got %v", ErrInvalidSchedule, err)
+			}
+		})
+	}
+}
+
+// recordRuns returns a RunFunc that records the schedules it runs.
+func recordRuns() (RunFunc, func() []string) {
+	var mu sync.Mutex
+	var ran []string
+	run := func(_ context.Context, e Entry) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, e.Name)
+		if e.Name == "failing" {
+			return "", errors.New("no sprouts")
+		}
+		return "jid-" + e.Name, nil
+	}
+	return run, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), ran...)
+	}
+}
+
+func TestSchedulerRunDue(t *testing.T) {
+	run, ran := recordRuns()
+	s := New("", run)
+	if err := s.Load(nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []Entry{
+		{Name: "hourly", Interval: time.Hour, Cohort: "all", Recipe: "patch"},
+		{Name: "failing", Interval: time.Hour, Cohort: "all", Command: "uptime"},
+		{Name: "paused", Interval: time.Hour, Cohort: "all", Recipe: "patch"},
+	} {
+		if _, err := s.Add(e); err != nil {
+			t.Fatalf("Add(%s): %v", e.Name, err)
+		}
+	}
+	if _, err := s.SetPaused("paused", true); err != nil {
+		t.Fatal(err)
+	}
+
+	s.runDue(context.Background(), time.Now())
+	s.runs.Wait()
+	if got := ran(); len(got) != 0 {
+		t.Fatalf("expected nothing to be due yet, ran %v", got)
+	}
+
+	at := time.Now().Add(time.Hour + time.Minute)
+	s.runDue(context.Background(), at)
+	s.runs.Wait()
+	if got := ran(); len(got) != 2 {
+		t.Fatalf("expected the two unpaused schedules to run, ran %v", got)
+	}
+	hourly, _ := s.Get("hourly")
+	if hourly.LastJID != "jid-hourly" || hourly.LastError != "" || !hourly.LastRun.Equal(at.UTC()) {
+		t.Errorf("unexpected run state %+v", hourly)
+	}
+	if !hourly.NextRun.After(at) {
+		t.Errorf("expected the next run after %v, got %v", at, hourly.NextRun)
+	}
+	failing, _ := s.Get("failing")
+	if failing.LastError != "no sprouts" {
+		t.Errorf("expected the run error to be kept, got %q", failing.LastError)
+	}
+
+	// Running again at the same time does nothing.
+	s.runDue(context.Background(), at)
+	s.runs.Wait()
+	if got := ran(); len(got) != 2 {
+		t.Errorf("expected no repeat runs, ran %v", got)
+	}
+}
+
+func TestSchedulerReloadDuringRun(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	runs := 0
+	s := New("", func(context.Context, Entry) (string, error) {
+		mu.Lock()
+		runs++
+		mu.Unlock()
+		<-release
+		return "jid", nil
+	})
+	config := []Entry{{Name: "hourly", Interval: time.Hour, Cohort: "all", Recipe: "patch"}}
+	if err := s.Load(config); err != nil {
+		t.Fatal(err)
+	}
+	s.runDue(context.Background(), time.Now().Add(time.Hour+time.Minute))
+
+	// Changing the timing while the run is in flight reschedules the
+	// entry, but does not let it start a second run.
+	config[0].Interval = 2 * time.Hour
+	if err := s.Load(config); err != nil {
+		t.Fatal(err)
+	}
+	s.runDue(context.Background(), time.Now().Add(24*time.Hour))
+	close(release)
+	s.runs.Wait()
+	if runs != 1 {
+		t.Errorf("expected one run while the first was in flight, got %d", runs)
+	}
+	if e, _ := s.Get("hourly"); e.Interval != 2*time.Hour || e.LastJID != "jid" {
+		t.Errorf("expected the reloaded schedule to record the run, got %+v", e)
+	}
+}
+
+func TestSchedulerPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedules.json")
+	run, _ := recordRuns()
+	config := []Entry{{Name: "from-config", Cron: "@daily", Cohort: "all", Recipe: "base"}}
+
+	s := New(path, run)
+	if err := s.Load(config); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(Entry{Name: "from-api", Interval: time.Hour, Cohort: "web", Command: "uptime", CreatedBy: "UKEY"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(Entry{Name: "from-api", Interval: time.Hour, Cohort: "web", Command: "uptime"}); !errors.Is(err, ErrScheduleExists) {
+		t.Errorf("expected %v, got %v", ErrScheduleExists, err)
+	}
+	if _, err := s.SetPaused("from-config", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Remove("from-config"); !errors.Is(err, ErrConfigSchedule) {
+		t.Errorf("expected %v, got %v", ErrConfigSchedule, err)
+	}
+	if err := s.Remove("missing"); !errors.Is(err, ErrNoSchedule) {
+		t.Errorf("expected %v, got %v", ErrNoSchedule, err)
+	}
+
+	restarted := New(path, run)
+	if err := restarted.Load(config); err != nil {
+		t.Fatal(err)
+	}
+	list := restarted.List()
+	if len(list) != 2 || list[0].Name != "from-api" || list[1].Name != "from-config" {
+		t.Fatalf("expected both schedules after a restart, got %+v", list)
+	}
+	if list[0].Source != SourceAPI || list[0].CreatedBy != "UKEY" {
+		t.Errorf("expected the API schedule's creator to be kept, got %+v", list[0])
+	}
+	if !list[1].Paused || list[1].Source != SourceConfig {
+		t.Errorf("expected the config schedule to stay paused, got %+v", list[1])
+	}
+
+	// Dropping a schedule from the config removes it.
+	if err := restarted.Load(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := restarted.Get("from-config"); !errors.Is(err, ErrNoSchedule) {
+		t.Errorf("expected the config schedule to be gone, got %v", err)
+	}
+	if err := restarted.Remove("from-api"); err != nil {
+		t.Errorf("Remove: %v", err)
+	}
+}
+
+func TestLoadFromConfig(t *testing.T) {
+	jety.Set("schedules", map[string]any{
+		"nightly": map[string]any{"cron": "0 3 * * *", "cohort": "web", "recipe": "patch", "splay": "10m"},
+		"uptime":  map[string]any{"interval": "15m", "cohort": "all", "command": "uptime", "test": true},
+	})
+	defer jety.Set("schedules", nil)
+	entries, err := LoadFromConfig()
+	if err != nil {
+		t.Fatalf("LoadFromConfig: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected two schedules, got %+v", entries)
+	}
+	if entries[0].Name != "nightly" || entries[0].Splay != 10*time.Minute || entries[0].Source != SourceConfig {
+		t.Errorf("unexpected entry %+v", entries[0])
+	}
+	if entries[1].Interval != 15*time.Minute || !entries[1].Test {
+		t.Errorf("unexpected entry %+v", entries[1])
+	}
+
+	jety.Set("schedules", map[string]any{
+		"broken": map[string]any{"cohort": "web"},
+		"uptime": map[string]any{"interval": "15m", "cohort": "all", "command": "uptime"},
+	})
+	entries, err = LoadFromConfig()
+	if !errors.Is(err, ErrInvalidSchedule) {
+		t.Errorf("expected %v, got %v", ErrInvalidSchedule, err)
+	}
+	if len(entries) != 1 || entries[0].Name != "uptime" {
+		t.Errorf("expected the valid schedule to be kept, got %+v", entries)
+	}
+}
@@ -162,6 +162,10 @@ func formatStepMessage(step cook.StepCompletion, jid string) string {
 		status = "failed"
 	case cook.StepSkipped:
 		status = "skipped"
+	case cook.StepTimedOut:
+		status = "timed out"
+	case cook.StepCancelled:
+		status = "cancelled"
 	case cook.StepCompleted:
 		status = "completed"
 	}